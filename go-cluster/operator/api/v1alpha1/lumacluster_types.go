@@ -7,11 +7,164 @@ import (
 // LumaClusterSpec defines the desired state of LumaCluster
 type LumaClusterSpec struct {
 	// Replicas is the number of LumaDB nodes
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
 	Replicas int32 `json:"replicas,omitempty"`
 	// Image is the container image to use
 	Image string `json:"image,omitempty"`
-	// StorageSize is the size of the PVC (e.g. "10Gi")
+	// StorageSize is the size of the PVC (e.g. "10Gi"). Superseded by
+	// Storage.Size; kept for clusters created before Storage was added.
 	StorageSize string `json:"storageSize,omitempty"`
+	// Storage configures the PVC generated for each replica. Defaults to a
+	// 10Gi PVC on the cluster's default StorageClass when unset.
+	Storage *StorageSpec `json:"storage,omitempty"`
+	// UpdateStrategy controls how a rolling upgrade proceeds across replicas.
+	UpdateStrategy UpdateStrategySpec `json:"updateStrategy,omitempty"`
+	// DefaultRetention, if set, is applied to every collection created
+	// without an explicit retention policy of its own.
+	DefaultRetention *RetentionPolicySpec `json:"defaultRetention,omitempty"`
+	// RetentionPolicies declares the TDengine-dialect retention policy for
+	// each named database up front, so the operator can reconcile them on
+	// cluster bootstrap instead of an operator issuing CREATE DATABASE ...
+	// KEEP/DURATION/REPLICA by hand against every database after the
+	// cluster comes up.
+	RetentionPolicies []DatabaseRetentionPolicySpec `json:"retentionPolicies,omitempty"`
+	// Subscriptions declares a tdengine CREATE SUBSCRIPTION to create on
+	// cluster bootstrap, so writes fan out to external sinks from the
+	// moment a database exists instead of an operator issuing the SQL by
+	// hand afterwards.
+	Subscriptions []SubscriptionSpec `json:"subscriptions,omitempty"`
+	// HintedHandoff configures the per-source hint queue a federated source
+	// falls back to while its target is unreachable.
+	HintedHandoff HintedHandoffSpec `json:"hintedHandoff,omitempty"`
+	// Ingestion toggles the cluster's schemaless ingestion endpoints beyond
+	// the always-on SQL and InfluxDB HTTP write paths.
+	Ingestion IngestionSpec `json:"ingestion,omitempty"`
+	// Federation lists the upstream LumaSources this cluster federates
+	// from.
+	Federation []FederatedSourceSpec `json:"federation,omitempty"`
+}
+
+// SubscriptionSpec is one entry in LumaClusterSpec.Subscriptions, mirroring
+// tdengine.Subscription's shape for the CRD.
+type SubscriptionSpec struct {
+	// Name identifies the subscription (tdengine's CREATE SUBSCRIPTION name).
+	Name string `json:"name"`
+	// Database is the database this subscription watches.
+	Database string `json:"database"`
+	// Measurement restricts the subscription to one measurement; empty
+	// matches every measurement in Database.
+	Measurement string `json:"measurement,omitempty"`
+	// Mode is "ALL" (fan out to every destination) or "ANY" (load-balance
+	// across destinations). Defaults to "ALL" when unset.
+	Mode string `json:"mode,omitempty"`
+	// Destinations are the HTTP sinks writes matching this subscription are
+	// forwarded to.
+	Destinations []string `json:"destinations"`
+}
+
+// HintedHandoffSpec mirrors federation.HintedHandoffConfig for use in the
+// CRD, where metav1.Duration (not Go's raw time.Duration) is the convention
+// for JSON/YAML-friendly durations.
+type HintedHandoffSpec struct {
+	// MaxQueueSize caps how many undelivered hints a single source may
+	// spool; once full, the oldest hint is dropped to make room for each new
+	// one. Defaults to 10000 when unset.
+	MaxQueueSize int32 `json:"maxQueueSize,omitempty"`
+	// MaxHintAge discards a hint once it has waited this long for
+	// delivery. Defaults to 24h when unset.
+	MaxHintAge metav1.Duration `json:"maxHintAge,omitempty"`
+	// BaseBackoff is the delay before the first redelivery attempt after a
+	// failed delivery. Defaults to 500ms when unset.
+	BaseBackoff metav1.Duration `json:"baseBackoff,omitempty"`
+	// MaxBackoff caps the exponential backoff between redelivery attempts.
+	// Defaults to 60s when unset.
+	MaxBackoff metav1.Duration `json:"maxBackoff,omitempty"`
+}
+
+// IngestionSpec toggles the cluster's schemaless ingestion endpoints and,
+// for protocols that have one, the port their raw-socket listener binds.
+type IngestionSpec struct {
+	// InfluxDB gates the /influxdb/v1/write HTTP endpoint.
+	InfluxDB *ProtocolIngestionSpec `json:"influxdb,omitempty"`
+	// OpenTSDB gates the /api/put HTTP endpoint and the raw telnet listener.
+	OpenTSDB *ProtocolIngestionSpec `json:"opentsdb,omitempty"`
+	// Graphite gates the /graphite/write HTTP endpoint and the raw Carbon
+	// TCP listener.
+	Graphite *ProtocolIngestionSpec `json:"graphite,omitempty"`
+}
+
+// ProtocolIngestionSpec is shared by every protocol in IngestionSpec.
+type ProtocolIngestionSpec struct {
+	// Enabled turns the protocol's HTTP endpoint (and raw listener, where
+	// one exists) on or off.
+	Enabled bool `json:"enabled"`
+	// ListenerPort binds a raw-socket listener alongside the protocol's
+	// HTTP endpoint, for protocols that have one (OpenTSDB telnet,
+	// Graphite Carbon). Ignored for protocols with no raw-socket form.
+	ListenerPort int32 `json:"listenerPort,omitempty"`
+}
+
+// FederatedSourceSpec is one entry in LumaClusterSpec.Federation, mirroring
+// federation.Source's identity plus how the cluster reaches and
+// authenticates against it.
+type FederatedSourceSpec struct {
+	// Name identifies the source within the cluster's federation registry.
+	Name string `json:"name"`
+	// Kind is the source driver, e.g. "influxdb" or "opentsdb".
+	Kind string `json:"kind"`
+	// Endpoint is the upstream's base URL.
+	Endpoint string `json:"endpoint"`
+	// CredentialsSecretRef names a Secret in the same namespace holding the
+	// credentials needed to authenticate against Endpoint.
+	CredentialsSecretRef string `json:"credentialsSecretRef,omitempty"`
+}
+
+// DatabaseRetentionPolicySpec is one entry in LumaClusterSpec.RetentionPolicies:
+// the TDengine dialect's per-database KEEP/DURATION/REPLICA, not to be
+// confused with RetentionPolicySpec's per-collection document retention.
+type DatabaseRetentionPolicySpec struct {
+	// Database is the name of the database this policy applies to.
+	Database string `json:"database"`
+	// Keep is how long a point is kept before the sweeper drops it
+	// (TDengine's KEEP clause).
+	Keep metav1.Duration `json:"keep"`
+	// ShardGroupDuration buckets Keep's window into shard groups
+	// (TDengine's DURATION clause). Defaults to Keep when unset.
+	ShardGroupDuration metav1.Duration `json:"shardGroupDuration,omitempty"`
+	// ReplicaN is the replica count for each shard group (TDengine's
+	// REPLICA clause). Defaults to 1.
+	ReplicaN int32 `json:"replicaN,omitempty"`
+}
+
+// StorageSpec configures the PVC generated for each StatefulSet replica.
+type StorageSpec struct {
+	// ClassName is the StorageClass to request. Empty uses the cluster's
+	// default StorageClass.
+	ClassName string `json:"className,omitempty"`
+	// Size is the PVC size (e.g. "10Gi"). Defaults to "10Gi" when unset.
+	Size string `json:"size,omitempty"`
+}
+
+// UpdateStrategySpec controls how a rolling upgrade proceeds across replicas.
+type UpdateStrategySpec struct {
+	// PreStopDrain, when true, adds a preStop lifecycle hook that cordons a
+	// node via the cluster API before its pod is terminated, so in-flight
+	// requests finish elsewhere first.
+	PreStopDrain bool `json:"preStopDrain,omitempty"`
+}
+
+// RetentionPolicySpec mirrors cluster.RetentionPolicy for use in the CRD,
+// where metav1.Duration (not Go's raw time.Duration) is the convention for
+// JSON/YAML-friendly durations.
+type RetentionPolicySpec struct {
+	// Duration is how long a document is kept before the sweeper drops it.
+	Duration metav1.Duration `json:"duration"`
+	// ShardGroupDuration buckets Duration's window into shard groups.
+	// Defaults to Duration when unset.
+	ShardGroupDuration metav1.Duration `json:"shardGroupDuration,omitempty"`
+	// ReplicaN is the replica count for each shard group. Defaults to 1.
+	ReplicaN int32 `json:"replicaN,omitempty"`
 }
 
 // LumaClusterStatus defines the observed state of LumaCluster
@@ -20,6 +173,33 @@ type LumaClusterStatus struct {
 	ActiveNodes int32 `json:"activeNodes"`
 	// Phase is the current state (Initializing, Running, Failed)
 	Phase string `json:"phase"`
+	// Retention summarizes the cluster's retention policy state.
+	Retention *RetentionStatus `json:"retention,omitempty"`
+	// RetentionReady reports whether every configured retention policy -
+	// spec.defaultRetention and each spec.retentionPolicies entry - has
+	// been pushed down to the StatefulSet's pod template. Like
+	// Retention.DefaultApplied, this is a push-down signal only; the
+	// reconciler has no live client to confirm a node actually applied it.
+	RetentionReady bool `json:"retentionReady,omitempty"`
+	// HintedHandoffBacklog is the total undelivered hint count last
+	// reported by the cluster's own REST admin API, summed across every
+	// source. Nil until the cluster has reported at least once.
+	HintedHandoffBacklog *int32 `json:"hintedHandoffBacklog,omitempty"`
+	// FederatedSourcesHealthy reports whether every spec.federation entry
+	// last reported healthy via the cluster's REST admin API. Nil until
+	// the cluster has reported at least once.
+	FederatedSourcesHealthy *bool `json:"federatedSourcesHealthy,omitempty"`
+	// PodPhases maps each replica's pod name to its current phase, for
+	// visibility into a rolling upgrade in progress.
+	PodPhases map[string]string `json:"podPhases,omitempty"`
+}
+
+// RetentionStatus is the aggregate retention policy state surfaced on
+// LumaCluster.Status.
+type RetentionStatus struct {
+	// DefaultApplied reports whether spec.defaultRetention has been pushed
+	// down to the StatefulSet's pod template.
+	DefaultApplied bool `json:"defaultApplied"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// adminHTTPTimeout bounds how long the reconciler waits on a node's REST
+// admin API before giving up, so a single slow or unreachable node can't
+// stall reconciliation.
+const adminHTTPTimeout = 5 * time.Second
+
+// hintedHandoffStatsResponse mirrors the JSON federation.Stats encodes, as
+// served by a node's GET /admin/hintedhandoff/stats.
+type hintedHandoffStatsResponse map[string]struct {
+	Depth int64 `json:"depth"`
+}
+
+// federatedSourceHealthResponse mirrors a node's GET /admin/federation/health.
+type federatedSourceHealthResponse map[string]struct {
+	Healthy bool `json:"healthy"`
+}
+
+// dataNodesResponse mirrors a node's GET /admin/meta/datanodes: the
+// replicated meta FSM's DataNodes list, one entry per node that has joined
+// the cluster.
+type dataNodesResponse []struct {
+	ID uint64 `json:"id"`
+}
+
+// fetchDataNodeCount returns the number of data nodes the replicated meta
+// FSM reports, or ok=false if the admin API can't be reached.
+func fetchDataNodeCount(ctx context.Context, baseURL string) (int32, bool) {
+	var nodes dataNodesResponse
+	if !getAdminJSON(ctx, baseURL+"/admin/meta/datanodes", &nodes) {
+		return 0, false
+	}
+	return int32(len(nodes)), true
+}
+
+// fetchHintedHandoffBacklog sums the queue depth a node's hinted handoff
+// admin endpoint reports across every source. It reports ok=false rather
+// than an error on any failure, since an unreachable cluster - mid-bootstrap,
+// say - is routine, not something Reconcile should fail over.
+func fetchHintedHandoffBacklog(ctx context.Context, baseURL string) (int32, bool) {
+	var stats hintedHandoffStatsResponse
+	if !getAdminJSON(ctx, baseURL+"/admin/hintedhandoff/stats", &stats) {
+		return 0, false
+	}
+	var total int32
+	for _, s := range stats {
+		total += int32(s.Depth)
+	}
+	return total, true
+}
+
+// fetchFederatedSourcesHealthy reports whether every federated source a
+// node knows about last reported healthy.
+func fetchFederatedSourcesHealthy(ctx context.Context, baseURL string) (bool, bool) {
+	var health federatedSourceHealthResponse
+	if !getAdminJSON(ctx, baseURL+"/admin/federation/health", &health) {
+		return false, false
+	}
+	for _, h := range health {
+		if !h.Healthy {
+			return false, true
+		}
+	}
+	return true, true
+}
+
+// getAdminJSON GETs url and decodes its JSON body into out, returning false
+// on any request, transport, status or decode error.
+func getAdminJSON(ctx context.Context, url string, out interface{}) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+	client := &http.Client{Timeout: adminHTTPTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	return json.NewDecoder(resp.Body).Decode(out) == nil
+}
@@ -2,19 +2,30 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
 
+	certmanagerv1 "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	lumav1alpha1 "github.com/lumadb/cluster/operator/api/v1alpha1"
 )
 
+// defaultPVCSize is used for each replica's data volume when neither
+// spec.storage.size nor the deprecated spec.storageSize is set.
+const defaultPVCSize = "10Gi"
+
 // LumaClusterReconciler reconciles a LumaCluster object
 type LumaClusterReconciler struct {
 	client.Client
@@ -25,6 +36,8 @@ type LumaClusterReconciler struct {
 // +kubebuilder:rbac:groups=luma.db,resources=lumaclusters/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch;delete
 
 func (r *LumaClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	l := log.FromContext(ctx)
@@ -40,10 +53,45 @@ func (r *LumaClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
-	// 2. Define the desired StatefulSet
-	ss := r.statefulSetForLumaCluster(lumaCluster)
+	// 2. Reconcile the headless and client Services
+	if err := r.reconcileService(ctx, lumaCluster, r.headlessServiceForLumaCluster(lumaCluster)); err != nil {
+		l.Error(err, "Failed to reconcile headless Service")
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileService(ctx, lumaCluster, r.clientServiceForLumaCluster(lumaCluster)); err != nil {
+		l.Error(err, "Failed to reconcile client Service")
+		return ctrl.Result{}, err
+	}
+
+	// 3. Reconcile the mTLS Certificate (cert-manager issues the Secret it names)
+	cert := r.certificateForLumaCluster(lumaCluster)
+	if err := controllerutil.SetControllerReference(lumaCluster, cert, r.Scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+	foundCert := &certmanagerv1.Certificate{}
+	err = r.Get(ctx, client.ObjectKey{Name: cert.Name, Namespace: cert.Namespace}, foundCert)
+	if err != nil && errors.IsNotFound(err) {
+		l.Info("Creating Certificate", "Certificate.Name", cert.Name)
+		if err := r.Create(ctx, cert); err != nil {
+			l.Error(err, "Failed to create Certificate")
+			return ctrl.Result{}, err
+		}
+	} else if err != nil {
+		l.Error(err, "Failed to get Certificate")
+		return ctrl.Result{}, err
+	}
+
+	// 4. Define the desired StatefulSet
+	ss, err := r.statefulSetForLumaCluster(lumaCluster)
+	if err != nil {
+		l.Error(err, "Failed to build StatefulSet")
+		return ctrl.Result{}, err
+	}
+	if err := controllerutil.SetControllerReference(lumaCluster, ss, r.Scheme); err != nil {
+		return ctrl.Result{}, err
+	}
 
-	// 3. Check if StatefulSet exists
+	// 5. Check if StatefulSet exists
 	found := &appsv1.StatefulSet{}
 	err = r.Get(ctx, client.ObjectKey{Name: ss.Name, Namespace: ss.Namespace}, found)
 	if err != nil && errors.IsNotFound(err) {
@@ -60,10 +108,86 @@ func (r *LumaClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
-	// 4. Update Status (Active Nodes)
-	if found.Status.ReadyReplicas != lumaCluster.Status.ActiveNodes {
-		lumaCluster.Status.ActiveNodes = found.Status.ReadyReplicas
+	// 6. Roll forward the pod template when the container image changes.
+	// Other spec changes (replicas, env, storage) are not applied to an
+	// existing StatefulSet. OrderedReady pod management (set on the desired
+	// StatefulSet) keeps this a controlled, one-pod-at-a-time upgrade.
+	if found.Spec.Template.Spec.Containers[0].Image != ss.Spec.Template.Spec.Containers[0].Image {
+		found.Spec.Template = ss.Spec.Template
+		if err := r.Update(ctx, found); err != nil {
+			l.Error(err, "Failed to roll out StatefulSet update")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// 7. Report per-pod phases, not just the ReadyReplicas count.
+	podPhases, err := r.podPhasesForLumaCluster(ctx, lumaCluster)
+	if err != nil {
+		l.Error(err, "Failed to list pods for status")
+		return ctrl.Result{}, err
+	}
+
+	statusChanged := false
+	activeNodes := found.Status.ReadyReplicas
+	// On a multi-node cluster, the replicated meta FSM's DataNodes list -
+	// not just how many pods the StatefulSet reports Ready - is the
+	// authoritative count of nodes that have actually joined the cluster.
+	// Fall back to ReadyReplicas when the admin API can't be reached yet
+	// (e.g. mid-bootstrap) or the cluster is single-node, where there's no
+	// FSM to ask.
+	if lumaCluster.Spec.Replicas > 1 && found.Status.ReadyReplicas > 0 {
+		adminURL := fmt.Sprintf("http://%s-client.%s.svc.cluster.local:8080", lumaCluster.Name, lumaCluster.Namespace)
+		if n, ok := fetchDataNodeCount(ctx, adminURL); ok {
+			activeNodes = n
+		}
+	}
+	if activeNodes != lumaCluster.Status.ActiveNodes {
+		lumaCluster.Status.ActiveNodes = activeNodes
 		lumaCluster.Status.Phase = "Running"
+		statusChanged = true
+	}
+	if !podPhasesEqual(lumaCluster.Status.PodPhases, podPhases) {
+		lumaCluster.Status.PodPhases = podPhases
+		statusChanged = true
+	}
+
+	// 8. Surface retention policy state. This reconciler has no live client to
+	// the running cluster's actual retention metadata, so CollectionsManaged
+	// isn't tracked here - only whether spec.defaultRetention was pushed down
+	// to the StatefulSet's pod template, which is all it can honestly know.
+	wantRetention := &lumav1alpha1.RetentionStatus{DefaultApplied: lumaCluster.Spec.DefaultRetention != nil}
+	if lumaCluster.Status.Retention == nil || *lumaCluster.Status.Retention != *wantRetention {
+		lumaCluster.Status.Retention = wantRetention
+		statusChanged = true
+	}
+	wantRetentionReady := lumaCluster.Spec.DefaultRetention != nil || len(lumaCluster.Spec.RetentionPolicies) > 0
+	if lumaCluster.Status.RetentionReady != wantRetentionReady {
+		lumaCluster.Status.RetentionReady = wantRetentionReady
+		statusChanged = true
+	}
+
+	// 9. Diff-apply hinted-handoff backlog and federated source health
+	// against the running cluster's own REST admin API, best-effort: until
+	// at least one pod is Ready there's nothing to ask, and a failed poll
+	// just leaves the last known status untouched rather than failing
+	// reconciliation.
+	if lumaCluster.Status.ActiveNodes > 0 {
+		adminURL := fmt.Sprintf("http://%s-client.%s.svc.cluster.local:8080", lumaCluster.Name, lumaCluster.Namespace)
+		if depth, ok := fetchHintedHandoffBacklog(ctx, adminURL); ok {
+			if lumaCluster.Status.HintedHandoffBacklog == nil || *lumaCluster.Status.HintedHandoffBacklog != depth {
+				lumaCluster.Status.HintedHandoffBacklog = &depth
+				statusChanged = true
+			}
+		}
+		if healthy, ok := fetchFederatedSourcesHealthy(ctx, adminURL); ok {
+			if lumaCluster.Status.FederatedSourcesHealthy == nil || *lumaCluster.Status.FederatedSourcesHealthy != healthy {
+				lumaCluster.Status.FederatedSourcesHealthy = &healthy
+				statusChanged = true
+			}
+		}
+	}
+
+	if statusChanged {
 		if err := r.Status().Update(ctx, lumaCluster); err != nil {
 			l.Error(err, "Failed to update LumaCluster status")
 			return ctrl.Result{}, err
@@ -73,10 +197,212 @@ func (r *LumaClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	return ctrl.Result{}, nil
 }
 
-func (r *LumaClusterReconciler) statefulSetForLumaCluster(l *lumav1alpha1.LumaCluster) *appsv1.StatefulSet {
-	labels := map[string]string{"app": "luma-db", "luma_cr": l.Name}
+// reconcileService creates svc if it doesn't exist yet. Services are
+// effectively immutable once created (ClusterIP, selectors), so unlike the
+// StatefulSet there's nothing to roll forward here.
+func (r *LumaClusterReconciler) reconcileService(ctx context.Context, l *lumav1alpha1.LumaCluster, svc *corev1.Service) error {
+	if err := controllerutil.SetControllerReference(l, svc, r.Scheme); err != nil {
+		return err
+	}
+	found := &corev1.Service{}
+	err := r.Get(ctx, client.ObjectKey{Name: svc.Name, Namespace: svc.Namespace}, found)
+	if err != nil && errors.IsNotFound(err) {
+		return r.Create(ctx, svc)
+	}
+	return err
+}
+
+// podPhasesForLumaCluster maps each replica pod's name to its current phase.
+func (r *LumaClusterReconciler) podPhasesForLumaCluster(ctx context.Context, l *lumav1alpha1.LumaCluster) (map[string]string, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(l.Namespace), client.MatchingLabels(lumaClusterLabels(l))); err != nil {
+		return nil, err
+	}
+	phases := make(map[string]string, len(pods.Items))
+	for _, pod := range pods.Items {
+		phases[pod.Name] = string(pod.Status.Phase)
+	}
+	return phases, nil
+}
+
+func podPhasesEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, phase := range a {
+		if b[name] != phase {
+			return false
+		}
+	}
+	return true
+}
+
+func lumaClusterLabels(l *lumav1alpha1.LumaCluster) map[string]string {
+	return map[string]string{"app": "luma-db", "luma_cr": l.Name}
+}
+
+// headlessServiceForLumaCluster is the StatefulSet's governing Service,
+// giving each pod a stable DNS identity for Raft peer discovery.
+func (r *LumaClusterReconciler) headlessServiceForLumaCluster(l *lumav1alpha1.LumaCluster) *corev1.Service {
+	labels := lumaClusterLabels(l)
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      l.Name + "-headless",
+			Namespace: l.Namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Selector:  labels,
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: 8080},
+				{Name: "grpc", Port: 9090},
+				{Name: "raft", Port: 10000},
+			},
+		},
+	}
+}
+
+// clientServiceForLumaCluster is the load-balanced entry point clients use
+// for HTTP traffic; it doesn't need to resolve to a specific pod.
+func (r *LumaClusterReconciler) clientServiceForLumaCluster(l *lumav1alpha1.LumaCluster) *corev1.Service {
+	labels := lumaClusterLabels(l)
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      l.Name + "-client",
+			Namespace: l.Namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: labels,
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: 8080},
+			},
+		},
+	}
+}
+
+// certificateForLumaCluster requests a cert-manager Certificate for mTLS
+// between nodes; cert-manager writes the resulting keypair to SecretName,
+// which statefulSetForLumaCluster mounts into every pod.
+func (r *LumaClusterReconciler) certificateForLumaCluster(l *lumav1alpha1.LumaCluster) *certmanagerv1.Certificate {
+	return &certmanagerv1.Certificate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      l.Name + "-tls",
+			Namespace: l.Namespace,
+			Labels:    lumaClusterLabels(l),
+		},
+		Spec: certmanagerv1.CertificateSpec{
+			SecretName: l.Name + "-tls",
+			DNSNames: []string{
+				fmt.Sprintf("*.%s-headless.%s.svc.cluster.local", l.Name, l.Namespace),
+				fmt.Sprintf("%s-client.%s.svc.cluster.local", l.Name, l.Namespace),
+			},
+			IssuerRef: certmanagerv1.ObjectReference{
+				Name: l.Name + "-issuer",
+				Kind: "Issuer",
+			},
+		},
+	}
+}
+
+func (r *LumaClusterReconciler) statefulSetForLumaCluster(l *lumav1alpha1.LumaCluster) (*appsv1.StatefulSet, error) {
+	labels := lumaClusterLabels(l)
 	replicas := l.Spec.Replicas
 
+	env := []corev1.EnvVar{
+		{
+			Name: "LUMA_NODE_ID",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+			},
+		},
+	}
+	if dr := l.Spec.DefaultRetention; dr != nil {
+		env = append(env,
+			corev1.EnvVar{Name: "LUMA_DEFAULT_RETENTION_DURATION", Value: dr.Duration.Duration.String()},
+			corev1.EnvVar{Name: "LUMA_DEFAULT_RETENTION_SHARD_GROUP_DURATION", Value: dr.ShardGroupDuration.Duration.String()},
+			corev1.EnvVar{Name: "LUMA_DEFAULT_RETENTION_REPLICA_N", Value: strconv.Itoa(int(dr.ReplicaN))},
+		)
+	}
+	if len(l.Spec.RetentionPolicies) > 0 {
+		if encoded, err := json.Marshal(l.Spec.RetentionPolicies); err == nil {
+			// Passed as a single JSON-encoded env var, not one var per
+			// field like LUMA_DEFAULT_RETENTION_* above, since the node
+			// has to apply a CREATE DATABASE ... KEEP/DURATION/REPLICA
+			// per entry on bootstrap rather than just one fixed default.
+			env = append(env, corev1.EnvVar{Name: "LUMA_RETENTION_POLICIES", Value: string(encoded)})
+		}
+	}
+	if len(l.Spec.Subscriptions) > 0 {
+		if encoded, err := json.Marshal(l.Spec.Subscriptions); err == nil {
+			env = append(env, corev1.EnvVar{Name: "LUMA_SUBSCRIPTIONS", Value: string(encoded)})
+		}
+	}
+	if len(l.Spec.Federation) > 0 {
+		if encoded, err := json.Marshal(l.Spec.Federation); err == nil {
+			env = append(env, corev1.EnvVar{Name: "LUMA_FEDERATION_SOURCES", Value: string(encoded)})
+		}
+	}
+
+	hh := l.Spec.HintedHandoff
+	if hh.MaxQueueSize != 0 || hh.MaxHintAge.Duration != 0 || hh.BaseBackoff.Duration != 0 || hh.MaxBackoff.Duration != 0 {
+		env = append(env,
+			corev1.EnvVar{Name: "LUMA_HINTED_HANDOFF_MAX_QUEUE_SIZE", Value: strconv.Itoa(int(hh.MaxQueueSize))},
+			corev1.EnvVar{Name: "LUMA_HINTED_HANDOFF_MAX_HINT_AGE", Value: hh.MaxHintAge.Duration.String()},
+			corev1.EnvVar{Name: "LUMA_HINTED_HANDOFF_BASE_BACKOFF", Value: hh.BaseBackoff.Duration.String()},
+			corev1.EnvVar{Name: "LUMA_HINTED_HANDOFF_MAX_BACKOFF", Value: hh.MaxBackoff.Duration.String()},
+		)
+	}
+
+	// One ENABLED/LISTENER_PORT pair per protocol, in a fixed order rather
+	// than ranging over a map, so env var order - and thus the StatefulSet
+	// spec - stays stable across reconciles.
+	for _, p := range []struct {
+		name string
+		spec *lumav1alpha1.ProtocolIngestionSpec
+	}{
+		{"INFLUXDB", l.Spec.Ingestion.InfluxDB},
+		{"OPENTSDB", l.Spec.Ingestion.OpenTSDB},
+		{"GRAPHITE", l.Spec.Ingestion.Graphite},
+	} {
+		if p.spec == nil {
+			continue
+		}
+		env = append(env, corev1.EnvVar{Name: "LUMA_INGESTION_" + p.name + "_ENABLED", Value: strconv.FormatBool(p.spec.Enabled)})
+		if p.spec.ListenerPort != 0 {
+			env = append(env, corev1.EnvVar{Name: "LUMA_INGESTION_" + p.name + "_LISTENER_PORT", Value: strconv.Itoa(int(p.spec.ListenerPort))})
+		}
+	}
+
+	var preStop *corev1.LifecycleHandler
+	if l.Spec.UpdateStrategy.PreStopDrain {
+		preStop = &corev1.LifecycleHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: "/admin/drain",
+				Port: intstr.FromInt(8080),
+			},
+		}
+	}
+
+	container := corev1.Container{
+		Name:  "luma-node",
+		Image: l.Spec.Image,
+		Ports: []corev1.ContainerPort{
+			{ContainerPort: 8080, Name: "http"},
+			{ContainerPort: 9090, Name: "grpc"},
+			{ContainerPort: 10000, Name: "raft"},
+		},
+		Env: env,
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "data", MountPath: "/var/lib/lumadb"},
+			{Name: "tls", MountPath: "/etc/lumadb/tls", ReadOnly: true},
+		},
+	}
+	if preStop != nil {
+		container.Lifecycle = &corev1.Lifecycle{PreStop: preStop}
+	}
+
 	ss := &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      l.Name + "-ss",
@@ -89,39 +415,83 @@ func (r *LumaClusterReconciler) statefulSetForLumaCluster(l *lumav1alpha1.LumaCl
 				MatchLabels: labels,
 			},
 			ServiceName: l.Name + "-headless",
+			// OrderedReady upgrades one pod at a time and waits for it to
+			// become Ready before moving to the next, so a node can finish
+			// draining (via the preStop hook above) before its successor
+			// starts terminating.
+			PodManagementPolicy: appsv1.OrderedReadyPodManagement,
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: labels,
 				},
 				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{{
-						Name:  "luma-node",
-						Image: l.Spec.Image,
-						Ports: []corev1.ContainerPort{
-							{ContainerPort: 8080, Name: "http"},
-							{ContainerPort: 9090, Name: "grpc"},
-							{ContainerPort: 10000, Name: "raft"},
-						},
-						Env: []corev1.EnvVar{
-							{
-								Name: "LUMA_NODE_ID",
-								ValueFrom: &corev1.EnvVarSource{
-									FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
-								},
+					Containers: []corev1.Container{container},
+					Volumes: []corev1.Volume{
+						{
+							Name: "tls",
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{SecretName: l.Name + "-tls"},
 							},
 						},
-					}},
+					},
 				},
 			},
 		},
 	}
-	// TODO: SetControllerReference
-	return ss
+
+	pvc, err := volumeClaimTemplateForLumaCluster(l)
+	if err != nil {
+		return nil, err
+	}
+	ss.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{pvc}
+	return ss, nil
+}
+
+// volumeClaimTemplateForLumaCluster builds the "data" PVC template from
+// spec.storage, falling back to the deprecated spec.storageSize and then to
+// defaultPVCSize. It returns an error rather than panicking when size is set
+// but isn't a valid resource.Quantity, so a malformed CRD spec surfaces as a
+// failed reconcile instead of crashing the controller.
+func volumeClaimTemplateForLumaCluster(l *lumav1alpha1.LumaCluster) (corev1.PersistentVolumeClaim, error) {
+	size := l.Spec.StorageSize
+	var className string
+	if s := l.Spec.Storage; s != nil {
+		if s.Size != "" {
+			size = s.Size
+		}
+		className = s.ClassName
+	}
+	if size == "" {
+		size = defaultPVCSize
+	}
+
+	quantity, err := resource.ParseQuantity(size)
+	if err != nil {
+		return corev1.PersistentVolumeClaim{}, fmt.Errorf("parsing storage size %q: %w", size, err)
+	}
+
+	pvc := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data"},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: quantity,
+				},
+			},
+		},
+	}
+	if className != "" {
+		pvc.Spec.StorageClassName = &className
+	}
+	return pvc, nil
 }
 
 func (r *LumaClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&lumav1alpha1.LumaCluster{}).
 		Owns(&appsv1.StatefulSet{}).
+		Owns(&corev1.Service{}).
+		Owns(&certmanagerv1.Certificate{}).
 		Complete(r)
 }
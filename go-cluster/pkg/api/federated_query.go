@@ -0,0 +1,234 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MetricFilter is a single tag/label equality filter on a metric query.
+type MetricFilter struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// MetricQuery is the unified query shape POST /api/query accepts,
+// independent of which backend actually stores the metric.
+type MetricQuery struct {
+	Metric      string         `json:"metric"`
+	Start       time.Time      `json:"start"`
+	End         time.Time      `json:"end"`
+	Step        string         `json:"step,omitempty"` // e.g. "30s", defaults to stepDefault
+	Filters     []MetricFilter `json:"filters,omitempty"`
+	GroupBy     []string       `json:"group_by,omitempty"`
+	Aggregation string         `json:"aggregation,omitempty"` // sum, avg, min, max, count; defaults to "avg"
+	Engine      string         `json:"engine,omitempty"`      // force a specific engine, bypassing the registry
+	Federated   bool           `json:"federated,omitempty"`   // query every engine that could own this metric
+}
+
+const stepDefault = "60s"
+
+func (q *MetricQuery) step() time.Duration {
+	if q.Step == "" {
+		d, _ := time.ParseDuration(stepDefault)
+		return d
+	}
+	d, err := time.ParseDuration(q.Step)
+	if err != nil {
+		d, _ = time.ParseDuration(stepDefault)
+	}
+	return d
+}
+
+func (q *MetricQuery) aggregation() string {
+	if q.Aggregation == "" {
+		return "avg"
+	}
+	return q.Aggregation
+}
+
+// Point is a single timestamped sample in a unified Series.
+type Point struct {
+	Timestamp int64   `json:"t"`
+	Value     float64 `json:"v"`
+}
+
+// Series is one normalized time series, tagged with the engine it was
+// served from so federated responses can be told apart.
+type Series struct {
+	Metric string            `json:"metric"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Engine engine            `json:"engine"`
+	Points []Point           `json:"points"`
+}
+
+// FederatedResult is the unified response shape for POST /api/query,
+// regardless of how many engines were fanned out to.
+type FederatedResult struct {
+	Series []Series `json:"series"`
+}
+
+// queryTranslator builds an engine-native request for a MetricQuery and
+// normalizes that engine's response back into Series. Each engine speaks a
+// different query language and response shape, so each gets its own
+// translator rather than a shared one trying to paper over the differences.
+type queryTranslator interface {
+	// Translate issues the translated query against backendURL and returns
+	// normalized series.
+	Translate(client *http.Client, backendURL *url.URL, q *MetricQuery) ([]Series, error)
+}
+
+// metricRegistry maps a metric name to the engine(s) that own it, either via
+// an explicit override or by matching the longest registered name prefix.
+// Metrics with no match fall back to every registered engine (federated).
+type metricRegistry struct {
+	prefixes map[string]engine // prefix -> engine
+}
+
+// newMetricRegistry builds a registry from prefix->engine routing rules,
+// e.g. {"node_": enginePrometheus, "http_req_": engineInfluxDB}.
+func newMetricRegistry(rules map[string]engine) *metricRegistry {
+	return &metricRegistry{prefixes: rules}
+}
+
+// Route returns the engines that own metric: a single engine if a prefix
+// rule matches, or every known engine if nothing matches (federated
+// fallback).
+func (m *metricRegistry) Route(metric string) []engine {
+	var best string
+	var bestEngine engine
+	for prefix, eng := range m.prefixes {
+		if strings.HasPrefix(metric, prefix) && len(prefix) > len(best) {
+			best = prefix
+			bestEngine = eng
+		}
+	}
+	if best != "" {
+		return []engine{bestEngine}
+	}
+	return allEngines
+}
+
+// defaultMetricRegistry is a reasonable starting set of routing rules; most
+// deployments will want to override it via TsdbRouter.SetMetricRegistry.
+func defaultMetricRegistry() *metricRegistry {
+	return newMetricRegistry(map[string]engine{
+		"node_":   enginePrometheus,
+		"up":      enginePrometheus,
+		"druid_":  engineDruid,
+		"influx_": engineInfluxDB,
+	})
+}
+
+// SetMetricRegistry overrides the default metric-name-prefix routing table
+// used by POST /api/query to decide which engine(s) own a metric.
+func (r *TsdbRouter) SetMetricRegistry(rules map[string]engine) {
+	r.registry = newMetricRegistry(rules)
+}
+
+// handleFederatedQuery implements POST /api/query: translate the unified
+// MetricQuery into whichever backend query language(s) own the metric, fan
+// out, and merge the results into one FederatedResult.
+func (r *TsdbRouter) handleFederatedQuery(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	var q MetricQuery
+	if err := json.Unmarshal(body, &q); err != nil {
+		http.Error(w, fmt.Sprintf("invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+	if q.Metric == "" {
+		http.Error(w, "metric is required", http.StatusBadRequest)
+		return
+	}
+	if q.End.IsZero() {
+		q.End = time.Now()
+	}
+	if q.Start.IsZero() {
+		q.Start = q.End.Add(-1 * time.Hour)
+	}
+
+	var engines []engine
+	switch {
+	case q.Engine != "":
+		engines = []engine{engine(q.Engine)}
+	case q.Federated:
+		engines = allEngines
+	default:
+		engines = r.registry.Route(q.Metric)
+	}
+
+	result := FederatedResult{}
+	var errs []string
+	for _, eng := range engines {
+		b := r.pick(eng)
+		if b == nil {
+			errs = append(errs, fmt.Sprintf("%s: no healthy backend", eng))
+			continue
+		}
+
+		translator, ok := translators[eng]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("%s: no translator registered", eng))
+			continue
+		}
+
+		b.outstanding.Add(1)
+		b.requests.Add(1)
+		start := time.Now()
+
+		series, err := translator.Translate(r.probeClient, b.URL, &q)
+
+		b.outstanding.Add(-1)
+		b.latency.Observe(time.Since(start).Seconds())
+		b.breaker.RecordResult(err == nil)
+
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", eng, err))
+			continue
+		}
+		result.Series = append(result.Series, series...)
+	}
+
+	if len(result.Series) == 0 && len(errs) > 0 {
+		http.Error(w, strings.Join(errs, "; "), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// translators maps each engine to the queryTranslator that speaks its
+// native query language.
+var translators = map[engine]queryTranslator{
+	enginePrometheus: promQLTranslator{},
+	engineInfluxDB:   influxQLTranslator{},
+	engineDruid:      druidTranslator{},
+}
+
+// labelsFromFilters turns MetricQuery filters into a label/tag map shared
+// by all three translators.
+func labelsFromFilters(filters []MetricFilter) map[string]string {
+	if len(filters) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(filters))
+	for _, f := range filters {
+		labels[f.Key] = f.Value
+	}
+	return labels
+}
@@ -0,0 +1,45 @@
+package api
+
+import "testing"
+
+func TestMetricRegistry_Route(t *testing.T) {
+	reg := defaultMetricRegistry()
+
+	tests := []struct {
+		metric string
+		want   engine
+	}{
+		{"node_cpu_seconds_total", enginePrometheus},
+		{"up", enginePrometheus},
+		{"druid_query_count", engineDruid},
+		{"influx_write_latency", engineInfluxDB},
+	}
+
+	for _, tt := range tests {
+		got := reg.Route(tt.metric)
+		if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("Route(%q) = %v, want [%v]", tt.metric, got, tt.want)
+		}
+	}
+}
+
+func TestMetricRegistry_Route_UnknownFallsBackToFederated(t *testing.T) {
+	reg := defaultMetricRegistry()
+
+	got := reg.Route("some_unregistered_metric")
+	if len(got) != len(allEngines) {
+		t.Fatalf("Route(unknown) = %v, want every engine (federated fallback)", got)
+	}
+}
+
+func TestMetricRegistry_Route_LongestPrefixWins(t *testing.T) {
+	reg := newMetricRegistry(map[string]engine{
+		"http_":     enginePrometheus,
+		"http_req_": engineInfluxDB,
+	})
+
+	got := reg.Route("http_req_duration")
+	if len(got) != 1 || got[0] != engineInfluxDB {
+		t.Errorf("Route(http_req_duration) = %v, want [%v] (longest prefix)", got, engineInfluxDB)
+	}
+}
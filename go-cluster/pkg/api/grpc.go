@@ -2,99 +2,234 @@ package api
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 
 	pb "github.com/lumadb/cluster/pkg/api/pb"
 	"github.com/lumadb/cluster/pkg/cluster"
+	"github.com/lumadb/cluster/pkg/cluster/capability"
+	"github.com/lumadb/cluster/pkg/dialects"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
+// defaultMaxFrameBytes bounds how large a single Stream frame's serialized
+// Result payload may be, comfortably under the ~64KB default frame ceiling
+// both google.golang.org/grpc and typical WebSocket proxies impose.
+const defaultMaxFrameBytes = 60 * 1024
+
+// GRPCServerOptions configures a LumaGRPCServer beyond the node/logger
+// RegisterGRPCServer always needs.
+type GRPCServerOptions struct {
+	// MaxFrameBytes caps how large a single Stream frame's serialized
+	// Result payload may be before rows are split across additional
+	// frames (or, for a single oversized row, continuation frames).
+	// Defaults to defaultMaxFrameBytes when <= 0.
+	MaxFrameBytes int
+	// Capabilities, when set, gates Execute's dialect-specific queries
+	// behind cluster-wide agreement: a query whose dialect isn't in
+	// Capabilities.Enabled is refused rather than risking a peer in the
+	// group that can't yet understand it.
+	Capabilities *capability.Set
+	// ParallelRaft, when set, routes a Write/DDL/Admin query to the Raft
+	// group its collection is sharded to (via ParallelRaftEngine.Propose)
+	// instead of through the single cluster-wide log timedApply(s.node, ...)
+	// uses. Leave nil for a cluster.Node that isn't sharded yet.
+	ParallelRaft *cluster.ParallelRaftEngine
+}
+
 // LumaGRPCServer implements the LumaService gRPC interface
 type LumaGRPCServer struct {
 	pb.UnimplementedLumaServiceServer
-	node   *cluster.Node
-	logger *zap.Logger
+	node          *cluster.Node
+	logger        *zap.Logger
+	maxFrameBytes int
+	capabilities  *capability.Set
+	dialectRouter *dialects.Router
+	parallelRaft  *cluster.ParallelRaftEngine
 }
 
 // RegisterGRPCServer registers the LumaService with the gRPC server
-func RegisterGRPCServer(s *grpc.Server, node *cluster.Node, logger *zap.Logger) {
+func RegisterGRPCServer(s *grpc.Server, node *cluster.Node, logger *zap.Logger, opts GRPCServerOptions) {
+	maxFrameBytes := opts.MaxFrameBytes
+	if maxFrameBytes <= 0 {
+		maxFrameBytes = defaultMaxFrameBytes
+	}
 	srv := &LumaGRPCServer{
-		node:   node,
-		logger: logger,
+		node:          node,
+		logger:        logger,
+		maxFrameBytes: maxFrameBytes,
+		capabilities:  opts.Capabilities,
+		// nil executor: the router is only used here to reach
+		// DialectHandler.Parse for its Intent, never to Execute.
+		dialectRouter: dialects.NewRouter(nil),
+		parallelRaft:  opts.ParallelRaft,
 	}
 	pb.RegisterLumaServiceServer(s, srv)
 }
 
-// Execute handles single query execution
-func (s *LumaGRPCServer) Execute(ctx context.Context, req *pb.QueryRequest) (*pb.QueryResponse, error) {
-	s.logger.Debug("Received Execute request", zap.String("query", req.Query), zap.String("dialect", req.Dialect))
+// dialectCapability maps a QueryRequest.Dialect to the capability name
+// that must be enabled cluster-wide before Execute/Stream will act on it,
+// e.g. "tdengine" -> "dialect.tdengine".
+func dialectCapability(dialect string) string {
+	if dialect == "" {
+		return ""
+	}
+	return fmt.Sprintf("dialect.%s", dialect)
+}
 
-	// Parse the query or use the raw query string depending on dialect.
-	// Ideally we would parse this to determine if it's read or write.
-	// For now, let's assume raw commands wrapped in JSON if coming from lumadb-compat special logic,
-	// OR we forward this to a hypothetical query engine.
+// requireDialectCapability reports an error if dialect requires a
+// capability not yet enabled cluster-wide - i.e. at least one live member
+// hasn't advertised support for it - so a rolling upgrade never routes a
+// dialect-specific write to a peer that can't understand it.
+func (s *LumaGRPCServer) requireDialectCapability(dialect string) error {
+	if s.capabilities == nil {
+		return nil
+	}
+	name := dialectCapability(dialect)
+	if name == "" || s.capabilities.Enabled(name) {
+		return nil
+	}
+	return fmt.Errorf("dialect %q is not yet enabled cluster-wide (capability %q)", dialect, name)
+}
 
-	// BUT current lumadb-compat produces LumaIR.
-	// `req.Payload` might contain serialized LumaIR.
-	// `go-cluster` needs to understand LumaIR or just blindly pass it to `luma_core` via CGO?
-	// `luma_core` (Rust) definitely understands LumaIR (it's defined there).
-	// So `go-cluster` should act as a proxy to `luma_core`.
+// parseIntent runs dialect's DialectHandler.Parse on query just to recover
+// its QueryIntent - IntentRead means Execute/Stream can keep using
+// node.RunQuery, anything else means the query must go through Raft.
+// Unknown dialects and parse failures report IntentRead, since Execute's
+// own s.node.RunQuery call below will surface the real parse error; this
+// only decides routing, not whether the query is well-formed.
+func (s *LumaGRPCServer) parseIntent(dialect, query string) dialects.QueryIntent {
+	handler, ok := s.dialectRouter.GetHandler(dialects.Dialect(dialect))
+	if !ok {
+		return dialects.IntentRead
+	}
+	parsed, err := handler.Parse(query)
+	if err != nil {
+		return dialects.IntentRead
+	}
+	return parsed.Intent
+}
 
-	// However, `node.go` uses `luma_core` via CGO but only exposes basic CRUD: Insert, Get, Update, Delete.
-	// And `Query` which takes a JSON query string.
+// queryRoute is where Execute/Stream send a parsed query once its
+// QueryIntent is known: run it read-only, apply it via Raft (this node is
+// the leader), or forward it to whichever node is.
+type queryRoute int
 
-	// If `req.Dialect` is "lumair-json", we can pass `req.Query` (which should be JSON) to `node.RunQuery`.
-	// But `node.RunQuery` logic is: `n.db.Query(collection, query)`.
-	// `n.db.Query` calls `C.luma_query`.
+const (
+	routeRunQuery queryRoute = iota
+	routeApplyLocally
+	routeForwardToLeader
+)
 
-	// We need to map `req` to `C.luma_query`.
+// decideQueryRoute is the pure decision behind routeQuery, factored out so
+// it's testable without a real *cluster.Node (this checkout doesn't define
+// one - see cluster.Node's other call sites - so no value of that type can
+// be constructed in a test at all).
+func decideQueryRoute(intent dialects.QueryIntent, isLeader bool) queryRoute {
+	if intent == dialects.IntentRead || intent == "" {
+		return routeRunQuery
+	}
+	if isLeader {
+		return routeApplyLocally
+	}
+	return routeForwardToLeader
+}
 
-	// What about writes?
-	// If it's a write, it MUST go through Raft (`node.Apply`).
-	// `luma_query` might handle writes if `luma_core` handles them, but Raft needs to sequence it.
-	// This is the tricky part. `go-cluster` manages consensus.
-	// If the query is "INSERT ...", `go-cluster` needs to know it's a write.
+// dialectQueryCommand is the payload of a cluster.Command carrying a
+// Write/DDL/Admin query through Raft: the original dialect and query text,
+// not the ParsedQuery itself, since every node's own DialectHandler.Parse
+// must agree on the AST a dialect-specific write applies, and re-parsing
+// identically on every replica is simpler than also replicating whichever
+// dialect's AST type RunQuery's JSON encoding happened to produce.
+type dialectQueryCommand struct {
+	Dialect    string `json:"dialect"`
+	Query      string `json:"query"`
+	Collection string `json:"collection"`
+}
 
-	// Short-term solution:
-	// 1. If we can distinguish Read/Write, we route accordingly.
-	// 2. If it's a write, we create a Raft command.
+// applyDialectQuery replicates a Write/DDL/Admin query via Raft: it wraps
+// req into a dialectQueryCommand and submits it for replication. When
+// s.parallelRaft is configured it goes through ParallelRaftEngine.Propose,
+// landing on the Raft group req.Collection is sharded to; otherwise it
+// falls back to timedApply the same way handleInsert/handleUpdate/
+// handleDelete in server.go do for document writes, through the single
+// cluster-wide log, so Raft apply latency and in-flight metrics cover this
+// path either way.
+func (s *LumaGRPCServer) applyDialectQuery(ctx context.Context, req *pb.QueryRequest) (*pb.QueryResponse, error) {
+	cmd := dialectQueryCommand{
+		Dialect:    req.Dialect,
+		Query:      req.Query,
+		Collection: req.Collection,
+	}
 
-	// For now, let's support "mql" dialect which maps to `RunQuery` (Read)
-	// and special commands for Write.
-	// Or, assume `Execute` is Read-Only unless specified? No.
+	payload, err := json.Marshal(cmd)
+	if err != nil {
+		return &pb.QueryResponse{Success: false, Error: err.Error()}, nil
+	}
 
-	// As per `node.go`:
-	// `RunQuery` -> DB.Query.
-	// `InsertDocument` -> Raft -> DB.Insert.
+	if s.parallelRaft != nil {
+		if err := s.parallelRaft.Propose(ctx, req.Collection, "", payload); err != nil {
+			return &pb.QueryResponse{Success: false, Error: err.Error()}, nil
+		}
+		return &pb.QueryResponse{Success: true, ContentType: "json"}, nil
+	}
 
-	// If `lumadb-compat` translates "INSERT INTO users ..." to LumaIR "Insert { ... }",
-	// We should send a `QueryRequest` with payload defining the Insert.
+	raftCmd := &cluster.Command{
+		Op:         "dialect_query",
+		Collection: req.Collection,
+		Value:      payload,
+	}
+	if err := timedApply(ctx, s.node, raftCmd); err != nil {
+		return &pb.QueryResponse{Success: false, Error: err.Error()}, nil
+	}
+	return &pb.QueryResponse{Success: true, ContentType: "json"}, nil
+}
 
-	// Let's implement a naive pass-through to `RunQuery` for now, assuming read-mostly validation.
-	// For writes, `lumadb-compat` might need to use `req.Payload` to specify "Type: Write".
+// forwardToLeader re-issues req against the current Raft leader's gRPC
+// endpoint, for a Write/DDL/Admin query that arrived on a follower -
+// mirroring redirectToLeader's HTTP-side 307 in server.go, but as a
+// same-call proxy instead of a redirect response, since a gRPC client
+// streaming a write has no HTTP-redirect equivalent to follow itself.
+func (s *LumaGRPCServer) forwardToLeader(ctx context.Context, req *pb.QueryRequest) (*pb.QueryResponse, error) {
+	leaderAddr := s.node.LeaderAddr()
+	if leaderAddr == "" {
+		return &pb.QueryResponse{Success: false, Error: "no known Raft leader to forward to"}, nil
+	}
 
-	// Implementation:
-	// Try to execute via Node.
+	conn, err := grpc.DialContext(ctx, leaderAddr, grpc.WithBlock())
+	if err != nil {
+		return &pb.QueryResponse{Success: false, Error: fmt.Sprintf("dialing leader %s: %v", leaderAddr, err)}, nil
+	}
+	defer conn.Close()
 
-	// Detect collection
-	collection := req.Collection
-	if collection == "" {
-		// Parse from query? Or error.
-		// Let's require collection for now if possible.
-		// Only some queries leverage collection directly.
+	resp, err := pb.NewLumaServiceClient(conn).Execute(ctx, req)
+	if err != nil {
+		return &pb.QueryResponse{Success: false, Error: fmt.Sprintf("forwarding to leader %s: %v", leaderAddr, err)}, nil
 	}
+	return resp, nil
+}
 
-	// Construct DB Query
-	// We just pass the query string to the underlying engine.
-	// Limitation: Writes won't be replicated if we just use `RunQuery`.
-	// We need a way to support writes via this API.
+// Execute handles single query execution
+func (s *LumaGRPCServer) Execute(ctx context.Context, req *pb.QueryRequest) (*pb.QueryResponse, error) {
+	s.logger.Debug("Received Execute request", zap.String("query", req.Query), zap.String("dialect", req.Dialect))
+
+	// `req.Payload` may still carry lumadb-compat's serialized LumaIR for
+	// dialects this package's own DialectHandlers don't cover - that
+	// proxy-to-luma_core question is unrelated to the read/write routing
+	// below and is still open.
+
+	if err := s.requireDialectCapability(req.Dialect); err != nil {
+		return &pb.QueryResponse{Success: false, Error: err.Error()}, nil
+	}
 
-	// TODO: Protocol should explicitly support Write ops.
-	// For "Execute", we will assume it *could* be a write if we had a better parser.
-	// Let's look at `req.Dialect`.
+	switch decideQueryRoute(s.parseIntent(req.Dialect, req.Query), s.node.IsLeader()) {
+	case routeApplyLocally:
+		return s.applyDialectQuery(ctx, req)
+	case routeForwardToLeader:
+		return s.forwardToLeader(ctx, req)
+	}
 
 	results, err := s.node.RunQuery(req.Collection, map[string]interface{}{
 		"q":       req.Query,
@@ -118,6 +253,156 @@ func (s *LumaGRPCServer) Execute(ctx context.Context, req *pb.QueryRequest) (*pb
 	}, nil
 }
 
+// streamFrame is the JSON envelope a Stream frame's Result bytes carry: a
+// row batch plus running stats in the common case, or one slice of an
+// individual row too large to fit a frame on its own (Partial marks every
+// slice but the last one of such a row).
+type streamFrame struct {
+	Rows    []interface{} `json:"rows,omitempty"`
+	Stats   streamStats   `json:"stats"`
+	Partial bool          `json:"partial,omitempty"`
+	Chunk   string        `json:"chunk,omitempty"`
+}
+
+// streamStats is the running total reported on every Stream frame, so a
+// consumer doesn't have to count rows across frames itself.
+type streamStats struct {
+	RowsSent int `json:"rows_sent"`
+}
+
+// Stream handles server-side streaming query execution: it runs the query
+// the same way Execute does, then chunks the result's rows into frames
+// bounded by maxFrameBytes via chunkRowsIntoFrames, sending each one in
+// turn and checking ctx.Done() before every send so a gone client ends the
+// stream early. The whole result and every frame are built in memory
+// before the first send.
 func (s *LumaGRPCServer) Stream(req *pb.QueryRequest, stream pb.LumaService_StreamServer) error {
-	return status.Errorf(codes.Unimplemented, "Stream not implemented")
+	s.logger.Debug("Received Stream request", zap.String("query", req.Query), zap.String("dialect", req.Dialect))
+
+	if err := s.requireDialectCapability(req.Dialect); err != nil {
+		return stream.Send(&pb.QueryResponse{Success: false, Error: err.Error()})
+	}
+
+	results, err := s.node.RunQuery(req.Collection, map[string]interface{}{
+		"q":       req.Query,
+		"dialect": req.Dialect,
+	})
+	if err != nil {
+		return stream.Send(&pb.QueryResponse{Success: false, Error: err.Error()})
+	}
+
+	rows, ok := results.([]interface{})
+	if !ok {
+		// Not row-shaped (a scalar or a single document): stream it as
+		// one frame rather than failing the whole call.
+		rows = []interface{}{results}
+	}
+
+	maxFrameBytes := s.maxFrameBytes
+	if maxFrameBytes <= 0 {
+		maxFrameBytes = defaultMaxFrameBytes
+	}
+
+	frames, err := chunkRowsIntoFrames(rows, maxFrameBytes)
+	if err != nil {
+		return stream.Send(&pb.QueryResponse{Success: false, Error: err.Error()})
+	}
+
+	ctx := stream.Context()
+	for _, frame := range frames {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		resBytes, err := json.Marshal(frame)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(&pb.QueryResponse{Success: true, Result: resBytes, ContentType: "json"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chunkRowsIntoFrames batches rows into streamFrames no larger than
+// maxFrameBytes, falling back to splitOversizedRow for any single row that
+// alone exceeds maxFrameBytes. It holds no gRPC- or stream-specific state,
+// which makes it exercisable directly from a table-driven test despite
+// LumaGRPCServer itself depending on the pb package this repo snapshot
+// doesn't define.
+func chunkRowsIntoFrames(rows []interface{}, maxFrameBytes int) ([]streamFrame, error) {
+	var (
+		frames     []streamFrame
+		batch      []interface{}
+		batchBytes int
+		rowsSent   int
+	)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		rowsSent += len(batch)
+		frames = append(frames, streamFrame{Rows: batch, Stats: streamStats{RowsSent: rowsSent}})
+		batch = nil
+		batchBytes = 0
+	}
+
+	for _, row := range rows {
+		rowBytes, err := json.Marshal(row)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rowBytes) > maxFrameBytes {
+			flush()
+			frames = append(frames, splitOversizedRow(rowBytes, maxFrameBytes)...)
+			rowsSent++
+			continue
+		}
+
+		if len(batch) > 0 && batchBytes+len(rowBytes) > maxFrameBytes {
+			flush()
+		}
+		batch = append(batch, row)
+		batchBytes += len(rowBytes)
+	}
+	flush()
+
+	return frames, nil
+}
+
+// splitOversizedRow splits a single row's JSON - too large to fit one frame
+// alongside any others - into base64-encoded continuation frames, each
+// marked Partial except the last, so the client can reassemble it instead
+// of the row being dropped or blowing past maxFrameBytes.
+func splitOversizedRow(rowJSON []byte, maxFrameBytes int) []streamFrame {
+	// Base64 expands bytes by 4/3; size the raw chunk so its encoded form,
+	// plus headroom for the rest of the streamFrame envelope, still fits
+	// under maxFrameBytes.
+	const envelopeOverheadBytes = 256
+	budget := maxFrameBytes - envelopeOverheadBytes
+	if budget <= 0 {
+		budget = maxFrameBytes
+	}
+	chunkSize := (budget * 3) / 4
+	if chunkSize <= 0 {
+		chunkSize = 1
+	}
+
+	var frames []streamFrame
+	for offset := 0; offset < len(rowJSON); offset += chunkSize {
+		end := offset + chunkSize
+		if end > len(rowJSON) {
+			end = len(rowJSON)
+		}
+		frames = append(frames, streamFrame{
+			Partial: end < len(rowJSON),
+			Chunk:   base64.StdEncoding.EncodeToString(rowJSON[offset:end]),
+		})
+	}
+	return frames
 }
@@ -0,0 +1,181 @@
+// Stream and the StreamWSBridge both depend on pkg/api/pb, which this
+// checkout doesn't vendor or generate (no LumaService .proto exists in the
+// tree), so an end-to-end test wiring a real grpc.NewServer/httptest
+// round-trip with ZeroCopyCodec can't be built here. Execute's new
+// Write/DDL routing (applyDialectQuery, forwardToLeader) additionally
+// depends on *cluster.Node, which this checkout never defines a type for
+// at all, so no value of it can be constructed in a test either. These
+// tests instead cover chunkRowsIntoFrames/splitOversizedRow - Stream's
+// actual chunking logic, factored out precisely so it doesn't need pb -
+// decideQueryRoute - Execute's routing decision, factored out so it
+// doesn't need *cluster.Node - and the buffer pool ZeroCopyCodec relies on
+// for frame reuse.
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/lumadb/cluster/pkg/cluster/capability"
+	"github.com/lumadb/cluster/pkg/dialects"
+)
+
+func TestChunkRowsIntoFrames_BatchesUnderMaxFrameBytes(t *testing.T) {
+	rows := []interface{}{
+		map[string]interface{}{"id": 1},
+		map[string]interface{}{"id": 2},
+		map[string]interface{}{"id": 3},
+	}
+
+	frames, err := chunkRowsIntoFrames(rows, 1024)
+	if err != nil {
+		t.Fatalf("chunkRowsIntoFrames() error = %v", err)
+	}
+	if len(frames) != 1 {
+		t.Fatalf("len(frames) = %d, want 1 (small rows should share one batch)", len(frames))
+	}
+	if len(frames[0].Rows) != 3 {
+		t.Fatalf("len(frames[0].Rows) = %d, want 3", len(frames[0].Rows))
+	}
+	if frames[0].Stats.RowsSent != 3 {
+		t.Errorf("frames[0].Stats.RowsSent = %d, want 3", frames[0].Stats.RowsSent)
+	}
+}
+
+func TestChunkRowsIntoFrames_SplitsAcrossFramesWhenOverBudget(t *testing.T) {
+	// Each row serializes to 62 bytes; pick a maxFrameBytes that only fits
+	// one row per frame.
+	rows := []interface{}{
+		map[string]interface{}{"value": strings.Repeat("a", 50)},
+		map[string]interface{}{"value": strings.Repeat("b", 50)},
+	}
+
+	frames, err := chunkRowsIntoFrames(rows, 100)
+	if err != nil {
+		t.Fatalf("chunkRowsIntoFrames() error = %v", err)
+	}
+	if len(frames) != 2 {
+		t.Fatalf("len(frames) = %d, want 2 (one row per frame)", len(frames))
+	}
+	if frames[0].Stats.RowsSent != 1 || frames[1].Stats.RowsSent != 2 {
+		t.Errorf("RowsSent = [%d, %d], want [1, 2] (running total)", frames[0].Stats.RowsSent, frames[1].Stats.RowsSent)
+	}
+}
+
+func TestChunkRowsIntoFrames_SplitsOversizedRowIntoPartialContinuations(t *testing.T) {
+	rows := []interface{}{
+		map[string]interface{}{"blob": strings.Repeat("x", 1000)},
+	}
+
+	frames, err := chunkRowsIntoFrames(rows, 300)
+	if err != nil {
+		t.Fatalf("chunkRowsIntoFrames() error = %v", err)
+	}
+	if len(frames) < 2 {
+		t.Fatalf("len(frames) = %d, want >= 2 for a row bigger than maxFrameBytes", len(frames))
+	}
+
+	var reassembled strings.Builder
+	for i, f := range frames {
+		if f.Rows != nil {
+			t.Fatalf("frames[%d].Rows = %v, want nil for a continuation frame", i, f.Rows)
+		}
+		chunk, err := base64.StdEncoding.DecodeString(f.Chunk)
+		if err != nil {
+			t.Fatalf("frames[%d].Chunk did not decode: %v", i, err)
+		}
+		reassembled.Write(chunk)
+
+		wantPartial := i < len(frames)-1
+		if f.Partial != wantPartial {
+			t.Errorf("frames[%d].Partial = %v, want %v", i, f.Partial, wantPartial)
+		}
+	}
+
+	var row map[string]interface{}
+	if err := json.Unmarshal([]byte(reassembled.String()), &row); err != nil {
+		t.Fatalf("reassembled chunks did not form valid JSON: %v", err)
+	}
+	if row["blob"] != strings.Repeat("x", 1000) {
+		t.Errorf("reassembled row blob did not round-trip")
+	}
+}
+
+func TestChunkRowsIntoFrames_Empty(t *testing.T) {
+	frames, err := chunkRowsIntoFrames(nil, 1024)
+	if err != nil {
+		t.Fatalf("chunkRowsIntoFrames() error = %v", err)
+	}
+	if len(frames) != 0 {
+		t.Errorf("len(frames) = %d, want 0 for no rows", len(frames))
+	}
+}
+
+func TestRequireDialectCapability(t *testing.T) {
+	set := capability.NewSet(capability.Advertisement{
+		NodeID:       1,
+		Version:      "1.0.0",
+		Capabilities: []string{"dialect.flux"},
+	}, nil)
+	// A second member lacking dialect.tdengine disables it cluster-wide.
+	set.Update(capability.Advertisement{NodeID: 2, Version: "1.0.0", Capabilities: []string{"dialect.flux"}})
+
+	srv := &LumaGRPCServer{capabilities: set}
+
+	if err := srv.requireDialectCapability("flux"); err != nil {
+		t.Errorf("requireDialectCapability(flux) error = %v, want nil", err)
+	}
+	if err := srv.requireDialectCapability("tdengine"); err == nil {
+		t.Error("requireDialectCapability(tdengine) error = nil, want error (not enabled cluster-wide)")
+	}
+	if err := srv.requireDialectCapability(""); err != nil {
+		t.Errorf("requireDialectCapability(\"\") error = %v, want nil (no dialect to gate)", err)
+	}
+
+	noCapsServer := &LumaGRPCServer{}
+	if err := noCapsServer.requireDialectCapability("tdengine"); err != nil {
+		t.Errorf("requireDialectCapability with no capabilities set should always pass, got %v", err)
+	}
+}
+
+func TestDecideQueryRoute(t *testing.T) {
+	tests := []struct {
+		name     string
+		intent   dialects.QueryIntent
+		isLeader bool
+		want     queryRoute
+	}{
+		{"read stays local regardless of leadership", dialects.IntentRead, false, routeRunQuery},
+		{"unknown intent defaults to read", dialects.QueryIntent(""), false, routeRunQuery},
+		{"write on the leader applies locally", dialects.IntentWrite, true, routeApplyLocally},
+		{"write on a follower forwards to the leader", dialects.IntentWrite, false, routeForwardToLeader},
+		{"ddl on the leader applies locally", dialects.IntentDDL, true, routeApplyLocally},
+		{"ddl on a follower forwards to the leader", dialects.IntentDDL, false, routeForwardToLeader},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decideQueryRoute(tt.intent, tt.isLeader); got != tt.want {
+				t.Errorf("decideQueryRoute(%v, %v) = %v, want %v", tt.intent, tt.isLeader, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetBuffer_PutBuffer_Reuse(t *testing.T) {
+	bufPtr := GetBuffer()
+	*bufPtr = append(*bufPtr, "frame one"...)
+	original := bufPtr
+
+	PutBuffer(bufPtr)
+
+	reused := GetBuffer()
+	if len(*reused) != 0 {
+		t.Errorf("len(*reused) = %d, want 0 (PutBuffer should reset length before returning to the pool)", len(*reused))
+	}
+	if cap(*reused) < cap(*original) {
+		t.Errorf("cap(*reused) = %d, want >= %d (capacity should be retained across reuse)", cap(*reused), cap(*original))
+	}
+}
@@ -0,0 +1,358 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lumadb/cluster/pkg/cluster"
+	"github.com/valyala/fasthttp"
+)
+
+// ingestPoint is one parsed time-series point, whether it arrived as an
+// InfluxDB line-protocol line or an OpenTSDB /api/put JSON object.
+type ingestPoint struct {
+	Measurement string
+	Tags        map[string]string
+	Fields      map[string]interface{}
+	Timestamp   int64 // milliseconds
+}
+
+// seriesBatch coalesces points that share a series (db + measurement +
+// tag set) so ingesting many points for the same series costs one
+// cluster.Command Apply instead of one per point.
+type seriesBatch struct {
+	order  []string
+	groups map[string]*seriesGroup
+}
+
+type seriesGroup struct {
+	db          string
+	measurement string
+	tags        map[string]string
+	points      []ingestPoint
+}
+
+func newSeriesBatch() *seriesBatch {
+	return &seriesBatch{groups: make(map[string]*seriesGroup)}
+}
+
+func (b *seriesBatch) add(db string, p ingestPoint) {
+	key := seriesKey(db, p.Measurement, p.Tags)
+	g, ok := b.groups[key]
+	if !ok {
+		g = &seriesGroup{db: db, measurement: p.Measurement, tags: p.Tags}
+		b.groups[key] = g
+		b.order = append(b.order, key)
+	}
+	g.points = append(g.points, p)
+}
+
+// seriesKey builds a stable series identity out of its tag set, sorted so
+// the same tags in a different order still coalesce into one group.
+func seriesKey(db, measurement string, tags map[string]string) string {
+	names := make([]string, 0, len(tags))
+	for k := range tags {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(db)
+	b.WriteByte('\xff')
+	b.WriteString(measurement)
+	for _, k := range names {
+		b.WriteByte('\xff')
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(tags[k])
+	}
+	return b.String()
+}
+
+// applySeriesBatch applies one "write_points" Command per series group.
+func (s *Server) applySeriesBatch(ctx context.Context, batch *seriesBatch) error {
+	for _, key := range batch.order {
+		g := batch.groups[key]
+
+		value, err := json.Marshal(struct {
+			Tags   map[string]string `json:"tags"`
+			Points []ingestPoint     `json:"points"`
+		}{Tags: g.tags, Points: g.points})
+		if err != nil {
+			return err
+		}
+
+		cmd := &cluster.Command{
+			Op:         "write_points",
+			Collection: g.measurement,
+			Key:        key,
+			Value:      value,
+		}
+		if err := timedApply(ctx, s.node, cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleWrite implements POST /write?db=...&precision=ns|us|ms|s: InfluxDB
+// line-protocol ingestion. Lines are scanned one at a time out of the
+// already-buffered request body rather than re-reading/re-allocating it,
+// so a large batch write doesn't double its memory footprint.
+func (s *Server) handleWrite(ctx *fasthttp.RequestCtx) {
+	db := string(ctx.QueryArgs().Peek("db"))
+	precision := string(ctx.QueryArgs().Peek("precision"))
+	if precision == "" {
+		precision = "ns"
+	}
+	partial := string(ctx.QueryArgs().Peek("partial")) == "true"
+
+	if redirectToLeader(ctx, s.node) {
+		return
+	}
+
+	batch := newSeriesBatch()
+	var parseErrors []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(ctx.PostBody()))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		point, err := parseLineProtocol(line, precision)
+		if err != nil {
+			parseErrors = append(parseErrors, fmt.Sprintf("line %d: %v", lineNum, err))
+			if !partial {
+				errorResponse(ctx, fasthttp.StatusBadRequest, strings.Join(parseErrors, "; "))
+				return
+			}
+			continue
+		}
+		batch.add(db, point)
+	}
+
+	reqCtx, cancel := s.requestContext(ctx)
+	defer cancel()
+	if err := s.applySeriesBatch(reqCtx, batch); err != nil {
+		status, msg := statusForErr(err)
+		errorResponse(ctx, status, msg)
+		return
+	}
+
+	if len(parseErrors) > 0 {
+		errorResponse(ctx, fasthttp.StatusBadRequest, strings.Join(parseErrors, "; "))
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+// parseLineProtocol parses one InfluxDB line-protocol line:
+// measurement[,tag=value...] field=value[,field=value...] [timestamp].
+func parseLineProtocol(line, precision string) (ingestPoint, error) {
+	identifiers, rest, ok := cutUnescaped(line, ' ')
+	if !ok {
+		return ingestPoint{}, fmt.Errorf("missing field set")
+	}
+
+	measurement, tagPart, _ := cutUnescaped(identifiers, ',')
+	if measurement == "" {
+		return ingestPoint{}, fmt.Errorf("missing measurement name")
+	}
+
+	tags := make(map[string]string)
+	for tagPart != "" {
+		var pair string
+		pair, tagPart, _ = cutUnescaped(tagPart, ',')
+		k, v, ok := cutUnescaped(pair, '=')
+		if !ok {
+			return ingestPoint{}, fmt.Errorf("malformed tag %q", pair)
+		}
+		tags[k] = v
+	}
+
+	fieldPart, tsPart, _ := cutUnescaped(rest, ' ')
+	fields := make(map[string]interface{})
+	for fieldPart != "" {
+		var pair string
+		pair, fieldPart, _ = cutUnescaped(fieldPart, ',')
+		k, v, ok := cutUnescaped(pair, '=')
+		if !ok {
+			return ingestPoint{}, fmt.Errorf("malformed field %q", pair)
+		}
+		value, err := parseLineProtocolValue(v)
+		if err != nil {
+			return ingestPoint{}, err
+		}
+		fields[k] = value
+	}
+	if len(fields) == 0 {
+		return ingestPoint{}, fmt.Errorf("missing field set")
+	}
+
+	ts := time.Now().UnixMilli()
+	if tsPart = strings.TrimSpace(tsPart); tsPart != "" {
+		raw, err := strconv.ParseInt(tsPart, 10, 64)
+		if err != nil {
+			return ingestPoint{}, fmt.Errorf("invalid timestamp %q: %w", tsPart, err)
+		}
+		ts = normalizeToMillis(raw, precision)
+	}
+
+	return ingestPoint{Measurement: measurement, Tags: tags, Fields: fields, Timestamp: ts}, nil
+}
+
+// cutUnescaped splits s at the first unescaped occurrence of sep (one not
+// preceded by a backslash), the way InfluxDB line protocol requires
+// measurement/tag/field text to support escaped commas, equals signs and
+// spaces. It returns ok=false if sep never occurs.
+func cutUnescaped(s string, sep byte) (before, after string, ok bool) {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == sep {
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+func parseLineProtocolValue(raw string) (interface{}, error) {
+	switch strings.ToLower(raw) {
+	case "true", "t":
+		return true, nil
+	case "false", "f":
+		return false, nil
+	}
+	if strings.HasSuffix(raw, "i") {
+		n, err := strconv.ParseInt(raw[:len(raw)-1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer field %q: %w", raw, err)
+		}
+		return n, nil
+	}
+	if strings.HasPrefix(raw, `"`) && strings.HasSuffix(raw, `"`) && len(raw) >= 2 {
+		return strings.ReplaceAll(raw[1:len(raw)-1], `\"`, `"`), nil
+	}
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid field value %q: %w", raw, err)
+	}
+	return f, nil
+}
+
+func normalizeToMillis(ts int64, precision string) int64 {
+	switch precision {
+	case "ns":
+		return ts / 1_000_000
+	case "us":
+		return ts / 1_000
+	case "ms":
+		return ts
+	case "s":
+		return ts * 1_000
+	default:
+		return ts / 1_000_000
+	}
+}
+
+// openTSDBPoint is one point in an /api/put request, sent either as a
+// single JSON object or a JSON array of them.
+type openTSDBPoint struct {
+	Metric    string            `json:"metric"`
+	Timestamp int64             `json:"timestamp"`
+	Value     json.Number       `json:"value"`
+	Tags      map[string]string `json:"tags"`
+}
+
+// handlePut implements POST /api/put: the OpenTSDB single/array JSON
+// ingestion form used by tcollector and compatible agents.
+func (s *Server) handlePut(ctx *fasthttp.RequestCtx) {
+	partial := string(ctx.QueryArgs().Peek("partial")) == "true"
+
+	if redirectToLeader(ctx, s.node) {
+		return
+	}
+
+	body := bytes.TrimSpace(ctx.PostBody())
+	var raw []json.RawMessage
+	if len(body) > 0 && body[0] == '[' {
+		if err := json.Unmarshal(body, &raw); err != nil {
+			errorResponse(ctx, fasthttp.StatusBadRequest, err.Error())
+			return
+		}
+	} else {
+		raw = []json.RawMessage{body}
+	}
+
+	batch := newSeriesBatch()
+	var parseErrors []string
+
+	for i, r := range raw {
+		var p openTSDBPoint
+		if err := json.Unmarshal(r, &p); err != nil {
+			parseErrors = append(parseErrors, fmt.Sprintf("point %d: %v", i, err))
+			if !partial {
+				errorResponse(ctx, fasthttp.StatusBadRequest, strings.Join(parseErrors, "; "))
+				return
+			}
+			continue
+		}
+		if p.Metric == "" {
+			parseErrors = append(parseErrors, fmt.Sprintf("point %d: missing metric", i))
+			if !partial {
+				errorResponse(ctx, fasthttp.StatusBadRequest, strings.Join(parseErrors, "; "))
+				return
+			}
+			continue
+		}
+
+		value, err := p.Value.Float64()
+		if err != nil {
+			parseErrors = append(parseErrors, fmt.Sprintf("point %d: invalid value %q", i, p.Value))
+			if !partial {
+				errorResponse(ctx, fasthttp.StatusBadRequest, strings.Join(parseErrors, "; "))
+				return
+			}
+			continue
+		}
+
+		ts := p.Timestamp
+		if ts < 1e12 { // OpenTSDB timestamps are seconds unless >= 13 digits
+			ts *= 1000
+		}
+
+		batch.add("", ingestPoint{
+			Measurement: p.Metric,
+			Tags:        p.Tags,
+			Fields:      map[string]interface{}{"value": value},
+			Timestamp:   ts,
+		})
+	}
+
+	reqCtx, cancel := s.requestContext(ctx)
+	defer cancel()
+	if err := s.applySeriesBatch(reqCtx, batch); err != nil {
+		status, msg := statusForErr(err)
+		errorResponse(ctx, status, msg)
+		return
+	}
+
+	if len(parseErrors) > 0 {
+		errorResponse(ctx, fasthttp.StatusBadRequest, strings.Join(parseErrors, "; "))
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
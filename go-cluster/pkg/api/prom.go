@@ -0,0 +1,439 @@
+package api
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/lumadb/cluster/pkg/cluster"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/valyala/fasthttp"
+	"google.golang.org/protobuf/proto"
+)
+
+const promCollection = "metrics"
+
+// promWriteBatcher accumulates per-series Commands between flushes, so a
+// remote_write request with thousands of series doesn't Apply one Raft
+// entry per series. It flushes whenever either the batch reaches
+// batchSize or flushInterval elapses, whichever comes first.
+type promWriteBatcher struct {
+	mu            sync.Mutex
+	pending       []*cluster.Command
+	node          *cluster.Node
+	flushInterval time.Duration
+	batchSize     int
+	logger        logFunc
+}
+
+// logFunc lets promWriteBatcher log a flush failure without pulling in a
+// zap dependency just for this file's tests.
+type logFunc func(err error)
+
+func newPromWriteBatcher(node *cluster.Node, flushInterval time.Duration, log logFunc) *promWriteBatcher {
+	b := &promWriteBatcher{
+		node:          node,
+		flushInterval: flushInterval,
+		batchSize:     500,
+		logger:        log,
+	}
+	go b.run()
+	return b
+}
+
+func (b *promWriteBatcher) run() {
+	ticker := time.NewTicker(b.flushInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		b.flush()
+	}
+}
+
+// Add enqueues cmd, flushing immediately if the batch is now full.
+func (b *promWriteBatcher) Add(cmd *cluster.Command) {
+	b.mu.Lock()
+	b.pending = append(b.pending, cmd)
+	full := len(b.pending) >= b.batchSize
+	b.mu.Unlock()
+
+	if full {
+		b.flush()
+	}
+}
+
+func (b *promWriteBatcher) flush() {
+	b.mu.Lock()
+	if len(b.pending) == 0 {
+		b.mu.Unlock()
+		return
+	}
+	batch := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	if err := b.node.ApplyBatch(batch, 5*time.Second); err != nil && b.logger != nil {
+		b.logger(err)
+	}
+}
+
+// seriesFingerprint is a stable FNV-1a hash over a series' sorted label
+// pairs, used as the per-series Command key so the same series always
+// routes to (and overwrites data on) the same shard.
+func seriesFingerprint(labels []prompb.Label) uint64 {
+	sorted := make([]prompb.Label, len(labels))
+	copy(sorted, labels)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	h := fnv.New64a()
+	for _, l := range sorted {
+		h.Write([]byte(l.Name))
+		h.Write([]byte{'='})
+		h.Write([]byte(l.Value))
+		h.Write([]byte{0xff})
+	}
+	return h.Sum64()
+}
+
+func metricName(labels []prompb.Label) string {
+	for _, l := range labels {
+		if l.Name == "__name__" {
+			return l.Value
+		}
+	}
+	return ""
+}
+
+// handlePromWrite implements POST /api/v1/prom/write: a Prometheus
+// remote_write endpoint. Each TimeSeries is hashed to a stable per-series
+// key, routed via router.Route to find its shard owner, and - for series
+// this node owns - queued on promBatch for a batched Raft Apply.
+func (s *Server) handlePromWrite(ctx *fasthttp.RequestCtx) {
+	data, err := snappy.Decode(nil, ctx.PostBody())
+	if err != nil {
+		errorResponse(ctx, fasthttp.StatusBadRequest, "invalid snappy encoding: "+err.Error())
+		return
+	}
+
+	var req prompb.WriteRequest
+	if err := proto.Unmarshal(data, &req); err != nil {
+		errorResponse(ctx, fasthttp.StatusBadRequest, "invalid protobuf: "+err.Error())
+		return
+	}
+
+	for _, ts := range req.Timeseries {
+		fp := seriesFingerprint(ts.Labels)
+		key := strconv.FormatUint(fp, 16)
+
+		target, err := s.router.Route(ctx, promCollection, []byte(key))
+		if err != nil {
+			errorResponse(ctx, fasthttp.StatusInternalServerError, err.Error())
+			return
+		}
+		if target != "localhost" && !s.node.IsLeader() {
+			// Cross-node write forwarding isn't wired up yet - same
+			// known limitation as handleInsert's leader-only redirect.
+			continue
+		}
+
+		value, err := proto.Marshal(&ts)
+		if err != nil {
+			errorResponse(ctx, fasthttp.StatusInternalServerError, err.Error())
+			return
+		}
+
+		s.promBatch.Add(&cluster.Command{
+			Op:         "set",
+			Collection: promCollection,
+			Key:        key,
+			Value:      value,
+		})
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+// handlePromRead implements POST /api/v1/prom/read: a Prometheus
+// remote_read endpoint. Each Query's matchers are fanned out to the shard
+// owner via router.RouteRead; results come back either as a classic
+// snappy-proto ReadResponse, or as a STREAMED_XOR_CHUNKS response when the
+// client's AcceptedResponseTypes advertises it.
+func (s *Server) handlePromRead(ctx *fasthttp.RequestCtx) {
+	data, err := snappy.Decode(nil, ctx.PostBody())
+	if err != nil {
+		errorResponse(ctx, fasthttp.StatusBadRequest, "invalid snappy encoding: "+err.Error())
+		return
+	}
+
+	var req prompb.ReadRequest
+	if err := proto.Unmarshal(data, &req); err != nil {
+		errorResponse(ctx, fasthttp.StatusBadRequest, "invalid protobuf: "+err.Error())
+		return
+	}
+
+	streamed := false
+	for _, rt := range req.AcceptedResponseTypes {
+		if rt == prompb.ReadRequest_STREAMED_XOR_CHUNKS {
+			streamed = true
+			break
+		}
+	}
+
+	results := make([][]*prompb.TimeSeries, len(req.Queries))
+	for i, q := range req.Queries {
+		measurement, filter := matchersToFilter(q.Matchers)
+
+		_, err := s.router.RouteRead(ctx, promCollection, []byte(measurement))
+		if err != nil {
+			errorResponse(ctx, fasthttp.StatusInternalServerError, err.Error())
+			return
+		}
+
+		series, err := s.node.QueryTimeseries(measurement, filter, q.StartTimestampMs, q.EndTimestampMs)
+		if err != nil {
+			errorResponse(ctx, fasthttp.StatusInternalServerError, err.Error())
+			return
+		}
+		results[i] = series
+	}
+
+	if streamed {
+		writeChunkedReadResponse(ctx, results)
+		return
+	}
+
+	resp := &prompb.ReadResponse{Results: make([]*prompb.QueryResult, len(results))}
+	for i, series := range results {
+		resp.Results[i] = &prompb.QueryResult{Timeseries: series}
+	}
+
+	out, err := proto.Marshal(resp)
+	if err != nil {
+		errorResponse(ctx, fasthttp.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.SetContentType("application/x-protobuf")
+	ctx.Response.Header.Set("Content-Encoding", "snappy")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	ctx.Write(snappy.Encode(nil, out))
+}
+
+// matchersToFilter translates a Prometheus remote_read query's label
+// matchers into the engine's measurement name plus equality filter. Only
+// MatchEqual is honored for labels other than "__name__"; regex and
+// negative matchers are dropped and do not narrow the result.
+func matchersToFilter(matchers []*prompb.LabelMatcher) (measurement string, filter map[string]string) {
+	filter = make(map[string]string)
+	for _, m := range matchers {
+		if m.Name == "__name__" {
+			if m.Type == prompb.LabelMatcher_EQ {
+				measurement = m.Value
+			}
+			continue
+		}
+		if m.Type == prompb.LabelMatcher_EQ {
+			filter[m.Name] = m.Value
+		}
+	}
+	return measurement, filter
+}
+
+// writeChunkedReadResponse streams results back using Prometheus'
+// chunked remote_read framing: each frame is a big-endian uint32 length of
+// a snappy-block-compressed ChunkedReadResponse, followed by a Castagnoli
+// CRC32 of the compressed bytes.
+func writeChunkedReadResponse(ctx *fasthttp.RequestCtx, results [][]*prompb.TimeSeries) {
+	ctx.SetContentType("application/x-streamed-protobuf; proto=prometheus.ChunkedReadResponse")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		for queryIdx, series := range results {
+			chunked := make([]*prompb.ChunkedSeries, 0, len(series))
+			for _, ts := range series {
+				chunked = append(chunked, &prompb.ChunkedSeries{
+					Labels: ts.Labels,
+					Chunks: []prompb.Chunk{encodeXORChunk(ts.Samples)},
+				})
+			}
+
+			frame, err := proto.Marshal(&prompb.ChunkedReadResponse{
+				ChunkedSeries: chunked,
+				QueryIndex:    int64(queryIdx),
+			})
+			if err != nil {
+				return
+			}
+
+			if err := writeChunkFrame(w, frame); err != nil {
+				return
+			}
+			w.Flush()
+		}
+	})
+}
+
+// writeChunkFrame writes one length-prefixed, CRC-checked, snappy-block
+// compressed frame per the remote_read streaming wire format.
+func writeChunkFrame(w io.Writer, frame []byte) error {
+	compressed := snappy.Encode(nil, frame)
+
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(compressed)))
+	if _, err := w.Write(lenBuf[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(compressed); err != nil {
+		return err
+	}
+
+	var crcBuf [4]byte
+	binary.BigEndian.PutUint32(crcBuf[:], crc32.Checksum(compressed, crc32.MakeTable(crc32.Castagnoli)))
+	_, err := w.Write(crcBuf[:])
+	return err
+}
+
+// bitWriter is a minimal MSB-first bit-level writer, the building block
+// Gorilla's XOR float and delta-of-delta timestamp encoding are written
+// with.
+type bitWriter struct {
+	buf  []byte
+	bits uint8 // number of valid bits already written into the last byte
+}
+
+func (w *bitWriter) writeBit(b bool) {
+	if w.bits == 0 {
+		w.buf = append(w.buf, 0)
+	}
+	if b {
+		w.buf[len(w.buf)-1] |= 1 << (7 - w.bits)
+	}
+	w.bits++
+	if w.bits == 8 {
+		w.bits = 0
+	}
+}
+
+func (w *bitWriter) writeBits(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.writeBit((v>>uint(i))&1 == 1)
+	}
+}
+
+// encodeXORChunk implements the Facebook Gorilla encoding: the first
+// sample is stored verbatim, every later timestamp is delta-of-delta
+// encoded and every later value is XORed against the previous value, both
+// using the variable-length bit-width buckets from the Gorilla paper.
+func encodeXORChunk(samples []prompb.Sample) prompb.Chunk {
+	bw := &bitWriter{}
+	if len(samples) == 0 {
+		return prompb.Chunk{Type: prompb.Chunk_XOR}
+	}
+
+	bw.writeBits(uint64(samples[0].Timestamp), 64)
+	bw.writeBits(math.Float64bits(samples[0].Value), 64)
+
+	prevTS := samples[0].Timestamp
+	prevValue := samples[0].Value
+	prevDelta := int64(0)
+	prevLeading, prevTrailing := uint8(64), uint8(0)
+
+	for i := 1; i < len(samples); i++ {
+		ts := samples[i].Timestamp
+		delta := ts - prevTS
+		dod := delta - prevDelta
+		writeDoD(bw, dod)
+		prevDelta = delta
+		prevTS = ts
+
+		prevLeading, prevTrailing = writeXORValue(bw, samples[i].Value, prevValue, prevLeading, prevTrailing)
+		prevValue = samples[i].Value
+	}
+
+	return prompb.Chunk{
+		MinTimeMs: samples[0].Timestamp,
+		MaxTimeMs: samples[len(samples)-1].Timestamp,
+		Type:      prompb.Chunk_XOR,
+		Data:      bw.buf,
+	}
+}
+
+// writeDoD writes a delta-of-delta timestamp using Gorilla's variable
+// length encoding: '0' for no change, then progressively wider prefixes
+// and value widths for larger swings.
+func writeDoD(bw *bitWriter, dod int64) {
+	switch {
+	case dod == 0:
+		bw.writeBit(false)
+	case dod >= -63 && dod <= 64:
+		bw.writeBits(0b10, 2)
+		bw.writeBits(uint64(dod)&0x7f, 7)
+	case dod >= -255 && dod <= 256:
+		bw.writeBits(0b110, 3)
+		bw.writeBits(uint64(dod)&0x1ff, 9)
+	case dod >= -2047 && dod <= 2048:
+		bw.writeBits(0b1110, 4)
+		bw.writeBits(uint64(dod)&0xfff, 12)
+	default:
+		bw.writeBits(0b1111, 4)
+		bw.writeBits(uint64(dod), 64)
+	}
+}
+
+// writeXORValue XORs value against prev and writes the result with
+// Gorilla's leading/trailing-zero-count control bits, returning the
+// leading/trailing zero counts to reuse if the next value's XOR block
+// falls inside the same window.
+func writeXORValue(bw *bitWriter, value, prev float64, prevLeading, prevTrailing uint8) (uint8, uint8) {
+	xor := math.Float64bits(value) ^ math.Float64bits(prev)
+	if xor == 0 {
+		bw.writeBit(false)
+		return prevLeading, prevTrailing
+	}
+
+	bw.writeBit(true)
+	leading := uint8(leadingZeros64(xor))
+	trailing := uint8(trailingZeros64(xor))
+
+	if leading >= prevLeading && trailing >= prevTrailing {
+		bw.writeBit(false)
+		bw.writeBits(xor>>prevTrailing, 64-int(prevLeading)-int(prevTrailing))
+		return prevLeading, prevTrailing
+	}
+
+	bw.writeBit(true)
+	bw.writeBits(uint64(leading), 5)
+	sigBits := 64 - int(leading) - int(trailing)
+	bw.writeBits(uint64(sigBits), 6)
+	bw.writeBits(xor>>trailing, sigBits)
+	return leading, trailing
+}
+
+func leadingZeros64(x uint64) int {
+	n := 0
+	for i := 63; i >= 0; i-- {
+		if (x>>uint(i))&1 == 1 {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+func trailingZeros64(x uint64) int {
+	n := 0
+	for i := 0; i < 64; i++ {
+		if (x>>uint(i))&1 == 1 {
+			break
+		}
+		n++
+	}
+	return n
+}
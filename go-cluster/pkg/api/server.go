@@ -2,69 +2,220 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http/pprof"
 	"time"
 
 	"github.com/fasthttp/router"
 	"github.com/lumadb/cluster/pkg/ai"
 	"github.com/lumadb/cluster/pkg/cluster"
+	"github.com/lumadb/cluster/pkg/cluster/capability"
+	"github.com/lumadb/cluster/pkg/dialects"
+	"github.com/lumadb/cluster/pkg/metrics"
 	clu_router "github.com/lumadb/cluster/pkg/router"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 )
 
+// defaultApplyTimeout is how long a handler waits on a Raft Apply when the
+// caller doesn't override it via X-Request-Timeout or ?timeout=.
+const defaultApplyTimeout = 5 * time.Second
+
+// statusClientClosedRequest is nginx's de facto 499, used when the caller
+// disconnects before an Apply completes - there's no IANA-registered status
+// for that case, and 499 is the convention operators already expect.
+const statusClientClosedRequest = 499
+
 // Server is the HTTP API server
 type Server struct {
-	node   *cluster.Node
-	router *clu_router.Router
-	rag    *ai.RAGService
-	logger *zap.Logger
-	r      *router.Router
+	node         *cluster.Node
+	router       *clu_router.Router
+	rag          *ai.RAGService
+	logger       *zap.Logger
+	r            *router.Router
+	promBatch    *promWriteBatcher
+	dialects     *dialects.Registry
+	capabilities *capability.Set
+
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	applyTimeout time.Duration
+
+	pprofEnabled     bool
+	streamWSBridgeOn bool
 }
 
 // NewServer creates a new API server
 func NewServer(node *cluster.Node, rtr *clu_router.Router, rag *ai.RAGService, logger *zap.Logger) *Server {
 	s := &Server{
-		node:   node,
-		router: rtr,
-		rag:    rag,
-		logger: logger,
-		r:      router.New(),
+		node:         node,
+		router:       rtr,
+		rag:          rag,
+		logger:       logger,
+		r:            router.New(),
+		dialects:     dialects.NewRegistry(),
+		readTimeout:  30 * time.Second,
+		writeTimeout: 30 * time.Second,
+		applyTimeout: defaultApplyTimeout,
 	}
+	s.promBatch = newPromWriteBatcher(node, time.Second, func(err error) {
+		logger.Error("prom write batch flush failed", zap.Error(err))
+	})
 
 	s.setupRoutes()
+	go s.reportConnectionPoolMetrics()
 	return s
 }
 
+// EnableProfiling mounts /debug/pprof/* once, if enabled is true. Intended
+// to be gated behind an operator-controlled config flag rather than always
+// exposed, since pprof's profile/trace endpoints are not something to
+// leave open on an untrusted network.
+func (s *Server) EnableProfiling(enabled bool) {
+	if !enabled || s.pprofEnabled {
+		return
+	}
+	s.pprofEnabled = true
+
+	s.r.GET("/debug/pprof/", fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Index))
+	s.r.GET("/debug/pprof/cmdline", fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Cmdline))
+	s.r.GET("/debug/pprof/profile", fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Profile))
+	s.r.GET("/debug/pprof/symbol", fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Symbol))
+	s.r.GET("/debug/pprof/trace", fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Trace))
+	s.r.GET("/debug/pprof/{profile}", fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Index))
+}
+
+// EnableStreamWSBridge mounts /ws/stream once, bridging LumaService.Stream
+// to browsers as newline-delimited JSON over a WebSocket, since browsers
+// can't speak gRPC directly. conn should point at a node capable of serving
+// LumaService - often this same process's own gRPC listener.
+func (s *Server) EnableStreamWSBridge(conn *grpc.ClientConn, opts StreamWSBridgeOptions) {
+	if s.streamWSBridgeOn {
+		return
+	}
+	s.streamWSBridgeOn = true
+
+	bridge := NewStreamWSBridge(conn, s.logger, opts)
+	s.r.GET("/ws/stream", fasthttpadaptor.NewFastHTTPHandlerFunc(bridge.ServeHTTP))
+}
+
+// EnableCapabilities gives the server a cluster-wide capability.Set and
+// mounts GET /cluster/capabilities, which reports it as
+// {cluster_version, enabled, per_node}.
+func (s *Server) EnableCapabilities(set *capability.Set) {
+	s.capabilities = set
+	s.r.GET("/cluster/capabilities", metrics.InstrumentRoute("/cluster/capabilities", s.handleCapabilities))
+}
+
+// reportConnectionPoolMetrics periodically samples the router's connection
+// pool so operators can see per-peer connection counts on the /metrics
+// endpoint without reconstructing them from Raft heartbeats.
+func (s *Server) reportConnectionPoolMetrics() {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		for addr, n := range s.router.PoolStats() {
+			metrics.ConnectionPoolSize.WithLabelValues(addr).Set(float64(n))
+		}
+	}
+}
+
+// SetDefaultTimeouts overrides the server's default read, write and Raft
+// apply timeouts, so operators can tune request SLAs without recompiling.
+// A caller can still override the apply timeout for a single request via
+// the X-Request-Timeout header or a ?timeout= query parameter.
+func (s *Server) SetDefaultTimeouts(read, write, apply time.Duration) {
+	s.readTimeout = read
+	s.writeTimeout = write
+	s.applyTimeout = apply
+}
+
+// Start begins serving the HTTP API on addr, applying the server's
+// configured read/write timeouts to the underlying fasthttp.Server.
+func (s *Server) Start(addr string) error {
+	srv := &fasthttp.Server{
+		Handler:      s.r.Handler,
+		ReadTimeout:  s.readTimeout,
+		WriteTimeout: s.writeTimeout,
+	}
+	return srv.ListenAndServe(addr)
+}
+
+// requestContext derives a context.Context for ctx bounded by the server's
+// applyTimeout (or an X-Request-Timeout header / ?timeout= override) that
+// is also canceled the moment the client disconnects, since
+// *fasthttp.RequestCtx already satisfies context.Context and closes its
+// own Done() on connection close.
+func (s *Server) requestContext(ctx *fasthttp.RequestCtx) (context.Context, context.CancelFunc) {
+	timeout := s.applyTimeout
+	if raw := ctx.Request.Header.Peek("X-Request-Timeout"); len(raw) > 0 {
+		if d, err := time.ParseDuration(string(raw)); err == nil {
+			timeout = d
+		}
+	}
+	if raw := ctx.QueryArgs().Peek("timeout"); len(raw) > 0 {
+		if d, err := time.ParseDuration(string(raw)); err == nil {
+			timeout = d
+		}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// timedApply calls node.Apply, recording Raft apply latency and in-flight
+// count around it so handlers don't each have to instrument their own
+// call site.
+func timedApply(ctx context.Context, node *cluster.Node, cmd *cluster.Command) error {
+	metrics.RaftApplyInflight.Inc()
+	defer metrics.RaftApplyInflight.Dec()
+
+	start := time.Now()
+	err := node.Apply(ctx, cmd)
+	metrics.RaftApplyDuration.Observe(time.Since(start).Seconds())
+	return err
+}
+
 func (s *Server) setupRoutes() {
 	// Health check
-	s.r.GET("/health", s.handleHealth)
+	s.r.GET("/health", metrics.InstrumentRoute("/health", s.handleHealth))
 
 	// Cluster info
-	s.r.GET("/cluster", s.handleClusterInfo)
-	s.r.GET("/cluster/topology", s.handleTopology)
+	s.r.GET("/cluster", metrics.InstrumentRoute("/cluster", s.handleClusterInfo))
+	s.r.GET("/cluster/topology", metrics.InstrumentRoute("/cluster/topology", s.handleTopology))
 
 	// Query API (stateless operations)
 	// API V1
-	s.r.POST("/api/v1/query", s.handleQuery)
+	s.r.POST("/api/v1/query", metrics.InstrumentRoute("/api/v1/query", s.handleQuery))
 	// Document operations
-	s.r.GET("/api/v1/collections/{collection}/{id}", s.handleGet)
-	s.r.POST("/api/v1/collections/{collection}", s.handleInsert)
-	s.r.PUT("/api/v1/collections/{collection}/{id}", s.handleUpdate)
-	s.r.DELETE("/api/v1/collections/{collection}/{id}", s.handleDelete)
+	s.r.GET("/api/v1/collections/{collection}/{id}", metrics.InstrumentRoute("/api/v1/collections/{collection}/{id}", s.handleGet))
+	s.r.POST("/api/v1/collections/{collection}", metrics.InstrumentRoute("/api/v1/collections/{collection}", s.handleInsert))
+	s.r.PUT("/api/v1/collections/{collection}/{id}", metrics.InstrumentRoute("/api/v1/collections/{collection}/{id}", s.handleUpdate))
+	s.r.DELETE("/api/v1/collections/{collection}/{id}", metrics.InstrumentRoute("/api/v1/collections/{collection}/{id}", s.handleDelete))
 
 	// Batch operations
-	s.r.POST("/api/v1/batch", s.handleBatch)
+	s.r.POST("/api/v1/batch", metrics.InstrumentRoute("/api/v1/batch", s.handleBatch))
 
 	// Collection management
-	s.r.GET("/api/v1/collections", s.handleListCollections)
-	s.r.POST("/api/v1/collections/{collection}/indexes", s.handleCreateIndex)
+	s.r.GET("/api/v1/collections", metrics.InstrumentRoute("/api/v1/collections", s.handleListCollections))
+	s.r.POST("/api/v1/collections/{collection}/indexes", metrics.InstrumentRoute("/api/v1/collections/{collection}/indexes", s.handleCreateIndex))
 
 	// RAG Ingest and Query
-	s.r.POST("/api/v1/rag/ingest", s.handleRAGIngest)
-	s.r.POST("/api/v1/rag/query", s.handleRAGQuery)
+	s.r.POST("/api/v1/rag/ingest", metrics.InstrumentRoute("/api/v1/rag/ingest", s.handleRAGIngest))
+	s.r.POST("/api/v1/rag/query", metrics.InstrumentRoute("/api/v1/rag/query", s.handleRAGQuery))
+
+	// Prometheus remote_write / remote_read
+	s.r.POST("/api/v1/prom/write", metrics.InstrumentRoute("/api/v1/prom/write", s.handlePromWrite))
+	s.r.POST("/api/v1/prom/read", metrics.InstrumentRoute("/api/v1/prom/read", s.handlePromRead))
+
+	// InfluxDB line protocol / OpenTSDB JSON ingestion
+	s.r.POST("/write", metrics.InstrumentRoute("/write", s.handleWrite))
+	s.r.POST("/api/put", metrics.InstrumentRoute("/api/put", s.handlePut))
 
 	// Metrics
 	s.r.GET("/metrics", s.handleMetrics)
@@ -88,6 +239,21 @@ func errorResponse(ctx *fasthttp.RequestCtx, code int, message string) {
 	jsonResponse(ctx, code, map[string]string{"error": message})
 }
 
+// redirectToLeader replies 307 to the leader's address and reports a
+// "redirect" router decision if node isn't the Raft leader. Every write
+// path must go through the leader, so this is the first check in every
+// handler that calls Apply.
+func redirectToLeader(ctx *fasthttp.RequestCtx, node *cluster.Node) bool {
+	if node.IsLeader() {
+		return false
+	}
+	metrics.RouterDecisions.WithLabelValues("redirect").Inc()
+	jsonResponse(ctx, fasthttp.StatusTemporaryRedirect, map[string]string{
+		"redirect": node.LeaderAddr(),
+	})
+	return true
+}
+
 func (s *Server) handleHealth(ctx *fasthttp.RequestCtx) {
 	jsonResponse(ctx, fasthttp.StatusOK, map[string]interface{}{
 		"status":    "healthy",
@@ -109,6 +275,25 @@ func (s *Server) handleTopology(ctx *fasthttp.RequestCtx) {
 	jsonResponse(ctx, fasthttp.StatusOK, s.router.GetClusterTopology())
 }
 
+// capabilitiesResponse is the body of GET /cluster/capabilities.
+type capabilitiesResponse struct {
+	ClusterVersion string                              `json:"cluster_version"`
+	Enabled        []string                            `json:"enabled"`
+	PerNode        map[uint64]capability.Advertisement `json:"per_node"`
+}
+
+func (s *Server) handleCapabilities(ctx *fasthttp.RequestCtx) {
+	if s.capabilities == nil {
+		errorResponse(ctx, fasthttp.StatusNotImplemented, "capability negotiation not configured")
+		return
+	}
+	jsonResponse(ctx, fasthttp.StatusOK, capabilitiesResponse{
+		ClusterVersion: s.capabilities.MinClusterVersion(),
+		Enabled:        s.capabilities.EnabledList(),
+		PerNode:        s.capabilities.PerNode(),
+	})
+}
+
 func (s *Server) handleQuery(ctx *fasthttp.RequestCtx) {
 	var req QueryRequest
 	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
@@ -116,8 +301,28 @@ func (s *Server) handleQuery(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	dialect, confidence, plan, err := s.dialects.Translate(req.Query)
+	if err != nil {
+		errorResponse(ctx, fasthttp.StatusBadRequest, err.Error())
+		return
+	}
+	ctx.SetUserValue("detected_dialect", string(dialect))
+
+	if string(ctx.QueryArgs().Peek("dry_run")) == "true" {
+		jsonResponse(ctx, fasthttp.StatusOK, map[string]interface{}{
+			"dialect":    dialect,
+			"confidence": confidence,
+			"plan":       plan,
+		})
+		return
+	}
+
 	// Route the query to appropriate node
-	target, err := s.router.Route(ctx, req.Collection, []byte(req.Query))
+	collection := req.Collection
+	if collection == "" {
+		collection = plan.Select.Source
+	}
+	target, err := s.router.Route(ctx, collection, []byte(req.Query))
 	if err != nil {
 		errorResponse(ctx, fasthttp.StatusInternalServerError, err.Error())
 		return
@@ -125,15 +330,20 @@ func (s *Server) handleQuery(ctx *fasthttp.RequestCtx) {
 
 	// If local, execute; otherwise forward
 	if target == "localhost" || s.node.IsLeader() {
-		// Execute locally
-		// TODO: Integrate with Rust storage engine
+		metrics.RouterDecisions.WithLabelValues("local").Inc()
+		result, err := s.node.ExecutePlan(&plan)
+		if err != nil {
+			errorResponse(ctx, fasthttp.StatusInternalServerError, err.Error())
+			return
+		}
 		jsonResponse(ctx, fasthttp.StatusOK, map[string]interface{}{
-			"status":    "ok",
-			"documents": []interface{}{},
-			"count":     0,
+			"status":  "ok",
+			"dialect": dialect,
+			"result":  result,
 		})
 	} else {
 		// Forward to leader - simplified redirect
+		metrics.RouterDecisions.WithLabelValues("forwarded").Inc()
 		jsonResponse(ctx, fasthttp.StatusTemporaryRedirect, map[string]string{
 			"redirect": target,
 		})
@@ -168,10 +378,7 @@ func (s *Server) handleInsert(ctx *fasthttp.RequestCtx) {
 	}
 
 	// Must go through Raft for consistency
-	if !s.node.IsLeader() {
-		jsonResponse(ctx, fasthttp.StatusTemporaryRedirect, map[string]string{
-			"redirect": s.node.LeaderAddr(),
-		})
+	if redirectToLeader(ctx, s.node) {
 		return
 	}
 
@@ -191,8 +398,11 @@ func (s *Server) handleInsert(ctx *fasthttp.RequestCtx) {
 		Value:      docBytes,
 	}
 
-	if err := s.node.Apply(cmd, 5*time.Second); err != nil {
-		errorResponse(ctx, fasthttp.StatusInternalServerError, err.Error())
+	reqCtx, cancel := s.requestContext(ctx)
+	defer cancel()
+	if err := timedApply(reqCtx, s.node, cmd); err != nil {
+		status, msg := statusForErr(err)
+		errorResponse(ctx, status, msg)
 		return
 	}
 
@@ -213,10 +423,7 @@ func (s *Server) handleUpdate(ctx *fasthttp.RequestCtx) {
 	}
 	doc["_id"] = id
 
-	if !s.node.IsLeader() {
-		jsonResponse(ctx, fasthttp.StatusTemporaryRedirect, map[string]string{
-			"redirect": s.node.LeaderAddr(),
-		})
+	if redirectToLeader(ctx, s.node) {
 		return
 	}
 
@@ -228,8 +435,11 @@ func (s *Server) handleUpdate(ctx *fasthttp.RequestCtx) {
 		Value:      docBytes,
 	}
 
-	if err := s.node.Apply(cmd, 5*time.Second); err != nil {
-		errorResponse(ctx, fasthttp.StatusInternalServerError, err.Error())
+	reqCtx, cancel := s.requestContext(ctx)
+	defer cancel()
+	if err := timedApply(reqCtx, s.node, cmd); err != nil {
+		status, msg := statusForErr(err)
+		errorResponse(ctx, status, msg)
 		return
 	}
 
@@ -243,10 +453,7 @@ func (s *Server) handleDelete(ctx *fasthttp.RequestCtx) {
 	collection := ctx.UserValue("collection").(string)
 	id := ctx.UserValue("id").(string)
 
-	if !s.node.IsLeader() {
-		jsonResponse(ctx, fasthttp.StatusTemporaryRedirect, map[string]string{
-			"redirect": s.node.LeaderAddr(),
-		})
+	if redirectToLeader(ctx, s.node) {
 		return
 	}
 
@@ -256,8 +463,11 @@ func (s *Server) handleDelete(ctx *fasthttp.RequestCtx) {
 		Key:        id,
 	}
 
-	if err := s.node.Apply(cmd, 5*time.Second); err != nil {
-		errorResponse(ctx, fasthttp.StatusInternalServerError, err.Error())
+	reqCtx, cancel := s.requestContext(ctx)
+	defer cancel()
+	if err := timedApply(reqCtx, s.node, cmd); err != nil {
+		status, msg := statusForErr(err)
+		errorResponse(ctx, status, msg)
 		return
 	}
 
@@ -267,6 +477,12 @@ func (s *Server) handleDelete(ctx *fasthttp.RequestCtx) {
 	})
 }
 
+// handleBatch implements POST /api/v1/batch. Each BatchOperation is
+// insert|update|delete|get, optionally CAS-guarded by if_match. When
+// atomic=true the whole batch is applied as one compound "txn" Command -
+// all-or-nothing, in one Raft log entry; when false, ops are applied
+// sequentially and independently, Elasticsearch _bulk style, with a
+// per-op status in the response rather than a single pass/fail.
 func (s *Server) handleBatch(ctx *fasthttp.RequestCtx) {
 	var req BatchRequest
 	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
@@ -274,20 +490,184 @@ func (s *Server) handleBatch(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	// Process batch operations
-	results := make([]map[string]interface{}, 0, len(req.Operations))
-	for _, op := range req.Operations {
-		results = append(results, map[string]interface{}{
-			"op":     op.Op,
-			"status": "ok",
-		})
+	if redirectToLeader(ctx, s.node) {
+		return
 	}
 
-	jsonResponse(ctx, fasthttp.StatusOK, map[string]interface{}{
-		"results": results,
+	reqCtx, cancel := s.requestContext(ctx)
+	defer cancel()
+
+	var results []BatchOpResult
+	if req.Atomic {
+		results = s.applyAtomicBatch(reqCtx, req.Operations)
+	} else {
+		results = s.applySequentialBatch(reqCtx, req.Operations)
+	}
+
+	jsonResponse(ctx, batchStatusCode(results), BatchResponse{
+		Atomic:  req.Atomic,
+		Results: results,
 	})
 }
 
+// applyAtomicBatch builds one compound "txn" Command carrying the ordered
+// op list and applies it as a single Raft log entry, so the FSM can
+// enforce all-or-nothing semantics before any op is visible.
+func (s *Server) applyAtomicBatch(ctx context.Context, ops []BatchOperation) []BatchOpResult {
+	cmds := make([]*cluster.Command, 0, len(ops))
+	for _, op := range ops {
+		cmd, errResult := batchOpToCommand(op)
+		if errResult != nil {
+			// A malformed op fails the whole transaction before it's
+			// ever sent to Raft - nothing has been applied yet.
+			return failAllBatch(ops, *errResult)
+		}
+		cmds = append(cmds, cmd)
+	}
+
+	txn := &cluster.Command{Op: "txn", Ops: cmds}
+	if err := timedApply(ctx, s.node, txn); err != nil {
+		status, msg := statusForErr(err)
+		return failAllBatch(ops, BatchOpResult{Status: status, Error: msg})
+	}
+
+	// A successful txn Apply implies every op committed; the FSM's
+	// per-op versions come back on txn.Results once ApplyBatch actually
+	// returns them per-op rather than one aggregate error.
+	results := make([]BatchOpResult, len(ops))
+	for i, op := range ops {
+		results[i] = BatchOpResult{Op: op.Op, ID: op.ID, Status: fasthttp.StatusOK}
+	}
+	return results
+}
+
+// applySequentialBatch applies each op independently, so one op's
+// failure (including a CAS conflict) doesn't prevent the rest of the
+// batch from being attempted.
+func (s *Server) applySequentialBatch(ctx context.Context, ops []BatchOperation) []BatchOpResult {
+	results := make([]BatchOpResult, len(ops))
+	for i, op := range ops {
+		results[i] = s.applyBatchOp(ctx, op)
+	}
+	return results
+}
+
+func (s *Server) applyBatchOp(ctx context.Context, op BatchOperation) BatchOpResult {
+	if op.Op == "get" {
+		doc, version, err := s.node.Get(op.Collection, op.ID)
+		if err != nil {
+			status, msg := statusForErr(err)
+			return BatchOpResult{Op: op.Op, ID: op.ID, Status: status, Error: msg}
+		}
+		_ = doc
+		return BatchOpResult{Op: op.Op, ID: op.ID, Status: fasthttp.StatusOK, Version: version}
+	}
+
+	cmd, errResult := batchOpToCommand(op)
+	if errResult != nil {
+		return *errResult
+	}
+
+	if err := timedApply(ctx, s.node, cmd); err != nil {
+		status, msg := statusForErr(err)
+		return BatchOpResult{Op: op.Op, ID: op.ID, Status: status, Error: msg}
+	}
+
+	return BatchOpResult{Op: op.Op, ID: op.ID, Status: batchSuccessStatus(op.Op), Version: op.IfMatch + 1}
+}
+
+// batchOpToCommand validates and converts one BatchOperation into the
+// cluster.Command Apply expects, or a failure BatchOpResult if the op is
+// malformed (e.g. update/delete without an ID).
+func batchOpToCommand(op BatchOperation) (*cluster.Command, *BatchOpResult) {
+	switch op.Op {
+	case "insert", "update":
+		id := op.ID
+		if id == "" {
+			if v, ok := op.Document["_id"].(string); ok {
+				id = v
+			}
+		}
+		if id == "" {
+			return nil, &BatchOpResult{Op: op.Op, Status: fasthttp.StatusBadRequest, Error: "missing _id"}
+		}
+		docBytes, _ := json.Marshal(op.Document)
+		return &cluster.Command{
+			Op:         "set",
+			Collection: op.Collection,
+			Key:        id,
+			Value:      docBytes,
+			IfMatch:    op.IfMatch,
+		}, nil
+	case "delete":
+		if op.ID == "" {
+			return nil, &BatchOpResult{Op: op.Op, Status: fasthttp.StatusBadRequest, Error: "missing id"}
+		}
+		return &cluster.Command{
+			Op:         "delete",
+			Collection: op.Collection,
+			Key:        op.ID,
+			IfMatch:    op.IfMatch,
+		}, nil
+	default:
+		return nil, &BatchOpResult{Op: op.Op, Status: fasthttp.StatusBadRequest, Error: fmt.Sprintf("unsupported op %q", op.Op)}
+	}
+}
+
+func batchSuccessStatus(op string) int {
+	if op == "insert" {
+		return fasthttp.StatusCreated
+	}
+	return fasthttp.StatusOK
+}
+
+// statusForErr maps an Apply/Get error to the HTTP status the caller
+// should see: 499 if the client disconnected before Apply finished, 504 if
+// the deadline fired first, 409 for a CAS conflict, 500 for anything else.
+func statusForErr(err error) (int, string) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return statusClientClosedRequest, err.Error()
+	case errors.Is(err, context.DeadlineExceeded):
+		return fasthttp.StatusGatewayTimeout, err.Error()
+	case errors.Is(err, cluster.ErrVersionConflict):
+		return fasthttp.StatusConflict, err.Error()
+	default:
+		return fasthttp.StatusInternalServerError, err.Error()
+	}
+}
+
+// failAllBatch reports result against every op in ops, used when an
+// atomic batch fails (or is rejected) before or during the single Raft
+// Apply, so no op in the transaction is left unreported.
+func failAllBatch(ops []BatchOperation, result BatchOpResult) []BatchOpResult {
+	results := make([]BatchOpResult, len(ops))
+	for i, op := range ops {
+		r := result
+		r.Op = op.Op
+		r.ID = op.ID
+		results[i] = r
+	}
+	return results
+}
+
+// batchStatusCode returns 207 Multi-Status whenever results mix successes
+// and failures, and the (shared) status of every result when they all
+// agree - matching Elasticsearch _bulk's convention of only claiming a
+// single status code when the whole batch behaved identically.
+func batchStatusCode(results []BatchOpResult) int {
+	if len(results) == 0 {
+		return fasthttp.StatusOK
+	}
+	first := results[0].Status
+	for _, r := range results[1:] {
+		if r.Status != first {
+			return fasthttp.StatusMultiStatus
+		}
+	}
+	return first
+}
+
 func (s *Server) handleListCollections(ctx *fasthttp.RequestCtx) {
 	jsonResponse(ctx, fasthttp.StatusOK, map[string]interface{}{
 		"collections": []string{},
@@ -322,12 +702,16 @@ func (s *Server) handleRAGQuery(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	metrics.RAGTokens.WithLabelValues("query").Observe(approxTokenCount(req.Question))
+
 	result, err := s.rag.Query(req.Collection, req.Question)
 	if err != nil {
+		metrics.RAGQueryTotal.WithLabelValues("error").Inc()
 		errorResponse(ctx, fasthttp.StatusInternalServerError, err.Error())
 		return
 	}
 
+	metrics.RAGQueryTotal.WithLabelValues("success").Inc()
 	jsonResponse(ctx, fasthttp.StatusOK, result)
 }
 
@@ -343,19 +727,35 @@ func (s *Server) handleRAGIngest(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	metrics.RAGTokens.WithLabelValues("ingest").Observe(approxTokenCount(req.Text))
+
 	result, err := s.rag.Ingest(req.Collection, req.Text, req.Metadata)
 	if err != nil {
+		metrics.RAGIngestTotal.WithLabelValues("error").Inc()
 		errorResponse(ctx, fasthttp.StatusInternalServerError, err.Error())
 		return
 	}
 
+	metrics.RAGIngestTotal.WithLabelValues("success").Inc()
 	jsonResponse(ctx, fasthttp.StatusCreated, result)
 }
 
+// approxTokenCount estimates a token count from character length (~4
+// characters per token), avoiding a dependency on a real tokenizer just to
+// size the RAGTokens histogram.
+func approxTokenCount(s string) float64 {
+	return float64(len(s)) / 4
+}
+
+// metricsHandler serves the default registry in both OpenMetrics and
+// classic text exposition formats, via content negotiation on Accept.
+var metricsHandler = fasthttpadaptor.NewFastHTTPHandler(promhttp.HandlerFor(
+	prometheus.DefaultGatherer,
+	promhttp.HandlerOpts{EnableOpenMetrics: true},
+))
+
 func (s *Server) handleMetrics(ctx *fasthttp.RequestCtx) {
-	// TODO: Prometheus metrics
-	ctx.SetStatusCode(fasthttp.StatusOK)
-	fmt.Fprintf(ctx, "# LumaDB Metrics\n")
+	metricsHandler(ctx)
 }
 
 // Request/Response types
@@ -365,15 +765,38 @@ type QueryRequest struct {
 	Collection string `json:"collection,omitempty"`
 }
 
+// BatchRequest is the body of POST /api/v1/batch. See handleBatch for how
+// Atomic changes the commit semantics.
 type BatchRequest struct {
 	Operations []BatchOperation `json:"operations"`
+	Atomic     bool             `json:"atomic,omitempty"`
 }
 
+// BatchOperation is one insert|update|delete|get op within a BatchRequest.
+// IfMatch, when set, requires the document's current version to equal it
+// (optimistic concurrency); a mismatch fails the op with
+// cluster.ErrVersionConflict instead of applying it.
 type BatchOperation struct {
 	Op         string                 `json:"op"`
 	Collection string                 `json:"collection"`
 	Document   map[string]interface{} `json:"document,omitempty"`
 	ID         string                 `json:"id,omitempty"`
+	IfMatch    int64                  `json:"if_match,omitempty"`
+}
+
+// BatchOpResult is one op's outcome within a BatchResponse.
+type BatchOpResult struct {
+	Op      string `json:"op"`
+	ID      string `json:"_id,omitempty"`
+	Status  int    `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Version int64  `json:"version,omitempty"`
+}
+
+// BatchResponse is the body of POST /api/v1/batch's response.
+type BatchResponse struct {
+	Atomic  bool            `json:"atomic"`
+	Results []BatchOpResult `json:"results"`
 }
 
 type CreateIndexRequest struct {
@@ -394,9 +817,12 @@ type RAGIngestRequest struct {
 	Metadata   map[string]interface{} `json:"metadata"`
 }
 
-// NewGRPCServer creates a new gRPC server
-func NewGRPCServer(node *cluster.Node, rtr *clu_router.Router, logger *zap.Logger) *grpc.Server {
-	server := grpc.NewServer()
-	RegisterGRPCServer(server, node, logger)
+// NewGRPCServer creates a new gRPC server. It always registers
+// ZeroCopyCodec as the gRPC wire codec, reusing pooled buffers per frame
+// instead of the default codec's per-message allocation - worthwhile here
+// since Stream can emit many frames per call.
+func NewGRPCServer(node *cluster.Node, rtr *clu_router.Router, logger *zap.Logger, opts GRPCServerOptions) *grpc.Server {
+	server := grpc.NewServer(grpc.CustomCodec(&ZeroCopyCodec{}))
+	RegisterGRPCServer(server, node, logger, opts)
 	return server
 }
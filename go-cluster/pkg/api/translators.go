@@ -0,0 +1,272 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// promQLTranslator builds a PromQL range query and normalizes Prometheus'
+// matrix response shape back into Series.
+type promQLTranslator struct{}
+
+func (promQLTranslator) Translate(client *http.Client, backendURL *url.URL, q *MetricQuery) ([]Series, error) {
+	selector := q.Metric
+	if len(q.Filters) > 0 {
+		pairs := make([]string, len(q.Filters))
+		for i, f := range q.Filters {
+			pairs[i] = fmt.Sprintf("%s=%q", f.Key, f.Value)
+		}
+		selector = fmt.Sprintf("%s{%s}", q.Metric, strings.Join(pairs, ","))
+	}
+
+	promQuery := fmt.Sprintf("%s(%s)", promAggFunc(q.aggregation()), selector)
+	if len(q.GroupBy) > 0 {
+		promQuery = fmt.Sprintf("%s by (%s)", promQuery, strings.Join(q.GroupBy, ","))
+	}
+
+	params := url.Values{}
+	params.Set("query", promQuery)
+	params.Set("start", strconv.FormatInt(q.Start.Unix(), 10))
+	params.Set("end", strconv.FormatInt(q.End.Unix(), 10))
+	params.Set("step", q.step().String())
+
+	reqURL := fmt.Sprintf("%s/api/v1/query_range?%s", backendURL.String(), params.Encode())
+	resp, err := client.Get(reqURL)
+	if err != nil {
+		return nil, fmt.Errorf("prometheus query: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Metric map[string]string `json:"metric"`
+				Values [][2]interface{}  `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode prometheus response: %w", err)
+	}
+	if body.Status != "success" {
+		return nil, fmt.Errorf("prometheus query failed: %s", body.Error)
+	}
+
+	series := make([]Series, 0, len(body.Data.Result))
+	for _, r := range body.Data.Result {
+		s := Series{Metric: q.Metric, Labels: r.Metric, Engine: enginePrometheus}
+		for _, v := range r.Values {
+			ts, _ := v[0].(float64)
+			valStr, _ := v[1].(string)
+			val, _ := strconv.ParseFloat(valStr, 64)
+			s.Points = append(s.Points, Point{Timestamp: int64(ts), Value: val})
+		}
+		series = append(series, s)
+	}
+	return series, nil
+}
+
+// promAggFunc maps a MetricQuery aggregation to its PromQL function name.
+func promAggFunc(agg string) string {
+	switch agg {
+	case "sum", "min", "max", "count", "avg":
+		return agg
+	default:
+		return "avg"
+	}
+}
+
+// influxQLTranslator builds an InfluxQL SELECT and normalizes InfluxDB's
+// nested results/series JSON response back into Series.
+type influxQLTranslator struct{}
+
+func (influxQLTranslator) Translate(client *http.Client, backendURL *url.URL, q *MetricQuery) ([]Series, error) {
+	var whereClauses []string
+	for _, f := range q.Filters {
+		whereClauses = append(whereClauses, fmt.Sprintf("%q = '%s'", f.Key, f.Value))
+	}
+	whereClauses = append(whereClauses,
+		fmt.Sprintf("time >= '%s'", q.Start.UTC().Format(time.RFC3339)),
+		fmt.Sprintf("time <= '%s'", q.End.UTC().Format(time.RFC3339)))
+
+	influxQL := fmt.Sprintf("SELECT %s(value) FROM %q WHERE %s",
+		influxAggFunc(q.aggregation()), q.Metric, strings.Join(whereClauses, " AND "))
+	if len(q.GroupBy) > 0 {
+		groupBy := append([]string{fmt.Sprintf("time(%s)", q.step())}, q.GroupBy...)
+		influxQL = fmt.Sprintf("%s GROUP BY %s", influxQL, strings.Join(groupBy, ","))
+	} else {
+		influxQL = fmt.Sprintf("%s GROUP BY time(%s)", influxQL, q.step())
+	}
+
+	params := url.Values{}
+	params.Set("q", influxQL)
+
+	reqURL := fmt.Sprintf("%s/query?%s", backendURL.String(), params.Encode())
+	resp, err := client.Get(reqURL)
+	if err != nil {
+		return nil, fmt.Errorf("influxdb query: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Results []struct {
+			Error  string `json:"error"`
+			Series []struct {
+				Name    string            `json:"name"`
+				Tags    map[string]string `json:"tags"`
+				Columns []string          `json:"columns"`
+				Values  [][]interface{}   `json:"values"`
+			} `json:"series"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode influxdb response: %w", err)
+	}
+
+	var series []Series
+	for _, result := range body.Results {
+		if result.Error != "" {
+			return nil, fmt.Errorf("influxdb query failed: %s", result.Error)
+		}
+		for _, s := range result.Series {
+			out := Series{Metric: q.Metric, Labels: s.Tags, Engine: engineInfluxDB}
+			valueIdx := indexOf(s.Columns, q.aggregation())
+			if valueIdx < 0 {
+				valueIdx = 1 // columns[0] is always "time"
+			}
+			for _, row := range s.Values {
+				ts, err := time.Parse(time.RFC3339, fmt.Sprintf("%v", row[0]))
+				if err != nil {
+					continue
+				}
+				val, _ := row[valueIdx].(float64)
+				out.Points = append(out.Points, Point{Timestamp: ts.Unix(), Value: val})
+			}
+			series = append(series, out)
+		}
+	}
+	return series, nil
+}
+
+func influxAggFunc(agg string) string {
+	switch agg {
+	case "sum", "min", "max", "count", "mean":
+		return agg
+	case "avg":
+		return "mean"
+	default:
+		return "mean"
+	}
+}
+
+func indexOf(haystack []string, needle string) int {
+	for i, v := range haystack {
+		if v == needle {
+			return i
+		}
+	}
+	return -1
+}
+
+// druidTranslator builds a Druid native timeseries query and normalizes
+// Druid's JSON array-of-buckets response back into Series.
+type druidTranslator struct{}
+
+func (druidTranslator) Translate(client *http.Client, backendURL *url.URL, q *MetricQuery) ([]Series, error) {
+	aggType, fieldName := druidAggregation(q.aggregation())
+
+	body := map[string]interface{}{
+		"queryType":   "timeseries",
+		"dataSource":  q.Metric,
+		"granularity": druidGranularity(q.step()),
+		"intervals":   []string{fmt.Sprintf("%s/%s", q.Start.UTC().Format(time.RFC3339), q.End.UTC().Format(time.RFC3339))},
+		"aggregations": []map[string]interface{}{
+			{"type": aggType, "name": "value", "fieldName": fieldName},
+		},
+	}
+	if filter := druidFilter(q.Filters); filter != nil {
+		body["filter"] = filter
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("encode druid query: %w", err)
+	}
+
+	resp, err := client.Post(backendURL.String()+"/druid/v2", "application/json", strings.NewReader(string(payload)))
+	if err != nil {
+		return nil, fmt.Errorf("druid query: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var buckets []struct {
+		Timestamp string             `json:"timestamp"`
+		Result    map[string]float64 `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&buckets); err != nil {
+		return nil, fmt.Errorf("decode druid response: %w", err)
+	}
+
+	series := Series{Metric: q.Metric, Labels: labelsFromFilters(q.Filters), Engine: engineDruid}
+	for _, bucket := range buckets {
+		ts, err := time.Parse(time.RFC3339, bucket.Timestamp)
+		if err != nil {
+			continue
+		}
+		series.Points = append(series.Points, Point{Timestamp: ts.Unix(), Value: bucket.Result["value"]})
+	}
+	return []Series{series}, nil
+}
+
+func druidAggregation(agg string) (aggType, fieldName string) {
+	switch agg {
+	case "sum":
+		return "doubleSum", "value"
+	case "min":
+		return "doubleMin", "value"
+	case "max":
+		return "doubleMax", "value"
+	case "count":
+		return "count", "value"
+	default:
+		return "doubleSum", "value"
+	}
+}
+
+// druidGranularity maps a query step duration to the closest Druid named
+// granularity; Druid's query language doesn't take arbitrary durations here.
+func druidGranularity(step time.Duration) string {
+	switch {
+	case step <= time.Minute:
+		return "minute"
+	case step <= time.Hour:
+		return "hour"
+	default:
+		return "day"
+	}
+}
+
+func druidFilter(filters []MetricFilter) map[string]interface{} {
+	if len(filters) == 0 {
+		return nil
+	}
+	if len(filters) == 1 {
+		return map[string]interface{}{
+			"type": "selector", "dimension": filters[0].Key, "value": filters[0].Value,
+		}
+	}
+
+	fields := make([]map[string]interface{}, len(filters))
+	for i, f := range filters {
+		fields[i] = map[string]interface{}{"type": "selector", "dimension": f.Key, "value": f.Value}
+	}
+	return map[string]interface{}{"type": "and", "fields": fields}
+}
@@ -7,97 +7,427 @@
 package api
 
 import (
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// TsdbRouter routes TSDB requests to the appropriate backend
-type TsdbRouter struct {
-	PrometheusURL *url.URL
-	InfluxDBURL   *url.URL
-	DruidURL      *url.URL
+// engine identifies one of the TSDB backends the router proxies to.
+type engine string
+
+const (
+	enginePrometheus engine = "prometheus"
+	engineInfluxDB   engine = "influxdb"
+	engineDruid      engine = "druid"
+)
+
+var allEngines = []engine{enginePrometheus, engineInfluxDB, engineDruid}
+
+// breakerState is the state of a per-backend circuit breaker.
+type breakerState int32
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+const (
+	breakerFailureThreshold = 5
+	breakerCooldown         = 30 * time.Second
+)
 
-	prometheusProxy *httputil.ReverseProxy
-	influxdbProxy   *httputil.ReverseProxy
-	druidProxy      *httputil.ReverseProxy
+// circuitBreaker trips a backend out of rotation after consecutive
+// failures and lets a single probe request through once breakerCooldown has
+// elapsed, closing the breaker again on its success.
+type circuitBreaker struct {
+	mu            sync.Mutex
+	state         breakerState
+	failures      int
+	openedAt      time.Time
+	probeInFlight bool
 }
 
-// NewTsdbRouter creates a new TSDB router
-func NewTsdbRouter(prometheusAddr, influxdbAddr, druidAddr string) (*TsdbRouter, error) {
-	prometheusURL, err := url.Parse(prometheusAddr)
-	if err != nil {
-		return nil, err
+// Allow reports whether a request may be sent to this backend right now. A
+// half-open breaker allows exactly one in-flight probe at a time.
+func (b *circuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < breakerCooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probeInFlight = true
+		return true
+	case breakerHalfOpen:
+		return !b.probeInFlight
+	default:
+		return true
 	}
+}
 
-	influxdbURL, err := url.Parse(influxdbAddr)
-	if err != nil {
-		return nil, err
+// RecordResult updates the breaker based on whether a request against this
+// backend succeeded (2xx/3xx/4xx) or failed (5xx/timeout/connection error).
+func (b *circuitBreaker) RecordResult(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ok {
+		b.failures = 0
+		b.probeInFlight = false
+		b.state = breakerClosed
+		return
+	}
+
+	b.probeInFlight = false
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= breakerFailureThreshold {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
 	}
+}
+
+func (b *circuitBreaker) State() breakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// latencyBuckets are the histogram bucket upper bounds, in seconds, used for
+// the per-backend request latency histogram exposed at /metrics.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// latencyHistogram is a minimal Prometheus-style cumulative histogram.
+type latencyHistogram struct {
+	mu     sync.Mutex
+	counts []uint64 // one per bucket in latencyBuckets, cumulative
+	sum    float64
+	total  uint64
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{counts: make([]uint64, len(latencyBuckets))}
+}
+
+func (h *latencyHistogram) Observe(seconds float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
-	druidURL, err := url.Parse(druidAddr)
+	h.sum += seconds
+	h.total++
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+}
+
+func (h *latencyHistogram) snapshot() (counts []uint64, sum float64, total uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	counts = make([]uint64, len(h.counts))
+	copy(counts, h.counts)
+	return counts, h.sum, h.total
+}
+
+// backend is one replica of a TSDB engine behind the router: its reverse
+// proxy, health state, circuit breaker and request-level metrics.
+type backend struct {
+	URL   *url.URL
+	proxy *httputil.ReverseProxy
+
+	healthy     atomic.Bool
+	outstanding atomic.Int64
+	requests    atomic.Uint64
+	breaker     *circuitBreaker
+	latency     *latencyHistogram
+}
+
+func newBackend(rawURL string) (*backend, error) {
+	u, err := url.Parse(rawURL)
 	if err != nil {
 		return nil, err
 	}
 
-	return &TsdbRouter{
-		PrometheusURL:   prometheusURL,
-		InfluxDBURL:     influxdbURL,
-		DruidURL:        druidURL,
-		prometheusProxy: httputil.NewSingleHostReverseProxy(prometheusURL),
-		influxdbProxy:   httputil.NewSingleHostReverseProxy(influxdbURL),
-		druidProxy:      httputil.NewSingleHostReverseProxy(druidURL),
-	}, nil
+	b := &backend{
+		URL:     u,
+		proxy:   httputil.NewSingleHostReverseProxy(u),
+		breaker: &circuitBreaker{},
+		latency: newLatencyHistogram(),
+	}
+	b.healthy.Store(true) // optimistic until the first probe runs
+	return b, nil
+}
+
+// healthProbe is the per-engine probe path used by CheckHealth and the
+// background prober, e.g. Prometheus' "/-/healthy".
+type healthProbe struct {
+	path           string
+	acceptedStatus func(int) bool
+}
+
+var healthProbes = map[engine]healthProbe{
+	enginePrometheus: {path: "/-/healthy", acceptedStatus: func(s int) bool { return s == http.StatusOK }},
+	engineInfluxDB:   {path: "/ping", acceptedStatus: func(s int) bool { return s == http.StatusOK || s == http.StatusNoContent }},
+	engineDruid:      {path: "/status/health", acceptedStatus: func(s int) bool { return s == http.StatusOK }},
+}
+
+// probe issues the engine's health check against this backend and updates
+// its healthy flag and circuit breaker accordingly.
+func (b *backend) probe(client *http.Client, hp healthProbe) {
+	resp, err := client.Get(b.URL.String() + hp.path)
+	ok := err == nil && hp.acceptedStatus(resp.StatusCode)
+	if resp != nil {
+		resp.Body.Close()
+	}
+
+	b.healthy.Store(ok)
+	b.breaker.RecordResult(ok)
+}
+
+// defaultProbeInterval is how often the background prober re-checks every
+// backend's health when the caller doesn't specify one.
+const defaultProbeInterval = 10 * time.Second
+
+// TsdbRouter routes TSDB requests to the healthiest replica of each engine,
+// probing replica health in the background and tripping a circuit breaker
+// on backends that start erroring or timing out. It replaces a static
+// single-URL reverse proxy with an HA gateway: ServeHTTP picks a healthy,
+// least-loaded replica via round-robin over the least-outstanding-request
+// candidates, and /metrics exposes per-backend request counts, latency
+// histograms and breaker state in Prometheus exposition format.
+type TsdbRouter struct {
+	backends map[engine][]*backend
+
+	probeInterval time.Duration
+	probeClient   *http.Client
+	stopProbe     chan struct{}
+
+	rrCursor map[engine]*atomic.Uint64
+	registry *metricRegistry
+}
+
+// NewTsdbRouter creates a router over one or more backend URLs per engine.
+// At least one URL is required for each engine.
+func NewTsdbRouter(prometheusAddrs, influxdbAddrs, druidAddrs []string) (*TsdbRouter, error) {
+	r := &TsdbRouter{
+		backends:      make(map[engine][]*backend),
+		probeInterval: defaultProbeInterval,
+		probeClient:   &http.Client{Timeout: 2 * time.Second},
+		stopProbe:     make(chan struct{}),
+		rrCursor:      make(map[engine]*atomic.Uint64),
+		registry:      defaultMetricRegistry(),
+	}
+
+	specs := map[engine][]string{
+		enginePrometheus: prometheusAddrs,
+		engineInfluxDB:   influxdbAddrs,
+		engineDruid:      druidAddrs,
+	}
+
+	for _, eng := range allEngines {
+		addrs := specs[eng]
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf("tsdb_router: %s requires at least one backend URL", eng)
+		}
+
+		backends := make([]*backend, 0, len(addrs))
+		for _, addr := range addrs {
+			b, err := newBackend(addr)
+			if err != nil {
+				return nil, fmt.Errorf("tsdb_router: %s backend %q: %w", eng, addr, err)
+			}
+			backends = append(backends, b)
+		}
+
+		r.backends[eng] = backends
+		r.rrCursor[eng] = &atomic.Uint64{}
+	}
+
+	return r, nil
 }
 
-// DefaultTsdbRouter creates a router with default local ports
+// DefaultTsdbRouter creates a router with a single default local backend per
+// engine.
 func DefaultTsdbRouter() (*TsdbRouter, error) {
 	return NewTsdbRouter(
-		"http://localhost:9090", // Prometheus
-		"http://localhost:8086", // InfluxDB
-		"http://localhost:8888", // Druid
+		[]string{"http://localhost:9090"}, // Prometheus
+		[]string{"http://localhost:8086"}, // InfluxDB
+		[]string{"http://localhost:8888"}, // Druid
 	)
 }
 
+// StartProbing launches the background health prober. Callers should defer
+// StopProbing. Probing happens eagerly once before the first interval tick
+// so ServeHTTP has accurate health state immediately.
+func (r *TsdbRouter) StartProbing() {
+	r.probeAll()
+	go func() {
+		ticker := time.NewTicker(r.probeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.probeAll()
+			case <-r.stopProbe:
+				return
+			}
+		}
+	}()
+}
+
+// StopProbing stops the background health prober.
+func (r *TsdbRouter) StopProbing() {
+	close(r.stopProbe)
+}
+
+func (r *TsdbRouter) probeAll() {
+	var wg sync.WaitGroup
+	for eng, backends := range r.backends {
+		hp := healthProbes[eng]
+		for _, b := range backends {
+			wg.Add(1)
+			go func(b *backend) {
+				defer wg.Done()
+				if !b.breaker.Allow() {
+					return
+				}
+				b.probe(r.probeClient, hp)
+			}(b)
+		}
+	}
+	wg.Wait()
+}
+
+// pick selects the least-loaded healthy, breaker-closed backend for an
+// engine, round-robining between ties. It returns nil if every backend is
+// unavailable.
+func (r *TsdbRouter) pick(eng engine) *backend {
+	backends := r.backends[eng]
+
+	var candidates []*backend
+	for _, b := range backends {
+		if !b.healthy.Load() {
+			continue
+		}
+		if !b.breaker.Allow() {
+			continue
+		}
+		candidates = append(candidates, b)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	best := candidates[0]
+	for _, c := range candidates[1:] {
+		if c.outstanding.Load() < best.outstanding.Load() {
+			best = c
+		}
+	}
+
+	// Break ties between equally-loaded candidates with round robin rather
+	// than always hammering candidates[0].
+	tied := make([]*backend, 0, len(candidates))
+	for _, c := range candidates {
+		if c.outstanding.Load() == best.outstanding.Load() {
+			tied = append(tied, c)
+		}
+	}
+	if len(tied) > 1 {
+		idx := r.rrCursor[eng].Add(1) % uint64(len(tied))
+		best = tied[idx]
+	}
+
+	return best
+}
+
+// serveVia proxies req through the given engine's healthiest backend,
+// tracking outstanding requests, latency and circuit breaker state. It
+// returns false if no backend in the engine is currently available.
+func (r *TsdbRouter) serveVia(eng engine, w http.ResponseWriter, req *http.Request) bool {
+	b := r.pick(eng)
+	if b == nil {
+		return false
+	}
+
+	b.outstanding.Add(1)
+	b.requests.Add(1)
+	start := time.Now()
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	b.proxy.ServeHTTP(rec, req)
+
+	b.outstanding.Add(-1)
+	b.latency.Observe(time.Since(start).Seconds())
+	b.breaker.RecordResult(rec.status < http.StatusInternalServerError)
+
+	return true
+}
+
+// statusRecorder captures the status code a reverse proxy writes so the
+// breaker can treat 5xx responses as failures.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
 // ServeHTTP implements http.Handler
 func (r *TsdbRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	path := req.URL.Path
 
-	// Route based on path prefix
+	if path == "/metrics" {
+		r.serveMetrics(w)
+		return
+	}
+
+	if path == "/api/query" {
+		r.handleFederatedQuery(w, req)
+		return
+	}
+
+	var eng engine
 	switch {
 	// Prometheus endpoints
-	case strings.HasPrefix(path, "/api/v1/"):
-		r.prometheusProxy.ServeHTTP(w, req)
-
-	case strings.HasPrefix(path, "/-/"):
-		// Prometheus health checks
-		r.prometheusProxy.ServeHTTP(w, req)
+	case strings.HasPrefix(path, "/api/v1/"), strings.HasPrefix(path, "/-/"):
+		eng = enginePrometheus
 
 	// Druid endpoints
-	case strings.HasPrefix(path, "/druid/"):
-		r.druidProxy.ServeHTTP(w, req)
-
-	case strings.HasPrefix(path, "/status"):
-		// Druid status
-		r.druidProxy.ServeHTTP(w, req)
-
-	// InfluxDB v2 endpoints
-	case strings.HasPrefix(path, "/api/v2/"):
-		r.influxdbProxy.ServeHTTP(w, req)
-
-	// InfluxDB v1 endpoints
-	case path == "/write" || strings.HasPrefix(path, "/write?"):
-		r.influxdbProxy.ServeHTTP(w, req)
+	case strings.HasPrefix(path, "/druid/"), strings.HasPrefix(path, "/status"):
+		eng = engineDruid
 
-	case path == "/query" || strings.HasPrefix(path, "/query?"):
-		r.influxdbProxy.ServeHTTP(w, req)
+	// InfluxDB endpoints
+	case strings.HasPrefix(path, "/api/v2/"),
+		path == "/write", strings.HasPrefix(path, "/write?"),
+		path == "/query", strings.HasPrefix(path, "/query?"),
+		path == "/ping", path == "/health", path == "/ready":
+		eng = engineInfluxDB
 
-	case path == "/ping" || path == "/health" || path == "/ready":
-		r.influxdbProxy.ServeHTTP(w, req)
-
-	// Default: return routing info
 	default:
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
@@ -105,26 +435,76 @@ func (r *TsdbRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
   "name": "LumaDB Universal TSDB Router",
   "version": "1.0.0",
   "engines": {
-    "prometheus": {"port": 9090, "paths": ["/api/v1/*", "/-/*"]},
-    "influxdb": {"port": 8086, "paths": ["/write", "/query", "/api/v2/*", "/ping", "/health"]},
-    "druid": {"port": 8888, "paths": ["/druid/*", "/status"]}
+    "prometheus": {"paths": ["/api/v1/*", "/-/*"]},
+    "influxdb": {"paths": ["/write", "/query", "/api/v2/*", "/ping", "/health"]},
+    "druid": {"paths": ["/druid/*", "/status"]}
   }
 }`)
+		return
+	}
+
+	if !r.serveVia(eng, w, req) {
+		http.Error(w, fmt.Sprintf("no healthy %s backend available", eng), http.StatusServiceUnavailable)
 	}
 }
 
-// StartRouter starts the unified TSDB router on the given port
+// serveMetrics writes per-backend request count, latency histogram and
+// breaker state in Prometheus text exposition format.
+func (r *TsdbRouter) serveMetrics(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP lumadb_tsdb_router_requests_total Requests proxied per backend.")
+	fmt.Fprintln(w, "# TYPE lumadb_tsdb_router_requests_total counter")
+	for _, eng := range allEngines {
+		for _, b := range r.backends[eng] {
+			fmt.Fprintf(w, "lumadb_tsdb_router_requests_total{engine=%q,backend=%q} %d\n",
+				eng, b.URL.String(), b.requests.Load())
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP lumadb_tsdb_router_breaker_state Circuit breaker state per backend (0=closed,1=open,2=half_open).")
+	fmt.Fprintln(w, "# TYPE lumadb_tsdb_router_breaker_state gauge")
+	for _, eng := range allEngines {
+		for _, b := range r.backends[eng] {
+			fmt.Fprintf(w, "lumadb_tsdb_router_breaker_state{engine=%q,backend=%q} %d\n",
+				eng, b.URL.String(), b.breaker.State())
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP lumadb_tsdb_router_request_duration_seconds Request latency per backend.")
+	fmt.Fprintln(w, "# TYPE lumadb_tsdb_router_request_duration_seconds histogram")
+	for _, eng := range allEngines {
+		for _, b := range r.backends[eng] {
+			counts, sum, total := b.latency.snapshot()
+			for i, bound := range latencyBuckets {
+				fmt.Fprintf(w, "lumadb_tsdb_router_request_duration_seconds_bucket{engine=%q,backend=%q,le=%q} %d\n",
+					eng, b.URL.String(), fmt.Sprintf("%g", bound), counts[i])
+			}
+			fmt.Fprintf(w, "lumadb_tsdb_router_request_duration_seconds_bucket{engine=%q,backend=%q,le=\"+Inf\"} %d\n",
+				eng, b.URL.String(), total)
+			fmt.Fprintf(w, "lumadb_tsdb_router_request_duration_seconds_sum{engine=%q,backend=%q} %g\n",
+				eng, b.URL.String(), sum)
+			fmt.Fprintf(w, "lumadb_tsdb_router_request_duration_seconds_count{engine=%q,backend=%q} %d\n",
+				eng, b.URL.String(), total)
+		}
+	}
+}
+
+// StartRouter starts the unified TSDB router on the given port.
 func StartRouter(port string) error {
 	router, err := DefaultTsdbRouter()
 	if err != nil {
 		return err
 	}
 
+	router.StartProbing()
+	defer router.StopProbing()
+
 	http.Handle("/", router)
 	return http.ListenAndServe(":"+port, nil)
 }
 
-// HealthCheck returns health status of all backends
+// HealthStatus reports health of all backends across all engines.
 type HealthStatus struct {
 	Prometheus bool `json:"prometheus"`
 	InfluxDB   bool `json:"influxdb"`
@@ -132,28 +512,25 @@ type HealthStatus struct {
 	Healthy    bool `json:"healthy"`
 }
 
-// CheckHealth checks all backend health endpoints
+// CheckHealth runs an on-demand health probe against every backend and
+// reports true for an engine if at least one of its replicas is healthy.
 func (r *TsdbRouter) CheckHealth() HealthStatus {
-	status := HealthStatus{}
+	r.probeAll()
 
-	// Check Prometheus
-	if resp, err := http.Get(r.PrometheusURL.String() + "/-/healthy"); err == nil {
-		status.Prometheus = resp.StatusCode == 200
-		resp.Body.Close()
-	}
-
-	// Check InfluxDB
-	if resp, err := http.Get(r.InfluxDBURL.String() + "/ping"); err == nil {
-		status.InfluxDB = resp.StatusCode == 204 || resp.StatusCode == 200
-		resp.Body.Close()
+	status := HealthStatus{
+		Prometheus: r.anyHealthy(enginePrometheus),
+		InfluxDB:   r.anyHealthy(engineInfluxDB),
+		Druid:      r.anyHealthy(engineDruid),
 	}
-
-	// Check Druid
-	if resp, err := http.Get(r.DruidURL.String() + "/status/health"); err == nil {
-		status.Druid = resp.StatusCode == 200
-		resp.Body.Close()
-	}
-
 	status.Healthy = status.Prometheus && status.InfluxDB && status.Druid
 	return status
 }
+
+func (r *TsdbRouter) anyHealthy(eng engine) bool {
+	for _, b := range r.backends[eng] {
+		if b.healthy.Load() {
+			return true
+		}
+	}
+	return false
+}
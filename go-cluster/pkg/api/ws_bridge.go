@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	pb "github.com/lumadb/cluster/pkg/api/pb"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// defaultMaxRespBodyBufferSize bounds how large a single newline-delimited
+// JSON frame the bridge writes to a WebSocket client may be, mirroring
+// MaxFrameBytes on the gRPC side of the same Stream call so a frame that
+// passed the gRPC ceiling doesn't then trip a WS proxy's own.
+const defaultMaxRespBodyBufferSize = 60 * 1024
+
+// streamBridgeUpgrader upgrades /ws/stream to a WebSocket, same CheckOrigin
+// posture as tdengine's own TMQ bridge: this is an API endpoint, not a
+// same-origin browser page, so the default same-origin check would reject
+// every legitimate caller.
+var streamBridgeUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// StreamWSBridgeOptions configures a StreamWSBridge.
+type StreamWSBridgeOptions struct {
+	// MaxRespBodyBufferSize caps how large a single newline-delimited JSON
+	// frame written to the WebSocket connection may be; a larger frame is
+	// reported as an error frame instead of being written. Defaults to
+	// defaultMaxRespBodyBufferSize when <= 0.
+	MaxRespBodyBufferSize int
+}
+
+// StreamWSBridge proxies LumaService.Stream to a browser as newline-
+// delimited JSON, one line per pb.QueryResponse frame, since browsers can't
+// speak gRPC directly.
+type StreamWSBridge struct {
+	client  pb.LumaServiceClient
+	logger  *zap.Logger
+	maxResp int
+}
+
+// NewStreamWSBridge builds a bridge that issues Stream calls over conn, an
+// already-dialed connection to a node capable of serving LumaService (often
+// this same process's own gRPC listener).
+func NewStreamWSBridge(conn *grpc.ClientConn, logger *zap.Logger, opts StreamWSBridgeOptions) *StreamWSBridge {
+	maxResp := opts.MaxRespBodyBufferSize
+	if maxResp <= 0 {
+		maxResp = defaultMaxRespBodyBufferSize
+	}
+	return &StreamWSBridge{
+		client:  pb.NewLumaServiceClient(conn),
+		logger:  logger,
+		maxResp: maxResp,
+	}
+}
+
+// ServeHTTP upgrades the request to a WebSocket, issues Stream with the
+// query/dialect/collection carried in the request's query string, and
+// writes each resulting frame as one newline-delimited JSON line until the
+// stream ends or the client disconnects.
+func (b *StreamWSBridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := streamBridgeUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	req := &pb.QueryRequest{
+		Query:      r.URL.Query().Get("query"),
+		Dialect:    r.URL.Query().Get("dialect"),
+		Collection: r.URL.Query().Get("collection"),
+	}
+
+	stream, err := b.client.Stream(r.Context(), req)
+	if err != nil {
+		conn.WriteJSON(map[string]string{"error": err.Error()})
+		return
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			conn.WriteJSON(map[string]string{"error": err.Error()})
+			return
+		}
+
+		line, err := json.Marshal(resp)
+		if err != nil {
+			continue
+		}
+		line = append(line, '\n')
+		if len(line) > b.maxResp {
+			conn.WriteJSON(map[string]string{"error": "frame exceeds MaxRespBodyBufferSize"})
+			continue
+		}
+		if err := conn.WriteMessage(websocket.TextMessage, line); err != nil {
+			b.logger.Debug("stream ws bridge write failed", zap.Error(err))
+			return
+		}
+	}
+}
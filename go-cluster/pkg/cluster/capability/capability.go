@@ -0,0 +1,199 @@
+// Package capability implements cluster-wide feature-capability
+// negotiation, modeled on etcd's capability map: every node advertises the
+// named capabilities its build supports (e.g. "dialect.flux",
+// "dialect.tdengine", "auth.bcrypt", "raft.parallel", "grpc.stream",
+// "codec.zerocopy") alongside a semver, and the cluster's effective
+// enabled set is the intersection across every live member - so a
+// mid-rolling-upgrade peer that doesn't understand a feature yet never
+// sees it used against it.
+//
+// This package models only the convergence logic - Set.Update/Remove and
+// the enabled-set recomputation - since each member's Advertisement still
+// needs to reach its peers via gossip on join and heartbeat, and this repo
+// doesn't vendor a gossip/membership library.
+// Whatever membership transport eventually ships those updates should call
+// Set.Update/Remove as members join, heartbeat and leave.
+package capability
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Advertisement is one node's self-reported version and capability set, as
+// gossiped on join and on every heartbeat.
+type Advertisement struct {
+	NodeID       uint64   `json:"node_id"`
+	Version      string   `json:"version"`
+	Capabilities []string `json:"capabilities"`
+}
+
+// Set tracks every live member's Advertisement and recomputes the
+// cluster's effective enabled set - and minimum cluster version - each
+// time membership changes.
+type Set struct {
+	mu      sync.RWMutex
+	members map[uint64]Advertisement
+	enabled map[string]struct{}
+	version string
+	logger  *zap.Logger
+}
+
+// NewSet creates a Set seeded with self, this node's own Advertisement, so
+// Enabled/MinClusterVersion behave sanely even before any peer has
+// gossiped in. A nil logger defaults to a no-op logger.
+func NewSet(self Advertisement, logger *zap.Logger) *Set {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	s := &Set{
+		members: make(map[uint64]Advertisement),
+		enabled: make(map[string]struct{}),
+		logger:  logger,
+	}
+	s.Update(self)
+	return s
+}
+
+// Update records (or refreshes) an Advertisement and recomputes the
+// effective enabled set, logging any capability that turned on or off
+// cluster-wide as a result.
+func (s *Set) Update(ad Advertisement) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := s.enabled
+	s.members[ad.NodeID] = ad
+	s.recomputeLocked()
+	s.logTransitionsLocked(before)
+}
+
+// Remove drops nodeID from the member set - e.g. once it's confirmed gone
+// rather than merely slow to heartbeat - and recomputes accordingly.
+func (s *Set) Remove(nodeID uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := s.enabled
+	delete(s.members, nodeID)
+	s.recomputeLocked()
+	s.logTransitionsLocked(before)
+}
+
+// Enabled reports whether name is in the cluster-wide enabled set: every
+// live member's Advertisement includes it.
+func (s *Set) Enabled(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.enabled[name]
+	return ok
+}
+
+// MinClusterVersion returns the lowest semver any live member advertised,
+// so operators can gate a new dialect handler behind a version bump: ship
+// it disabled until MinClusterVersion() reaches the release that
+// introduced it.
+func (s *Set) MinClusterVersion() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
+
+// EnabledList returns the cluster-wide enabled set as a sorted slice, for
+// the /cluster/capabilities endpoint.
+func (s *Set) EnabledList() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]string, 0, len(s.enabled))
+	for name := range s.enabled {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// PerNode returns a copy of every live member's Advertisement, keyed by
+// node ID, for the /cluster/capabilities endpoint.
+func (s *Set) PerNode() map[uint64]Advertisement {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[uint64]Advertisement, len(s.members))
+	for id, ad := range s.members {
+		out[id] = ad
+	}
+	return out
+}
+
+// recomputeLocked rebuilds enabled and version from members: a capability
+// is enabled only if every live member advertises it, and the cluster
+// version is the lowest version any member reports.
+func (s *Set) recomputeLocked() {
+	if len(s.members) == 0 {
+		s.enabled = make(map[string]struct{})
+		s.version = ""
+		return
+	}
+
+	counts := make(map[string]int, len(s.enabled))
+	var minVersion string
+	for _, ad := range s.members {
+		for _, name := range ad.Capabilities {
+			counts[name]++
+		}
+		if minVersion == "" || compareSemver(ad.Version, minVersion) < 0 {
+			minVersion = ad.Version
+		}
+	}
+
+	enabled := make(map[string]struct{}, len(counts))
+	for name, n := range counts {
+		if n == len(s.members) {
+			enabled[name] = struct{}{}
+		}
+	}
+	s.enabled = enabled
+	s.version = minVersion
+}
+
+// logTransitionsLocked logs every capability that moved between before and
+// the freshly recomputed s.enabled, so an operator can see exactly when a
+// rolling upgrade finished converging on a feature.
+func (s *Set) logTransitionsLocked(before map[string]struct{}) {
+	for name := range s.enabled {
+		if _, ok := before[name]; !ok {
+			s.logger.Info("capability enabled cluster-wide", zap.String("capability", name))
+		}
+	}
+	for name := range before {
+		if _, ok := s.enabled[name]; !ok {
+			s.logger.Info("capability disabled cluster-wide", zap.String("capability", name))
+		}
+	}
+}
+
+// compareSemver compares two dotted version strings (an optional leading
+// "v", then numeric dot-separated components) numerically component by
+// component - not full semver, no pre-release/build metadata handling -
+// returning <0, 0, >0 as a < b, a == b, a > b. A component that fails to
+// parse as an integer is treated as 0 rather than causing a panic.
+func compareSemver(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var av, bv int
+		if i < len(as) {
+			av, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			bv, _ = strconv.Atoi(bs[i])
+		}
+		if av != bv {
+			return av - bv
+		}
+	}
+	return 0
+}
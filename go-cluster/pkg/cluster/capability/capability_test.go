@@ -0,0 +1,84 @@
+package capability
+
+import "testing"
+
+func TestSet_EnabledIsIntersectionAcrossMembers(t *testing.T) {
+	s := NewSet(Advertisement{NodeID: 1, Version: "1.2.0", Capabilities: []string{"dialect.flux", "raft.parallel"}}, nil)
+
+	if !s.Enabled("dialect.flux") {
+		t.Fatal("dialect.flux should be enabled with a single member advertising it")
+	}
+
+	// A second member that lacks raft.parallel should disable it
+	// cluster-wide, without affecting dialect.flux, which both advertise.
+	s.Update(Advertisement{NodeID: 2, Version: "1.1.0", Capabilities: []string{"dialect.flux"}})
+
+	if !s.Enabled("dialect.flux") {
+		t.Error("dialect.flux should still be enabled when every member advertises it")
+	}
+	if s.Enabled("raft.parallel") {
+		t.Error("raft.parallel should be disabled once a member without it joins")
+	}
+}
+
+func TestSet_RemoveRecomputesEnabledSet(t *testing.T) {
+	s := NewSet(Advertisement{NodeID: 1, Version: "1.0.0", Capabilities: []string{"grpc.stream"}}, nil)
+	s.Update(Advertisement{NodeID: 2, Version: "1.0.0", Capabilities: []string{}})
+
+	if s.Enabled("grpc.stream") {
+		t.Fatal("grpc.stream should be disabled while node 2 lacks it")
+	}
+
+	s.Remove(2)
+
+	if !s.Enabled("grpc.stream") {
+		t.Error("grpc.stream should re-enable once the member lacking it leaves")
+	}
+}
+
+func TestSet_MinClusterVersion(t *testing.T) {
+	s := NewSet(Advertisement{NodeID: 1, Version: "1.4.2"}, nil)
+	s.Update(Advertisement{NodeID: 2, Version: "1.3.9"})
+	s.Update(Advertisement{NodeID: 3, Version: "2.0.0"})
+
+	if got := s.MinClusterVersion(); got != "1.3.9" {
+		t.Errorf("MinClusterVersion() = %q, want %q", got, "1.3.9")
+	}
+}
+
+func TestSet_PerNodeAndEnabledList(t *testing.T) {
+	s := NewSet(Advertisement{NodeID: 1, Version: "1.0.0", Capabilities: []string{"b.cap", "a.cap"}}, nil)
+
+	if got := s.EnabledList(); len(got) != 2 || got[0] != "a.cap" || got[1] != "b.cap" {
+		t.Errorf("EnabledList() = %v, want sorted [a.cap b.cap]", got)
+	}
+
+	perNode := s.PerNode()
+	if len(perNode) != 1 || perNode[1].Version != "1.0.0" {
+		t.Errorf("PerNode() = %v, want one entry for node 1", perNode)
+	}
+}
+
+func TestCompareSemver(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.0", "1.2.0", 0},
+		{"1.2.0", "1.3.0", -1},
+		{"1.10.0", "1.9.0", 1},
+		{"v2.0.0", "1.9.9", 1},
+	}
+
+	for _, tt := range tests {
+		got := compareSemver(tt.a, tt.b)
+		switch {
+		case tt.want == 0 && got != 0:
+			t.Errorf("compareSemver(%q, %q) = %d, want 0", tt.a, tt.b, got)
+		case tt.want < 0 && got >= 0:
+			t.Errorf("compareSemver(%q, %q) = %d, want < 0", tt.a, tt.b, got)
+		case tt.want > 0 && got <= 0:
+			t.Errorf("compareSemver(%q, %q) = %d, want > 0", tt.a, tt.b, got)
+		}
+	}
+}
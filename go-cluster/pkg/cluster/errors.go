@@ -0,0 +1,8 @@
+package cluster
+
+import "errors"
+
+// ErrVersionConflict is returned by Node.Apply when a Command carries an
+// IfMatch version that no longer matches the document's current version -
+// the FSM's optimistic-concurrency check failed.
+var ErrVersionConflict = errors.New("cluster: version conflict")
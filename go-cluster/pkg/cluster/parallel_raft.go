@@ -4,73 +4,338 @@ package cluster
 
 import (
 	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
 	"sync"
 	"time"
 
+	"github.com/lumadb/cluster/pkg/cluster/capability"
+	"go.etcd.io/etcd/raft/v3"
+	"go.etcd.io/etcd/raft/v3/raftpb"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 )
 
+// capabilityRaftParallel gates parallel ticking of Raft groups: if any live
+// member hasn't advertised it, groups are ticked sequentially instead, so a
+// peer mid-rolling-upgrade that doesn't understand concurrent group
+// application yet is never handed ticks out of order.
+const capabilityRaftParallel = "raft.parallel"
+
 // ParallelRaftEngine manages multiple Raft groups efficiently
 type ParallelRaftEngine struct {
 	groups   map[uint64]*RaftGroup
 	groupsMu sync.RWMutex
 
+	router *ShardRouter // optional; set via EnableSharding
+
 	batchWriter *BatchWriter
 	transport   *PipelineTransport
 
 	tickInterval time.Duration
 	logger       *zap.Logger
+
+	capabilities *capability.Set // optional; set via EnableCapabilities
+}
+
+// EnableCapabilities gives the engine a cluster-wide capability.Set,
+// consulted on every Tick to decide whether groups may be ticked in
+// parallel.
+func (e *ParallelRaftEngine) EnableCapabilities(set *capability.Set) {
+	e.capabilities = set
+}
+
+// EnableSharding gives the engine a ShardRouter, so Propose can route a
+// write to the group responsible for its collection/key without the caller
+// hardcoding group IDs.
+func (e *ParallelRaftEngine) EnableSharding(router *ShardRouter) {
+	e.router = router
 }
 
-// RaftGroup represents a single Raft consensus group
+// RaftGroup is a thin adapter over a single go.etcd.io/etcd/raft/v3 Node,
+// one per data shard. It owns the Node's MemoryStorage so
+// ParallelRaftEngine can Tick, drain Ready, and Advance many groups without
+// the engine reaching into etcd/raft internals itself.
 type RaftGroup struct {
-	ID     uint64
-	Leader string
-	// In a real impl, this would wrap hashicorp/raft or etcd/raft
+	ID uint64
+
+	node    raft.Node
+	storage *raft.MemoryStorage
+
+	mu     sync.RWMutex
+	leader uint64 // raft ID of the group's current leader, 0 if unknown
+}
+
+// NewRaftGroup starts a fresh single-group etcd/raft Node with the given
+// member IDs (selfID must appear in peers). ElectionTick/HeartbeatTick
+// follow etcd/raft's own recommended 10:1 ratio.
+func NewRaftGroup(groupID, selfID uint64, peers []uint64) *RaftGroup {
+	storage := raft.NewMemoryStorage()
+
+	raftPeers := make([]raft.Peer, 0, len(peers))
+	for _, id := range peers {
+		raftPeers = append(raftPeers, raft.Peer{ID: id})
+	}
+
+	cfg := &raft.Config{
+		ID:              selfID,
+		ElectionTick:    10,
+		HeartbeatTick:   1,
+		Storage:         storage,
+		MaxSizePerMsg:   1024 * 1024,
+		MaxInflightMsgs: 256,
+	}
+
+	return &RaftGroup{
+		ID:      groupID,
+		node:    raft.StartNode(cfg, raftPeers),
+		storage: storage,
+	}
+}
+
+// Tick advances the group's logical clock by one tick, driving election
+// timeouts and heartbeats. Callers don't use this directly - see
+// ParallelRaftEngine.Tick, which ticks every registered group then drains
+// Ready().
+func (g *RaftGroup) Tick() {
+	g.node.Tick()
+}
+
+// Propose submits data to be appended to the group's Raft log. It returns
+// once the Node has accepted the proposal and does not wait for commit; the
+// caller learns of commit by observing the entry among a later
+// RaftReady.CommittedEntries.
+func (g *RaftGroup) Propose(ctx context.Context, data []byte) error {
+	return g.node.Propose(ctx, data)
+}
+
+// Step hands an inbound Raft message to this group's Node - the dispatch
+// point PipelineTransport routes messages to by GroupID.
+func (g *RaftGroup) Step(ctx context.Context, msg raftpb.Message) error {
+	return g.node.Step(ctx, msg)
 }
 
-// RaftReady contains updates from a Raft tick
+// ApplySnapshot installs a snapshot received from a peer (via
+// PipelineTransport.Snapshot) directly into this group's storage and Node.
+// Snapshot installation is a one-off catch-up, not a per-tick occurrence,
+// so it bypasses the normal Ready()/Advance() cycle entirely.
+func (g *RaftGroup) ApplySnapshot(snap raftpb.Snapshot) error {
+	if err := g.storage.ApplySnapshot(snap); err != nil {
+		return fmt.Errorf("raft group %d: apply snapshot to storage: %w", g.ID, err)
+	}
+	return g.node.Step(context.Background(), raftpb.Message{Type: raftpb.MsgSnap, Snapshot: snap})
+}
+
+// Leader returns the raft ID this group currently believes leads it, 0 if
+// unknown (e.g. no SoftState observed yet right after startup).
+func (g *RaftGroup) Leader() uint64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.leader
+}
+
+// Stop releases the group's Node goroutines. Safe to call once.
+func (g *RaftGroup) Stop() {
+	g.node.Stop()
+}
+
+// drain reads one Ready from the group if one is immediately available,
+// persists its HardState/Entries/Snapshot to the group's own storage,
+// translates it into a RaftReady for the caller, and calls Advance().
+// Non-blocking, so ticking many groups in one pass never stalls waiting on
+// a group with nothing new to report.
+func (g *RaftGroup) drain() (RaftReady, bool) {
+	select {
+	case rd := <-g.node.Ready():
+		if rd.SoftState != nil {
+			g.mu.Lock()
+			g.leader = rd.SoftState.Lead
+			g.mu.Unlock()
+		}
+
+		if !raft.IsEmptyHardState(rd.HardState) {
+			g.storage.SetHardState(rd.HardState)
+		}
+		if len(rd.Entries) > 0 {
+			g.storage.Append(rd.Entries)
+		}
+		if !raft.IsEmptySnap(rd.Snapshot) {
+			g.storage.ApplySnapshot(rd.Snapshot)
+		}
+
+		ready := RaftReady{
+			GroupID:          g.ID,
+			HardState:        rd.HardState,
+			Entries:          rd.Entries,
+			CommittedEntries: rd.CommittedEntries,
+			Messages:         rd.Messages,
+			Snapshot:         rd.Snapshot,
+		}
+
+		g.node.Advance()
+		return ready, true
+	default:
+		return RaftReady{}, false
+	}
+}
+
+// RaftReady is one group's drained Raft state for a tick: the HardState and
+// Entries BatchWriter must persist before anything is considered committed,
+// the CommittedEntries the caller should apply to its state machine, the
+// Messages PipelineTransport must deliver to peers, and any Snapshot to
+// install.
 type RaftReady struct {
-	GroupID uint64
-	Entries [][]byte
-	// ... other raft state
+	GroupID          uint64
+	HardState        raftpb.HardState
+	Entries          []raftpb.Entry
+	CommittedEntries []raftpb.Entry
+	Messages         []raftpb.Message
+	Snapshot         raftpb.Snapshot
 }
 
 func (r *RaftReady) HasUpdates() bool {
-	return len(r.Entries) > 0
+	return len(r.Entries) > 0 || len(r.CommittedEntries) > 0 || len(r.Messages) > 0 || !raft.IsEmptySnap(r.Snapshot)
 }
 
-// BatchWriter batches Raft log writes
+// BatchWriter coalesces every group's Entries from one tick into a single
+// WAL fsync - the whole point of ticking groups in parallel is that N
+// groups producing updates in the same tick cost one fsync, not N.
+// HardState is not written to the WAL; it is kept only in each group's
+// MemoryStorage.
 type BatchWriter struct {
-	mu      sync.Mutex
-	pending []RaftReady
+	mu  sync.Mutex
+	wal *os.File
 }
 
-func (bw *BatchWriter) PersistBatch(ready []RaftReady) error {
-	// In real impl: batch write to RocksDB/BoltDB
-	return nil
+// NewBatchWriter opens (creating if needed) the WAL file at path, ready to
+// receive batched writes. Pass an empty path in tests that never expect
+// PersistBatch to durably persist anything - entries still live in each
+// group's own MemoryStorage either way.
+func NewBatchWriter(path string) (*BatchWriter, error) {
+	if path == "" {
+		return &BatchWriter{}, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("raft batch writer: open wal: %w", err)
+	}
+	return &BatchWriter{wal: f}, nil
+}
+
+// PersistBatch appends every ready's Entries to the WAL, in the order batch
+// lists them, and fsyncs exactly once - regardless of how many groups
+// contributed entries this tick.
+func (bw *BatchWriter) PersistBatch(batch []RaftReady) error {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+
+	if bw.wal == nil {
+		return nil // no WAL configured (tests); entries already live in each group's MemoryStorage
+	}
+
+	var wrote bool
+	for _, r := range batch {
+		for _, e := range r.Entries {
+			if err := writeWALRecord(bw.wal, r.GroupID, e); err != nil {
+				return err
+			}
+			wrote = true
+		}
+	}
+	if !wrote {
+		return nil
+	}
+
+	return bw.wal.Sync()
 }
 
-// NewParallelRaftEngine creates a new parallel Raft engine
-func NewParallelRaftEngine(logger *zap.Logger, tickInterval time.Duration) *ParallelRaftEngine {
+// writeWALRecord appends one length-prefixed, group-tagged entry to the
+// WAL: an 8-byte group ID, a 4-byte big-endian length, then the marshaled
+// entry.
+func writeWALRecord(w *os.File, groupID uint64, e raftpb.Entry) error {
+	data, err := e.Marshal()
+	if err != nil {
+		return fmt.Errorf("raft batch writer: marshal entry: %w", err)
+	}
+
+	header := make([]byte, 12)
+	binary.BigEndian.PutUint64(header[0:8], groupID)
+	binary.BigEndian.PutUint32(header[8:12], uint32(len(data)))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// NewParallelRaftEngine creates a new parallel Raft engine. transport may be
+// nil during tests that never tick a real cluster; callers that need
+// inter-node replication should build one with NewPipelineTransport and a
+// PeerDialer covering the group's peers. batchWriter may be nil to skip WAL
+// persistence entirely (tests); production callers should pass one from
+// NewBatchWriter.
+func NewParallelRaftEngine(logger *zap.Logger, tickInterval time.Duration, transport *PipelineTransport, batchWriter *BatchWriter) *ParallelRaftEngine {
+	if transport == nil {
+		transport = NewPipelineTransport(nil, logger)
+	}
+	if batchWriter == nil {
+		batchWriter = &BatchWriter{}
+	}
 	return &ParallelRaftEngine{
 		groups:       make(map[uint64]*RaftGroup),
-		batchWriter:  &BatchWriter{},
-		transport:    NewPipelineTransport(),
+		batchWriter:  batchWriter,
+		transport:    transport,
 		tickInterval: tickInterval,
 		logger:       logger,
 	}
 }
 
-// AddGroup adds a new Raft group
-func (e *ParallelRaftEngine) AddGroup(id uint64) {
+// AddGroup starts and registers a new Raft group: groupID identifies the
+// shard, selfID is this node's raft ID within the group, and peers is the
+// full member list (including self). The group is also registered with the
+// engine's PipelineTransport, keyed by groupID, so inbound messages for it
+// route straight to RaftGroup.Step.
+func (e *ParallelRaftEngine) AddGroup(groupID, selfID uint64, peers []uint64) *RaftGroup {
+	group := NewRaftGroup(groupID, selfID, peers)
+
 	e.groupsMu.Lock()
-	defer e.groupsMu.Unlock()
-	e.groups[id] = &RaftGroup{ID: id}
+	e.groups[groupID] = group
+	e.groupsMu.Unlock()
+
+	if e.transport != nil {
+		e.transport.RegisterGroup(groupID, group)
+	}
+
+	return group
+}
+
+// Propose routes data to the Raft group ShardRouter assigns collection/key
+// to (see EnableSharding) and submits it to that group's log - the
+// integration point LumaGRPCServer's write path uses once it has resolved a
+// dialect write down to a target collection/key.
+func (e *ParallelRaftEngine) Propose(ctx context.Context, collection, key string, data []byte) error {
+	if e.router == nil {
+		return fmt.Errorf("raft: no ShardRouter configured, call EnableSharding first")
+	}
+	groupID := e.router.GroupFor(collection, key)
+
+	e.groupsMu.RLock()
+	group, ok := e.groups[groupID]
+	e.groupsMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("raft: no group registered for shard %d (collection %q)", groupID, collection)
+	}
+
+	return group.Propose(ctx, data)
 }
 
-// Tick processes all Raft groups in parallel
+// Tick processes all Raft groups, in parallel when every live cluster
+// member has advertised the raft.parallel capability, falling back to
+// ticking them sequentially otherwise - a peer mid-rolling-upgrade that
+// doesn't understand concurrent group application yet should never be
+// handed ticks out of order.
 func (e *ParallelRaftEngine) Tick(ctx context.Context) error {
 	e.groupsMu.RLock()
 	groups := make([]*RaftGroup, 0, len(e.groups))
@@ -83,39 +348,82 @@ func (e *ParallelRaftEngine) Tick(ctx context.Context) error {
 		return nil
 	}
 
-	g, ctx := errgroup.WithContext(ctx)
 	var allReady []RaftReady
-	var readyMu sync.Mutex
-
-	// Parallel tick all groups
-	for _, group := range groups {
-		group := group
-		g.Go(func() error {
+	if e.capabilities != nil && !e.capabilities.Enabled(capabilityRaftParallel) {
+		for _, group := range groups {
 			ready := e.tickGroup(group)
 			if ready.HasUpdates() {
-				readyMu.Lock()
 				allReady = append(allReady, ready)
-				readyMu.Unlock()
 			}
-			return nil
-		})
-	}
+		}
+	} else {
+		g, ctx := errgroup.WithContext(ctx)
+		var readyMu sync.Mutex
 
-	if err := g.Wait(); err != nil {
-		return err
+		for _, group := range groups {
+			group := group
+			g.Go(func() error {
+				ready := e.tickGroup(group)
+				if ready.HasUpdates() {
+					readyMu.Lock()
+					allReady = append(allReady, ready)
+					readyMu.Unlock()
+				}
+				return nil
+			})
+		}
+
+		if err := g.Wait(); err != nil {
+			return err
+		}
 	}
 
-	// Batch persist all Raft logs in single write
+	// Batch persist all Raft logs in a single write, then fan out whatever
+	// messages each group produced.
 	if len(allReady) > 0 {
-		return e.batchWriter.PersistBatch(allReady)
+		if err := e.batchWriter.PersistBatch(allReady); err != nil {
+			return err
+		}
+		e.dispatchMessages(allReady)
 	}
 
 	return nil
 }
 
 func (e *ParallelRaftEngine) tickGroup(group *RaftGroup) RaftReady {
-	// In real impl: call raft.Node.Tick() and collect Ready
-	return RaftReady{GroupID: group.ID}
+	group.Tick()
+	ready, ok := group.drain()
+	if !ok {
+		return RaftReady{GroupID: group.ID}
+	}
+	return ready
+}
+
+// dispatchMessages hands every group's outbound Raft messages to the
+// transport, which multiplexes them onto the right peer's long-lived
+// stream.
+func (e *ParallelRaftEngine) dispatchMessages(batch []RaftReady) {
+	if e.transport == nil {
+		return
+	}
+	for _, r := range batch {
+		for _, m := range r.Messages {
+			data, err := m.Marshal()
+			if err != nil {
+				e.logger.Error("raft: failed to marshal outbound message", zap.Uint64("group", r.GroupID), zap.Error(err))
+				continue
+			}
+			if err := e.transport.Send(RaftMessage{
+				From:    m.From,
+				To:      m.To,
+				GroupID: r.GroupID,
+				Type:    m.Type.String(),
+				Data:    data,
+			}); err != nil {
+				e.logger.Warn("raft: failed to send outbound message", zap.Uint64("group", r.GroupID), zap.Uint64("to", m.To), zap.Error(err))
+			}
+		}
+	}
 }
 
 // Run starts the tick loop
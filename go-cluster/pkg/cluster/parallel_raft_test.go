@@ -0,0 +1,130 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestShardRouter_GroupForIsDeterministicAndDistributes(t *testing.T) {
+	r := NewShardRouter(4)
+
+	if r.GroupFor("docs", "k1") != r.GroupFor("docs", "k1") {
+		t.Fatal("GroupFor should be deterministic for the same collection/key")
+	}
+	if r.GroupFor("docs", "") != r.GroupFor("docs", "") {
+		t.Fatal("GroupFor should be deterministic for an empty key (collection-level DDL)")
+	}
+
+	seen := make(map[uint64]bool)
+	for i := 0; i < 100; i++ {
+		seen[r.GroupFor("docs", fmt.Sprintf("key-%d", i))] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("GroupFor spread 100 keys across only %d of %d groups, want more spread", len(seen), r.GroupCount())
+	}
+}
+
+func newSingleNodeGroup(t *testing.T, engine *ParallelRaftEngine, groupID uint64) *RaftGroup {
+	t.Helper()
+	g := engine.AddGroup(groupID, 1, []uint64{1})
+	t.Cleanup(g.Stop)
+	return g
+}
+
+// tickUntilLeader ticks engine until every group reports raft ID 1 (the
+// sole member of each single-node test group) as its leader, or fails the
+// test if that never happens within timeout.
+func tickUntilLeader(t *testing.T, engine *ParallelRaftEngine, groups []*RaftGroup, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if err := engine.Tick(context.Background()); err != nil {
+			t.Fatalf("Tick() error = %v", err)
+		}
+
+		allLeaders := true
+		for _, g := range groups {
+			if g.Leader() != 1 {
+				allLeaders = false
+				break
+			}
+		}
+		if allLeaders {
+			return
+		}
+	}
+	t.Fatalf("groups never elected a leader within %s", timeout)
+}
+
+func TestRaftGroup_SingleNodeElectsItselfLeader(t *testing.T) {
+	engine := NewParallelRaftEngine(zap.NewNop(), time.Millisecond, nil, nil)
+	g := newSingleNodeGroup(t, engine, 1)
+
+	tickUntilLeader(t, engine, []*RaftGroup{g}, time.Second)
+
+	if g.Leader() != 1 {
+		t.Errorf("Leader() = %d, want 1 (sole member of a single-node group)", g.Leader())
+	}
+}
+
+// TestParallelRaftEngine_ProposeCommitsAndBatchWriterPersistsAcrossGroups
+// proposes to two different groups (picked by ShardRouter: "alpha" hashes
+// to group 1, "gamma" to group 0) in the same tick window and checks that
+// ticking makes the BatchWriter grow the WAL past what leader election
+// already wrote.
+func TestParallelRaftEngine_ProposeCommitsAndBatchWriterPersistsAcrossGroups(t *testing.T) {
+	walPath := filepath.Join(t.TempDir(), "raft.wal")
+	bw, err := NewBatchWriter(walPath)
+	if err != nil {
+		t.Fatalf("NewBatchWriter() error = %v", err)
+	}
+
+	engine := NewParallelRaftEngine(zap.NewNop(), time.Millisecond, nil, bw)
+	engine.EnableSharding(NewShardRouter(2))
+
+	g0 := newSingleNodeGroup(t, engine, 0)
+	g1 := newSingleNodeGroup(t, engine, 1)
+	tickUntilLeader(t, engine, []*RaftGroup{g0, g1}, time.Second)
+
+	var before int64
+	if info, err := os.Stat(walPath); err == nil {
+		before = info.Size()
+	}
+
+	if err := engine.Propose(context.Background(), "docs", "alpha", []byte("alpha-data")); err != nil {
+		t.Fatalf("Propose(alpha) error = %v", err)
+	}
+	if err := engine.Propose(context.Background(), "docs", "gamma", []byte("gamma-data")); err != nil {
+		t.Fatalf("Propose(gamma) error = %v", err)
+	}
+
+	persisted := false
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) && !persisted {
+		if err := engine.Tick(context.Background()); err != nil {
+			t.Fatalf("Tick() error = %v", err)
+		}
+		if info, err := os.Stat(walPath); err == nil && info.Size() > before {
+			persisted = true
+		}
+	}
+
+	if !persisted {
+		t.Fatal("BatchWriter never persisted any entries to the WAL after proposing on two groups")
+	}
+}
+
+func TestParallelRaftEngine_ProposeWithoutShardingFails(t *testing.T) {
+	engine := NewParallelRaftEngine(zap.NewNop(), time.Millisecond, nil, nil)
+	newSingleNodeGroup(t, engine, 0)
+
+	if err := engine.Propose(context.Background(), "docs", "alpha", []byte("data")); err == nil {
+		t.Error("Propose() error = nil, want an error when EnableSharding was never called")
+	}
+}
@@ -1,9 +1,21 @@
 // Package cluster implements pipelined Raft transport
-// for non-blocking message delivery.
+// over gRPC streams, with inflight-aware backpressure.
 package cluster
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "github.com/lumadb/cluster/pkg/api/pb"
+	"go.etcd.io/etcd/raft/v3/raftpb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
 // RaftMessage represents a Raft protocol message
@@ -15,13 +27,6 @@ type RaftMessage struct {
 	Data    []byte
 }
 
-// PipelineTransport sends Raft messages without waiting for responses
-type PipelineTransport struct {
-	mu       sync.RWMutex
-	streams  map[uint64]chan RaftMessage
-	inflight *InflightTracker
-}
-
 // InflightTracker tracks messages in flight
 type InflightTracker struct {
 	mu      sync.Mutex
@@ -54,66 +59,475 @@ func (t *InflightTracker) Release(nodeID uint64) {
 	}
 }
 
-// NewPipelineTransport creates a new pipelined transport
-func NewPipelineTransport() *PipelineTransport {
+// Total returns the sum of inflight counts across every peer, for metrics.
+func (t *InflightTracker) Total() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var total int64
+	for _, n := range t.pending {
+		total += int64(n)
+	}
+	return total
+}
+
+// TransportMetrics is a point-in-time snapshot of PipelineTransport's
+// counters, exposed through /health so operators can see backpressure and
+// reconnect churn without a separate metrics scrape.
+type TransportMetrics struct {
+	MsgsInflight     int64
+	MsgsDropped      int64
+	StreamReconnects int64
+}
+
+// PeerDialer opens the gRPC stream used to reach a peer node, so tests can
+// substitute an in-process pipe instead of dialing real TCP.
+type PeerDialer interface {
+	Dial(ctx context.Context, nodeID uint64) (pb.RaftTransportClient, error)
+}
+
+// grpcPeerDialer is the production PeerDialer: it dials (and caches) one
+// *grpc.ClientConn per peer, keyed by the address map it was built with.
+type grpcPeerDialer struct {
+	mu    sync.Mutex
+	addrs map[uint64]string
+	conns map[uint64]*grpc.ClientConn
+}
+
+// NewGRPCPeerDialer builds a PeerDialer that reaches peers at the given
+// nodeID -> "host:port" addresses.
+func NewGRPCPeerDialer(addrs map[uint64]string) PeerDialer {
+	return &grpcPeerDialer{
+		addrs: addrs,
+		conns: make(map[uint64]*grpc.ClientConn),
+	}
+}
+
+func (d *grpcPeerDialer) Dial(ctx context.Context, nodeID uint64) (pb.RaftTransportClient, error) {
+	d.mu.Lock()
+	conn, ok := d.conns[nodeID]
+	d.mu.Unlock()
+
+	if !ok {
+		addr, ok := d.addrs[nodeID]
+		if !ok {
+			return nil, fmt.Errorf("raft transport: no address configured for peer %d", nodeID)
+		}
+		var err error
+		conn, err = grpc.DialContext(ctx, addr, grpc.WithBlock())
+		if err != nil {
+			return nil, err
+		}
+		d.mu.Lock()
+		d.conns[nodeID] = conn
+		d.mu.Unlock()
+	}
+
+	return pb.NewRaftTransportClient(conn), nil
+}
+
+// PipelineTransport sends Raft messages over one long-lived, reconnecting
+// gRPC stream per peer, with InflightTracker gating how many unacked
+// messages may be outstanding to any single peer at once.
+type PipelineTransport struct {
+	mu    sync.RWMutex
+	peers map[uint64]*peerStream
+
+	groupsMu sync.RWMutex
+	groups   map[uint64]*RaftGroup // groupID -> group, see RegisterGroup
+
+	inflight *InflightTracker
+	dialer   PeerDialer
+	logger   *zap.Logger
+
+	msgsDropped      int64
+	streamReconnects int64
+}
+
+// NewPipelineTransport creates a transport that dials peers through
+// dialer. Pass a nil dialer in tests that only exercise Receive/local
+// delivery; a nil logger defaults to a no-op logger.
+func NewPipelineTransport(dialer PeerDialer, logger *zap.Logger) *PipelineTransport {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
 	return &PipelineTransport{
-		streams:  make(map[uint64]chan RaftMessage),
+		peers:    make(map[uint64]*peerStream),
+		groups:   make(map[uint64]*RaftGroup),
 		inflight: NewInflightTracker(100),
+		dialer:   dialer,
+		logger:   logger,
 	}
 }
 
-// Connect creates a stream to a peer
+// RegisterGroup tells the transport to dispatch inbound messages carrying
+// GroupID straight to group.Step, instead of leaving the raw RaftMessage on
+// the sending peer's Receive() channel for a caller to decode itself. This
+// is what lets one reconnecting stream per peer carry traffic for every
+// Raft group that peer participates in.
+func (t *PipelineTransport) RegisterGroup(groupID uint64, group *RaftGroup) {
+	t.groupsMu.Lock()
+	defer t.groupsMu.Unlock()
+	t.groups[groupID] = group
+}
+
+func (t *PipelineTransport) groupFor(groupID uint64) (*RaftGroup, bool) {
+	t.groupsMu.RLock()
+	defer t.groupsMu.RUnlock()
+	g, ok := t.groups[groupID]
+	return g, ok
+}
+
+// Connect idempotently starts the reconnecting stream to nodeID. It
+// returns immediately; the stream is established in the background with
+// exponential backoff, so an unreachable peer never blocks the caller.
 func (t *PipelineTransport) Connect(nodeID uint64) {
 	t.mu.Lock()
-	defer t.mu.Unlock()
-	if _, exists := t.streams[nodeID]; !exists {
-		t.streams[nodeID] = make(chan RaftMessage, 1000)
+	if _, exists := t.peers[nodeID]; exists {
+		t.mu.Unlock()
+		return
 	}
+	p := newPeerStream(nodeID, t.dialer, t.inflight, t.logger, &t.msgsDropped, &t.streamReconnects)
+	t.peers[nodeID] = p
+	t.mu.Unlock()
+
+	go p.run()
 }
 
-// Send queues a message for delivery (non-blocking)
-func (t *PipelineTransport) Send(msg RaftMessage) error {
+func (t *PipelineTransport) peer(nodeID uint64) *peerStream {
 	t.mu.RLock()
-	stream, exists := t.streams[msg.To]
-	t.mu.RUnlock()
+	defer t.mu.RUnlock()
+	return t.peers[nodeID]
+}
 
-	if !exists {
-		// Auto-connect
-		t.Connect(msg.To)
-		t.mu.RLock()
-		stream = t.streams[msg.To]
-		t.mu.RUnlock()
-	}
+// Send queues a message for delivery (non-blocking), acquiring an
+// inflight slot first. The slot is released once the peer acks the
+// message (see peerStream.pump) or the message is dropped because the
+// outbox is full.
+func (t *PipelineTransport) Send(msg RaftMessage) error {
+	t.Connect(msg.To)
+	p := t.peer(msg.To)
 
-	// Non-blocking send with flow control
 	if !t.inflight.Acquire(msg.To) {
-		// Backpressure: drop or block
-		return nil
+		return fmt.Errorf("raft transport: peer %d is at its inflight limit", msg.To)
 	}
 
 	select {
-	case stream <- msg:
+	case p.outbox <- msg:
 		return nil
 	default:
 		t.inflight.Release(msg.To)
-		// Channel full, drop message (in real impl: buffer or retry)
-		return nil
+		atomic.AddInt64(&t.msgsDropped, 1)
+		return fmt.Errorf("raft transport: outbox full for peer %d, message dropped", msg.To)
 	}
 }
 
-// Pipeline sends multiple messages without waiting
+// Pipeline sends a batch of messages, each through Send. A peer's
+// peerStream collects whatever is sitting in its outbox into one batch and
+// writes it back-to-back on the peer's stream, but each message is still
+// its own stream Send; messages are not merged into one wire frame.
 func (t *PipelineTransport) Pipeline(msgs []RaftMessage) error {
+	var firstErr error
 	for _, msg := range msgs {
-		if err := t.Send(msg); err != nil {
+		if err := t.Send(msg); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// maxSnapshotChunkBytes bounds a single SnapshotChunk's payload so a
+// multi-megabyte Raft snapshot doesn't show up as one oversized gRPC
+// message.
+const maxSnapshotChunkBytes = 256 * 1024
+
+// Snapshot streams groupID's Raft snapshot to nodeID over the dedicated
+// Snapshot RPC, which bypasses the InflightTracker entirely - a snapshot
+// is one big transfer, not a stream of small messages competing for the
+// same backpressure budget as normal Raft traffic.
+func (t *PipelineTransport) Snapshot(ctx context.Context, nodeID, groupID uint64, snap raftpb.Snapshot) error {
+	if t.dialer == nil {
+		return fmt.Errorf("raft transport: no dialer configured, cannot snapshot peer %d", nodeID)
+	}
+
+	data, err := snap.Marshal()
+	if err != nil {
+		return fmt.Errorf("raft transport: marshal snapshot for group %d: %w", groupID, err)
+	}
+
+	client, err := t.dialer.Dial(ctx, nodeID)
+	if err != nil {
+		return err
+	}
+
+	stream, err := client.Snapshot(ctx)
+	if err != nil {
+		return err
+	}
+
+	for len(data) > 0 {
+		n := maxSnapshotChunkBytes
+		if n > len(data) {
+			n = len(data)
+		}
+		if err := stream.Send(&pb.SnapshotChunk{NodeId: nodeID, GroupId: groupID, Data: data[:n]}); err != nil {
 			return err
 		}
+		data = data[n:]
 	}
-	return nil
+
+	_, err = stream.CloseAndRecv()
+	return err
 }
 
-// Receive gets messages for a node (for testing/local delivery)
+// Receive gets messages for a node (for testing/local delivery) - the
+// channel messages land on once the peer's stream acks them.
 func (t *PipelineTransport) Receive(nodeID uint64) <-chan RaftMessage {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
-	return t.streams[nodeID]
+	t.Connect(nodeID)
+	return t.peer(nodeID).inbox
+}
+
+// Metrics returns a snapshot of the transport's backpressure and
+// reconnect counters, consumed by tdengine.API.Health.
+func (t *PipelineTransport) Metrics() TransportMetrics {
+	return TransportMetrics{
+		MsgsInflight:     t.inflight.Total(),
+		MsgsDropped:      atomic.LoadInt64(&t.msgsDropped),
+		StreamReconnects: atomic.LoadInt64(&t.streamReconnects),
+	}
+}
+
+// MsgsInflight, MsgsDropped and StreamReconnects implement
+// tdengine.TransportHealth, so a *PipelineTransport can be handed
+// straight to tdengine.API.Transport without tdengine importing this
+// package.
+func (t *PipelineTransport) MsgsInflight() int64     { return t.Metrics().MsgsInflight }
+func (t *PipelineTransport) MsgsDropped() int64      { return t.Metrics().MsgsDropped }
+func (t *PipelineTransport) StreamReconnects() int64 { return t.Metrics().StreamReconnects }
+
+// peerStream owns one reconnecting bidirectional Stream RPC to a single
+// peer. Messages enqueued on outbox are coalesced into batches and
+// written to the current stream; acks read back release the
+// corresponding InflightTracker slot and are forwarded to inbox.
+type peerStream struct {
+	nodeID   uint64
+	dialer   PeerDialer
+	inflight *InflightTracker
+	logger   *zap.Logger
+
+	outbox chan RaftMessage
+	inbox  chan RaftMessage
+
+	dropped    *int64
+	reconnects *int64
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newPeerStream(nodeID uint64, dialer PeerDialer, inflight *InflightTracker, logger *zap.Logger, dropped, reconnects *int64) *peerStream {
+	return &peerStream{
+		nodeID:     nodeID,
+		dialer:     dialer,
+		inflight:   inflight,
+		logger:     logger,
+		outbox:     make(chan RaftMessage, 1024),
+		inbox:      make(chan RaftMessage, 1024),
+		dropped:    dropped,
+		reconnects: reconnects,
+		closed:     make(chan struct{}),
+	}
+}
+
+// run dials the peer and pumps messages for as long as the transport
+// lives, reconnecting with exponential backoff and jitter whenever the
+// stream breaks.
+func (p *peerStream) run() {
+	if p.dialer == nil {
+		return // local-only peer (tests); nothing to dial
+	}
+
+	backoff := 100 * time.Millisecond
+	const maxBackoff = 10 * time.Second
+
+	for attempt := 0; ; attempt++ {
+		select {
+		case <-p.closed:
+			return
+		default:
+		}
+
+		if attempt > 0 {
+			atomic.AddInt64(p.reconnects, 1)
+			jitter := time.Duration(rand.Int63n(int64(backoff)/2 + 1))
+			select {
+			case <-time.After(backoff + jitter):
+			case <-p.closed:
+				return
+			}
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		client, err := p.dialer.Dial(ctx, p.nodeID)
+		if err != nil {
+			p.logger.Warn("raft transport: dial failed, backing off",
+				zap.Uint64("peer", p.nodeID), zap.Error(err))
+			cancel()
+			continue
+		}
+
+		stream, err := client.Stream(ctx)
+		if err != nil {
+			p.logger.Warn("raft transport: stream open failed, backing off",
+				zap.Uint64("peer", p.nodeID), zap.Error(err))
+			cancel()
+			continue
+		}
+
+		backoff = 100 * time.Millisecond
+		p.pump(stream)
+		cancel()
+	}
+}
+
+// pump drains outbox into batched Sends and acks from the stream into
+// InflightTracker.Release + inbox, until the stream errors or closes.
+func (p *peerStream) pump(stream pb.RaftTransport_StreamClient) {
+	recvDone := make(chan struct{})
+	go func() {
+		defer close(recvDone)
+		for {
+			ack, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			p.inflight.Release(p.nodeID)
+			p.inbox <- RaftMessage{From: p.nodeID, GroupID: ack.GroupId, Type: "ack"}
+		}
+	}()
+
+	const coalesceWindow = 5 * time.Millisecond
+	for {
+		select {
+		case <-recvDone:
+			return
+		case <-p.closed:
+			stream.CloseSend()
+			return
+		case msg := <-p.outbox:
+			batch := []RaftMessage{msg}
+			timer := time.NewTimer(coalesceWindow)
+		drain:
+			for {
+				select {
+				case next := <-p.outbox:
+					batch = append(batch, next)
+				case <-timer.C:
+					break drain
+				}
+				if len(p.outbox) == 0 {
+					timer.Stop()
+					break drain
+				}
+			}
+
+			for _, m := range batch {
+				if err := stream.Send(toPB(m)); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+func toPB(m RaftMessage) *pb.RaftMessage {
+	return &pb.RaftMessage{From: m.From, To: m.To, GroupId: m.GroupID, Type: m.Type, Data: m.Data}
+}
+
+// raftTransportServer implements pb.RaftTransportServer: the receiving
+// side of the bidirectional Stream RPC, delivering incoming messages to
+// the transport's local inbox and acking each one back to the sender.
+type raftTransportServer struct {
+	pb.UnimplementedRaftTransportServer
+	t *PipelineTransport
+}
+
+// RegisterRaftTransportServer registers the RaftTransport service so this
+// node can receive peers' gRPC streams, mirroring RegisterGRPCServer in
+// package api.
+func RegisterRaftTransportServer(s *grpc.Server, t *PipelineTransport) {
+	pb.RegisterRaftTransportServer(s, &raftTransportServer{t: t})
+}
+
+// Stream receives inbound Raft messages from a peer. A message whose
+// GroupID has a registered RaftGroup (see RegisterGroup) is decoded and
+// stepped straight into that group's Node; anything else falls back to the
+// sending peer's Receive() channel, for tests and any group a caller wants
+// to drain manually.
+func (s *raftTransportServer) Stream(stream pb.RaftTransport_StreamServer) error {
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		s.t.Connect(msg.From)
+
+		if group, ok := s.t.groupFor(msg.GroupId); ok {
+			var raftMsg raftpb.Message
+			if err := raftMsg.Unmarshal(msg.Data); err != nil {
+				return fmt.Errorf("raft transport: decode message for group %d: %w", msg.GroupId, err)
+			}
+			if err := group.Step(stream.Context(), raftMsg); err != nil {
+				s.t.logger.Warn("raft transport: group step failed", zap.Uint64("group", msg.GroupId), zap.Error(err))
+			}
+		} else if p := s.t.peer(msg.From); p != nil {
+			p.inbox <- RaftMessage{From: msg.From, To: msg.To, GroupID: msg.GroupId, Type: msg.Type, Data: msg.Data}
+		}
+
+		if err := stream.Send(&pb.RaftAck{GroupId: msg.GroupId}); err != nil {
+			return err
+		}
+	}
+}
+
+// Snapshot receives a chunked Raft snapshot transfer and, once fully
+// reassembled, installs it into the chunk's GroupID's registered RaftGroup.
+// Chunks with no registered group (e.g. in tests exercising only the wire
+// format) are accepted and discarded, matching Stream's fallback behavior.
+func (s *raftTransportServer) Snapshot(stream pb.RaftTransport_SnapshotServer) error {
+	var buf bytes.Buffer
+	var groupID uint64
+
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			if buf.Len() == 0 {
+				return stream.SendAndClose(&pb.SnapshotAck{})
+			}
+
+			var snap raftpb.Snapshot
+			if err := snap.Unmarshal(buf.Bytes()); err != nil {
+				return fmt.Errorf("raft transport: decode snapshot for group %d: %w", groupID, err)
+			}
+			if group, ok := s.t.groupFor(groupID); ok {
+				if err := group.ApplySnapshot(snap); err != nil {
+					return fmt.Errorf("raft transport: apply snapshot for group %d: %w", groupID, err)
+				}
+			}
+			return stream.SendAndClose(&pb.SnapshotAck{})
+		}
+		if err != nil {
+			return err
+		}
+
+		groupID = chunk.GroupId
+		buf.Write(chunk.Data)
+	}
 }
@@ -0,0 +1,112 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RetentionPolicy mirrors InfluxDB's RetentionPolicyInfo: how long a
+// collection keeps its documents, how that window is broken into shard
+// groups, and how many replicas each shard group gets.
+type RetentionPolicy struct {
+	Duration           time.Duration `json:"duration"`
+	ShardGroupDuration time.Duration `json:"shardGroupDuration"`
+	ReplicaN           int           `json:"replicaN"`
+}
+
+// MarshalBinary/UnmarshalBinary let a RetentionPolicy travel through the
+// Raft log as a Command's Value, the same way document bodies already do.
+func (p RetentionPolicy) MarshalBinary() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+func (p *RetentionPolicy) UnmarshalBinary(data []byte) error {
+	return json.Unmarshal(data, p)
+}
+
+// OpSetRetentionPolicy is the Command.Op that persists a collection's
+// RetentionPolicy in cluster metadata.
+const OpSetRetentionPolicy = "set_retention_policy"
+
+// RetentionPolicyCommand builds the Command that persists policy for
+// collection, ready to pass to Node.Apply.
+func RetentionPolicyCommand(collection string, policy RetentionPolicy) (*Command, error) {
+	value, err := policy.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("marshal retention policy: %w", err)
+	}
+	return &Command{
+		Op:         OpSetRetentionPolicy,
+		Collection: collection,
+		Value:      value,
+	}, nil
+}
+
+// RetentionSweeper periodically drops documents older than their
+// collection's retention policy and compacts the resulting tombstones,
+// mirroring InfluxDB's shard-group-based TTL compaction.
+type RetentionSweeper struct {
+	node     *Node
+	logger   *zap.Logger
+	interval time.Duration
+}
+
+// NewRetentionSweeper creates a sweeper that checks every collection's
+// retention policy once per interval.
+func NewRetentionSweeper(node *Node, logger *zap.Logger, interval time.Duration) *RetentionSweeper {
+	return &RetentionSweeper{node: node, logger: logger, interval: interval}
+}
+
+// Run sweeps on interval until ctx is canceled.
+func (s *RetentionSweeper) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			s.sweepOnce()
+		}
+	}
+}
+
+// sweepOnce drops expired documents and compacts tombstones for every
+// collection that carries a retention policy with a positive Duration.
+func (s *RetentionSweeper) sweepOnce() {
+	collections, err := s.node.ListCollections()
+	if err != nil {
+		s.logger.Error("retention sweep: failed to list collections", zap.Error(err))
+		return
+	}
+
+	for _, col := range collections {
+		policy, err := s.node.GetRetentionPolicy(col)
+		if err != nil || policy == nil || policy.Duration <= 0 {
+			continue
+		}
+
+		cutoff := time.Now().Add(-policy.Duration)
+		dropped, err := s.node.DropExpiredDocuments(col, cutoff)
+		if err != nil {
+			s.logger.Error("retention sweep: failed to drop expired documents",
+				zap.String("collection", col), zap.Error(err))
+			continue
+		}
+		if dropped == 0 {
+			continue
+		}
+
+		s.logger.Info("retention sweep: dropped expired documents",
+			zap.String("collection", col), zap.Int("dropped", dropped))
+		if err := s.node.CompactTombstones(col); err != nil {
+			s.logger.Error("retention sweep: failed to compact tombstones",
+				zap.String("collection", col), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package cluster
+
+import "hash/fnv"
+
+// ShardRouter maps a collection/key pair to the Raft group responsible for
+// it, so ParallelRaftEngine.Propose and a caller building the command to
+// propose (e.g. LumaGRPCServer's write path) agree on which group owns a
+// given write without either side hardcoding group IDs.
+type ShardRouter struct {
+	groupCount uint64
+}
+
+// NewShardRouter returns a router that spreads keys across groupCount
+// groups, numbered 0..groupCount-1. groupCount must match the number of
+// groups registered with the engine via AddGroup.
+func NewShardRouter(groupCount uint64) *ShardRouter {
+	if groupCount == 0 {
+		groupCount = 1
+	}
+	return &ShardRouter{groupCount: groupCount}
+}
+
+// GroupFor returns the group ID that owns collection/key. Hashing on
+// "collection/key" rather than key alone keeps every write to a collection
+// on the same group when key is empty (a collection-level DDL), while still
+// spreading per-document writes within a collection across groups.
+func (r *ShardRouter) GroupFor(collection, key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(collection))
+	h.Write([]byte{'/'})
+	h.Write([]byte(key))
+	return h.Sum64() % r.groupCount
+}
+
+// GroupCount returns the number of groups this router spreads keys across.
+func (r *ShardRouter) GroupCount() uint64 {
+	return r.groupCount
+}
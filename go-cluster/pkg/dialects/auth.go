@@ -0,0 +1,162 @@
+package dialects
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/lumadb/cluster/pkg/platform/auth"
+)
+
+// contextKey namespaces values this package stores on a request's context so
+// they can't collide with keys set by other packages.
+type contextKey string
+
+const userContextKey contextKey = "dialects.user"
+
+// UserFromContext returns the authenticated user attached by Authorizer,
+// if any. Handlers can use this to scope queries to a user's namespace.
+func UserFromContext(ctx context.Context) (*auth.User, bool) {
+	u, ok := ctx.Value(userContextKey).(*auth.User)
+	return u, ok
+}
+
+// dialectAction is the auth.Action required to use a given dialect endpoint.
+// Every endpoint here is read-only query execution today, so all map to
+// ActionRead; this is a single place to tighten a specific dialect later
+// (e.g. once write endpoints exist) without touching ServeHTTP's routing.
+var dialectAction = map[Dialect]auth.Action{
+	DialectPromQL:     auth.ActionRead,
+	DialectInfluxQL:   auth.ActionRead,
+	DialectFlux:       auth.ActionRead,
+	DialectDruidSQL:   auth.ActionRead,
+	DialectOpenTSDB:   auth.ActionRead,
+	DialectGraphite:   auth.ActionRead,
+	DialectQuestDB:    auth.ActionRead,
+	DialectSQL:        auth.ActionRead,
+	DialectTimescale:  auth.ActionRead,
+	DialectClickHouse: auth.ActionRead,
+}
+
+// Authorizer authenticates incoming requests against an auth.AuthEngine and
+// enforces role-based, per-dialect permissions before a handler runs. Set
+// DisableAuthentication to skip both steps entirely for single-node dev
+// setups that have no users configured.
+type Authorizer struct {
+	Engine                *auth.AuthEngine
+	DisableAuthentication bool
+}
+
+// NewAuthorizer creates an Authorizer backed by engine. Authentication is
+// enabled by default; set DisableAuthentication on the returned value to
+// turn it off.
+func NewAuthorizer(engine *auth.AuthEngine) *Authorizer {
+	return &Authorizer{Engine: engine}
+}
+
+// Authenticate resolves credentials from req using whichever scheme is
+// present: HTTP Basic, a bearer JWT, InfluxDB v1 "u"/"p" query-string
+// parameters, or an InfluxDB v2 "Authorization: Token <jwt>" header.
+func (a *Authorizer) Authenticate(req *http.Request) (*auth.User, error) {
+	if username, password, ok := basicCredentials(req); ok {
+		return a.authenticatePassword(username, password)
+	}
+
+	if username := req.URL.Query().Get("u"); username != "" {
+		return a.authenticatePassword(username, req.URL.Query().Get("p"))
+	}
+
+	if token := bearerOrTokenCredential(req); token != "" {
+		claims, err := a.Engine.ValidateToken(token)
+		if err != nil {
+			return nil, err
+		}
+		return &auth.User{ID: claims.UserID, Username: claims.UserID, Role: claims.Role, Namespace: claims.Namespace}, nil
+	}
+
+	return nil, auth.ErrInvalidToken
+}
+
+func (a *Authorizer) authenticatePassword(username, password string) (*auth.User, error) {
+	return a.Engine.AuthenticateUser(username, password)
+}
+
+// basicCredentials extracts HTTP Basic auth credentials, if present.
+func basicCredentials(req *http.Request) (username, password string, ok bool) {
+	h := req.Header.Get("Authorization")
+	if !strings.HasPrefix(h, "Basic ") {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(h, "Basic "))
+	if err != nil {
+		return "", "", false
+	}
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// bearerOrTokenCredential extracts the credential from either
+// "Authorization: Bearer <jwt>" (v1-style JWT auth) or
+// "Authorization: Token <jwt>" (InfluxDB v2 style).
+func bearerOrTokenCredential(req *http.Request) string {
+	h := req.Header.Get("Authorization")
+	switch {
+	case strings.HasPrefix(h, "Bearer "):
+		return strings.TrimPrefix(h, "Bearer ")
+	case strings.HasPrefix(h, "Token "):
+		return strings.TrimPrefix(h, "Token ")
+	default:
+		return ""
+	}
+}
+
+// Authorize authenticates req and checks the resulting user's role against
+// the permission required for dialect. It returns the authenticated user
+// (for callers to stash in the request context) or an error describing why
+// the request was rejected.
+func (a *Authorizer) Authorize(req *http.Request, dialect Dialect) (*auth.User, error) {
+	user, err := a.Authenticate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	required, ok := dialectAction[dialect]
+	if !ok {
+		required = auth.ActionRead
+	}
+	if !a.Engine.IsAuthorized(user.Role, required) {
+		return nil, auth.ErrInvalidToken
+	}
+
+	return user, nil
+}
+
+// dialectForPath mirrors ServeHTTP's own routing so the Authorizer can tell
+// which dialect's permission to enforce before the matching handler runs.
+func dialectForPath(path string) Dialect {
+	switch {
+	case strings.HasPrefix(path, "/api/v1/query"),
+		strings.HasPrefix(path, "/api/v1/series"),
+		strings.HasPrefix(path, "/api/v1/labels"),
+		strings.HasPrefix(path, "/api/v1/label/"):
+		return DialectPromQL
+	case strings.HasPrefix(path, "/query"):
+		return DialectInfluxQL
+	case strings.HasPrefix(path, "/api/v2/query"):
+		return DialectFlux
+	case strings.HasPrefix(path, "/druid/v2"):
+		return DialectDruidSQL
+	case strings.HasPrefix(path, "/api/query"):
+		return DialectOpenTSDB
+	case strings.HasPrefix(path, "/render"):
+		return DialectGraphite
+	case strings.HasPrefix(path, "/exec"):
+		return DialectQuestDB
+	default:
+		return DialectSQL
+	}
+}
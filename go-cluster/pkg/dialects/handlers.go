@@ -6,6 +6,9 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/lumadb/cluster/pkg/dialects/influxql"
+	"github.com/lumadb/cluster/pkg/dialects/promql"
 )
 
 // InfluxQLHandler handles InfluxQL queries
@@ -15,123 +18,388 @@ func (h *InfluxQLHandler) Dialect() Dialect {
 	return DialectInfluxQL
 }
 
+// influxqlAggFuncs are the call names treated as aggregations rather than
+// plain scalar expressions when walking a SELECT list.
+var influxqlAggFuncs = map[string]bool{
+	"mean": true, "sum": true, "count": true, "min": true, "max": true,
+	"first": true, "last": true, "median": true, "stddev": true, "spread": true,
+	"non_negative_derivative": true,
+}
+
+// Parse parses query with the real InfluxQL scanner/parser in the
+// influxql package and walks the resulting AST into a ParsedQuery. A
+// query can contain several semicolon-separated statements; ParsedQuery
+// only describes one, so - same as the regex version this replaced -
+// only the first statement is represented here. Unlike the regex version,
+// the walk below is driven by an actual parse tree, so it survives
+// subqueries, INTO, absolute time literals, and arithmetic in the SELECT
+// list instead of silently missing them.
 func (h *InfluxQLHandler) Parse(query string) (*ParsedQuery, error) {
 	query = strings.TrimSpace(query)
-	upper := strings.ToUpper(query)
 
 	parsed := &ParsedQuery{
 		Dialect:       DialectInfluxQL,
 		OriginalQuery: query,
+		Intent:        IntentRead,
 	}
 
-	// Parse FROM clause
-	fromRe := regexp.MustCompile(`(?i)FROM\s+["']?(\w+)["']?`)
-	if matches := fromRe.FindStringSubmatch(query); len(matches) > 1 {
-		parsed.Sources = append(parsed.Sources, DataSource{
-			Name: matches[1],
-		})
+	// INSERT/DELETE/DROP/CREATE DATABASE aren't in the influxql package's
+	// grammar at all (see parseStatement: it only recognizes SELECT, SHOW,
+	// and CREATE CONTINUOUS QUERY), so these can't be classified from a
+	// real AST node the way SELECT/SHOW are below - a keyword match on the
+	// raw query is the only option.
+	if intent, ok := influxqlKeywordIntent(query); ok {
+		parsed.Intent = intent
+		return parsed, nil
 	}
 
-	// Parse time range
-	timeRe := regexp.MustCompile(`(?i)WHERE\s+.*time\s*([><]=?)\s*now\(\)\s*-\s*(\d+)([smhd])`)
-	if matches := timeRe.FindStringSubmatch(query); len(matches) > 3 {
-		value, _ := strconv.ParseInt(matches[2], 10, 64)
-		unit := matches[3]
+	stmts, err := influxql.NewParser(query).ParseQuery()
+	if err != nil {
+		return nil, fmt.Errorf("influxql: %w", err)
+	}
+	if len(stmts) == 0 {
+		return parsed, nil
+	}
 
-		var duration time.Duration
-		switch unit {
-		case "s":
-			duration = time.Duration(value) * time.Second
-		case "m":
-			duration = time.Duration(value) * time.Minute
-		case "h":
-			duration = time.Duration(value) * time.Hour
-		case "d":
-			duration = time.Duration(value) * 24 * time.Hour
-		}
+	var sel *influxql.SelectStatement
+	switch stmt := stmts[0].(type) {
+	case *influxql.SelectStatement:
+		sel = stmt
+	case *influxql.CreateContinuousQueryStatement:
+		parsed.Intent = IntentDDL
+		sel = stmt.Source
+	case *influxql.ShowMeasurementsStatement:
+		parsed.Limit, parsed.Offset = stmt.Limit, stmt.Offset
+		return parsed, nil
+	case *influxql.ShowSeriesStatement:
+		parsed.Sources = influxqlDataSources(stmt.Sources)
+		return parsed, nil
+	case *influxql.ShowTagKeysStatement:
+		parsed.Sources = influxqlDataSources(stmt.Sources)
+		return parsed, nil
+	case *influxql.ShowTagValuesStatement:
+		parsed.Sources = influxqlDataSources(stmt.Sources)
+		return parsed, nil
+	case *influxql.ShowFieldKeysStatement:
+		parsed.Sources = influxqlDataSources(stmt.Sources)
+		return parsed, nil
+	default:
+		return parsed, nil
+	}
+
+	parsed.Sources = influxqlDataSources(sel.Sources)
+	parsed.Limit = sel.Limit
+	parsed.Offset = sel.Offset
+
+	for _, f := range sel.Fields {
+		walkInfluxQLAggregations(f, &parsed.Aggregations)
+	}
+
+	for _, d := range sel.Dimensions {
+		parsed.GroupBy = append(parsed.GroupBy, influxqlDimensionString(d))
+	}
+
+	for _, sf := range sel.SortFields {
+		parsed.OrderBy = append(parsed.OrderBy, OrderBy{Column: sf.Name, Ascending: sf.Ascending})
+	}
 
-		parsed.TimeRange = &TimeRange{
-			End:      time.Now(),
-			Start:    time.Now().Add(-duration),
-			Duration: duration,
+	if sel.Condition != nil {
+		for _, conjunct := range influxqlFlattenAnd(sel.Condition) {
+			if tr := influxqlTimeRangeFromExpr(conjunct); tr != nil {
+				parsed.TimeRange = tr
+				continue
+			}
+			if f, ok := influxqlFilterFromExpr(conjunct); ok {
+				parsed.Filters = append(parsed.Filters, f)
+			}
+			// Anything else under this conjunct (a nested OR/NOT subtree)
+			// can't be represented by ParsedQuery's flat Filters list, so
+			// - same limitation the regex parser had, just narrowed to
+			// exactly the part of the tree that's genuinely inexpressible
+			// instead of the whole query - it's dropped here rather than
+			// misreported as an AND condition.
 		}
 	}
 
-	// Parse aggregations
-	aggRe := regexp.MustCompile(`(?i)(mean|sum|count|min|max|first|last|median|stddev|spread)\s*\(\s*["']?(\w+)["']?\s*\)`)
-	for _, match := range aggRe.FindAllStringSubmatch(query, -1) {
-		if len(match) > 2 {
-			parsed.Aggregations = append(parsed.Aggregations, Aggregation{
-				Function: strings.ToLower(match[1]),
-				Column:   match[2],
-			})
+	return parsed, nil
+}
+
+// influxqlKeywordIntent recognizes the statement kinds the influxql
+// package's parser can't: INSERT and DELETE mutate documents, DROP and
+// CREATE DATABASE/RETENTION POLICY change schema or retention. Reports ok
+// false for anything else, leaving classification to the real parser.
+func influxqlKeywordIntent(query string) (QueryIntent, bool) {
+	upper := strings.ToUpper(query)
+	switch {
+	case strings.HasPrefix(upper, "INSERT"):
+		return IntentWrite, true
+	case strings.HasPrefix(upper, "DELETE"):
+		return IntentWrite, true
+	case strings.HasPrefix(upper, "DROP"):
+		return IntentDDL, true
+	case strings.HasPrefix(upper, "CREATE DATABASE"), strings.HasPrefix(upper, "CREATE RETENTION POLICY"):
+		return IntentDDL, true
+	default:
+		return "", false
+	}
+}
+
+func influxqlDataSources(sources []influxql.Source) []DataSource {
+	var out []DataSource
+	for _, src := range sources {
+		m, ok := src.(*influxql.Measurement)
+		if !ok {
+			// A SubQuery source has no flat (Name, Database, Alias) form;
+			// ParsedQuery.Sources can't represent it yet, so it's omitted
+			// rather than reported under the wrong name.
+			continue
+		}
+		name := m.Name
+		if m.Regex != nil {
+			name = "/" + m.Regex.Val + "/"
 		}
+		out = append(out, DataSource{Name: name, Database: m.Database})
 	}
+	return out
+}
 
-	// Parse GROUP BY time
-	groupTimeRe := regexp.MustCompile(`(?i)GROUP\s+BY\s+time\s*\(\s*(\d+)([smhd])\s*\)`)
-	if matches := groupTimeRe.FindStringSubmatch(query); len(matches) > 2 {
-		parsed.GroupBy = append(parsed.GroupBy, fmt.Sprintf("time(%s%s)", matches[1], matches[2]))
+func influxqlDimensionString(d *influxql.Dimension) string {
+	switch e := d.Expr.(type) {
+	case *influxql.Call:
+		args := make([]string, len(e.Args))
+		for i, a := range e.Args {
+			args[i] = influxqlExprString(a)
+		}
+		return fmt.Sprintf("%s(%s)", e.Name, strings.Join(args, ", "))
+	case *influxql.Wildcard:
+		return "*"
+	default:
+		return influxqlExprString(d.Expr)
 	}
+}
 
-	// Parse LIMIT
-	limitRe := regexp.MustCompile(`(?i)LIMIT\s+(\d+)`)
-	if matches := limitRe.FindStringSubmatch(query); len(matches) > 1 {
-		parsed.Limit, _ = strconv.ParseInt(matches[1], 10, 64)
+func influxqlExprString(e influxql.Expr) string {
+	switch v := e.(type) {
+	case *influxql.VarRef:
+		return v.Val
+	case *influxql.DurationLiteral:
+		return formatInfluxQLDuration(v.Val)
+	case *influxql.NumberLiteral:
+		if v.IsInt {
+			return strconv.FormatInt(int64(v.Val), 10)
+		}
+		return strconv.FormatFloat(v.Val, 'f', -1, 64)
+	case *influxql.StringLiteral:
+		return v.Val
+	default:
+		return ""
 	}
+}
 
-	// Parse ORDER BY
-	orderRe := regexp.MustCompile(`(?i)ORDER\s+BY\s+(\w+)(?:\s+(ASC|DESC))?`)
-	if matches := orderRe.FindStringSubmatch(query); len(matches) > 1 {
-		ascending := true
-		if len(matches) > 2 && strings.ToUpper(matches[2]) == "DESC" {
-			ascending = false
+// walkInfluxQLAggregations finds every aggregate Call in field's
+// expression (including ones nested inside arithmetic, e.g. `mean(a) *
+// 2`) and appends an Aggregation for each.
+func walkInfluxQLAggregations(field *influxql.Field, out *[]Aggregation) {
+	var walk func(e influxql.Expr)
+	walk = func(e influxql.Expr) {
+		switch v := e.(type) {
+		case *influxql.Call:
+			if influxqlAggFuncs[strings.ToLower(v.Name)] {
+				column := ""
+				if len(v.Args) > 0 {
+					column = influxqlExprString(v.Args[0])
+				}
+				agg := Aggregation{Function: strings.ToLower(v.Name), Column: column, Alias: field.Alias}
+				*out = append(*out, agg)
+				return
+			}
+			for _, a := range v.Args {
+				walk(a)
+			}
+		case *influxql.BinaryExpr:
+			walk(v.LHS)
+			walk(v.RHS)
+		case *influxql.ParenExpr:
+			walk(v.Expr)
+		case *influxql.UnaryExpr:
+			walk(v.Expr)
 		}
-		parsed.OrderBy = append(parsed.OrderBy, OrderBy{
-			Column:    matches[1],
-			Ascending: ascending,
-		})
 	}
+	walk(field.Expr)
+}
 
-	_ = upper // silence unused variable
-	return parsed, nil
+// influxqlFlattenAnd splits a WHERE condition on its top-level ANDs,
+// leaving any OR/NOT subtree as a single opaque conjunct - the caller
+// decides what, if anything, it can do with those.
+func influxqlFlattenAnd(e influxql.Expr) []influxql.Expr {
+	if b, ok := e.(*influxql.BinaryExpr); ok && b.Op == influxql.AND {
+		return append(influxqlFlattenAnd(b.LHS), influxqlFlattenAnd(b.RHS)...)
+	}
+	if p, ok := e.(*influxql.ParenExpr); ok {
+		return influxqlFlattenAnd(p.Expr)
+	}
+	return []influxql.Expr{e}
 }
 
-func (h *InfluxQLHandler) FormatResponse(result *QueryResult, format string) (interface{}, error) {
-	// Format as InfluxDB response
-	type series struct {
-		Name    string          `json:"name"`
-		Columns []string        `json:"columns"`
-		Values  [][]interface{} `json:"values"`
+// influxqlTimeRangeFromExpr recognizes a `time <op> <time-or-duration>`
+// conjunct, both the absolute form (`time > '2024-01-01T00:00:00Z'`,
+// already resolved to a TimeLiteral by the parser) and the relative form
+// (`time > now() - 5m`).
+func influxqlTimeRangeFromExpr(e influxql.Expr) *TimeRange {
+	b, ok := e.(*influxql.BinaryExpr)
+	if !ok || !isComparison(b.Op) {
+		return nil
+	}
+	ref, ok := b.LHS.(*influxql.VarRef)
+	if !ok || strings.ToLower(ref.Val) != "time" {
+		return nil
 	}
 
-	type resultType struct {
-		StatementID int      `json:"statement_id"`
-		Series      []series `json:"series"`
+	switch rhs := b.RHS.(type) {
+	case *influxql.TimeLiteral:
+		tr := &TimeRange{}
+		switch b.Op {
+		case influxql.GT, influxql.GTE:
+			tr.Start = rhs.Val
+			tr.End = time.Now()
+		case influxql.LT, influxql.LTE:
+			tr.End = rhs.Val
+		default:
+			tr.Start, tr.End = rhs.Val, rhs.Val
+		}
+		tr.Duration = tr.End.Sub(tr.Start)
+		return tr
+	case *influxql.BinaryExpr:
+		call, ok := rhs.LHS.(*influxql.Call)
+		dur, okDur := rhs.RHS.(*influxql.DurationLiteral)
+		if !ok || !okDur || strings.ToLower(call.Name) != "now" {
+			return nil
+		}
+		now := time.Now()
+		offset := dur.Val
+		if rhs.Op == influxql.SUB {
+			return &TimeRange{Start: now.Add(-offset), End: now, Duration: offset}
+		}
+		if rhs.Op == influxql.ADD {
+			return &TimeRange{Start: now, End: now.Add(offset), Duration: offset}
+		}
 	}
+	return nil
+}
 
-	s := series{
-		Columns: make([]string, len(result.Columns)),
-		Values:  result.Rows,
+// formatInfluxQLDuration renders d the way it would have been written in
+// InfluxQL source (e.g. "5m", "1h"), rather than Go's "5m0s" - used when
+// re-deriving GROUP BY time() text from the parsed Dimension.
+func formatInfluxQLDuration(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	case d%time.Second == 0:
+		return fmt.Sprintf("%ds", d/time.Second)
+	default:
+		return fmt.Sprintf("%dms", d/time.Millisecond)
 	}
+}
 
-	for i, col := range result.Columns {
-		s.Columns[i] = col.Name
+func isComparison(t influxql.Token) bool {
+	switch t {
+	case influxql.EQ, influxql.NEQ, influxql.LT, influxql.LTE, influxql.GT, influxql.GTE:
+		return true
 	}
+	return false
+}
 
-	if len(result.Columns) > 0 {
-		// Try to get measurement name from first column
-		s.Name = "results"
+// influxqlFilterFromExpr recognizes a plain `column <op> literal`
+// conjunct as a Filter. Anything else (the `time` comparisons handled by
+// influxqlTimeRangeFromExpr above, regex matches, or a conjunct that
+// isn't a simple comparison at all) reports ok=false.
+func influxqlFilterFromExpr(e influxql.Expr) (Filter, bool) {
+	b, ok := e.(*influxql.BinaryExpr)
+	if !ok {
+		return Filter{}, false
+	}
+	ref, ok := b.LHS.(*influxql.VarRef)
+	if !ok || strings.ToLower(ref.Val) == "time" {
+		return Filter{}, false
 	}
 
-	return map[string]interface{}{
-		"results": []resultType{
-			{
-				StatementID: 0,
-				Series:      []series{s},
-			},
-		},
-	}, nil
+	var value interface{}
+	switch rhs := b.RHS.(type) {
+	case *influxql.StringLiteral:
+		value = rhs.Val
+	case *influxql.NumberLiteral:
+		value = rhs.Val
+	case *influxql.BooleanLiteral:
+		value = rhs.Val
+	case *influxql.RegexLiteral:
+		value = rhs.Val
+	default:
+		return Filter{}, false
+	}
+
+	return Filter{Column: ref.Val, Operator: b.Op.String(), Value: value}, true
+}
+
+func (h *InfluxQLHandler) FormatResponse(result *QueryResult, format string) (interface{}, error) {
+	return h.FormatResults(QueryResults{
+		Statements: []StatementResult{{StatementID: 0, Result: result}},
+	}, format)
+}
+
+// influxqlSeries is one series entry within a statement's results, InfluxDB's
+// {"name":...,"columns":[...],"values":[[...]]} shape.
+type influxqlSeries struct {
+	Name    string          `json:"name"`
+	Columns []string        `json:"columns"`
+	Values  [][]interface{} `json:"values"`
+}
+
+// influxqlStatementResult is one entry in the "results" array of InfluxDB's
+// {"results":[{"statement_id":0,"series":[...]},{"statement_id":1,"error":"..."}]}
+// response. Series and Error are mutually exclusive, same as StatementResult's
+// Result/Err.
+type influxqlStatementResult struct {
+	StatementID int              `json:"statement_id"`
+	Series      []influxqlSeries `json:"series,omitempty"`
+	Error       string           `json:"error,omitempty"`
+}
+
+// FormatResults is the multi-statement counterpart to FormatResponse: it
+// renders a ';'-separated batch's StatementResults into InfluxDB's own
+// {"results":[...]} shape, one entry per statement in request order, so a
+// failed statement is reported in place rather than failing the statements
+// around it in the same batch.
+func (h *InfluxQLHandler) FormatResults(results QueryResults, format string) (interface{}, error) {
+	out := make([]influxqlStatementResult, len(results.Statements))
+
+	for i, sr := range results.Statements {
+		rt := influxqlStatementResult{StatementID: sr.StatementID}
+		if sr.Err != nil {
+			rt.Error = sr.Err.Error()
+			out[i] = rt
+			continue
+		}
+
+		s := influxqlSeries{
+			Columns: make([]string, len(sr.Result.Columns)),
+			Values:  sr.Result.Rows,
+		}
+		for j, col := range sr.Result.Columns {
+			s.Columns[j] = col.Name
+		}
+		if len(sr.Result.Columns) > 0 {
+			// Try to get measurement name from first column
+			s.Name = "results"
+		}
+		rt.Series = []influxqlSeries{s}
+		out[i] = rt
+	}
+
+	return map[string]interface{}{"results": out}, nil
 }
 
 // FluxHandler handles Flux queries
@@ -141,12 +409,30 @@ func (h *FluxHandler) Dialect() Dialect {
 	return DialectFlux
 }
 
+// Parse recognizes a Flux pipeline one stage at a time (from/range/filter/
+// group/aggregateWindow/limit), rather than the single
+// r._measurement == "..." regex it used to be limited to, so a query with
+// several |> filter(...) stages or a |> group(columns: [...]) actually
+// populates ParsedQuery.Filters/GroupBy instead of silently matching
+// nothing past the first stage. Flux has no AST package in this repo the
+// way InfluxQL and PromQL now do, so each stage is still extracted with a
+// targeted regex/paren-scan rather than a real parser - just one scoped to
+// its own stage instead of the whole query text.
 func (h *FluxHandler) Parse(query string) (*ParsedQuery, error) {
 	query = strings.TrimSpace(query)
 
 	parsed := &ParsedQuery{
 		Dialect:       DialectFlux,
 		OriginalQuery: query,
+		Intent:        IntentRead,
+	}
+
+	// A |> to(bucket: ...) stage writes its input table back to storage
+	// instead of returning it - Flux's equivalent of InfluxQL's INSERT -
+	// so its presence anywhere in the pipeline makes the whole query a
+	// write, same as fluxPipeStageArgs' own single-stage-type lookups.
+	if len(fluxPipeStageArgs(query, "to")) > 0 {
+		parsed.Intent = IntentWrite
 	}
 
 	// Parse bucket
@@ -172,11 +458,37 @@ func (h *FluxHandler) Parse(query string) (*ParsedQuery, error) {
 		}
 	}
 
-	// Parse measurement filter
-	measurementRe := regexp.MustCompile(`r\._measurement\s*==\s*"([^"]+)"`)
-	if matches := measurementRe.FindStringSubmatch(query); len(matches) > 1 {
-		if len(parsed.Sources) == 0 {
-			parsed.Sources = append(parsed.Sources, DataSource{Name: matches[1]})
+	// Parse every |> filter(fn: (r) => ...) stage. Each predicate is
+	// scanned for every `r.field <op> value` comparison it contains,
+	// ANDing them all into parsed.Filters - same narrowing InfluxQLHandler
+	// already accepts for a nested OR/NOT subtree its flat Filters list
+	// can't represent.
+	for _, body := range fluxPipeStageArgs(query, "filter") {
+		pred := body
+		if i := strings.Index(pred, "=>"); i >= 0 {
+			pred = pred[i+2:]
+		}
+		for _, m := range fluxFilterPredicateRe.FindAllStringSubmatch(pred, -1) {
+			field, op, val := m[1], m[2], strings.Trim(m[3], `"`)
+			if field == "_measurement" {
+				if len(parsed.Sources) == 0 {
+					parsed.Sources = append(parsed.Sources, DataSource{Name: val})
+				}
+				continue
+			}
+			parsed.Filters = append(parsed.Filters, Filter{Column: field, Operator: op, Value: val})
+		}
+	}
+
+	// Parse |> group(columns: [...])
+	for _, body := range fluxPipeStageArgs(query, "group") {
+		if m := fluxColumnsRe.FindStringSubmatch(body); len(m) > 1 {
+			for _, col := range strings.Split(m[1], ",") {
+				col = strings.Trim(strings.TrimSpace(col), `"`)
+				if col != "" {
+					parsed.GroupBy = append(parsed.GroupBy, col)
+				}
+			}
 		}
 	}
 
@@ -190,6 +502,14 @@ func (h *FluxHandler) Parse(query string) (*ParsedQuery, error) {
 		})
 	}
 
+	// |> pivot(...) and |> map(fn: ...) reshape the result table itself
+	// (widening rows, or projecting new columns from an expression) rather
+	// than selecting/grouping/aggregating it, and ParsedQuery has no field
+	// for either - so they are not parsed at all, and the stage-scoped
+	// extraction above never looks inside them. |> yield(name: "...") only labels the
+	// result stream for a multi-yield script; ParsedQuery describes one
+	// result, so its name is likewise not carried.
+
 	// Parse limit
 	limitRe := regexp.MustCompile(`\|>\s*limit\s*\(\s*n\s*:\s*(\d+)\s*\)`)
 	if matches := limitRe.FindStringSubmatch(query); len(matches) > 1 {
@@ -199,9 +519,59 @@ func (h *FluxHandler) Parse(query string) (*ParsedQuery, error) {
 	return parsed, nil
 }
 
+// fluxFilterPredicateRe matches one `r.field <op> value` comparison inside
+// a filter predicate, where value is a quoted string, a bare number, or
+// true/false.
+var fluxFilterPredicateRe = regexp.MustCompile(`r\.(\w+)\s*(==|!=|>=|<=|=~|!~|>|<)\s*("[^"]*"|-?\d+(?:\.\d+)?|true|false)`)
+
+// fluxColumnsRe matches a group() stage's `columns: [...]` argument.
+var fluxColumnsRe = regexp.MustCompile(`columns\s*:\s*\[([^\]]*)\]`)
+
+// fluxPipeStageArgs returns the raw argument-list text of every `|>
+// stage(...)` call in query, paren-balanced so an argument containing its
+// own nested parens (a filter predicate, typically) doesn't truncate the
+// match early the way a non-greedy regex would.
+func fluxPipeStageArgs(query, stage string) []string {
+	re := regexp.MustCompile(`\|>\s*` + regexp.QuoteMeta(stage) + `\s*\(`)
+	var out []string
+	offset := 0
+	for offset < len(query) {
+		loc := re.FindStringIndex(query[offset:])
+		if loc == nil {
+			break
+		}
+		openIdx := offset + loc[1] - 1
+		closeIdx := fluxMatchingParen(query, openIdx)
+		if closeIdx < 0 {
+			break
+		}
+		out = append(out, query[openIdx+1:closeIdx])
+		offset = closeIdx + 1
+	}
+	return out
+}
+
+// fluxMatchingParen returns the index of the ')' matching the '(' at
+// openIdx, or -1 if s is unbalanced from that point on.
+func fluxMatchingParen(s string, openIdx int) int {
+	depth := 0
+	for i := openIdx; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
 func (h *FluxHandler) FormatResponse(result *QueryResult, format string) (interface{}, error) {
 	if format == "csv" {
-		return formatAsCSV(result), nil
+		return fluxAnnotatedCSV(result), nil
 	}
 	return formatAsJSON(result), nil
 }
@@ -213,114 +583,270 @@ func (h *PromQLHandler) Dialect() Dialect {
 	return DialectPromQL
 }
 
+// Parse parses query with the real PromQL scanner/parser in the promql
+// package and walks the resulting expression tree into a ParsedQuery. The
+// full tree is also kept on ParsedQuery.AST, since PromQL's nested binary
+// operators, vector matching, and subqueries don't fit the flat
+// Sources/Filters/Aggregations shape ParsedQuery offers every dialect.
+// PromQL has no write or DDL syntax of its own, so Intent is always
+// IntentRead.
 func (h *PromQLHandler) Parse(query string) (*ParsedQuery, error) {
 	query = strings.TrimSpace(query)
 
 	parsed := &ParsedQuery{
 		Dialect:       DialectPromQL,
 		OriginalQuery: query,
+		Intent:        IntentRead,
 	}
 
-	// First, try to extract metric from inside function calls like rate(metric{...}[5m])
-	// Look for the innermost metric selector
-	innerSelectorRe := regexp.MustCompile(`([a-zA-Z_:][a-zA-Z0-9_:]*)\s*\{([^}]*)\}\s*(?:\[(\d+[smhdwy])\])?`)
-	if matches := innerSelectorRe.FindStringSubmatch(query); len(matches) > 1 {
-		parsed.Sources = append(parsed.Sources, DataSource{
-			Name: matches[1],
-		})
+	expr, err := promql.NewParser(query).ParseExpr()
+	if err != nil {
+		return nil, fmt.Errorf("promql: %w", err)
+	}
+	parsed.AST = expr
 
-		// Parse labels
-		if len(matches) > 2 && matches[2] != "" {
-			labelRe := regexp.MustCompile(`(\w+)\s*(=~|!~|!=|=)\s*"([^"]*)"`)
-			for _, lm := range labelRe.FindAllStringSubmatch(matches[2], -1) {
-				if len(lm) > 3 {
-					parsed.Filters = append(parsed.Filters, Filter{
-						Column:   lm[1],
-						Operator: lm[2],
-						Value:    lm[3],
-					})
-				}
-			}
-		}
+	walkPromQLExpr(expr, parsed)
 
-		// Parse range
-		if len(matches) > 3 && matches[3] != "" {
-			duration := parsePromQLDuration(matches[3])
-			parsed.TimeRange = &TimeRange{
-				Duration: duration,
-			}
-		}
-	} else {
-		// Fallback: Parse simple metric name (no labels)
-		simpleRe := regexp.MustCompile(`([a-zA-Z_:][a-zA-Z0-9_:]*)\s*(?:\[(\d+[smhdwy])\])?$`)
-		if matches := simpleRe.FindStringSubmatch(query); len(matches) > 1 {
-			parsed.Sources = append(parsed.Sources, DataSource{
-				Name: matches[1],
+	return parsed, nil
+}
+
+// walkPromQLExpr descends expr, projecting what it finds onto parsed's
+// flat fields: every VectorSelector becomes a DataSource plus its label
+// matchers as Filters, every AggregateExpr becomes an Aggregation plus a
+// GroupBy (when it groups by rather than without), and the widest
+// range/subquery window seen becomes TimeRange.Duration. A without(...)
+// grouping can't be told apart from a by(...) one in the flat GroupBy
+// list - same kind of narrowing InfluxQLHandler.Parse already accepts for
+// constructs its ParsedQuery projection can't represent.
+func walkPromQLExpr(e promql.Expr, parsed *ParsedQuery) {
+	switch expr := e.(type) {
+	case *promql.VectorSelector:
+		src := DataSource{Name: expr.Name}
+		parsed.Sources = append(parsed.Sources, src)
+		for _, m := range expr.LabelMatchers {
+			parsed.Filters = append(parsed.Filters, Filter{
+				Column:   m.Name,
+				Operator: m.Op.String(),
+				Value:    m.Value,
 			})
-			if len(matches) > 2 && matches[2] != "" {
-				duration := parsePromQLDuration(matches[2])
-				parsed.TimeRange = &TimeRange{
-					Duration: duration,
-				}
+		}
+	case *promql.MatrixSelector:
+		if parsed.TimeRange == nil || expr.Range > parsed.TimeRange.Duration {
+			parsed.TimeRange = &TimeRange{Duration: expr.Range}
+		}
+		walkPromQLExpr(expr.VectorSelector, parsed)
+	case *promql.SubqueryExpr:
+		if parsed.TimeRange == nil || expr.Range > parsed.TimeRange.Duration {
+			parsed.TimeRange = &TimeRange{Duration: expr.Range}
+		}
+		walkPromQLExpr(expr.Expr, parsed)
+	case *promql.Call:
+		for _, arg := range expr.Args {
+			walkPromQLExpr(arg, parsed)
+		}
+	case *promql.AggregateExpr:
+		parsed.Aggregations = append(parsed.Aggregations, Aggregation{Function: expr.Op})
+		if !expr.Without {
+			parsed.GroupBy = append(parsed.GroupBy, expr.Grouping...)
+		}
+		if expr.Param != nil {
+			walkPromQLExpr(expr.Param, parsed)
+		}
+		walkPromQLExpr(expr.Expr, parsed)
+	case *promql.BinaryExpr:
+		walkPromQLExpr(expr.LHS, parsed)
+		walkPromQLExpr(expr.RHS, parsed)
+	case *promql.UnaryExpr:
+		walkPromQLExpr(expr.Expr, parsed)
+	case *promql.ParenExpr:
+		walkPromQLExpr(expr.Expr, parsed)
+	}
+}
+
+// FormatResponse renders result in the shape matching one of the four
+// Prometheus resultTypes (scalar, string, vector, matrix). The choice
+// between them is made from the caller (instant vs. range query, via the
+// format hint) and from the shape of result itself (a string-typed value
+// column means a string result), not from the parsed expression's own
+// type - PromQL's own type-checking would need real sample evaluation,
+// which QueryResult's flat row shape doesn't carry.
+func (h *PromQLHandler) FormatResponse(result *QueryResult, format string) (interface{}, error) {
+	switch format {
+	case "prometheus_range":
+		return promQLMatrix(result), nil
+	case "prometheus_scalar":
+		return promQLScalar(result), nil
+	default:
+		return promQLVectorOrString(result), nil
+	}
+}
+
+// promQLMetric collects a row's tag columns into the label set Prometheus
+// calls a sample's "metric".
+func promQLMetric(columns []ColumnMeta, row []interface{}) map[string]string {
+	metric := make(map[string]string)
+	for i, col := range columns {
+		if col.IsTag {
+			if v, ok := row[i].(string); ok {
+				metric[col.Name] = v
 			}
 		}
 	}
+	return metric
+}
 
-	// Parse functions
-	funcRe := regexp.MustCompile(`(rate|irate|increase|delta|deriv|sum|avg|min|max|count|stddev|topk|bottomk|quantile)\s*(?:\(|by|without)`)
-	for _, match := range funcRe.FindAllStringSubmatch(query, -1) {
-		if len(match) > 1 {
-			parsed.Aggregations = append(parsed.Aggregations, Aggregation{
-				Function: match[1],
-			})
+// promQLValueColumn returns the index of the column holding a row's sample
+// value - the first column that's neither the timestamp nor a tag.
+func promQLValueColumn(columns []ColumnMeta) int {
+	for i, col := range columns {
+		if !col.IsTime && !col.IsTag {
+			return i
 		}
 	}
+	return -1
+}
 
-	// Parse by/without clauses
-	byRe := regexp.MustCompile(`(?:sum|avg|min|max|count)\s+by\s*\(([^)]+)\)`)
-	if matches := byRe.FindStringSubmatch(query); len(matches) > 1 {
-		for _, label := range strings.Split(matches[1], ",") {
-			parsed.GroupBy = append(parsed.GroupBy, strings.TrimSpace(label))
+// promQLTimeColumn returns the index of row's timestamp column, or -1.
+func promQLTimeColumn(columns []ColumnMeta) int {
+	for i, col := range columns {
+		if col.IsTime {
+			return i
 		}
 	}
+	return -1
+}
 
-	return parsed, nil
+func promQLScalar(result *QueryResult) map[string]interface{} {
+	ts := time.Now().Unix()
+	var val interface{} = 0
+	if len(result.Rows) > 0 {
+		row := result.Rows[0]
+		if ti := promQLTimeColumn(result.Columns); ti >= 0 {
+			ts = toUnixSeconds(row[ti])
+		}
+		if vi := promQLValueColumn(result.Columns); vi >= 0 {
+			val = row[vi]
+		}
+	}
+	return map[string]interface{}{
+		"resultType": "scalar",
+		"result":     []interface{}{ts, fmt.Sprintf("%v", val)},
+	}
 }
 
-func (h *PromQLHandler) FormatResponse(result *QueryResult, format string) (interface{}, error) {
-	// Format as Prometheus response
+func promQLVectorOrString(result *QueryResult) map[string]interface{} {
+	vi := promQLValueColumn(result.Columns)
+	ti := promQLTimeColumn(result.Columns)
+
+	if vi >= 0 && result.Columns[vi].Type == "string" {
+		var val string
+		ts := time.Now().Unix()
+		if len(result.Rows) > 0 {
+			if s, ok := result.Rows[0][vi].(string); ok {
+				val = s
+			}
+			if ti >= 0 {
+				ts = toUnixSeconds(result.Rows[0][ti])
+			}
+		}
+		return map[string]interface{}{
+			"resultType": "string",
+			"result":     []interface{}{ts, val},
+		}
+	}
+
 	type sample struct {
 		Metric map[string]string `json:"metric"`
 		Value  []interface{}     `json:"value,omitempty"`
-		Values [][]interface{}   `json:"values,omitempty"`
 	}
 
-	samples := make([]sample, 0)
+	samples := make([]sample, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		s := sample{Metric: promQLMetric(result.Columns, row)}
+		if ti >= 0 && vi >= 0 {
+			s.Value = []interface{}{row[ti], fmt.Sprintf("%v", row[vi])}
+		}
+		samples = append(samples, s)
+	}
+
+	return map[string]interface{}{
+		"resultType": "vector",
+		"result":     samples,
+	}
+}
+
+// promQLMatrix groups result's rows into one series per distinct tag
+// combination, the shape a Prometheus range query's matrix result needs.
+func promQLMatrix(result *QueryResult) map[string]interface{} {
+	vi := promQLValueColumn(result.Columns)
+	ti := promQLTimeColumn(result.Columns)
+
+	type series struct {
+		Metric map[string]string `json:"metric"`
+		Values [][]interface{}   `json:"values"`
+	}
+
+	order := make([]string, 0)
+	byFingerprint := make(map[string]*series)
 
 	for _, row := range result.Rows {
-		s := sample{
-			Metric: make(map[string]string),
+		fp := promQLTagFingerprint(result.Columns, row)
+		s, ok := byFingerprint[fp]
+		if !ok {
+			s = &series{Metric: promQLMetric(result.Columns, row)}
+			byFingerprint[fp] = s
+			order = append(order, fp)
 		}
 
-		for i, col := range result.Columns {
-			if col.IsTag {
-				if v, ok := row[i].(string); ok {
-					s.Metric[col.Name] = v
-				}
-			} else if col.IsTime {
-				if len(row) > i+1 {
-					s.Value = []interface{}{row[i], row[i+1]}
-				}
-			}
+		var point [2]interface{}
+		if ti >= 0 {
+			point[0] = row[ti]
+		}
+		if vi >= 0 {
+			point[1] = fmt.Sprintf("%v", row[vi])
 		}
+		s.Values = append(s.Values, []interface{}{point[0], point[1]})
+	}
 
-		samples = append(samples, s)
+	matrix := make([]*series, 0, len(order))
+	for _, fp := range order {
+		matrix = append(matrix, byFingerprint[fp])
 	}
 
 	return map[string]interface{}{
-		"resultType": "vector",
-		"result":     samples,
-	}, nil
+		"resultType": "matrix",
+		"result":     matrix,
+	}
+}
+
+// promQLTagFingerprint returns a stable key identifying the distinct label
+// set a row belongs to, so matrix rows can be grouped into separate series.
+func promQLTagFingerprint(columns []ColumnMeta, row []interface{}) string {
+	var b strings.Builder
+	for i, col := range columns {
+		if col.IsTag {
+			fmt.Fprintf(&b, "%s=%v;", col.Name, row[i])
+		}
+	}
+	return b.String()
+}
+
+// toUnixSeconds coerces a column value that's either already a Unix
+// timestamp (int64/float64, seconds) or a time.Time into Prometheus's
+// expected Unix-seconds form.
+func toUnixSeconds(v interface{}) int64 {
+	switch t := v.(type) {
+	case time.Time:
+		return t.Unix()
+	case int64:
+		return t
+	case int:
+		return int64(t)
+	case float64:
+		return int64(t)
+	default:
+		return 0
+	}
 }
 
 // SQLHandler handles generic SQL queries
@@ -337,6 +863,7 @@ func (h *SQLHandler) Parse(query string) (*ParsedQuery, error) {
 	parsed := &ParsedQuery{
 		Dialect:       DialectSQL,
 		OriginalQuery: query,
+		Intent:        sqlKeywordIntent(upper),
 	}
 
 	// Parse FROM
@@ -366,10 +893,30 @@ func (h *SQLHandler) Parse(query string) (*ParsedQuery, error) {
 		})
 	}
 
-	_ = upper
 	return parsed, nil
 }
 
+// sqlKeywordIntent classifies a generic SQL (including TDengine, which
+// routes through this handler - see router.go's GetHandler(DialectSQL)
+// fallback) statement by its leading keyword: CREATE/DROP/ALTER change
+// schema (TDengine's "CREATE STABLE" included), INSERT/DELETE/UPDATE mutate
+// rows, and SELECT/SHOW/DESCRIBE/EXPLAIN only read. This is a routing
+// decision that gates Raft replication (see decideQueryRoute), so the
+// default must fail closed: a leading keyword this switch doesn't recognize
+// (TRUNCATE, REPLACE INTO, MERGE, UPSERT, a CTE's WITH ... INSERT, CALL
+// proc(...), ...) is treated as a write and routed through Raft rather than
+// applied locally and never replicated.
+func sqlKeywordIntent(upper string) QueryIntent {
+	switch {
+	case strings.HasPrefix(upper, "CREATE"), strings.HasPrefix(upper, "DROP"), strings.HasPrefix(upper, "ALTER"):
+		return IntentDDL
+	case strings.HasPrefix(upper, "SELECT"), strings.HasPrefix(upper, "SHOW"), strings.HasPrefix(upper, "DESCRIBE"), strings.HasPrefix(upper, "DESC"), strings.HasPrefix(upper, "EXPLAIN"):
+		return IntentRead
+	default:
+		return IntentWrite
+	}
+}
+
 func (h *SQLHandler) FormatResponse(result *QueryResult, format string) (interface{}, error) {
 	return formatAsJSON(result), nil
 }
@@ -395,28 +942,6 @@ func parseFluxDuration(s string) time.Duration {
 	return 0
 }
 
-func parsePromQLDuration(s string) time.Duration {
-	re := regexp.MustCompile(`(\d+)([smhdwy])`)
-	if matches := re.FindStringSubmatch(s); len(matches) > 2 {
-		value, _ := strconv.ParseInt(matches[1], 10, 64)
-		switch matches[2] {
-		case "s":
-			return time.Duration(value) * time.Second
-		case "m":
-			return time.Duration(value) * time.Minute
-		case "h":
-			return time.Duration(value) * time.Hour
-		case "d":
-			return time.Duration(value) * 24 * time.Hour
-		case "w":
-			return time.Duration(value) * 7 * 24 * time.Hour
-		case "y":
-			return time.Duration(value) * 365 * 24 * time.Hour
-		}
-	}
-	return 0
-}
-
 func formatAsJSON(result *QueryResult) interface{} {
 	columns := make([]string, len(result.Columns))
 	for i, col := range result.Columns {
@@ -434,28 +959,146 @@ func formatAsJSON(result *QueryResult) interface{} {
 	}
 }
 
-func formatAsCSV(result *QueryResult) string {
-	var sb strings.Builder
+// fluxResultGroup is one table in an annotated-CSV response: the rows
+// sharing one combination of tag-column values, the unit Flux groups
+// results into.
+type fluxResultGroup struct {
+	rows [][]interface{}
+}
 
-	// Header
-	for i, col := range result.Columns {
-		if i > 0 {
-			sb.WriteString(",")
+// fluxAnnotatedCSV renders result as InfluxDB's annotated CSV dialect:
+// #datatype/#group/#default annotation rows, a header row, then one data
+// row per result row, with a blank-line-separated table per distinct
+// combination of tag-column values - the shape real Flux clients and
+// Grafana's Flux datasource expect, InfluxDB's own "table" grouping synthesized
+// here from result's IsTag columns the same way promQLMatrix groups
+// Prometheus series from tag columns.
+func fluxAnnotatedCSV(result *QueryResult) string {
+	var tagCols []int
+	for i, c := range result.Columns {
+		if c.IsTag {
+			tagCols = append(tagCols, i)
 		}
-		sb.WriteString(col.Name)
 	}
-	sb.WriteString("\n")
 
-	// Rows
+	var groups []*fluxResultGroup
+	index := make(map[string]*fluxResultGroup)
 	for _, row := range result.Rows {
-		for i, val := range row {
-			if i > 0 {
-				sb.WriteString(",")
+		var key strings.Builder
+		for _, i := range tagCols {
+			if i < len(row) {
+				fmt.Fprintf(&key, "%s=%v;", result.Columns[i].Name, row[i])
 			}
-			sb.WriteString(fmt.Sprintf("%v", val))
 		}
-		sb.WriteString("\n")
+		g, ok := index[key.String()]
+		if !ok {
+			g = &fluxResultGroup{}
+			index[key.String()] = g
+			groups = append(groups, g)
+		}
+		g.rows = append(g.rows, row)
+	}
+	if len(groups) == 0 {
+		groups = append(groups, &fluxResultGroup{})
+	}
+
+	datatypes := make([]string, len(result.Columns))
+	groupFlags := make([]string, len(result.Columns))
+	names := make([]string, len(result.Columns))
+	defaults := make([]string, len(result.Columns))
+	for i, c := range result.Columns {
+		datatypes[i] = fluxDatatype(c)
+		groupFlags[i] = strconv.FormatBool(c.IsTag)
+		names[i] = c.Name
 	}
 
+	var sb strings.Builder
+	for tableIdx, g := range groups {
+		if tableIdx > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(fluxCSVRow("#datatype", append([]string{"string", "long"}, datatypes...)))
+		sb.WriteString(fluxCSVRow("#group", append([]string{"true", "true"}, groupFlags...)))
+		sb.WriteString(fluxCSVRow("#default", append([]string{"_result", ""}, defaults...)))
+		sb.WriteString(fluxCSVRow("", append([]string{"result", "table"}, names...)))
+
+		for _, row := range g.rows {
+			values := make([]string, 0, len(row)+2)
+			values = append(values, "_result", strconv.Itoa(tableIdx))
+			for i, v := range row {
+				dt := "string"
+				if i < len(datatypes) {
+					dt = datatypes[i]
+				}
+				values = append(values, fluxCSVFormatValue(v, dt))
+			}
+			sb.WriteString(fluxCSVRow("", values))
+		}
+	}
 	return sb.String()
 }
+
+// fluxDatatype maps a ColumnMeta onto one of the annotated CSV dialect's
+// datatype names (string, long, double, boolean, dateTime:RFC3339, duration).
+func fluxDatatype(c ColumnMeta) string {
+	if c.IsTime {
+		return "dateTime:RFC3339"
+	}
+	switch strings.ToLower(c.Type) {
+	case "bool", "boolean":
+		return "boolean"
+	case "float", "float32", "float64", "double":
+		return "double"
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint32", "uint64", "long":
+		return "long"
+	case "duration":
+		return "duration"
+	case "time", "timestamp", "datetime", "time.time":
+		return "dateTime:RFC3339"
+	default:
+		return "string"
+	}
+}
+
+// fluxCSVFormatValue renders v as datatype's annotated-CSV text form.
+func fluxCSVFormatValue(v interface{}, datatype string) string {
+	if v == nil {
+		return ""
+	}
+	switch datatype {
+	case "dateTime:RFC3339":
+		if t, ok := v.(time.Time); ok {
+			return t.UTC().Format(time.RFC3339)
+		}
+	case "duration":
+		if d, ok := v.(time.Duration); ok {
+			return d.String()
+		}
+	case "boolean":
+		if b, ok := v.(bool); ok {
+			return strconv.FormatBool(b)
+		}
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// fluxCSVRow renders one annotated-CSV line: prefix (the "#datatype" /
+// "#group" / "#default" marker, or "" for header/data rows) followed by
+// values, each escaped per RFC 4180.
+func fluxCSVRow(prefix string, values []string) string {
+	cells := make([]string, 0, len(values)+1)
+	cells = append(cells, fluxCSVEscape(prefix))
+	for _, v := range values {
+		cells = append(cells, fluxCSVEscape(v))
+	}
+	return strings.Join(cells, ",") + "\n"
+}
+
+// fluxCSVEscape quotes s, doubling any embedded quotes, if it contains a
+// comma, quote, or newline - the cases RFC 4180 requires quoting for.
+func fluxCSVEscape(s string) string {
+	if strings.ContainsAny(s, ",\"\n\r") {
+		return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+	}
+	return s
+}
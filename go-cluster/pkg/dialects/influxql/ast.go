@@ -0,0 +1,358 @@
+package influxql
+
+import "time"
+
+// Node is any AST element.
+type Node interface {
+	node()
+}
+
+// Statement is a single top-level InfluxQL statement.
+type Statement interface {
+	Node
+	stmt()
+}
+
+// Expr is anything that evaluates to a value: a literal, a variable
+// reference, a function call, or a binary/parenthesized combination of
+// those.
+type Expr interface {
+	Node
+	expr()
+}
+
+// DataType is the inferred type of an expression, mirroring InfluxQL's own
+// field/tag type model.
+type DataType int
+
+const (
+	Unknown DataType = iota
+	Float
+	Integer
+	String
+	Boolean
+	Time
+	Duration
+	Tag
+)
+
+// String names a DataType, for diagnostics and EXPLAIN-style output.
+func (d DataType) String() string {
+	switch d {
+	case Float:
+		return "float"
+	case Integer:
+		return "integer"
+	case String:
+		return "string"
+	case Boolean:
+		return "boolean"
+	case Time:
+		return "time"
+	case Duration:
+		return "duration"
+	case Tag:
+		return "tag"
+	default:
+		return "unknown"
+	}
+}
+
+// InspectDataType infers the static DataType of expr without evaluating
+// it - literals carry their type directly, VarRef carries whatever type
+// the scanner/planner has annotated it with, and Call falls back to the
+// type of its first argument (sufficient for the aggregate functions
+// InfluxQL supports, which all preserve their input's type except COUNT).
+func InspectDataType(expr Expr) DataType {
+	switch e := expr.(type) {
+	case *NumberLiteral:
+		if e.IsInt {
+			return Integer
+		}
+		return Float
+	case *StringLiteral:
+		return String
+	case *BooleanLiteral:
+		return Boolean
+	case *TimeLiteral:
+		return Time
+	case *DurationLiteral:
+		return Duration
+	case *VarRef:
+		if e.Type != Unknown {
+			return e.Type
+		}
+		return Unknown
+	case *Call:
+		if lower(e.Name) == "count" {
+			return Integer
+		}
+		if len(e.Args) > 0 {
+			return InspectDataType(e.Args[0])
+		}
+		return Unknown
+	case *ParenExpr:
+		return InspectDataType(e.Expr)
+	case *BinaryExpr:
+		lt := InspectDataType(e.LHS)
+		if lt != Unknown {
+			return lt
+		}
+		return InspectDataType(e.RHS)
+	}
+	return Unknown
+}
+
+// VarRef is a reference to a field or tag, e.g. `value` or `host`.
+type VarRef struct {
+	Val  string
+	Type DataType
+}
+
+func (*VarRef) node() {}
+func (*VarRef) expr() {}
+
+// Wildcard is the `*` field/measurement wildcard, optionally restricted to
+// one type (`*::field` or `*::tag`).
+type Wildcard struct {
+	Type DataType // Unknown, Tag, or a field type meaning "all fields"
+}
+
+func (*Wildcard) node() {}
+func (*Wildcard) expr() {}
+
+// RegexLiteral is a `/pattern/` regular expression literal, used in
+// measurement and tag-value matching.
+type RegexLiteral struct {
+	Val string
+}
+
+func (*RegexLiteral) node() {}
+func (*RegexLiteral) expr() {}
+
+// NumberLiteral is an integer or floating-point constant.
+type NumberLiteral struct {
+	Val   float64
+	IsInt bool
+}
+
+func (*NumberLiteral) node() {}
+func (*NumberLiteral) expr() {}
+
+// StringLiteral is a quoted string constant.
+type StringLiteral struct {
+	Val string
+}
+
+func (*StringLiteral) node() {}
+func (*StringLiteral) expr() {}
+
+// BooleanLiteral is the `true`/`false` constant.
+type BooleanLiteral struct {
+	Val bool
+}
+
+func (*BooleanLiteral) node() {}
+func (*BooleanLiteral) expr() {}
+
+// TimeLiteral is an absolute timestamp, e.g. `'2024-01-01T00:00:00Z'` when
+// compared against the `time` column.
+type TimeLiteral struct {
+	Val time.Time
+}
+
+func (*TimeLiteral) node() {}
+func (*TimeLiteral) expr() {}
+
+// DurationLiteral is a relative duration, e.g. `30s` or `1h`.
+type DurationLiteral struct {
+	Val time.Duration
+}
+
+func (*DurationLiteral) node() {}
+func (*DurationLiteral) expr() {}
+
+// BinaryExpr is any `LHS Op RHS` combination - arithmetic (+ - * / %),
+// comparison (= != < <= > >=), regex match (=~ !~), or boolean (AND OR).
+// NOT is represented as UnaryExpr instead, since it takes one operand.
+type BinaryExpr struct {
+	Op  Token
+	LHS Expr
+	RHS Expr
+}
+
+func (*BinaryExpr) node() {}
+func (*BinaryExpr) expr() {}
+
+// UnaryExpr is `NOT expr`.
+type UnaryExpr struct {
+	Op   Token
+	Expr Expr
+}
+
+func (*UnaryExpr) node() {}
+func (*UnaryExpr) expr() {}
+
+// ParenExpr groups a sub-expression to control precedence.
+type ParenExpr struct {
+	Expr Expr
+}
+
+func (*ParenExpr) node() {}
+func (*ParenExpr) expr() {}
+
+// Call is a function invocation, e.g. `mean(value)` or `count(*)`.
+type Call struct {
+	Name string
+	Args []Expr
+}
+
+func (*Call) node() {}
+func (*Call) expr() {}
+
+// Field is one SELECT-list entry: an expression plus its optional alias.
+type Field struct {
+	Expr  Expr
+	Alias string
+}
+
+// Fields is the ordered SELECT list.
+type Fields []*Field
+
+// Source is anything a query can read from: a Measurement or a
+// parenthesized subquery.
+type Source interface {
+	Node
+	source()
+}
+
+// Measurement identifies a measurement, optionally qualified by database
+// and retention policy, or given as a regex to match several at once.
+type Measurement struct {
+	Database        string
+	RetentionPolicy string
+	Name            string
+	Regex           *RegexLiteral
+}
+
+func (*Measurement) node()   {}
+func (*Measurement) source() {}
+
+// SubQuery is a `(SELECT ...)` used as a FROM source.
+type SubQuery struct {
+	Statement *SelectStatement
+}
+
+func (*SubQuery) node()   {}
+func (*SubQuery) source() {}
+
+// Dimension is one GROUP BY entry: a tag key, `time(interval[, offset])`,
+// or `*` (group by all tags).
+type Dimension struct {
+	Expr Expr
+}
+
+// SortField is one ORDER BY entry.
+type SortField struct {
+	Name      string // "time" in InfluxQL - it's the only sortable column
+	Ascending bool
+}
+
+// Fill describes the `FILL(...)` clause on a GROUP BY time() query.
+type Fill struct {
+	Option FillOption
+	Value  float64 // set when Option == FillValue
+}
+
+// FillOption enumerates FILL(...) modes.
+type FillOption int
+
+const (
+	FillNullOption FillOption = iota
+	FillNoneOption
+	FillNumber
+	FillPrevious
+	FillLinearOption
+)
+
+// SelectStatement is a full InfluxQL SELECT, covering subqueries, INTO,
+// GROUP BY time()/tags, FILL, SLIMIT/SOFFSET alongside ordinary
+// LIMIT/OFFSET.
+type SelectStatement struct {
+	Fields     Fields
+	Sources    []Source
+	Condition  Expr
+	Dimensions []*Dimension
+	SortFields []*SortField
+	Target     *Measurement // INTO target, nil if absent
+	Limit      int64
+	Offset     int64
+	SLimit     int64
+	SOffset    int64
+	Fill       *Fill
+}
+
+func (*SelectStatement) node() {}
+func (*SelectStatement) stmt() {}
+
+// ShowMeasurementsStatement represents `SHOW MEASUREMENTS [ON db] [WHERE
+// ...] [LIMIT n] [OFFSET n]`.
+type ShowMeasurementsStatement struct {
+	Database  string
+	Condition Expr
+	Limit     int64
+	Offset    int64
+}
+
+func (*ShowMeasurementsStatement) node() {}
+func (*ShowMeasurementsStatement) stmt() {}
+
+// ShowSeriesStatement represents `SHOW SERIES [FROM ...] [WHERE ...]`.
+type ShowSeriesStatement struct {
+	Sources   []Source
+	Condition Expr
+	Limit     int64
+	Offset    int64
+}
+
+func (*ShowSeriesStatement) node() {}
+func (*ShowSeriesStatement) stmt() {}
+
+// ShowTagKeysStatement represents `SHOW TAG KEYS [FROM ...] [WHERE ...]`.
+type ShowTagKeysStatement struct {
+	Sources   []Source
+	Condition Expr
+}
+
+func (*ShowTagKeysStatement) node() {}
+func (*ShowTagKeysStatement) stmt() {}
+
+// ShowTagValuesStatement represents `SHOW TAG VALUES [FROM ...] WITH KEY =
+// "key" [WHERE ...]`.
+type ShowTagValuesStatement struct {
+	Sources   []Source
+	Key       string
+	Condition Expr
+}
+
+func (*ShowTagValuesStatement) node() {}
+func (*ShowTagValuesStatement) stmt() {}
+
+// ShowFieldKeysStatement represents `SHOW FIELD KEYS [FROM ...]`.
+type ShowFieldKeysStatement struct {
+	Sources []Source
+}
+
+func (*ShowFieldKeysStatement) node() {}
+func (*ShowFieldKeysStatement) stmt() {}
+
+// CreateContinuousQueryStatement represents `CREATE CONTINUOUS QUERY name
+// ON db BEGIN <select> END`.
+type CreateContinuousQueryStatement struct {
+	Name     string
+	Database string
+	Source   *SelectStatement
+}
+
+func (*CreateContinuousQueryStatement) node() {}
+func (*CreateContinuousQueryStatement) stmt() {}
@@ -0,0 +1,848 @@
+package influxql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scannedTok is one token of lookahead, buffered so the parser can unscan.
+type scannedTok struct {
+	tok Token
+	lit string
+	pos int
+}
+
+// Parser is a recursive-descent parser over a Scanner, producing the
+// typed AST in ast.go. It replaces per-dialect regex matching with real
+// grammar recognition, so InfluxQL features the regexes in handlers.go
+// never saw - subqueries, AND/OR/NOT trees, arithmetic in SELECT,
+// multi-statement input, INTO, CREATE CONTINUOUS QUERY, SHOW variants,
+// absolute time literals, and quoting - all parse correctly.
+type Parser struct {
+	s       *Scanner
+	buf     []scannedTok
+	bufPos  int
+	lastPos int
+}
+
+// NewParser creates a Parser over src.
+func NewParser(src string) *Parser {
+	return &Parser{s: NewScanner(src)}
+}
+
+func (p *Parser) scan() (Token, string, int) {
+	if p.bufPos < len(p.buf) {
+		t := p.buf[p.bufPos]
+		p.bufPos++
+		p.lastPos = t.pos
+		return t.tok, t.lit, t.pos
+	}
+	tok, lit, pos := p.s.Scan()
+	p.buf = append(p.buf, scannedTok{tok, lit, pos})
+	p.bufPos++
+	p.lastPos = pos
+	return tok, lit, pos
+}
+
+func (p *Parser) unscan() {
+	if p.bufPos > 0 {
+		p.bufPos--
+	}
+}
+
+// rescanAsRegex re-reads the next (not-yet-consumed) token as a `/.../ `
+// regex literal, resolving the lexical ambiguity between division and
+// regex once the parser knows a regex is grammatically expected here. Call
+// it right after peek() saw a DIV token, before scanning past it.
+func (p *Parser) rescanAsRegex() (Token, string) {
+	if p.bufPos >= len(p.buf) {
+		return ILLEGAL, ""
+	}
+	pos := p.buf[p.bufPos].pos
+	tok, lit, _ := p.s.ScanRegex(pos)
+	p.buf[p.bufPos] = scannedTok{tok, lit, pos}
+	return tok, lit
+}
+
+func (p *Parser) peek() (Token, string) {
+	tok, lit, _ := p.scan()
+	p.unscan()
+	return tok, lit
+}
+
+func (p *Parser) expect(tok Token) (string, error) {
+	got, lit, _ := p.scan()
+	if got != tok {
+		return "", fmt.Errorf("expected %s, found %q near position %d", tok, lit, p.lastPos)
+	}
+	return lit, nil
+}
+
+// ParseQuery parses src as a semicolon-separated sequence of statements.
+func (p *Parser) ParseQuery() ([]Statement, error) {
+	var stmts []Statement
+	for {
+		tok, _ := p.peek()
+		if tok == EOF {
+			break
+		}
+		if tok == SEMICOLON {
+			p.scan()
+			continue
+		}
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return stmts, err
+		}
+		stmts = append(stmts, stmt)
+
+		tok, _ = p.peek()
+		if tok == SEMICOLON {
+			p.scan()
+		} else if tok != EOF {
+			return stmts, fmt.Errorf("expected ; or EOF, found %q near position %d", tok, p.lastPos)
+		}
+	}
+	return stmts, nil
+}
+
+func (p *Parser) parseStatement() (Statement, error) {
+	tok, _ := p.peek()
+	switch tok {
+	case SELECT:
+		return p.parseSelectStatement()
+	case SHOW:
+		return p.parseShowStatement()
+	case CREATE:
+		return p.parseCreateContinuousQuery()
+	default:
+		return nil, fmt.Errorf("unsupported statement starting with %q near position %d", tok, p.lastPos)
+	}
+}
+
+// parseSelectStatement parses `SELECT fields FROM sources [WHERE cond]
+// [GROUP BY dims [FILL(...)]] [ORDER BY time [ASC|DESC]] [LIMIT n]
+// [OFFSET n] [SLIMIT n] [SOFFSET n] [INTO target]`. InfluxQL actually
+// allows INTO before FROM; both orderings are accepted here.
+func (p *Parser) parseSelectStatement() (*SelectStatement, error) {
+	if _, err := p.expect(SELECT); err != nil {
+		return nil, err
+	}
+
+	stmt := &SelectStatement{}
+
+	fields, err := p.parseFields()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Fields = fields
+
+	if tok, _ := p.peek(); tok == INTO {
+		p.scan()
+		target, err := p.parseMeasurement()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Target = target
+	}
+
+	if _, err := p.expect(FROM); err != nil {
+		return nil, err
+	}
+	sources, err := p.parseSources()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Sources = sources
+
+	if tok, _ := p.peek(); tok == WHERE {
+		p.scan()
+		cond, err := p.parseExpr(0)
+		if err != nil {
+			return nil, err
+		}
+		stmt.Condition = cond
+	}
+
+	if tok, _ := p.peek(); tok == GROUP {
+		p.scan()
+		if _, err := p.expect(BY); err != nil {
+			return nil, err
+		}
+		dims, fill, err := p.parseDimensions()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Dimensions = dims
+		stmt.Fill = fill
+	}
+
+	if tok, _ := p.peek(); tok == ORDER {
+		p.scan()
+		if _, err := p.expect(BY); err != nil {
+			return nil, err
+		}
+		sf, err := p.parseSortFields()
+		if err != nil {
+			return nil, err
+		}
+		stmt.SortFields = sf
+	}
+
+	for {
+		tok, _ := p.peek()
+		switch tok {
+		case LIMIT:
+			p.scan()
+			n, err := p.parseIntLiteral()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Limit = n
+			continue
+		case OFFSET:
+			p.scan()
+			n, err := p.parseIntLiteral()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Offset = n
+			continue
+		case SLIMIT:
+			p.scan()
+			n, err := p.parseIntLiteral()
+			if err != nil {
+				return nil, err
+			}
+			stmt.SLimit = n
+			continue
+		case SOFFSET:
+			p.scan()
+			n, err := p.parseIntLiteral()
+			if err != nil {
+				return nil, err
+			}
+			stmt.SOffset = n
+			continue
+		}
+		break
+	}
+
+	if tok, _ := p.peek(); tok == INTO && stmt.Target == nil {
+		p.scan()
+		target, err := p.parseMeasurement()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Target = target
+	}
+
+	return stmt, nil
+}
+
+func (p *Parser) parseIntLiteral() (int64, error) {
+	lit, err := p.expect(NUMBER)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(lit, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer literal %q", lit)
+	}
+	return n, nil
+}
+
+// parseFields parses the SELECT list: comma-separated expressions, each
+// with an optional `AS alias`.
+func (p *Parser) parseFields() (Fields, error) {
+	var fields Fields
+	for {
+		expr, err := p.parseExpr(0)
+		if err != nil {
+			return nil, err
+		}
+		f := &Field{Expr: expr}
+		if tok, _ := p.peek(); tok == AS {
+			p.scan()
+			alias, err := p.parseIdentLike()
+			if err != nil {
+				return nil, err
+			}
+			f.Alias = alias
+		}
+		fields = append(fields, f)
+
+		if tok, _ := p.peek(); tok == COMMA {
+			p.scan()
+			continue
+		}
+		break
+	}
+	return fields, nil
+}
+
+// parseIdentLike accepts IDENT or any keyword token's literal text, so an
+// alias like `key` isn't rejected just because it happens to share
+// spelling with a keyword used elsewhere in the grammar.
+func (p *Parser) parseIdentLike() (string, error) {
+	tok, lit, pos := p.scan()
+	if tok == IDENT || tok.IsKeyword() {
+		return lit, nil
+	}
+	return "", fmt.Errorf("expected identifier, found %q near position %d", lit, pos)
+}
+
+// parseSources parses comma-separated FROM sources: measurements,
+// possibly database/retention-policy-qualified or regex-matched, or
+// parenthesized subqueries.
+func (p *Parser) parseSources() ([]Source, error) {
+	var sources []Source
+	for {
+		tok, _ := p.peek()
+		if tok == LPAREN {
+			p.scan()
+			sub, err := p.parseSelectStatement()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(RPAREN); err != nil {
+				return nil, err
+			}
+			sources = append(sources, &SubQuery{Statement: sub})
+		} else {
+			m, err := p.parseMeasurement()
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, m)
+		}
+
+		if tok, _ := p.peek(); tok == COMMA {
+			p.scan()
+			continue
+		}
+		break
+	}
+	return sources, nil
+}
+
+// parseMeasurement parses a possibly `db`.`rp`.`name` qualified
+// measurement name, or a bare `/regex/`.
+func (p *Parser) parseMeasurement() (*Measurement, error) {
+	if tok, _ := p.peek(); tok == DIV {
+		tok, lit := p.rescanAsRegex()
+		if tok != REGEX {
+			return nil, fmt.Errorf("invalid regex literal near position %d", p.lastPos)
+		}
+		p.scan()
+		return &Measurement{Regex: &RegexLiteral{Val: lit}}, nil
+	}
+
+	var parts []string
+	for {
+		part, err := p.parseIdentLike()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+		if tok, _ := p.peek(); tok == DOT {
+			p.scan()
+			continue
+		}
+		break
+	}
+
+	m := &Measurement{}
+	switch len(parts) {
+	case 1:
+		m.Name = parts[0]
+	case 2:
+		m.Database, m.Name = parts[0], parts[1]
+	default:
+		m.Database, m.RetentionPolicy, m.Name = parts[0], parts[1], parts[2]
+	}
+	return m, nil
+}
+
+// parseExpr parses a binary expression using precedence climbing: minPrec
+// is the lowest operator precedence this call will consume, so nested
+// calls for tighter-binding operators pass a higher minPrec.
+func (p *Parser) parseExpr(minPrec int) (Expr, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok, _ := p.peek()
+		if tok == DIV {
+			// A DIV here is always arithmetic division (a regex can only
+			// follow =~/!~, handled directly in parseUnary's caller).
+		}
+		prec := tok.Precedence()
+		if prec == 0 || prec < minPrec {
+			break
+		}
+		p.scan()
+
+		var rhs Expr
+		if tok == EQREGEX || tok == NEQREGEX {
+			rhs, err = p.parseRegexOperand()
+		} else {
+			rhs, err = p.parseExpr(prec + 1)
+		}
+		if err != nil {
+			return nil, err
+		}
+		lhs = convertTimeLiteral(&BinaryExpr{Op: tok, LHS: lhs, RHS: rhs})
+	}
+	return lhs, nil
+}
+
+func (p *Parser) parseRegexOperand() (Expr, error) {
+	if tok, _ := p.peek(); tok == DIV {
+		rtok, lit := p.rescanAsRegex()
+		if rtok != REGEX {
+			return nil, fmt.Errorf("invalid regex literal near position %d", p.lastPos)
+		}
+		p.scan()
+		return &RegexLiteral{Val: lit}, nil
+	}
+	return p.parseUnary()
+}
+
+// convertTimeLiteral upgrades a string operand compared against the `time`
+// column into a parsed TimeLiteral, the way InfluxQL's own parser resolves
+// absolute time literals contextually rather than lexically.
+func convertTimeLiteral(b *BinaryExpr) Expr {
+	if ref, ok := b.LHS.(*VarRef); ok && lower(ref.Val) == "time" {
+		if s, ok := b.RHS.(*StringLiteral); ok {
+			if t, err := parseTimeString(s.Val); err == nil {
+				b.RHS = &TimeLiteral{Val: t}
+			}
+		}
+	}
+	return b
+}
+
+func parseTimeString(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, time.RFC3339, "2006-01-02 15:04:05.999999999", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("not a time literal: %s", s)
+}
+
+// parseUnary parses a primary expression: literal, VarRef, wildcard,
+// function call, `NOT expr`, or a parenthesized sub-expression, and any
+// leading unary minus.
+func (p *Parser) parseUnary() (Expr, error) {
+	tok, lit, pos := p.scan()
+	switch tok {
+	case NOT:
+		operand, err := p.parseExpr(Token(AND).Precedence())
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{Op: NOT, Expr: operand}, nil
+	case SUB:
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if n, ok := operand.(*NumberLiteral); ok {
+			n.Val = -n.Val
+			return n, nil
+		}
+		return &BinaryExpr{Op: SUB, LHS: &NumberLiteral{Val: 0, IsInt: true}, RHS: operand}, nil
+	case LPAREN:
+		inner, err := p.parseExpr(0)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(RPAREN); err != nil {
+			return nil, err
+		}
+		return &ParenExpr{Expr: inner}, nil
+	case NUMBER:
+		return parseNumberLiteral(lit)
+	case DURATION:
+		d, err := parseDurationLiteral(lit)
+		if err != nil {
+			return nil, err
+		}
+		return &DurationLiteral{Val: d}, nil
+	case STRING:
+		return &StringLiteral{Val: lit}, nil
+	case TRUE:
+		return &BooleanLiteral{Val: true}, nil
+	case FALSE:
+		return &BooleanLiteral{Val: false}, nil
+	case MUL:
+		return &Wildcard{}, nil
+	case IDENT, TIME:
+		name := lit
+		if tok == TIME {
+			name = "time"
+		}
+		if tok2, _ := p.peek(); tok2 == LPAREN {
+			p.scan()
+			args, err := p.parseCallArgs()
+			if err != nil {
+				return nil, err
+			}
+			return &Call{Name: name, Args: args}, nil
+		}
+		if tok2, _ := p.peek(); tok2 == DOT {
+			// *::field / *::tag style suffix isn't modeled separately;
+			// fall through treating it as a plain reference past the dot.
+		}
+		return &VarRef{Val: name}, nil
+	default:
+		return nil, fmt.Errorf("unexpected token %q near position %d", lit, pos)
+	}
+}
+
+func (p *Parser) parseCallArgs() ([]Expr, error) {
+	var args []Expr
+	if tok, _ := p.peek(); tok == RPAREN {
+		p.scan()
+		return args, nil
+	}
+	for {
+		arg, err := p.parseExpr(0)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if tok, _ := p.peek(); tok == COMMA {
+			p.scan()
+			continue
+		}
+		break
+	}
+	if _, err := p.expect(RPAREN); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+func parseNumberLiteral(lit string) (*NumberLiteral, error) {
+	if !strings.Contains(lit, ".") {
+		if n, err := strconv.ParseInt(lit, 10, 64); err == nil {
+			return &NumberLiteral{Val: float64(n), IsInt: true}, nil
+		}
+	}
+	f, err := strconv.ParseFloat(lit, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number literal %q", lit)
+	}
+	return &NumberLiteral{Val: f}, nil
+}
+
+func parseDurationLiteral(lit string) (time.Duration, error) {
+	for _, unit := range []string{"ms", "u", "µ", "s", "m", "h", "d", "w"} {
+		if strings.HasSuffix(lit, unit) {
+			numPart := strings.TrimSuffix(lit, unit)
+			n, err := strconv.ParseFloat(numPart, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration literal %q", lit)
+			}
+			switch unit {
+			case "u", "µ":
+				return time.Duration(n * float64(time.Microsecond)), nil
+			case "ms":
+				return time.Duration(n * float64(time.Millisecond)), nil
+			case "s":
+				return time.Duration(n * float64(time.Second)), nil
+			case "m":
+				return time.Duration(n * float64(time.Minute)), nil
+			case "h":
+				return time.Duration(n * float64(time.Hour)), nil
+			case "d":
+				return time.Duration(n * 24 * float64(time.Hour)), nil
+			case "w":
+				return time.Duration(n * 7 * 24 * float64(time.Hour)), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("invalid duration literal %q", lit)
+}
+
+// parseDimensions parses the GROUP BY list and an optional trailing
+// FILL(...) clause.
+func (p *Parser) parseDimensions() ([]*Dimension, *Fill, error) {
+	var dims []*Dimension
+	for {
+		expr, err := p.parseExpr(0)
+		if err != nil {
+			return nil, nil, err
+		}
+		dims = append(dims, &Dimension{Expr: expr})
+		if tok, _ := p.peek(); tok == COMMA {
+			p.scan()
+			continue
+		}
+		break
+	}
+
+	var fill *Fill
+	if tok, lit := p.peek(); tok == IDENT && lower(lit) == "fill" {
+		p.scan()
+		if _, err := p.expect(LPAREN); err != nil {
+			return nil, nil, err
+		}
+		f, err := p.parseFillOption()
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := p.expect(RPAREN); err != nil {
+			return nil, nil, err
+		}
+		fill = f
+	}
+	return dims, fill, nil
+}
+
+func (p *Parser) parseFillOption() (*Fill, error) {
+	tok, lit, pos := p.scan()
+	switch {
+	case tok == NUMBER:
+		v, err := strconv.ParseFloat(lit, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &Fill{Option: FillNumber, Value: v}, nil
+	case tok == IDENT && lower(lit) == "null":
+		return &Fill{Option: FillNullOption}, nil
+	case tok == IDENT && lower(lit) == "none":
+		return &Fill{Option: FillNoneOption}, nil
+	case tok == IDENT && lower(lit) == "previous":
+		return &Fill{Option: FillPrevious}, nil
+	case tok == IDENT && lower(lit) == "linear":
+		return &Fill{Option: FillLinearOption}, nil
+	default:
+		return nil, fmt.Errorf("invalid FILL option %q near position %d", lit, pos)
+	}
+}
+
+// parseSortFields parses ORDER BY; InfluxQL only ever sorts by time, but
+// the direction and an optional explicit `time` name are both accepted.
+func (p *Parser) parseSortFields() ([]*SortField, error) {
+	var fields []*SortField
+	for {
+		name := "time"
+		if tok, lit := p.peek(); tok == TIME || tok == IDENT {
+			p.scan()
+			name = lit
+			if tok == TIME {
+				name = "time"
+			}
+		}
+		sf := &SortField{Name: name, Ascending: true}
+		if tok, _ := p.peek(); tok == ASC || tok == DESC {
+			t, _, _ := p.scan()
+			sf.Ascending = t == ASC
+		}
+		fields = append(fields, sf)
+		if tok, _ := p.peek(); tok == COMMA {
+			p.scan()
+			continue
+		}
+		break
+	}
+	return fields, nil
+}
+
+// parseShowStatement parses the handful of introspection statements this
+// parser supports: SHOW MEASUREMENTS, SHOW SERIES, SHOW TAG KEYS, SHOW TAG
+// VALUES, SHOW FIELD KEYS.
+func (p *Parser) parseShowStatement() (Statement, error) {
+	if _, err := p.expect(SHOW); err != nil {
+		return nil, err
+	}
+	tok, _ := p.peek()
+	switch tok {
+	case MEASUREMENTS:
+		p.scan()
+		stmt := &ShowMeasurementsStatement{}
+		if t, _ := p.peek(); t == ON {
+			p.scan()
+			db, err := p.parseIdentLike()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Database = db
+		}
+		if t, _ := p.peek(); t == WHERE {
+			p.scan()
+			cond, err := p.parseExpr(0)
+			if err != nil {
+				return nil, err
+			}
+			stmt.Condition = cond
+		}
+		if t, _ := p.peek(); t == LIMIT {
+			p.scan()
+			n, err := p.parseIntLiteral()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Limit = n
+		}
+		if t, _ := p.peek(); t == OFFSET {
+			p.scan()
+			n, err := p.parseIntLiteral()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Offset = n
+		}
+		return stmt, nil
+	case SERIES:
+		p.scan()
+		stmt := &ShowSeriesStatement{}
+		if t, _ := p.peek(); t == FROM {
+			p.scan()
+			sources, err := p.parseSources()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Sources = sources
+		}
+		if t, _ := p.peek(); t == WHERE {
+			p.scan()
+			cond, err := p.parseExpr(0)
+			if err != nil {
+				return nil, err
+			}
+			stmt.Condition = cond
+		}
+		return stmt, nil
+	case TAG:
+		p.scan()
+		if t, _ := p.peek(); t == VALUES {
+			return p.parseShowTagValuesStatement()
+		}
+		if _, err := p.expect(KEYS); err != nil {
+			return nil, err
+		}
+		stmt := &ShowTagKeysStatement{}
+		if t, _ := p.peek(); t == FROM {
+			p.scan()
+			sources, err := p.parseSources()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Sources = sources
+		}
+		if t, _ := p.peek(); t == WHERE {
+			p.scan()
+			cond, err := p.parseExpr(0)
+			if err != nil {
+				return nil, err
+			}
+			stmt.Condition = cond
+		}
+		return stmt, nil
+	case FIELD:
+		p.scan()
+		if _, err := p.expect(KEYS); err != nil {
+			return nil, err
+		}
+		stmt := &ShowFieldKeysStatement{}
+		if t, _ := p.peek(); t == FROM {
+			p.scan()
+			sources, err := p.parseSources()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Sources = sources
+		}
+		return stmt, nil
+	default:
+		return nil, fmt.Errorf("unsupported SHOW statement near position %d", p.lastPos)
+	}
+}
+
+// parseShowTagValuesStatement parses `SHOW TAG VALUES [FROM ...] WITH KEY =
+// "key" [WHERE ...]`, the TAG/VALUES tokens already having been consumed by
+// the caller.
+func (p *Parser) parseShowTagValuesStatement() (Statement, error) {
+	p.scan() // VALUES
+	stmt := &ShowTagValuesStatement{}
+
+	if t, _ := p.peek(); t == FROM {
+		p.scan()
+		sources, err := p.parseSources()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Sources = sources
+	}
+
+	if _, err := p.expect(WITH); err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(KEY); err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(EQ); err != nil {
+		return nil, err
+	}
+	tok, lit, pos := p.scan()
+	if tok != STRING && tok != IDENT {
+		return nil, fmt.Errorf("expected tag key after WITH KEY =, found %q near position %d", lit, pos)
+	}
+	stmt.Key = lit
+
+	if t, _ := p.peek(); t == WHERE {
+		p.scan()
+		cond, err := p.parseExpr(0)
+		if err != nil {
+			return nil, err
+		}
+		stmt.Condition = cond
+	}
+
+	return stmt, nil
+}
+
+// parseCreateContinuousQuery parses `CREATE CONTINUOUS QUERY name ON db
+// BEGIN select END`.
+func (p *Parser) parseCreateContinuousQuery() (*CreateContinuousQueryStatement, error) {
+	if _, err := p.expect(CREATE); err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(CONTINUOUS); err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(QUERY); err != nil {
+		return nil, err
+	}
+	name, err := p.parseIdentLike()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(ON); err != nil {
+		return nil, err
+	}
+	db, err := p.parseIdentLike()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(BEGIN); err != nil {
+		return nil, err
+	}
+	sel, err := p.parseSelectStatement()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(END); err != nil {
+		return nil, err
+	}
+	return &CreateContinuousQueryStatement{Name: name, Database: db, Source: sel}, nil
+}
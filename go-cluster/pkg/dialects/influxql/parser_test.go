@@ -0,0 +1,84 @@
+package influxql
+
+import "testing"
+
+func TestParser_ShowTagKeys(t *testing.T) {
+	stmts, err := NewParser(`SHOW TAG KEYS FROM cpu`).ParseQuery()
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+	if len(stmts) != 1 {
+		t.Fatalf("ParseQuery() returned %d statements, want 1", len(stmts))
+	}
+
+	stmt, ok := stmts[0].(*ShowTagKeysStatement)
+	if !ok {
+		t.Fatalf("stmts[0] = %T, want *ShowTagKeysStatement", stmts[0])
+	}
+	if len(stmt.Sources) != 1 {
+		t.Fatalf("Sources = %v, want 1 source", stmt.Sources)
+	}
+}
+
+func TestParser_ShowTagValues(t *testing.T) {
+	stmts, err := NewParser(`SHOW TAG VALUES FROM cpu WITH KEY = "host"`).ParseQuery()
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+	if len(stmts) != 1 {
+		t.Fatalf("ParseQuery() returned %d statements, want 1", len(stmts))
+	}
+
+	stmt, ok := stmts[0].(*ShowTagValuesStatement)
+	if !ok {
+		t.Fatalf("stmts[0] = %T, want *ShowTagValuesStatement", stmts[0])
+	}
+	if len(stmt.Sources) != 1 {
+		t.Fatalf("Sources = %v, want 1 source", stmt.Sources)
+	}
+	if stmt.Key != "host" {
+		t.Errorf("Key = %q, want %q", stmt.Key, "host")
+	}
+}
+
+func TestParser_ShowTagValues_NoFrom(t *testing.T) {
+	stmts, err := NewParser(`SHOW TAG VALUES WITH KEY = "region"`).ParseQuery()
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+
+	stmt, ok := stmts[0].(*ShowTagValuesStatement)
+	if !ok {
+		t.Fatalf("stmts[0] = %T, want *ShowTagValuesStatement", stmts[0])
+	}
+	if stmt.Sources != nil {
+		t.Errorf("Sources = %v, want nil", stmt.Sources)
+	}
+	if stmt.Key != "region" {
+		t.Errorf("Key = %q, want %q", stmt.Key, "region")
+	}
+}
+
+func TestParser_ShowTagValues_MissingWithKey(t *testing.T) {
+	if _, err := NewParser(`SHOW TAG VALUES FROM cpu`).ParseQuery(); err == nil {
+		t.Fatal("ParseQuery() error = nil, want an error for missing WITH KEY clause")
+	}
+}
+
+func TestParser_SelectBasic(t *testing.T) {
+	stmts, err := NewParser(`SELECT mean(value) FROM cpu WHERE time > now() - 1h GROUP BY time(5m) LIMIT 10`).ParseQuery()
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+
+	stmt, ok := stmts[0].(*SelectStatement)
+	if !ok {
+		t.Fatalf("stmts[0] = %T, want *SelectStatement", stmts[0])
+	}
+	if len(stmt.Fields) != 1 {
+		t.Fatalf("Fields = %v, want 1 field", stmt.Fields)
+	}
+	if stmt.Limit != 10 {
+		t.Errorf("Limit = %d, want 10", stmt.Limit)
+	}
+}
@@ -0,0 +1,328 @@
+package influxql
+
+import (
+	"strings"
+)
+
+const eof = rune(0)
+
+// Scanner turns InfluxQL source text into a stream of tokens, skipping
+// whitespace and both comment forms InfluxQL accepts (`-- line` and
+// `/* block */`).
+type Scanner struct {
+	src []rune
+	pos int
+}
+
+// NewScanner creates a Scanner over src.
+func NewScanner(src string) *Scanner {
+	return &Scanner{src: []rune(src)}
+}
+
+func (s *Scanner) peek() rune {
+	if s.pos >= len(s.src) {
+		return eof
+	}
+	return s.src[s.pos]
+}
+
+func (s *Scanner) peekAt(offset int) rune {
+	if s.pos+offset >= len(s.src) {
+		return eof
+	}
+	return s.src[s.pos+offset]
+}
+
+func (s *Scanner) next() rune {
+	r := s.peek()
+	if r != eof {
+		s.pos++
+	}
+	return r
+}
+
+// Scan returns the next token, its literal text, and its starting position.
+func (s *Scanner) Scan() (tok Token, lit string, pos int) {
+	s.skipWhitespaceAndComments()
+	pos = s.pos
+	r := s.peek()
+
+	switch {
+	case r == eof:
+		return EOF, "", pos
+	case isLetter(r) || r == '_':
+		lit = s.scanIdent()
+		return Lookup(lit), lit, pos
+	case r == '"':
+		lit, ok := s.scanQuotedIdent()
+		if !ok {
+			return BADSTRING, lit, pos
+		}
+		return IDENT, lit, pos
+	case r == '\'':
+		lit, ok := s.scanString('\'')
+		if !ok {
+			return BADSTRING, lit, pos
+		}
+		return STRING, lit, pos
+	case isDigit(r):
+		tok, lit := s.scanNumberOrDuration()
+		return tok, lit, pos
+	}
+
+	switch r {
+	case '+':
+		s.next()
+		return ADD, "+", pos
+	case '-':
+		s.next()
+		return SUB, "-", pos
+	case '*':
+		s.next()
+		return MUL, "*", pos
+	case '%':
+		s.next()
+		return MOD, "%", pos
+	case '/':
+		// Ambiguous with regex literals; a regex can only start where an
+		// expression is expected, so the parser re-scans via ScanRegex
+		// when it knows that's the context. Bare Scan treats it as DIV.
+		s.next()
+		return DIV, "/", pos
+	case '(':
+		s.next()
+		return LPAREN, "(", pos
+	case ')':
+		s.next()
+		return RPAREN, ")", pos
+	case ',':
+		s.next()
+		return COMMA, ",", pos
+	case ':':
+		s.next()
+		return COLON, ":", pos
+	case ';':
+		s.next()
+		return SEMICOLON, ";", pos
+	case '.':
+		s.next()
+		return DOT, ".", pos
+	case '=':
+		s.next()
+		if s.peek() == '~' {
+			s.next()
+			return EQREGEX, "=~", pos
+		}
+		return EQ, "=", pos
+	case '!':
+		s.next()
+		if s.peek() == '~' {
+			s.next()
+			return NEQREGEX, "!~", pos
+		}
+		if s.peek() == '=' {
+			s.next()
+			return NEQ, "!=", pos
+		}
+		return ILLEGAL, "!", pos
+	case '<':
+		s.next()
+		if s.peek() == '=' {
+			s.next()
+			return LTE, "<=", pos
+		}
+		return LT, "<", pos
+	case '>':
+		s.next()
+		if s.peek() == '=' {
+			s.next()
+			return GTE, ">=", pos
+		}
+		return GT, ">", pos
+	}
+
+	s.next()
+	return ILLEGAL, string(r), pos
+}
+
+// ScanRegex rescans from pos as a `/.../ ` regex literal. The parser calls
+// this only when a regex is grammatically valid at the current position
+// (after =~ or !~, or as a FROM measurement), resolving the lexical
+// ambiguity with division.
+func (s *Scanner) ScanRegex(pos int) (tok Token, lit string, newPos int) {
+	s.pos = pos
+	s.skipWhitespaceAndComments()
+	start := s.pos
+	if s.peek() != '/' {
+		return s.Scan()
+	}
+	s.next()
+	var b strings.Builder
+	for {
+		r := s.next()
+		if r == eof {
+			return BADSTRING, b.String(), start
+		}
+		if r == '\\' && s.peek() == '/' {
+			b.WriteRune(s.next())
+			continue
+		}
+		if r == '/' {
+			break
+		}
+		b.WriteRune(r)
+	}
+	return REGEX, b.String(), start
+}
+
+func (s *Scanner) skipWhitespaceAndComments() {
+	for {
+		r := s.peek()
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			s.next()
+		case r == '-' && s.peekAt(1) == '-':
+			for s.peek() != '\n' && s.peek() != eof {
+				s.next()
+			}
+		case r == '/' && s.peekAt(1) == '*':
+			s.next()
+			s.next()
+			for !(s.peek() == '*' && s.peekAt(1) == '/') && s.peek() != eof {
+				s.next()
+			}
+			if s.peek() != eof {
+				s.next()
+				s.next()
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (s *Scanner) scanIdent() string {
+	var b strings.Builder
+	for isLetter(s.peek()) || isDigit(s.peek()) || s.peek() == '_' {
+		b.WriteRune(s.next())
+	}
+	return b.String()
+}
+
+// scanQuotedIdent scans a double-quoted identifier, InfluxQL's quoting form
+// for names containing characters that would otherwise be ambiguous.
+func (s *Scanner) scanQuotedIdent() (string, bool) {
+	s.next() // opening quote
+	var b strings.Builder
+	for {
+		r := s.next()
+		if r == eof {
+			return b.String(), false
+		}
+		if r == '\\' {
+			b.WriteRune(s.next())
+			continue
+		}
+		if r == '"' {
+			return b.String(), true
+		}
+		b.WriteRune(r)
+	}
+}
+
+func (s *Scanner) scanString(quote rune) (string, bool) {
+	s.next() // opening quote
+	var b strings.Builder
+	for {
+		r := s.next()
+		if r == eof {
+			return b.String(), false
+		}
+		if r == '\\' {
+			b.WriteRune(s.next())
+			continue
+		}
+		if r == quote {
+			return b.String(), true
+		}
+		b.WriteRune(r)
+	}
+}
+
+// scanNumberOrDuration scans a numeric literal, recognizing the trailing
+// duration unit (u/µ/ms/s/m/h/d/w) InfluxQL allows directly after a number
+// with no space, e.g. `30s` or `1.5h`.
+func (s *Scanner) scanNumberOrDuration() (Token, string) {
+	var b strings.Builder
+	for isDigit(s.peek()) {
+		b.WriteRune(s.next())
+	}
+	if s.peek() == '.' && isDigit(s.peekAt(1)) {
+		b.WriteRune(s.next())
+		for isDigit(s.peek()) {
+			b.WriteRune(s.next())
+		}
+	}
+
+	if exp := s.scanExponent(); exp != "" {
+		b.WriteString(exp)
+		return NUMBER, b.String()
+	}
+
+	switch s.peek() {
+	case 'u', 'µ', 's', 'm', 'h', 'd', 'w':
+		unitStart := s.pos
+		unit := s.scanDurationUnit()
+		if unit != "" {
+			b.WriteString(unit)
+			return DURATION, b.String()
+		}
+		s.pos = unitStart
+	}
+	return NUMBER, b.String()
+}
+
+// scanExponent scans a scientific-notation exponent suffix (e.g. "e3",
+// "E-2") following a number's integer/fractional part, the same suffix
+// strconv.ParseFloat accepts. A number with an exponent is never a
+// duration, so scanNumberOrDuration returns NUMBER immediately on a match
+// rather than falling through to the duration-unit switch.
+func (s *Scanner) scanExponent() string {
+	if s.peek() != 'e' && s.peek() != 'E' {
+		return ""
+	}
+
+	save := s.pos
+	var b strings.Builder
+	b.WriteRune(s.next())
+
+	if s.peek() == '+' || s.peek() == '-' {
+		b.WriteRune(s.next())
+	}
+	if !isDigit(s.peek()) {
+		s.pos = save
+		return ""
+	}
+	for isDigit(s.peek()) {
+		b.WriteRune(s.next())
+	}
+	return b.String()
+}
+
+func (s *Scanner) scanDurationUnit() string {
+	switch s.peek() {
+	case 'u', 'µ', 's', 'd', 'w', 'h':
+		return string(s.next())
+	case 'm':
+		s.next()
+		if s.peek() == 's' {
+			s.next()
+			return "ms"
+		}
+		return "m"
+	}
+	return ""
+}
+
+func isLetter(r rune) bool { return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') }
+func isDigit(r rune) bool  { return r >= '0' && r <= '9' }
@@ -0,0 +1,91 @@
+package influxql
+
+import "testing"
+
+func TestScanner_Number(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"123", "123"},
+		{"1.5", "1.5"},
+		{"1e3", "1e3"},
+		{"1E3", "1E3"},
+		{"1e+3", "1e+3"},
+		{"1e-2", "1e-2"},
+		{"1.5e10", "1.5e10"},
+	}
+
+	for _, tt := range tests {
+		s := NewScanner(tt.src)
+		tok, lit, _ := s.Scan()
+		if tok != NUMBER {
+			t.Errorf("Scan(%q) token = %v, want NUMBER", tt.src, tok)
+		}
+		if lit != tt.want {
+			t.Errorf("Scan(%q) literal = %q, want %q", tt.src, lit, tt.want)
+		}
+	}
+}
+
+func TestScanner_Duration(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"30s", "30s"},
+		{"1.5h", "1.5h"},
+		{"500ms", "500ms"},
+		{"1d", "1d"},
+		{"1w", "1w"},
+	}
+
+	for _, tt := range tests {
+		s := NewScanner(tt.src)
+		tok, lit, _ := s.Scan()
+		if tok != DURATION {
+			t.Errorf("Scan(%q) token = %v, want DURATION", tt.src, tok)
+		}
+		if lit != tt.want {
+			t.Errorf("Scan(%q) literal = %q, want %q", tt.src, lit, tt.want)
+		}
+	}
+}
+
+func TestScanner_NumberWithExponentIsNeverDuration(t *testing.T) {
+	// A trailing "s" after an exponent digit would otherwise look like a
+	// duration unit if scanNumberOrDuration didn't return early on a match.
+	s := NewScanner("1e3s")
+	tok, lit, _ := s.Scan()
+	if tok != NUMBER || lit != "1e3" {
+		t.Fatalf("Scan(%q) = (%v, %q), want (NUMBER, \"1e3\")", "1e3s", tok, lit)
+	}
+	tok, lit, _ = s.Scan()
+	if tok != IDENT || lit != "s" {
+		t.Errorf("Scan() second token = (%v, %q), want (IDENT, \"s\")", tok, lit)
+	}
+}
+
+func TestScanner_Keywords(t *testing.T) {
+	s := NewScanner("SELECT FROM WHERE")
+	for _, want := range []Token{SELECT, FROM, WHERE} {
+		tok, _, _ := s.Scan()
+		if tok != want {
+			t.Errorf("Scan() = %v, want %v", tok, want)
+		}
+	}
+}
+
+func TestScanner_QuotedIdentAndString(t *testing.T) {
+	s := NewScanner(`"my measurement" 'a string'`)
+
+	tok, lit, _ := s.Scan()
+	if tok != IDENT || lit != "my measurement" {
+		t.Errorf("Scan() = (%v, %q), want (IDENT, \"my measurement\")", tok, lit)
+	}
+
+	tok, lit, _ = s.Scan()
+	if tok != STRING || lit != "a string" {
+		t.Errorf("Scan() = (%v, %q), want (STRING, \"a string\")", tok, lit)
+	}
+}
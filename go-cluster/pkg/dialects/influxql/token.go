@@ -0,0 +1,250 @@
+// Package influxql implements a scanner and recursive-descent parser for
+// InfluxQL, producing a typed AST instead of the line-by-line regex
+// matching the rest of this repo's dialect handlers use. The type names
+// (SelectStatement, BinaryExpr, Call, VarRef, ...) deliberately follow
+// InfluxQL's own vocabulary, since that's the grammar being modeled.
+package influxql
+
+// Token identifies the lexical class of a scanned lexeme.
+type Token int
+
+const (
+	ILLEGAL Token = iota
+	EOF
+
+	literalBeg
+	IDENT    // measurement, field, or column name
+	NUMBER   // 12345.67
+	DURATION // 12h, 30s, 1d
+	STRING   // 'foo' ("foo" scans as IDENT)
+	BADSTRING
+	BADESCAPE
+	TRUE
+	FALSE
+	REGEX // /^foo/
+	literalEnd
+
+	operatorBeg
+	ADD // +
+	SUB // -
+	MUL // *
+	DIV // /
+	MOD // %
+
+	AND // AND
+	OR  // OR
+
+	EQ       // =
+	NEQ      // !=
+	EQREGEX  // =~
+	NEQREGEX // !~
+	LT       // <
+	LTE      // <=
+	GT       // >
+	GTE      // >=
+	operatorEnd
+
+	LPAREN    // (
+	RPAREN    // )
+	COMMA     // ,
+	COLON     // :
+	SEMICOLON // ;
+	DOT       // .
+
+	keywordBeg
+	ALL
+	ALTER
+	AS
+	ASC
+	BEGIN
+	BY
+	CONTINUOUS
+	CREATE
+	DATABASE
+	DESC
+	DISTINCT
+	DROP
+	END
+	FIELD
+	FROM
+	GROUP
+	INTO
+	KEY
+	KEYS
+	LIMIT
+	MEASUREMENT
+	MEASUREMENTS
+	NOT
+	OFFSET
+	ON
+	ORDER
+	QUERY
+	QUERIES
+	SELECT
+	SERIES
+	SHOW
+	SLIMIT
+	SOFFSET
+	TAG
+	TIME
+	VALUES
+	WHERE
+	WITH
+	keywordEnd
+)
+
+var keywords = map[string]Token{
+	"all":          ALL,
+	"alter":        ALTER,
+	"and":          AND,
+	"as":           AS,
+	"asc":          ASC,
+	"begin":        BEGIN,
+	"by":           BY,
+	"continuous":   CONTINUOUS,
+	"create":       CREATE,
+	"database":     DATABASE,
+	"desc":         DESC,
+	"distinct":     DISTINCT,
+	"drop":         DROP,
+	"end":          END,
+	"false":        FALSE,
+	"field":        FIELD,
+	"from":         FROM,
+	"group":        GROUP,
+	"into":         INTO,
+	"key":          KEY,
+	"keys":         KEYS,
+	"limit":        LIMIT,
+	"measurement":  MEASUREMENT,
+	"measurements": MEASUREMENTS,
+	"not":          NOT,
+	"offset":       OFFSET,
+	"on":           ON,
+	"or":           OR,
+	"order":        ORDER,
+	"queries":      QUERIES,
+	"query":        QUERY,
+	"select":       SELECT,
+	"series":       SERIES,
+	"show":         SHOW,
+	"slimit":       SLIMIT,
+	"soffset":      SOFFSET,
+	"tag":          TAG,
+	"time":         TIME,
+	"true":         TRUE,
+	"values":       VALUES,
+	"where":        WHERE,
+	"with":         WITH,
+}
+
+// Lookup returns the keyword token for a case-insensitive identifier, or
+// IDENT if it isn't a reserved word.
+func Lookup(ident string) Token {
+	if tok, ok := keywords[lower(ident)]; ok {
+		return tok
+	}
+	return IDENT
+}
+
+func lower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + ('a' - 'A')
+		}
+	}
+	return string(b)
+}
+
+// IsOperator returns true for tokens that can appear as a binary operator.
+func (t Token) IsOperator() bool { return t > operatorBeg && t < operatorEnd }
+
+// IsKeyword returns true for reserved words.
+func (t Token) IsKeyword() bool { return t > keywordBeg && t < keywordEnd }
+
+// Precedence returns the binary operator precedence for t, or 0 if t isn't
+// an operator. Higher binds tighter: AND/OR bind loosest, then comparisons,
+// then +/-, then tightest */, /, %.
+func (t Token) Precedence() int {
+	switch t {
+	case OR:
+		return 1
+	case AND:
+		return 2
+	case EQ, NEQ, EQREGEX, NEQREGEX, LT, LTE, GT, GTE:
+		return 3
+	case ADD, SUB:
+		return 4
+	case MUL, DIV, MOD:
+		return 5
+	}
+	return 0
+}
+
+// String reports the literal text of a fixed-spelling token, for error
+// messages.
+func (t Token) String() string {
+	switch t {
+	case EOF:
+		return "EOF"
+	case IDENT:
+		return "IDENT"
+	case NUMBER:
+		return "NUMBER"
+	case DURATION:
+		return "DURATION"
+	case STRING:
+		return "STRING"
+	case REGEX:
+		return "REGEX"
+	case ADD:
+		return "+"
+	case SUB:
+		return "-"
+	case MUL:
+		return "*"
+	case DIV:
+		return "/"
+	case MOD:
+		return "%"
+	case AND:
+		return "AND"
+	case OR:
+		return "OR"
+	case EQ:
+		return "="
+	case NEQ:
+		return "!="
+	case EQREGEX:
+		return "=~"
+	case NEQREGEX:
+		return "!~"
+	case LT:
+		return "<"
+	case LTE:
+		return "<="
+	case GT:
+		return ">"
+	case GTE:
+		return ">="
+	case LPAREN:
+		return "("
+	case RPAREN:
+		return ")"
+	case COMMA:
+		return ","
+	case COLON:
+		return ":"
+	case SEMICOLON:
+		return ";"
+	case DOT:
+		return "."
+	}
+	for s, tok := range keywords {
+		if tok == t {
+			return s
+		}
+	}
+	return "ILLEGAL"
+}
@@ -0,0 +1,210 @@
+package dialects
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MetadataExecutor is an optional extension of QueryExecutor that answers
+// Prometheus's series/label metadata endpoints directly, without running a
+// full query over sample data. Executors that don't implement it make
+// those endpoints report an empty result - the same response Prometheus
+// itself gives for a target with no matching series.
+type MetadataExecutor interface {
+	// LabelNames returns every label key known across the series matched by
+	// matchers within tr (a zero TimeRange means "all time").
+	LabelNames(matchers []string, tr TimeRange) ([]string, error)
+	// LabelValues returns every distinct value label has taken across the
+	// series matched by matchers within tr.
+	LabelValues(label string, matchers []string, tr TimeRange) ([]string, error)
+	// Series returns the label set of every series matched by matchers
+	// within tr.
+	Series(matchers []string, tr TimeRange) ([]map[string]string, error)
+}
+
+// handlePromQLRange handles the Prometheus range-query endpoint,
+// /api/v1/query_range, which differs from /api/v1/query only in that it
+// evaluates over [start, end] at Step intervals instead of at one instant.
+func (r *Router) handlePromQLRange(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query().Get("query")
+	if query == "" && req.Method == "POST" {
+		if err := req.ParseForm(); err == nil {
+			query = req.FormValue("query")
+		}
+	}
+	if query == "" {
+		r.writeError(w, http.StatusBadRequest, "missing query parameter", "bad_data")
+		return
+	}
+
+	start, ok := parsePromTimestamp(req.URL.Query().Get("start"))
+	if !ok {
+		r.writeError(w, http.StatusBadRequest, "missing or invalid start parameter", "bad_data")
+		return
+	}
+	end, ok := parsePromTimestamp(req.URL.Query().Get("end"))
+	if !ok {
+		r.writeError(w, http.StatusBadRequest, "missing or invalid end parameter", "bad_data")
+		return
+	}
+
+	handler, ok := r.GetHandler(DialectPromQL)
+	if !ok {
+		r.writeError(w, http.StatusInternalServerError, "PromQL handler not registered", "internal")
+		return
+	}
+
+	parsed, err := handler.Parse(query)
+	if err != nil {
+		r.writeError(w, http.StatusBadRequest, err.Error(), "bad_data")
+		return
+	}
+	parsed.TimeRange = &TimeRange{Start: start, End: end, Duration: end.Sub(start)}
+
+	opts := &ExecuteOptions{Step: 15 * time.Second}
+	if step := req.URL.Query().Get("step"); step != "" {
+		if d, err := time.ParseDuration(step); err == nil {
+			opts.Step = d
+		} else if f, err := strconv.ParseFloat(step, 64); err == nil {
+			opts.Step = time.Duration(f * float64(time.Second))
+		}
+	}
+	opts.Timeout = parseTimeout(req.URL.Query().Get("timeout"))
+	parsed.QueryRange = &QueryRange{Start: start, End: end, Step: opts.Step}
+
+	ctx, cancel := requestContext(req, opts)
+	defer cancel()
+
+	if se, ok := r.streamExecutor(); ok {
+		r.streamPromQL(ctx, w, se, parsed, opts)
+		return
+	}
+
+	result, err := r.executor.Execute(parsed, opts)
+	if err != nil {
+		r.writeError(w, http.StatusInternalServerError, err.Error(), "execution")
+		return
+	}
+
+	response, err := handler.FormatResponse(result, "prometheus_range")
+	if err != nil {
+		r.writeError(w, http.StatusInternalServerError, err.Error(), "internal")
+		return
+	}
+
+	r.writeJSON(w, http.StatusOK, QueryResponse{
+		Status: "success",
+		Data:   response,
+	})
+}
+
+// handlePromQLSeries handles /api/v1/series, returning the label set of
+// every series matching the given match[] selectors.
+func (r *Router) handlePromQLSeries(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		r.writeError(w, http.StatusBadRequest, "failed to parse form", "bad_data")
+		return
+	}
+	matchers := req.Form["match[]"]
+	tr := promQLMetadataRange(req)
+
+	me, ok := r.executor.(MetadataExecutor)
+	if !ok {
+		r.writeJSON(w, http.StatusOK, QueryResponse{Status: "success", Data: []map[string]string{}})
+		return
+	}
+
+	series, err := me.Series(matchers, tr)
+	if err != nil {
+		r.writeError(w, http.StatusInternalServerError, err.Error(), "execution")
+		return
+	}
+	r.writeJSON(w, http.StatusOK, QueryResponse{Status: "success", Data: series})
+}
+
+// handlePromQLLabels handles /api/v1/labels, returning every label name
+// known across the series matched by the optional match[] selectors.
+func (r *Router) handlePromQLLabels(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		r.writeError(w, http.StatusBadRequest, "failed to parse form", "bad_data")
+		return
+	}
+	matchers := req.Form["match[]"]
+	tr := promQLMetadataRange(req)
+
+	me, ok := r.executor.(MetadataExecutor)
+	if !ok {
+		r.writeJSON(w, http.StatusOK, QueryResponse{Status: "success", Data: []string{}})
+		return
+	}
+
+	names, err := me.LabelNames(matchers, tr)
+	if err != nil {
+		r.writeError(w, http.StatusInternalServerError, err.Error(), "execution")
+		return
+	}
+	r.writeJSON(w, http.StatusOK, QueryResponse{Status: "success", Data: names})
+}
+
+// handlePromQLLabelValues handles /api/v1/label/<name>/values.
+func (r *Router) handlePromQLLabelValues(w http.ResponseWriter, req *http.Request) {
+	label := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/api/v1/label/"), "/values")
+	if label == "" {
+		r.writeError(w, http.StatusBadRequest, "missing label name", "bad_data")
+		return
+	}
+
+	if err := req.ParseForm(); err != nil {
+		r.writeError(w, http.StatusBadRequest, "failed to parse form", "bad_data")
+		return
+	}
+	matchers := req.Form["match[]"]
+	tr := promQLMetadataRange(req)
+
+	me, ok := r.executor.(MetadataExecutor)
+	if !ok {
+		r.writeJSON(w, http.StatusOK, QueryResponse{Status: "success", Data: []string{}})
+		return
+	}
+
+	values, err := me.LabelValues(label, matchers, tr)
+	if err != nil {
+		r.writeError(w, http.StatusInternalServerError, err.Error(), "execution")
+		return
+	}
+	r.writeJSON(w, http.StatusOK, QueryResponse{Status: "success", Data: values})
+}
+
+// promQLMetadataRange reads the optional start/end parameters the
+// series/labels endpoints accept, leaving a zero TimeRange (meaning "all
+// time") when they're absent.
+func promQLMetadataRange(req *http.Request) TimeRange {
+	var tr TimeRange
+	if start, ok := parsePromTimestamp(req.URL.Query().Get("start")); ok {
+		tr.Start = start
+	}
+	if end, ok := parsePromTimestamp(req.URL.Query().Get("end")); ok {
+		tr.End = end
+	}
+	return tr
+}
+
+// parsePromTimestamp parses a Prometheus API timestamp parameter, accepted
+// either as a Unix epoch in (possibly fractional) seconds or as RFC3339 -
+// the two forms /api/v1/query_range and the metadata endpoints allow.
+func parsePromTimestamp(s string) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, false
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		sec := int64(f)
+		nsec := int64((f - float64(sec)) * float64(time.Second))
+		return time.Unix(sec, nsec).UTC(), true
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.UTC(), true
+	}
+	return time.Time{}, false
+}
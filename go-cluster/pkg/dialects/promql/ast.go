@@ -0,0 +1,175 @@
+package promql
+
+import "time"
+
+// Node is any AST element.
+type Node interface {
+	node()
+}
+
+// Expr is anything that evaluates to a value: a literal, a selector, a
+// function/aggregate call, or a binary/unary/parenthesized combination of
+// those.
+type Expr interface {
+	Node
+	expr()
+}
+
+// NumberLiteral is a scalar constant, e.g. `1`, `3.14`, `+Inf`.
+type NumberLiteral struct {
+	Val float64
+}
+
+func (*NumberLiteral) node() {}
+func (*NumberLiteral) expr() {}
+
+// StringLiteral is a quoted string constant, e.g. an argument to
+// label_replace().
+type StringLiteral struct {
+	Val string
+}
+
+func (*StringLiteral) node() {}
+func (*StringLiteral) expr() {}
+
+// MatchOp identifies a label matcher's comparison.
+type MatchOp int
+
+const (
+	MatchEqual MatchOp = iota
+	MatchNotEqual
+	MatchRegexp
+	MatchNotRegexp
+)
+
+// String renders a MatchOp as PromQL spells it.
+func (m MatchOp) String() string {
+	switch m {
+	case MatchEqual:
+		return "="
+	case MatchNotEqual:
+		return "!="
+	case MatchRegexp:
+		return "=~"
+	case MatchNotRegexp:
+		return "!~"
+	}
+	return "?"
+}
+
+// LabelMatcher is one `label<op>"value"` entry inside a vector selector's
+// `{...}`, including the implicit `__name__=...` matcher a bare metric
+// name contributes.
+type LabelMatcher struct {
+	Name  string
+	Op    MatchOp
+	Value string
+}
+
+// VectorSelector selects an instant vector: a metric name and/or a set of
+// label matchers, with optional offset/@ modifiers.
+type VectorSelector struct {
+	Name          string
+	LabelMatchers []*LabelMatcher
+	Offset        time.Duration
+	Timestamp     *time.Time
+	AtModifier    string // "start" or "end" when @ start()/@ end() was used, instead of a literal timestamp
+}
+
+func (*VectorSelector) node() {}
+func (*VectorSelector) expr() {}
+
+// MatrixSelector is a range vector selector, e.g. `http_requests_total[5m]`.
+type MatrixSelector struct {
+	VectorSelector *VectorSelector
+	Range          time.Duration
+}
+
+func (*MatrixSelector) node() {}
+func (*MatrixSelector) expr() {}
+
+// SubqueryExpr evaluates Expr (itself an instant vector expression) as a
+// range vector by re-running it at Step intervals over Range, e.g.
+// `rate(x[5m])[1h:1m]`.
+type SubqueryExpr struct {
+	Expr      Expr
+	Range     time.Duration
+	Step      time.Duration // 0 means "use the query's default resolution"
+	Offset    time.Duration
+	Timestamp *time.Time
+}
+
+func (*SubqueryExpr) node() {}
+func (*SubqueryExpr) expr() {}
+
+// Call is a function invocation, e.g. `rate(x[5m])` or `label_replace(...)`.
+type Call struct {
+	Func string
+	Args []Expr
+}
+
+func (*Call) node() {}
+func (*Call) expr() {}
+
+// AggregateExpr is an aggregation operator applied to a vector, e.g.
+// `sum by (job) (rate(x[5m]))` or `topk(5, x)`.
+type AggregateExpr struct {
+	Op       string
+	Expr     Expr
+	Param    Expr // the k in topk(k, ...)/quantile(q, ...), or the label in count_values("label", ...)
+	Grouping []string
+	Without  bool
+}
+
+func (*AggregateExpr) node() {}
+func (*AggregateExpr) expr() {}
+
+// VectorMatchCardinality describes the cardinality of a binary vector
+// operation's matching, set by an explicit group_left/group_right.
+type VectorMatchCardinality int
+
+const (
+	CardOneToOne VectorMatchCardinality = iota
+	CardManyToOne
+	CardOneToMany
+	CardManyToMany
+)
+
+// VectorMatching holds a BinaryExpr's `on(...)`/`ignoring(...)` and
+// `group_left(...)`/`group_right(...)` clauses.
+type VectorMatching struct {
+	Card           VectorMatchCardinality
+	MatchingLabels []string
+	On             bool // true for on(...), false for ignoring(...) (or neither specified)
+	Include        []string
+}
+
+// BinaryExpr is any `LHS Op RHS` combination: arithmetic, comparison, or
+// the and/or/unless set operators.
+type BinaryExpr struct {
+	Op             Token
+	LHS            Expr
+	RHS            Expr
+	VectorMatching *VectorMatching
+	ReturnBool     bool // true when a comparison carried the `bool` modifier
+}
+
+func (*BinaryExpr) node() {}
+func (*BinaryExpr) expr() {}
+
+// UnaryExpr is a leading `+` or `-` applied to an expression.
+type UnaryExpr struct {
+	Op   Token
+	Expr Expr
+}
+
+func (*UnaryExpr) node() {}
+func (*UnaryExpr) expr() {}
+
+// ParenExpr groups a sub-expression, e.g. to apply a subquery to it.
+type ParenExpr struct {
+	Expr Expr
+}
+
+func (*ParenExpr) node() {}
+func (*ParenExpr) expr() {}
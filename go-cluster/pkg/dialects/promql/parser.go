@@ -0,0 +1,552 @@
+package promql
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// scannedTok is one token of lookahead, buffered so the parser can unscan.
+type scannedTok struct {
+	tok Token
+	lit string
+	pos int
+}
+
+// Parser is a recursive-descent, precedence-climbing parser over a
+// Scanner, producing the typed AST in ast.go. It replaces the single
+// regex PromQLHandler used to scrape a metric selector with real grammar
+// recognition: binary arithmetic, vector matching (on/ignoring/
+// group_left/group_right), aggregations (by/without, topk/quantile's
+// parameter), subqueries, offset, and the @ modifier.
+type Parser struct {
+	s       *Scanner
+	buf     []scannedTok
+	bufPos  int
+	lastPos int
+}
+
+// NewParser creates a Parser over src.
+func NewParser(src string) *Parser {
+	return &Parser{s: NewScanner(src)}
+}
+
+func (p *Parser) scan() (Token, string, int) {
+	if p.bufPos < len(p.buf) {
+		t := p.buf[p.bufPos]
+		p.bufPos++
+		p.lastPos = t.pos
+		return t.tok, t.lit, t.pos
+	}
+	tok, lit, pos := p.s.Scan()
+	p.buf = append(p.buf, scannedTok{tok, lit, pos})
+	p.bufPos++
+	p.lastPos = pos
+	return tok, lit, pos
+}
+
+func (p *Parser) unscan() {
+	if p.bufPos > 0 {
+		p.bufPos--
+	}
+}
+
+func (p *Parser) peek() (Token, string) {
+	tok, lit, _ := p.scan()
+	p.unscan()
+	return tok, lit
+}
+
+func (p *Parser) expect(tok Token) (string, error) {
+	got, lit, _ := p.scan()
+	if got != tok {
+		return "", fmt.Errorf("expected %s, found %q near position %d", tok, lit, p.lastPos)
+	}
+	return lit, nil
+}
+
+// ParseExpr parses src as a single PromQL expression.
+func (p *Parser) ParseExpr() (Expr, error) {
+	e, err := p.parseExpr(precOr)
+	if err != nil {
+		return nil, err
+	}
+	if tok, lit := p.peek(); tok != EOF {
+		return nil, fmt.Errorf("unexpected token %q near position %d", lit, p.lastPos)
+	}
+	return e, nil
+}
+
+// parseExpr implements precedence-climbing over the binary operator table
+// in token.go: `^` binds tightest and is right-associative; every other
+// operator is left-associative, from `* / % atan2` down through `+ -`,
+// comparisons, `and`/`unless`, to `or` (loosest).
+func (p *Parser) parseExpr(minPrec int) (Expr, error) {
+	lhs, err := p.parseUnaryExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok, _ := p.peek()
+		prec := tok.Precedence()
+		if prec == 0 || prec < minPrec {
+			return lhs, nil
+		}
+		p.scan()
+
+		returnBool := false
+		if tok.IsComparisonOperator() {
+			if t, _ := p.peek(); t == BOOL {
+				p.scan()
+				returnBool = true
+			}
+		}
+
+		var vm *VectorMatching
+		if t, _ := p.peek(); t == ON || t == IGNORING {
+			vm, err = p.parseVectorMatching()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		nextMinPrec := prec + 1
+		if tok.RightAssociative() {
+			nextMinPrec = prec
+		}
+		rhs, err := p.parseExpr(nextMinPrec)
+		if err != nil {
+			return nil, err
+		}
+		lhs = &BinaryExpr{Op: tok, LHS: lhs, RHS: rhs, VectorMatching: vm, ReturnBool: returnBool}
+	}
+}
+
+func (p *Parser) parseVectorMatching() (*VectorMatching, error) {
+	tok, _, _ := p.scan() // ON or IGNORING
+	vm := &VectorMatching{On: tok == ON, Card: CardOneToOne}
+
+	if _, err := p.expect(LPAREN); err != nil {
+		return nil, err
+	}
+	labels, err := p.parseLabelList()
+	if err != nil {
+		return nil, err
+	}
+	vm.MatchingLabels = labels
+	if _, err := p.expect(RPAREN); err != nil {
+		return nil, err
+	}
+
+	if t, _ := p.peek(); t == GROUP_LEFT || t == GROUP_RIGHT {
+		p.scan()
+		if t == GROUP_LEFT {
+			vm.Card = CardManyToOne
+		} else {
+			vm.Card = CardOneToMany
+		}
+		if t2, _ := p.peek(); t2 == LPAREN {
+			p.scan()
+			include, err := p.parseLabelList()
+			if err != nil {
+				return nil, err
+			}
+			vm.Include = include
+			if _, err := p.expect(RPAREN); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return vm, nil
+}
+
+// parseUnaryExpr handles a leading `+`/`-`, then defers to parsePrimaryExpr
+// and applies any trailing range/subquery, offset, and @ modifiers.
+func (p *Parser) parseUnaryExpr() (Expr, error) {
+	if tok, _ := p.peek(); tok == ADD || tok == SUB {
+		p.scan()
+		e, err := p.parseUnaryExpr()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{Op: tok, Expr: e}, nil
+	}
+
+	e, err := p.parsePrimaryExpr()
+	if err != nil {
+		return nil, err
+	}
+	return p.applyModifiers(e)
+}
+
+func (p *Parser) parsePrimaryExpr() (Expr, error) {
+	tok, lit := p.peek()
+	switch tok {
+	case NUMBER:
+		p.scan()
+		val, err := strconv.ParseFloat(lit, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q near position %d", lit, p.lastPos)
+		}
+		return &NumberLiteral{Val: val}, nil
+	case STRING:
+		p.scan()
+		return &StringLiteral{Val: lit}, nil
+	case LPAREN:
+		p.scan()
+		inner, err := p.parseExpr(precOr)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(RPAREN); err != nil {
+			return nil, err
+		}
+		return &ParenExpr{Expr: inner}, nil
+	case LBRACE:
+		return p.parseVectorSelector("")
+	case IDENT:
+		p.scan()
+		next, _ := p.peek()
+		if IsAggFunc(lit) && (next == LPAREN || next == BY || next == WITHOUT) {
+			return p.parseAggregateExpr(lower(lit))
+		}
+		if next == LPAREN {
+			return p.parseCall(lit)
+		}
+		return p.parseVectorSelector(lit)
+	}
+	return nil, fmt.Errorf("unexpected token %q near position %d", lit, p.lastPos)
+}
+
+func (p *Parser) parseCall(name string) (Expr, error) {
+	if _, err := p.expect(LPAREN); err != nil {
+		return nil, err
+	}
+	args, err := p.parseArgs()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(RPAREN); err != nil {
+		return nil, err
+	}
+	return &Call{Func: name, Args: args}, nil
+}
+
+// parseArgs parses a comma-separated, possibly empty, expression list up
+// to (but not consuming) the closing RPAREN.
+func (p *Parser) parseArgs() ([]Expr, error) {
+	var args []Expr
+	if tok, _ := p.peek(); tok == RPAREN {
+		return args, nil
+	}
+	for {
+		arg, err := p.parseExpr(precOr)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if tok, _ := p.peek(); tok == COMMA {
+			p.scan()
+			continue
+		}
+		break
+	}
+	return args, nil
+}
+
+// parseLabelList parses a comma-separated, possibly empty, list of label
+// names up to (but not consuming) the closing RPAREN - the grouping list
+// in `by (...)`/`without (...)`/`on (...)`/`ignoring (...)`.
+func (p *Parser) parseLabelList() ([]string, error) {
+	var labels []string
+	if tok, _ := p.peek(); tok == RPAREN {
+		return labels, nil
+	}
+	for {
+		tok, lit, _ := p.scan()
+		if tok != IDENT && !tok.IsKeyword() {
+			return nil, fmt.Errorf("expected label name, found %q near position %d", lit, p.lastPos)
+		}
+		labels = append(labels, lit)
+		if t, _ := p.peek(); t == COMMA {
+			p.scan()
+			continue
+		}
+		break
+	}
+	return labels, nil
+}
+
+// parseAggregateExpr parses an aggregation, accepting both the
+// `op (expr) by (labels)` and `op by (labels) (expr)` orderings, plus the
+// leading parameter `topk`/`bottomk`/`quantile`/`count_values` take.
+func (p *Parser) parseAggregateExpr(op string) (Expr, error) {
+	agg := &AggregateExpr{Op: op}
+
+	if tok, _ := p.peek(); tok == BY || tok == WITHOUT {
+		p.scan()
+		agg.Without = tok == WITHOUT
+		if _, err := p.expect(LPAREN); err != nil {
+			return nil, err
+		}
+		grouping, err := p.parseLabelList()
+		if err != nil {
+			return nil, err
+		}
+		agg.Grouping = grouping
+		if _, err := p.expect(RPAREN); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.expect(LPAREN); err != nil {
+		return nil, err
+	}
+	args, err := p.parseArgs()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(RPAREN); err != nil {
+		return nil, err
+	}
+
+	if agg.Grouping == nil {
+		if tok, _ := p.peek(); tok == BY || tok == WITHOUT {
+			p.scan()
+			agg.Without = tok == WITHOUT
+			if _, err := p.expect(LPAREN); err != nil {
+				return nil, err
+			}
+			grouping, err := p.parseLabelList()
+			if err != nil {
+				return nil, err
+			}
+			agg.Grouping = grouping
+			if _, err := p.expect(RPAREN); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	switch len(args) {
+	case 1:
+		agg.Expr = args[0]
+	case 2:
+		agg.Param = args[0]
+		agg.Expr = args[1]
+	case 0:
+		return nil, fmt.Errorf("aggregation %q takes at least one argument near position %d", op, p.lastPos)
+	default:
+		agg.Param = args[0]
+		agg.Expr = args[len(args)-1]
+	}
+	return agg, nil
+}
+
+// parseVectorSelector parses the `{label matchers}` that may follow a
+// metric name (already scanned as name), or stand alone when name is "".
+func (p *Parser) parseVectorSelector(name string) (Expr, error) {
+	vs := &VectorSelector{Name: name}
+	if tok, _ := p.peek(); tok == LBRACE {
+		p.scan()
+		matchers, err := p.parseLabelMatchers()
+		if err != nil {
+			return nil, err
+		}
+		vs.LabelMatchers = matchers
+	}
+	return vs, nil
+}
+
+func (p *Parser) parseLabelMatchers() ([]*LabelMatcher, error) {
+	var matchers []*LabelMatcher
+	if tok, _ := p.peek(); tok == RBRACE {
+		p.scan()
+		return matchers, nil
+	}
+	for {
+		nameLit, err := p.expect(IDENT)
+		if err != nil {
+			return nil, err
+		}
+		opTok, opLit, pos := p.scan()
+		var op MatchOp
+		switch opTok {
+		case ASSIGN:
+			op = MatchEqual
+		case NEQ:
+			op = MatchNotEqual
+		case EQLREGEX:
+			op = MatchRegexp
+		case NEQREGEX:
+			op = MatchNotRegexp
+		default:
+			return nil, fmt.Errorf("expected label matcher operator, found %q near position %d", opLit, pos)
+		}
+		valLit, err := p.expect(STRING)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, &LabelMatcher{Name: nameLit, Op: op, Value: valLit})
+
+		if t, _ := p.peek(); t == COMMA {
+			p.scan()
+			continue
+		}
+		break
+	}
+	if _, err := p.expect(RBRACE); err != nil {
+		return nil, err
+	}
+	return matchers, nil
+}
+
+// applyModifiers attaches a trailing `[range]`/`[range:step]` selector,
+// `offset`, and `@` modifier to the expression they bind to.
+func (p *Parser) applyModifiers(expr Expr) (Expr, error) {
+	if tok, _ := p.peek(); tok == LBRACKET {
+		p.scan()
+		rangeLit, err := p.expect(DURATION)
+		if err != nil {
+			return nil, err
+		}
+		rng := parseDurationLit(rangeLit)
+
+		hasColon := false
+		var step time.Duration
+		if t, _ := p.peek(); t == COLON {
+			p.scan()
+			hasColon = true
+			if t2, lit2 := p.peek(); t2 == DURATION {
+				p.scan()
+				step = parseDurationLit(lit2)
+			}
+		}
+		if _, err := p.expect(RBRACKET); err != nil {
+			return nil, err
+		}
+
+		if hasColon {
+			expr = &SubqueryExpr{Expr: expr, Range: rng, Step: step}
+		} else if vs, ok := expr.(*VectorSelector); ok {
+			expr = &MatrixSelector{VectorSelector: vs, Range: rng}
+		} else {
+			expr = &SubqueryExpr{Expr: expr, Range: rng}
+		}
+	}
+
+	if tok, _ := p.peek(); tok == OFFSET {
+		p.scan()
+		neg := false
+		if t, _ := p.peek(); t == SUB {
+			p.scan()
+			neg = true
+		}
+		durLit, err := p.expect(DURATION)
+		if err != nil {
+			return nil, err
+		}
+		d := parseDurationLit(durLit)
+		if neg {
+			d = -d
+		}
+		switch e := expr.(type) {
+		case *VectorSelector:
+			e.Offset = d
+		case *MatrixSelector:
+			e.VectorSelector.Offset = d
+		case *SubqueryExpr:
+			e.Offset = d
+		}
+	}
+
+	if tok, _ := p.peek(); tok == AT {
+		p.scan()
+		t2, lit2 := p.peek()
+		if t2 == IDENT && (lower(lit2) == "start" || lower(lit2) == "end") {
+			p.scan()
+			if _, err := p.expect(LPAREN); err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(RPAREN); err != nil {
+				return nil, err
+			}
+			switch e := expr.(type) {
+			case *VectorSelector:
+				e.AtModifier = lower(lit2)
+			case *MatrixSelector:
+				e.VectorSelector.AtModifier = lower(lit2)
+			case *SubqueryExpr:
+				// SubqueryExpr has no AtModifier field of its own; the @
+				// start()/end() form is rare enough on subqueries that we
+				// only carry the literal-timestamp form (Timestamp) there.
+			}
+		} else {
+			numLit, err := p.expect(NUMBER)
+			if err != nil {
+				return nil, err
+			}
+			sec, err := strconv.ParseFloat(numLit, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid @ timestamp %q near position %d", numLit, p.lastPos)
+			}
+			ts := time.Unix(int64(sec), int64((sec-math.Trunc(sec))*float64(time.Second))).UTC()
+			switch e := expr.(type) {
+			case *VectorSelector:
+				e.Timestamp = &ts
+			case *MatrixSelector:
+				e.VectorSelector.Timestamp = &ts
+			case *SubqueryExpr:
+				e.Timestamp = &ts
+			}
+		}
+	}
+
+	return expr, nil
+}
+
+// parseDurationLit parses a (possibly compound) duration literal like
+// "5m", "1h30m", or "500ms" into a time.Duration.
+func parseDurationLit(s string) time.Duration {
+	var d time.Duration
+	i, n := 0, len(s)
+	for i < n {
+		start := i
+		for i < n && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		if i == start {
+			break
+		}
+		numStr := s[start:i]
+
+		unitStart := i
+		if i < n && s[i] == 'm' && i+1 < n && s[i+1] == 's' {
+			i += 2
+		} else if i < n {
+			i++
+		}
+		unit := s[unitStart:i]
+
+		val, _ := strconv.ParseInt(numStr, 10, 64)
+		switch unit {
+		case "ms":
+			d += time.Duration(val) * time.Millisecond
+		case "s":
+			d += time.Duration(val) * time.Second
+		case "m":
+			d += time.Duration(val) * time.Minute
+		case "h":
+			d += time.Duration(val) * time.Hour
+		case "d":
+			d += time.Duration(val) * 24 * time.Hour
+		case "w":
+			d += time.Duration(val) * 7 * 24 * time.Hour
+		case "y":
+			d += time.Duration(val) * 365 * 24 * time.Hour
+		}
+	}
+	return d
+}
@@ -0,0 +1,334 @@
+package promql
+
+import "strings"
+
+const eof = rune(0)
+
+// Scanner turns PromQL source text into a stream of tokens, skipping
+// whitespace and `#`-prefixed line comments.
+type Scanner struct {
+	src []rune
+	pos int
+}
+
+// NewScanner creates a Scanner over src.
+func NewScanner(src string) *Scanner {
+	return &Scanner{src: []rune(src)}
+}
+
+func (s *Scanner) peek() rune {
+	if s.pos >= len(s.src) {
+		return eof
+	}
+	return s.src[s.pos]
+}
+
+func (s *Scanner) peekAt(offset int) rune {
+	if s.pos+offset >= len(s.src) {
+		return eof
+	}
+	return s.src[s.pos+offset]
+}
+
+func (s *Scanner) next() rune {
+	r := s.peek()
+	if r != eof {
+		s.pos++
+	}
+	return r
+}
+
+// Scan returns the next token, its literal text, and its starting position.
+func (s *Scanner) Scan() (tok Token, lit string, pos int) {
+	s.skipWhitespaceAndComments()
+	pos = s.pos
+	r := s.peek()
+
+	switch {
+	case r == eof:
+		return EOF, "", pos
+	case isLetter(r) || r == '_':
+		lit = s.scanIdent()
+		return Lookup(lit), lit, pos
+	case r == '"' || r == '\'':
+		lit, ok := s.scanString(r)
+		if !ok {
+			return ILLEGAL, lit, pos
+		}
+		return STRING, lit, pos
+	case r == '`':
+		lit, ok := s.scanRawString()
+		if !ok {
+			return ILLEGAL, lit, pos
+		}
+		return STRING, lit, pos
+	case isDigit(r) || (r == '.' && isDigit(s.peekAt(1))):
+		return s.scanNumberOrDuration()
+	}
+
+	switch r {
+	case '+':
+		s.next()
+		return ADD, "+", pos
+	case '-':
+		s.next()
+		return SUB, "-", pos
+	case '*':
+		s.next()
+		return MUL, "*", pos
+	case '/':
+		s.next()
+		return DIV, "/", pos
+	case '%':
+		s.next()
+		return MOD, "%", pos
+	case '^':
+		s.next()
+		return POW, "^", pos
+	case '(':
+		s.next()
+		return LPAREN, "(", pos
+	case ')':
+		s.next()
+		return RPAREN, ")", pos
+	case '{':
+		s.next()
+		return LBRACE, "{", pos
+	case '}':
+		s.next()
+		return RBRACE, "}", pos
+	case '[':
+		s.next()
+		return LBRACKET, "[", pos
+	case ']':
+		s.next()
+		return RBRACKET, "]", pos
+	case ',':
+		s.next()
+		return COMMA, ",", pos
+	case ':':
+		s.next()
+		return COLON, ":", pos
+	case '@':
+		s.next()
+		return AT, "@", pos
+	case '=':
+		s.next()
+		if s.peek() == '=' {
+			s.next()
+			return EQLC, "==", pos
+		}
+		if s.peek() == '~' {
+			s.next()
+			return EQLREGEX, "=~", pos
+		}
+		return ASSIGN, "=", pos
+	case '!':
+		s.next()
+		if s.peek() == '=' {
+			s.next()
+			return NEQ, "!=", pos
+		}
+		if s.peek() == '~' {
+			s.next()
+			return NEQREGEX, "!~", pos
+		}
+		return ILLEGAL, "!", pos
+	case '<':
+		s.next()
+		if s.peek() == '=' {
+			s.next()
+			return LTE, "<=", pos
+		}
+		return LT, "<", pos
+	case '>':
+		s.next()
+		if s.peek() == '=' {
+			s.next()
+			return GTE, ">=", pos
+		}
+		return GT, ">", pos
+	}
+
+	s.next()
+	return ILLEGAL, string(r), pos
+}
+
+func (s *Scanner) skipWhitespaceAndComments() {
+	for {
+		r := s.peek()
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			s.next()
+		case r == '#':
+			for s.peek() != '\n' && s.peek() != eof {
+				s.next()
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (s *Scanner) scanIdent() string {
+	var b strings.Builder
+	for isLetter(s.peek()) || isDigit(s.peek()) || s.peek() == '_' || s.peek() == ':' {
+		b.WriteRune(s.next())
+	}
+	return b.String()
+}
+
+func (s *Scanner) scanString(quote rune) (string, bool) {
+	s.next() // opening quote
+	var b strings.Builder
+	for {
+		r := s.next()
+		if r == eof {
+			return b.String(), false
+		}
+		if r == '\\' {
+			b.WriteRune(s.scanEscape())
+			continue
+		}
+		if r == quote {
+			return b.String(), true
+		}
+		b.WriteRune(r)
+	}
+}
+
+// scanEscape resolves the character after a backslash in a quoted string,
+// supporting the common Go-style escapes PromQL string literals accept.
+func (s *Scanner) scanEscape() rune {
+	r := s.next()
+	switch r {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	case '\\', '\'', '"':
+		return r
+	default:
+		return r
+	}
+}
+
+// scanRawString scans a backtick-quoted string, which PromQL (like Go)
+// treats literally with no escape processing.
+func (s *Scanner) scanRawString() (string, bool) {
+	s.next() // opening backtick
+	var b strings.Builder
+	for {
+		r := s.next()
+		if r == eof {
+			return b.String(), false
+		}
+		if r == '`' {
+			return b.String(), true
+		}
+		b.WriteRune(r)
+	}
+}
+
+// scanNumberOrDuration scans a numeric literal, or - if one or more
+// (number, unit) pairs follow with no separating space, e.g. `5m`,
+// `1h30m`, `500ms` - a DURATION token.
+func (s *Scanner) scanNumberOrDuration() (Token, string, int) {
+	start := s.pos
+	var b strings.Builder
+	for isDigit(s.peek()) {
+		b.WriteRune(s.next())
+	}
+	if s.peek() == '.' && isDigit(s.peekAt(1)) {
+		b.WriteRune(s.next())
+		for isDigit(s.peek()) {
+			b.WriteRune(s.next())
+		}
+	}
+
+	if exp := s.scanExponent(); exp != "" {
+		b.WriteString(exp)
+		return NUMBER, b.String(), start
+	}
+
+	if unit := s.scanDurationUnit(); unit != "" {
+		b.WriteString(unit)
+		for isDigit(s.peek()) {
+			part := s.scanDurationPart()
+			if part == "" {
+				break
+			}
+			b.WriteString(part)
+		}
+		return DURATION, b.String(), start
+	}
+
+	return NUMBER, b.String(), start
+}
+
+// scanDurationPart scans one more (digits, unit) pair of a compound
+// duration like the "30m" in "1h30m", returning "" if what follows isn't
+// actually another duration part (so the caller can stop cleanly).
+func (s *Scanner) scanDurationPart() string {
+	save := s.pos
+	var b strings.Builder
+	for isDigit(s.peek()) {
+		b.WriteRune(s.next())
+	}
+	if unit := s.scanDurationUnit(); unit != "" {
+		b.WriteString(unit)
+		return b.String()
+	}
+	s.pos = save
+	return ""
+}
+
+// scanExponent scans a scientific-notation exponent suffix (e.g. "e3",
+// "E-2") following a number's integer/fractional part, the same suffix
+// strconv.ParseFloat accepts. A number with an exponent is never a
+// duration, so scanNumberOrDuration returns NUMBER immediately on a match
+// rather than falling through to scanDurationUnit.
+func (s *Scanner) scanExponent() string {
+	if s.peek() != 'e' && s.peek() != 'E' {
+		return ""
+	}
+
+	save := s.pos
+	var b strings.Builder
+	b.WriteRune(s.next())
+
+	if s.peek() == '+' || s.peek() == '-' {
+		b.WriteRune(s.next())
+	}
+	if !isDigit(s.peek()) {
+		s.pos = save
+		return ""
+	}
+	for isDigit(s.peek()) {
+		b.WriteRune(s.next())
+	}
+	return b.String()
+}
+
+func (s *Scanner) scanDurationUnit() string {
+	switch s.peek() {
+	case 'y', 'w', 'd', 'h':
+		return string(s.next())
+	case 's':
+		s.next()
+		return "s"
+	case 'm':
+		s.next()
+		if s.peek() == 's' {
+			s.next()
+			return "ms"
+		}
+		return "m"
+	}
+	return ""
+}
+
+func isLetter(r rune) bool { return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') }
+func isDigit(r rune) bool  { return r >= '0' && r <= '9' }
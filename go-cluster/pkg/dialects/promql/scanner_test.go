@@ -0,0 +1,97 @@
+package promql
+
+import "testing"
+
+func TestScanner_Number(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"123", "123"},
+		{"1.5", "1.5"},
+		{"1e3", "1e3"},
+		{"1E3", "1E3"},
+		{"1e+3", "1e+3"},
+		{"1e-2", "1e-2"},
+		{"1.5e10", "1.5e10"},
+	}
+
+	for _, tt := range tests {
+		s := NewScanner(tt.src)
+		tok, lit, _ := s.Scan()
+		if tok != NUMBER {
+			t.Errorf("Scan(%q) token = %v, want NUMBER", tt.src, tok)
+		}
+		if lit != tt.want {
+			t.Errorf("Scan(%q) literal = %q, want %q", tt.src, lit, tt.want)
+		}
+	}
+}
+
+func TestScanner_Duration(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"5m", "5m"},
+		{"1h30m", "1h30m"},
+		{"500ms", "500ms"},
+		{"1d", "1d"},
+		{"1w", "1w"},
+		{"1y", "1y"},
+	}
+
+	for _, tt := range tests {
+		s := NewScanner(tt.src)
+		tok, lit, _ := s.Scan()
+		if tok != DURATION {
+			t.Errorf("Scan(%q) token = %v, want DURATION", tt.src, tok)
+		}
+		if lit != tt.want {
+			t.Errorf("Scan(%q) literal = %q, want %q", tt.src, lit, tt.want)
+		}
+	}
+}
+
+func TestScanner_NumberWithExponentIsNeverDuration(t *testing.T) {
+	// A trailing "m" after an exponent digit would otherwise look like a
+	// duration unit if scanNumberOrDuration didn't return early on a match.
+	s := NewScanner("1e3m")
+	tok, lit, _ := s.Scan()
+	if tok != NUMBER || lit != "1e3" {
+		t.Fatalf("Scan(%q) = (%v, %q), want (NUMBER, \"1e3\")", "1e3m", tok, lit)
+	}
+	tok, lit, _ = s.Scan()
+	if tok != IDENT || lit != "m" {
+		t.Errorf("Scan() second token = (%v, %q), want (IDENT, \"m\")", tok, lit)
+	}
+}
+
+func TestScanner_SelectorAndRange(t *testing.T) {
+	s := NewScanner(`rate(http_requests_total{job="api"}[5m])`)
+
+	want := []struct {
+		tok Token
+		lit string
+	}{
+		{IDENT, "rate"},
+		{LPAREN, "("},
+		{IDENT, "http_requests_total"},
+		{LBRACE, "{"},
+		{IDENT, "job"},
+		{ASSIGN, "="},
+		{STRING, "api"},
+		{RBRACE, "}"},
+		{LBRACKET, "["},
+		{DURATION, "5m"},
+		{RBRACKET, "]"},
+		{RPAREN, ")"},
+	}
+
+	for i, w := range want {
+		tok, lit, _ := s.Scan()
+		if tok != w.tok || lit != w.lit {
+			t.Fatalf("Scan() token %d = (%v, %q), want (%v, %q)", i, tok, lit, w.tok, w.lit)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+// Package promql implements a scanner and recursive-descent (precedence
+// climbing) parser for PromQL, producing a typed AST instead of the
+// single-regex metric scraping PromQLHandler previously relied on. Node
+// names (VectorSelector, MatrixSelector, SubqueryExpr, AggregateExpr,
+// BinaryExpr, VectorMatching, ...) follow PromQL's own vocabulary, since
+// that's the grammar being modeled.
+package promql
+
+// Token identifies the lexical class of a scanned lexeme.
+type Token int
+
+const (
+	ILLEGAL Token = iota
+	EOF
+
+	literalBeg
+	IDENT    // a metric name, label name, or function/aggregation name
+	NUMBER   // 3.14
+	DURATION // 5m, 1h30m
+	STRING   // "foo", 'foo', or `foo`
+	literalEnd
+
+	operatorBeg
+	ADD // +
+	SUB // -
+	MUL // *
+	DIV // /
+	MOD // %
+	POW // ^
+
+	ASSIGN   // =   (label matcher equality)
+	EQLC     // ==  (comparison equality)
+	NEQ      // !=
+	EQLREGEX // =~
+	NEQREGEX // !~
+	LT       // <
+	LTE      // <=
+	GT       // >
+	GTE      // >=
+	AT       // @
+	operatorEnd
+
+	LPAREN   // (
+	RPAREN   // )
+	LBRACE   // {
+	RBRACE   // }
+	LBRACKET // [
+	RBRACKET // ]
+	COMMA    // ,
+	COLON    // :
+
+	keywordBeg
+	AND
+	OR
+	UNLESS
+	ATAN2
+	BY
+	WITHOUT
+	ON
+	IGNORING
+	GROUP_LEFT
+	GROUP_RIGHT
+	OFFSET
+	BOOL
+	keywordEnd
+)
+
+var keywords = map[string]Token{
+	"and":         AND,
+	"or":          OR,
+	"unless":      UNLESS,
+	"atan2":       ATAN2,
+	"by":          BY,
+	"without":     WITHOUT,
+	"on":          ON,
+	"ignoring":    IGNORING,
+	"group_left":  GROUP_LEFT,
+	"group_right": GROUP_RIGHT,
+	"offset":      OFFSET,
+	"bool":        BOOL,
+}
+
+// aggFuncs are the identifiers that introduce an AggregateExpr when they
+// appear in expression position, rather than an ordinary function Call.
+var aggFuncs = map[string]bool{
+	"sum":          true,
+	"avg":          true,
+	"min":          true,
+	"max":          true,
+	"count":        true,
+	"stddev":       true,
+	"stdvar":       true,
+	"topk":         true,
+	"bottomk":      true,
+	"quantile":     true,
+	"count_values": true,
+	"group":        true,
+}
+
+// IsAggFunc reports whether name (matched case-insensitively) is an aggregation
+// operator rather than an ordinary function.
+func IsAggFunc(name string) bool { return aggFuncs[lower(name)] }
+
+// Lookup returns the keyword token for a case-insensitive identifier, or
+// IDENT if it isn't reserved.
+func Lookup(ident string) Token {
+	if tok, ok := keywords[lower(ident)]; ok {
+		return tok
+	}
+	return IDENT
+}
+
+func lower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + ('a' - 'A')
+		}
+	}
+	return string(b)
+}
+
+// IsKeyword returns true for reserved words.
+func (t Token) IsKeyword() bool { return t > keywordBeg && t < keywordEnd }
+
+// precedence levels, lowest to highest. `^` is right-associative; every
+// other operator is left-associative.
+const (
+	precOr = iota + 1
+	precAndUnless
+	precComparison
+	precAddSub
+	precMulDivModAtan2
+	precPow
+)
+
+// Precedence returns t's binary operator precedence, or 0 if t isn't one.
+func (t Token) Precedence() int {
+	switch t {
+	case OR:
+		return precOr
+	case AND, UNLESS:
+		return precAndUnless
+	case EQLC, NEQ, LTE, LT, GTE, GT:
+		return precComparison
+	case ADD, SUB:
+		return precAddSub
+	case MUL, DIV, MOD, ATAN2:
+		return precMulDivModAtan2
+	case POW:
+		return precPow
+	}
+	return 0
+}
+
+// RightAssociative is true only for `^`, the one PromQL operator that
+// associates right-to-left (`2^3^2` is `2^(3^2)`).
+func (t Token) RightAssociative() bool { return t == POW }
+
+// IsSetOperator is true for and/or/unless, the vector-matching operators
+// that only ever combine two instant vectors.
+func (t Token) IsSetOperator() bool { return t == AND || t == OR || t == UNLESS }
+
+// IsComparisonOperator is true for the ordering/equality operators that
+// accept an optional `bool` modifier.
+func (t Token) IsComparisonOperator() bool {
+	switch t {
+	case EQLC, NEQ, LTE, LT, GTE, GT:
+		return true
+	}
+	return false
+}
+
+// String reports the literal text of a fixed-spelling token, for error
+// messages.
+func (t Token) String() string {
+	switch t {
+	case EOF:
+		return "EOF"
+	case IDENT:
+		return "IDENT"
+	case NUMBER:
+		return "NUMBER"
+	case DURATION:
+		return "DURATION"
+	case STRING:
+		return "STRING"
+	case ADD:
+		return "+"
+	case SUB:
+		return "-"
+	case MUL:
+		return "*"
+	case DIV:
+		return "/"
+	case MOD:
+		return "%"
+	case POW:
+		return "^"
+	case ASSIGN:
+		return "="
+	case EQLC:
+		return "=="
+	case NEQ:
+		return "!="
+	case EQLREGEX:
+		return "=~"
+	case NEQREGEX:
+		return "!~"
+	case LT:
+		return "<"
+	case LTE:
+		return "<="
+	case GT:
+		return ">"
+	case GTE:
+		return ">="
+	case AT:
+		return "@"
+	case LPAREN:
+		return "("
+	case RPAREN:
+		return ")"
+	case LBRACE:
+		return "{"
+	case RBRACE:
+		return "}"
+	case LBRACKET:
+		return "["
+	case RBRACKET:
+		return "]"
+	case COMMA:
+		return ","
+	case COLON:
+		return ":"
+	}
+	for s, tok := range keywords {
+		if tok == t {
+			return s
+		}
+	}
+	return "ILLEGAL"
+}
@@ -0,0 +1,309 @@
+// Package queue buffers non-interactive dialect queries - writes and
+// long-running materializations that don't need to hold an HTTP connection
+// open - behind a bounded in-memory queue backed by an append-only WAL, so a
+// burst of ingest can't stall a dialect endpoint waiting on QueryExecutor.
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Status is the lifecycle state of a queued Job.
+type Status string
+
+const (
+	StatusQueued  Status = "queued"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+// ErrJobNotFound is returned by Get when no job with the given ID exists.
+var ErrJobNotFound = errors.New("job not found")
+
+// ErrQueueFull is returned by Enqueue when the queue already holds
+// Config.MaxPending jobs that haven't finished executing yet.
+var ErrQueueFull = errors.New("queue full")
+
+// Job is one queued query, from submission through to its cached result.
+type Job struct {
+	ID        string          `json:"id"`
+	Dialect   string          `json:"dialect"`
+	Query     string          `json:"query"`
+	Database  string          `json:"database"`
+	Status    Status          `json:"status"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+// Executor parses and runs one query for a dialect, returning its response
+// already formatted for the caller (the same JSON a synchronous dialect
+// endpoint would return). The queue has no opinion on dialect semantics; it
+// only schedules calls to Execute and persists their outcome.
+type Executor interface {
+	Execute(dialect, query, database string) (json.RawMessage, error)
+}
+
+// Config controls batching: jobs queued for the same database are coalesced
+// into one flush once either BatchSize jobs have accumulated or MaxWait has
+// elapsed since the oldest of them was queued, whichever comes first.
+type Config struct {
+	BatchSize int
+	MaxWait   time.Duration
+	Workers   int
+
+	// MaxPending caps how many jobs may be queued or running at once, across
+	// all databases combined. Enqueue returns ErrQueueFull once it's
+	// reached, rather than letting a slow or stuck executor grow the queue
+	// without bound. Zero means unbounded.
+	MaxPending int
+
+	// MaxCompletedJobs caps how many StatusDone/StatusFailed jobs are kept
+	// around for Get lookups; the oldest are evicted once the cap is
+	// exceeded. Zero means unbounded.
+	MaxCompletedJobs int
+}
+
+// DefaultConfig matches typical ingest-burst sizing: small batches flushed
+// quickly, so interactive-ish jobs still see sub-second turnaround.
+func DefaultConfig() Config {
+	return Config{
+		BatchSize:        50,
+		MaxWait:          200 * time.Millisecond,
+		Workers:          4,
+		MaxPending:       10000,
+		MaxCompletedJobs: 10000,
+	}
+}
+
+// Queue is a bounded, durable job queue for dialect query execution.
+type Queue struct {
+	cfg      Config
+	executor Executor
+	wal      *WAL
+
+	mu           sync.Mutex
+	jobs         map[string]*Job
+	pending      map[string][]*Job // database -> jobs awaiting a batch flush
+	active       int               // queued + running jobs, bounded by cfg.MaxPending
+	completedIDs []string          // StatusDone/StatusFailed job IDs, oldest first
+
+	incoming chan struct{} // signals workers that pending has new work
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New creates a Queue backed by a WAL at walPath, replaying any jobs left
+// over from a prior process's shutdown so they are not lost. Jobs still
+// StatusQueued or StatusRunning at the time of the crash are re-queued;
+// StatusDone/StatusFailed jobs keep their cached result so GET lookups for
+// them keep working across a restart.
+func New(walPath string, executor Executor, cfg Config) (*Queue, error) {
+	wal, entries, err := openWAL(walPath)
+	if err != nil {
+		return nil, fmt.Errorf("open queue wal: %w", err)
+	}
+
+	q := &Queue{
+		cfg:      cfg,
+		executor: executor,
+		wal:      wal,
+		jobs:     make(map[string]*Job),
+		pending:  make(map[string][]*Job),
+		incoming: make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+
+	for _, job := range entries {
+		j := job
+		q.jobs[j.ID] = j
+		switch j.Status {
+		case StatusQueued, StatusRunning:
+			j.Status = StatusQueued
+			q.pending[j.Database] = append(q.pending[j.Database], j)
+			q.active++
+		case StatusDone, StatusFailed:
+			q.completedIDs = append(q.completedIDs, j.ID)
+		}
+	}
+	q.pruneCompletedLocked()
+
+	for i := 0; i < cfg.Workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	if len(q.pending) > 0 {
+		q.notify()
+	}
+
+	return q, nil
+}
+
+// Enqueue durably records a new job and returns its ID immediately; the
+// query itself runs asynchronously on the worker pool. It returns
+// ErrQueueFull without touching the WAL if the queue already holds
+// Config.MaxPending unfinished jobs.
+func (q *Queue) Enqueue(dialect, query, database string, id string) (*Job, error) {
+	q.mu.Lock()
+	if q.cfg.MaxPending > 0 && q.active >= q.cfg.MaxPending {
+		q.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+	q.active++
+	q.mu.Unlock()
+
+	job := &Job{
+		ID:        id,
+		Dialect:   dialect,
+		Query:     query,
+		Database:  database,
+		Status:    StatusQueued,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := q.wal.Append(job); err != nil {
+		q.mu.Lock()
+		q.active--
+		q.mu.Unlock()
+		return nil, fmt.Errorf("append job to wal: %w", err)
+	}
+
+	q.mu.Lock()
+	q.jobs[job.ID] = job
+	q.pending[database] = append(q.pending[database], job)
+	q.mu.Unlock()
+
+	q.notify()
+	return job, nil
+}
+
+// Get returns the current state of a job by ID.
+func (q *Queue) Get(id string) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	clone := *job
+	return &clone, nil
+}
+
+// Stop signals all workers to finish their current batch and exit.
+func (q *Queue) Stop() {
+	close(q.stop)
+	q.wg.Wait()
+}
+
+func (q *Queue) notify() {
+	select {
+	case q.incoming <- struct{}{}:
+	default:
+	}
+}
+
+// worker repeatedly waits for pending work, then drains and executes one
+// ready batch per database. Multiple workers may run concurrently, each
+// claiming a different database's batch, so throughput scales with the
+// number of distinct databases being written to concurrently.
+func (q *Queue) worker() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.cfg.MaxWait)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		case <-q.incoming:
+		case <-ticker.C:
+		}
+		q.flushReady()
+	}
+}
+
+// flushReady claims and runs every database's batch that is either full
+// (BatchSize reached) or has been waiting longer than MaxWait.
+func (q *Queue) flushReady() {
+	for {
+		database, batch := q.claimBatch()
+		if batch == nil {
+			return
+		}
+		q.runBatch(database, batch)
+	}
+}
+
+func (q *Queue) claimBatch() (string, []*Job) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for database, jobs := range q.pending {
+		if len(jobs) == 0 {
+			continue
+		}
+		ready := len(jobs) >= q.cfg.BatchSize || time.Since(jobs[0].CreatedAt) >= q.cfg.MaxWait
+		if !ready {
+			continue
+		}
+		delete(q.pending, database)
+		return database, jobs
+	}
+	return "", nil
+}
+
+// runBatch re-parses and executes each job in the batch via the registered
+// DialectHandler/QueryExecutor. The underlying QueryExecutor has no native
+// multi-statement entry point, so "batching" here means coalescing the
+// queue-side scheduling decision (one flush per database) rather than a
+// single combined Execute call; each job still gets its own Execute. The
+// jobs are claimed together, but each job's status changes are appended to
+// the WAL one job at a time as it runs.
+func (q *Queue) runBatch(database string, jobs []*Job) {
+	for _, job := range jobs {
+		q.mu.Lock()
+		job.Status = StatusRunning
+		job.UpdatedAt = time.Now()
+		q.mu.Unlock()
+		q.wal.Append(job)
+
+		result, err := q.executor.Execute(job.Dialect, job.Query, job.Database)
+
+		q.mu.Lock()
+		job.UpdatedAt = time.Now()
+		if err != nil {
+			job.Status = StatusFailed
+			job.Error = err.Error()
+		} else {
+			job.Status = StatusDone
+			job.Result = result
+		}
+		q.active--
+		q.completedIDs = append(q.completedIDs, job.ID)
+		q.pruneCompletedLocked()
+		q.mu.Unlock()
+		q.wal.Append(job)
+	}
+}
+
+// pruneCompletedLocked evicts the oldest completed jobs once more than
+// Config.MaxCompletedJobs are retained, so a long-running process doesn't
+// accumulate finished jobs in q.jobs forever. Callers must hold q.mu.
+func (q *Queue) pruneCompletedLocked() {
+	if q.cfg.MaxCompletedJobs <= 0 {
+		return
+	}
+	for len(q.completedIDs) > q.cfg.MaxCompletedJobs {
+		id := q.completedIDs[0]
+		q.completedIDs = q.completedIDs[1:]
+		delete(q.jobs, id)
+	}
+}
@@ -0,0 +1,91 @@
+package queue
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// WAL is an append-only, newline-delimited JSON log of Job state
+// transitions. It exists purely for crash recovery: Enqueue and every
+// status change append a new line, and New replays the file on startup to
+// reconstruct in-flight jobs so a restart doesn't silently drop them.
+type WAL struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// openWAL opens (creating if necessary) the WAL file at path and replays it
+// into a slice of Jobs reflecting their last-known state. Later entries for
+// the same job ID override earlier ones, same as any append-only log.
+func openWAL(path string) (*WAL, []*Job, error) {
+	jobs, err := replayWAL(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &WAL{file: file, enc: json.NewEncoder(file)}, jobs, nil
+}
+
+func replayWAL(path string) ([]*Job, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	byID := make(map[string]*Job)
+	order := make([]string, 0)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var job Job
+		if err := json.Unmarshal(scanner.Bytes(), &job); err != nil {
+			// A torn write from a crash mid-append; skip it and keep
+			// replaying rather than failing startup over one bad line.
+			continue
+		}
+		if _, seen := byID[job.ID]; !seen {
+			order = append(order, job.ID)
+		}
+		j := job
+		byID[job.ID] = &j
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*Job, 0, len(order))
+	for _, id := range order {
+		jobs = append(jobs, byID[id])
+	}
+	return jobs, nil
+}
+
+// Append durably writes job's current state as a new WAL entry.
+func (w *WAL) Append(job *Job) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.enc.Encode(job); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+// Close releases the underlying WAL file handle.
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
@@ -0,0 +1,219 @@
+package dialects
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Renderer renders a normalized ParsedQuery back into one dialect's native
+// query text - the mirror image of DialectHandler.Parse. Router's
+// /dialect/translate endpoint chains a source dialect's Parse with a target
+// dialect's Render so users can migrate off InfluxDB/Prometheus/Graphite/
+// OpenTSDB onto LumaDB incrementally, one query at a time, rather than all
+// at once.
+type Renderer interface {
+	Render(q *ParsedQuery) (string, error)
+}
+
+// formatDialectDuration renders d the way InfluxQL/Flux/PromQL literals do:
+// the largest whole unit that divides it evenly, falling back to seconds.
+func formatDialectDuration(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "0s"
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+}
+
+// influxQLRenderer renders a ParsedQuery as an InfluxQL SELECT statement.
+type influxQLRenderer struct{}
+
+func (influxQLRenderer) Render(q *ParsedQuery) (string, error) {
+	if len(q.Sources) == 0 {
+		return "", fmt.Errorf("influxql renderer: query has no source to select from")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	if len(q.Aggregations) > 0 {
+		parts := make([]string, 0, len(q.Aggregations))
+		for _, a := range q.Aggregations {
+			parts = append(parts, fmt.Sprintf("%s(%s)", a.Function, a.Column))
+		}
+		sb.WriteString(strings.Join(parts, ", "))
+	} else {
+		sb.WriteString("*")
+	}
+	fmt.Fprintf(&sb, ` FROM "%s"`, q.Sources[0].Name)
+
+	if clause := whereClause(q, "time", "'%s'", func(t time.Time) string { return t.Format(time.RFC3339) }); clause != "" {
+		sb.WriteString(" WHERE " + clause)
+	}
+
+	groupBy := append([]string{}, q.GroupBy...)
+	if q.TimeRange != nil && q.TimeRange.Duration > 0 {
+		groupBy = append([]string{fmt.Sprintf("time(%s)", formatDialectDuration(q.TimeRange.Duration))}, groupBy...)
+	}
+	if len(groupBy) > 0 {
+		sb.WriteString(" GROUP BY " + strings.Join(groupBy, ", "))
+	}
+
+	if q.Limit > 0 {
+		fmt.Fprintf(&sb, " LIMIT %d", q.Limit)
+	}
+
+	return sb.String(), nil
+}
+
+// sqlRenderer renders a ParsedQuery as a time_bucket-style SQL SELECT, the
+// same shape timescaleTranspiler parses back in the other direction.
+type sqlRenderer struct{}
+
+func (sqlRenderer) Render(q *ParsedQuery) (string, error) {
+	if len(q.Sources) == 0 {
+		return "", fmt.Errorf("sql renderer: query has no source to select from")
+	}
+
+	var selected []string
+	if q.TimeRange != nil && q.TimeRange.Duration > 0 {
+		selected = append(selected, fmt.Sprintf("time_bucket('%s', time) AS bucket", formatTimescaleInterval(q.TimeRange.Duration)))
+	}
+	for _, a := range q.Aggregations {
+		alias := a.Alias
+		if alias == "" {
+			alias = fmt.Sprintf("%s_%s", a.Function, a.Column)
+		}
+		selected = append(selected, fmt.Sprintf("%s(%s) AS %s", a.Function, a.Column, alias))
+	}
+	if len(selected) == 0 {
+		selected = []string{"*"}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "SELECT %s FROM %s", strings.Join(selected, ", "), q.Sources[0].Name)
+
+	if clause := whereClause(q, "time", "'%s'", func(t time.Time) string { return t.Format(time.RFC3339) }); clause != "" {
+		sb.WriteString(" WHERE " + clause)
+	}
+
+	groupBy := q.GroupBy
+	if q.TimeRange != nil && q.TimeRange.Duration > 0 {
+		groupBy = append([]string{"bucket"}, groupBy...)
+	}
+	if len(groupBy) > 0 {
+		sb.WriteString(" GROUP BY " + strings.Join(groupBy, ", "))
+	}
+
+	if q.Limit > 0 {
+		fmt.Fprintf(&sb, " LIMIT %d", q.Limit)
+	}
+
+	return sb.String(), nil
+}
+
+// formatTimescaleInterval renders d as a Postgres INTERVAL literal body,
+// e.g. "1 hour" - the inverse of parseTimescaleBucketWidth.
+func formatTimescaleInterval(d time.Duration) string {
+	switch {
+	case d%(7*24*time.Hour) == 0:
+		return fmt.Sprintf("%d week", d/(7*24*time.Hour))
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%d day", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%d hour", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%d minute", d/time.Minute)
+	default:
+		return fmt.Sprintf("%d second", d/time.Second)
+	}
+}
+
+// promQLRenderer renders a ParsedQuery as a PromQL vector selector with an
+// optional aggregation and range-vector/rate wrapper.
+type promQLRenderer struct{}
+
+func (promQLRenderer) Render(q *ParsedQuery) (string, error) {
+	if len(q.Sources) == 0 {
+		return "", fmt.Errorf("promql renderer: query has no metric to select")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(q.Sources[0].Name)
+
+	if len(q.Filters) > 0 {
+		parts := make([]string, 0, len(q.Filters))
+		for _, f := range q.Filters {
+			parts = append(parts, fmt.Sprintf("%s%s%q", f.Column, promQLMatchOp(f.Operator), fmt.Sprintf("%v", f.Value)))
+		}
+		fmt.Fprintf(&sb, "{%s}", strings.Join(parts, ","))
+	}
+
+	if q.TimeRange != nil && q.TimeRange.Duration > 0 {
+		fmt.Fprintf(&sb, "[%s]", formatDialectDuration(q.TimeRange.Duration))
+	}
+
+	selector := sb.String()
+	if len(q.Aggregations) == 0 {
+		return selector, nil
+	}
+
+	agg := q.Aggregations[0]
+	fn := promQLAggFunctions[strings.ToLower(agg.Function)]
+	if fn == "" {
+		fn = strings.ToLower(agg.Function)
+	}
+
+	var groupBy string
+	if len(q.GroupBy) > 0 {
+		groupBy = fmt.Sprintf(" by (%s)", strings.Join(q.GroupBy, ", "))
+	}
+
+	return fmt.Sprintf("%s(%s)%s", fn, selector, groupBy), nil
+}
+
+// promQLAggFunctions maps the aggregation function names InfluxQL/Flux/SQL
+// use onto PromQL's own names where they differ.
+var promQLAggFunctions = map[string]string{
+	"mean":                    "avg",
+	"non_negative_derivative": "rate",
+}
+
+func promQLMatchOp(op string) string {
+	switch op {
+	case "!=":
+		return "!="
+	case "=~":
+		return "=~"
+	case "!~":
+		return "!~"
+	default:
+		return "="
+	}
+}
+
+// whereClause joins a ParsedQuery's TimeRange and Filters into one AND-ed
+// clause body (without the leading "WHERE "), shared by the InfluxQL and SQL
+// renderers since both use the same "field op value" condition syntax.
+func whereClause(q *ParsedQuery, timeField, timeFormat string, formatTime func(time.Time) string) string {
+	var clauses []string
+	if q.TimeRange != nil {
+		if !q.TimeRange.Start.IsZero() {
+			clauses = append(clauses, fmt.Sprintf("%s >= "+timeFormat, timeField, formatTime(q.TimeRange.Start)))
+		}
+		if !q.TimeRange.End.IsZero() {
+			clauses = append(clauses, fmt.Sprintf("%s <= "+timeFormat, timeField, formatTime(q.TimeRange.End)))
+		}
+	}
+	for _, f := range q.Filters {
+		clauses = append(clauses, fmt.Sprintf("%s %s '%v'", f.Column, f.Operator, f.Value))
+	}
+	return strings.Join(clauses, " AND ")
+}
@@ -6,13 +6,19 @@
 package dialects
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/lumadb/cluster/pkg/dialects/queue"
 )
 
 // Dialect represents a supported query dialect
@@ -66,10 +72,14 @@ type ExecutionStats struct {
 
 // Router handles multi-dialect query routing
 type Router struct {
-	mu       sync.RWMutex
-	handlers map[Dialect]DialectHandler
-	detector *DialectDetector
-	executor QueryExecutor
+	mu         sync.RWMutex
+	handlers   map[Dialect]DialectHandler
+	renderers  map[Dialect]Renderer
+	detector   *DialectDetector
+	executor   QueryExecutor
+	authorizer *Authorizer
+	jobQueue   *queue.Queue
+	scheduler  *QueryScheduler
 }
 
 // DialectHandler processes queries for a specific dialect
@@ -87,6 +97,27 @@ type QueryExecutor interface {
 	Execute(query *ParsedQuery, opts *ExecuteOptions) (*QueryResult, error)
 }
 
+// QueryIntent classifies what a parsed query does to storage, so a caller
+// like LumaGRPCServer.Execute can decide whether to run it read-only or
+// route it through Raft.
+type QueryIntent string
+
+const (
+	// IntentRead is a query that only reads existing data (SELECT, PromQL
+	// vector/matrix selectors, SHOW statements). Safe to run on any node.
+	IntentRead QueryIntent = "read"
+	// IntentWrite mutates documents (INSERT, DELETE, Flux `to()`). Must be
+	// replicated via Raft.
+	IntentWrite QueryIntent = "write"
+	// IntentDDL changes schema or retention (DROP, CREATE DATABASE, CREATE
+	// STABLE, CREATE CONTINUOUS QUERY). Must be replicated via Raft.
+	IntentDDL QueryIntent = "ddl"
+	// IntentAdmin is a cluster/administrative operation (e.g. user or
+	// permission management) that isn't representable as a read or a data
+	// mutation. Must be replicated via Raft.
+	IntentAdmin QueryIntent = "admin"
+)
+
 // ParsedQuery represents a parsed and normalized query
 type ParsedQuery struct {
 	Dialect       Dialect
@@ -94,12 +125,27 @@ type ParsedQuery struct {
 	Database      string
 	Sources       []DataSource
 	TimeRange     *TimeRange
+	QueryRange    *QueryRange
 	Filters       []Filter
 	Aggregations  []Aggregation
 	GroupBy       []string
 	OrderBy       []OrderBy
 	Limit         int64
 	Offset        int64
+	// Intent says whether OriginalQuery reads or mutates storage, set by
+	// the dialect's own Parse (falling back to a keyword check for
+	// statement kinds the dialect's own parser - when it has a real one -
+	// doesn't represent as an AST node, e.g. InfluxQL INSERT/DELETE/DROP).
+	// Every DialectHandler.Parse in this package sets it explicitly; treat
+	// the zero value ("") as "unknown", not as IntentRead.
+	Intent QueryIntent
+	// AST is the dialect's own parse tree, when that dialect's handler
+	// builds one (currently only PromQLHandler, via the promql package).
+	// ParsedQuery's flat fields above are a lossy projection of it -
+	// downstream evaluators that need the full expression tree (nested
+	// binary ops, vector matching, subqueries) should type-assert this
+	// rather than trying to reconstruct it from Sources/Filters/etc.
+	AST interface{}
 }
 
 // DataSource represents a data source (table, metric, measurement)
@@ -116,6 +162,15 @@ type TimeRange struct {
 	Duration time.Duration
 }
 
+// QueryRange distinguishes a PromQL range query ("/api/v1/query_range")
+// from an instant one: its presence on a ParsedQuery is what tells
+// PromQLHandler.FormatResponse to render a matrix instead of a vector.
+type QueryRange struct {
+	Start time.Time
+	End   time.Time
+	Step  time.Duration
+}
+
 // Filter represents a filter condition
 type Filter struct {
 	Column   string
@@ -143,6 +198,34 @@ type QueryResult struct {
 	Stats   ExecutionStats
 }
 
+// QueryResults is the per-statement envelope a ';'-separated batch query
+// (see ParseStatements) returns: one StatementResult per statement,
+// addressed by StatementID, so a parse or execution error on one
+// statement doesn't keep the others in the same batch from reporting a
+// result - the same tolerance InfluxDB's own
+// {"results":[{"statement_id":0,...},{"statement_id":1,"error":"..."}]}
+// response gives a batch.
+type QueryResults struct {
+	Statements []StatementResult
+}
+
+// StatementResult is one statement's outcome within a QueryResults batch.
+// Result and Err are mutually exclusive: a statement that failed to parse
+// or execute carries Err and a nil Result.
+type StatementResult struct {
+	StatementID int
+	Result      *QueryResult
+	Err         error
+}
+
+// BatchFormatter is implemented by a DialectHandler whose response shape
+// changes for a ';'-separated batch of statements - today only
+// InfluxQLHandler, whose {"results":[...]} envelope addresses each
+// statement by StatementID the way real InfluxDB clients expect.
+type BatchFormatter interface {
+	FormatResults(results QueryResults, format string) (interface{}, error)
+}
+
 // ColumnMeta represents column metadata
 type ColumnMeta struct {
 	Name   string
@@ -161,9 +244,10 @@ type ExecuteOptions struct {
 // NewRouter creates a new dialect router
 func NewRouter(executor QueryExecutor) *Router {
 	r := &Router{
-		handlers: make(map[Dialect]DialectHandler),
-		detector: NewDialectDetector(),
-		executor: executor,
+		handlers:  make(map[Dialect]DialectHandler),
+		renderers: make(map[Dialect]Renderer),
+		detector:  NewDialectDetector(),
+		executor:  executor,
 	}
 
 	// Register default handlers
@@ -172,6 +256,13 @@ func NewRouter(executor QueryExecutor) *Router {
 	r.RegisterHandler(&PromQLHandler{})
 	r.RegisterHandler(&SQLHandler{})
 
+	// Register default renderers, used by /dialect/translate to go the
+	// other direction: a normalized ParsedQuery back to a target dialect's
+	// native text.
+	r.RegisterRenderer(DialectInfluxQL, influxQLRenderer{})
+	r.RegisterRenderer(DialectSQL, sqlRenderer{})
+	r.RegisterRenderer(DialectPromQL, promQLRenderer{})
+
 	return r
 }
 
@@ -190,15 +281,213 @@ func (r *Router) GetHandler(dialect Dialect) (DialectHandler, bool) {
 	return h, ok
 }
 
+// RegisterRenderer adds or replaces the Renderer used to translate a
+// ParsedQuery into dialect's native text.
+func (r *Router) RegisterRenderer(dialect Dialect, renderer Renderer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.renderers[dialect] = renderer
+}
+
+// GetRenderer returns the renderer for a dialect
+func (r *Router) GetRenderer(dialect Dialect) (Renderer, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rend, ok := r.renderers[dialect]
+	return rend, ok
+}
+
+// EnableQueue backs the router with a durable job queue at walPath for
+// POST /dialect/enqueue and GET /dialect/jobs/{id}, so bursty, non-
+// interactive writes don't have to hold an HTTP connection open through
+// QueryExecutor.Execute. Jobs queued but not yet finished at process exit
+// are replayed from the WAL the next time EnableQueue runs.
+func (r *Router) EnableQueue(walPath string, cfg queue.Config) error {
+	q, err := queue.New(walPath, routerQueueExecutor{r}, cfg)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.jobQueue = q
+	r.mu.Unlock()
+	return nil
+}
+
+// routerQueueExecutor adapts Router's own handler/executor pair into the
+// queue.Executor interface so queue.Queue never has to know about
+// DialectHandler or ParsedQuery.
+type routerQueueExecutor struct {
+	router *Router
+}
+
+func (e routerQueueExecutor) Execute(dialect, query, database string) (json.RawMessage, error) {
+	handler, ok := e.router.GetHandler(Dialect(dialect))
+	if !ok {
+		return nil, fmt.Errorf("no handler registered for dialect: %s", dialect)
+	}
+
+	result, err := e.router.RunQuery(Dialect(dialect), query, database)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := handler.FormatResponse(result, "json")
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(response)
+}
+
+// RunQuery parses query text as dialect and executes it, returning the raw
+// QueryResult rather than a dialect-formatted response - the entry point
+// other packages (the GraphQL façade, in particular) use to run a query
+// through this Router without going through an HTTP handler.
+func (r *Router) RunQuery(dialect Dialect, query, database string) (*QueryResult, error) {
+	handler, ok := r.GetHandler(dialect)
+	if !ok {
+		return nil, fmt.Errorf("no handler registered for dialect: %s", dialect)
+	}
+
+	parsed, err := handler.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.executor.Execute(parsed, &ExecuteOptions{Database: database})
+}
+
+// generateJobID returns a random 16-byte hex job identifier.
+func generateJobID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// handleEnqueue accepts {query, dialect, database}, durably records it on
+// the job queue, and returns its job id without waiting for execution.
+func (r *Router) handleEnqueue(w http.ResponseWriter, req *http.Request) {
+	r.mu.RLock()
+	q := r.jobQueue
+	r.mu.RUnlock()
+	if q == nil {
+		r.writeError(w, http.StatusServiceUnavailable, "job queue not enabled", "unavailable")
+		return
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		r.writeError(w, http.StatusBadRequest, "failed to read request body", "bad_request")
+		return
+	}
+
+	var enqueueReq struct {
+		Query    string `json:"query"`
+		Dialect  string `json:"dialect"`
+		Database string `json:"database"`
+	}
+	if err := json.Unmarshal(body, &enqueueReq); err != nil {
+		r.writeError(w, http.StatusBadRequest, "invalid request body", "bad_request")
+		return
+	}
+	if enqueueReq.Query == "" || enqueueReq.Dialect == "" {
+		r.writeError(w, http.StatusBadRequest, "missing query or dialect", "bad_request")
+		return
+	}
+
+	id, err := generateJobID()
+	if err != nil {
+		r.writeError(w, http.StatusInternalServerError, "failed to generate job id", "internal")
+		return
+	}
+
+	job, err := q.Enqueue(enqueueReq.Dialect, enqueueReq.Query, enqueueReq.Database, id)
+	if errors.Is(err, queue.ErrQueueFull) {
+		r.writeError(w, http.StatusServiceUnavailable, err.Error(), "queue_full")
+		return
+	}
+	if err != nil {
+		r.writeError(w, http.StatusInternalServerError, err.Error(), "internal")
+		return
+	}
+
+	r.writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"status": "queued",
+		"id":     job.ID,
+	})
+}
+
+// handleJobStatus serves GET /dialect/jobs/{id}: the job's current status
+// and, once done, its cached response.
+func (r *Router) handleJobStatus(w http.ResponseWriter, req *http.Request) {
+	r.mu.RLock()
+	q := r.jobQueue
+	r.mu.RUnlock()
+	if q == nil {
+		r.writeError(w, http.StatusServiceUnavailable, "job queue not enabled", "unavailable")
+		return
+	}
+
+	id := strings.TrimPrefix(req.URL.Path, "/dialect/jobs/")
+	if id == "" {
+		r.writeError(w, http.StatusBadRequest, "missing job id", "bad_request")
+		return
+	}
+
+	job, err := q.Get(id)
+	if err != nil {
+		r.writeError(w, http.StatusNotFound, err.Error(), "not_found")
+		return
+	}
+
+	r.writeJSON(w, http.StatusOK, job)
+}
+
+// SetAuthorizer installs the Authorizer ServeHTTP runs before every handler.
+// Pass nil (the default) to leave the router open, matching its behavior
+// before authentication existed.
+func (r *Router) SetAuthorizer(a *Authorizer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.authorizer = a
+}
+
 // ServeHTTP implements http.Handler for the router
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	// Route based on URL path
 	path := req.URL.Path
 
+	r.mu.RLock()
+	authorizer := r.authorizer
+	r.mu.RUnlock()
+
+	if authorizer != nil && !authorizer.DisableAuthentication {
+		user, err := authorizer.Authorize(req, dialectForPath(path))
+		if err != nil {
+			r.writeError(w, http.StatusUnauthorized, err.Error(), "unauthorized")
+			return
+		}
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+	}
+
 	switch {
+	case strings.HasPrefix(path, "/api/v1/query_range"):
+		// PromQL-style range query, evaluated over [start, end] at Step
+		r.handlePromQLRange(w, req)
 	case strings.HasPrefix(path, "/api/v1/query"):
-		// PromQL-style endpoint
+		// PromQL-style instant query
 		r.handlePromQL(w, req)
+	case strings.HasPrefix(path, "/api/v1/series"):
+		// PromQL series metadata endpoint
+		r.handlePromQLSeries(w, req)
+	case strings.HasPrefix(path, "/api/v1/labels"):
+		// PromQL label-name metadata endpoint
+		r.handlePromQLLabels(w, req)
+	case strings.HasPrefix(path, "/api/v1/label/"):
+		// PromQL label-value metadata endpoint
+		r.handlePromQLLabelValues(w, req)
 	case strings.HasPrefix(path, "/query"):
 		// InfluxQL-style endpoint
 		r.handleInfluxQL(w, req)
@@ -220,6 +509,24 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	case strings.HasPrefix(path, "/dialect/auto"):
 		// Auto-detect and execute
 		r.handleAutoDetect(w, req)
+	case strings.HasPrefix(path, "/dialect/translate"):
+		// Translate a query from one dialect's text into another's
+		r.handleTranslate(w, req)
+	case strings.HasPrefix(path, "/dialect/enqueue"):
+		// Buffer a non-interactive query behind the durable job queue
+		r.handleEnqueue(w, req)
+	case strings.HasPrefix(path, "/dialect/jobs/"):
+		// Look up a queued job's status/result
+		r.handleJobStatus(w, req)
+	case strings.HasPrefix(path, "/dialect/schedules/") && req.Method == http.MethodDelete:
+		// Remove a scheduled/materialized query
+		r.handleDeleteSchedule(w, req)
+	case strings.HasPrefix(path, "/dialect/schedules"):
+		// List registered scheduled/materialized queries
+		r.handleListSchedules(w, req)
+	case strings.HasPrefix(path, "/dialect/schedule"):
+		// Register a new recurring scheduled/materialized query
+		r.handleSchedule(w, req)
 	default:
 		// Generic SQL endpoint
 		r.handleSQL(w, req)
@@ -252,26 +559,27 @@ func (r *Router) handlePromQL(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// Parse time parameters
+	// An instant query evaluates at "time" (default now); range evaluation
+	// over [start, end] is handled separately by handlePromQLRange.
 	opts := &ExecuteOptions{}
 	if t := req.URL.Query().Get("time"); t != "" {
-		// Parse instant query time
-	}
-	if start := req.URL.Query().Get("start"); start != "" {
-		// Parse range start
-	}
-	if end := req.URL.Query().Get("end"); end != "" {
-		// Parse range end
+		if at, ok := parsePromTimestamp(t); ok {
+			parsed.TimeRange = &TimeRange{Start: at, End: at}
+		}
 	}
 	if step := req.URL.Query().Get("step"); step != "" {
 		if d, err := time.ParseDuration(step); err == nil {
 			opts.Step = d
 		}
 	}
-	if timeout := req.URL.Query().Get("timeout"); timeout != "" {
-		if d, err := time.ParseDuration(timeout); err == nil {
-			opts.Timeout = d
-		}
+	opts.Timeout = parseTimeout(req.URL.Query().Get("timeout"))
+
+	ctx, cancel := requestContext(req, opts)
+	defer cancel()
+
+	if se, ok := r.streamExecutor(); ok {
+		r.streamPromQL(ctx, w, se, parsed, opts)
+		return
 	}
 
 	result, err := r.executor.Execute(parsed, opts)
@@ -280,7 +588,14 @@ func (r *Router) handlePromQL(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	response, err := handler.FormatResponse(result, "prometheus")
+	// A query with no metric selector at all (e.g. a bare numeric
+	// expression) evaluates to a scalar rather than an instant vector.
+	formatHint := "prometheus_instant"
+	if len(parsed.Sources) == 0 {
+		formatHint = "prometheus_scalar"
+	}
+
+	response, err := handler.FormatResponse(result, formatHint)
 	if err != nil {
 		r.writeError(w, http.StatusInternalServerError, err.Error(), "internal")
 		return
@@ -311,23 +626,66 @@ func (r *Router) handleInfluxQL(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	parsed, err := handler.Parse(query)
-	if err != nil {
-		r.writeError(w, http.StatusBadRequest, err.Error(), "bad_request")
+	opts := &ExecuteOptions{
+		Database: req.URL.Query().Get("db"),
+		Timeout:  parseTimeout(req.URL.Query().Get("timeout")),
+	}
+
+	ctx, cancel := requestContext(req, opts)
+	defer cancel()
+
+	parsedStmts, parseErrs := ParseStatements(handler, query)
+
+	// The common case - one statement, no trailing ';' batch - keeps the
+	// streaming path available, which a multi-statement batch can't use
+	// since it has to collect every statement's result before responding.
+	if len(parsedStmts) == 1 {
+		if parseErrs[0] != nil {
+			r.writeError(w, http.StatusBadRequest, parseErrs[0].Error(), "bad_request")
+			return
+		}
+
+		if se, ok := r.streamExecutor(); ok {
+			r.streamInfluxQL(ctx, w, se, parsedStmts[0], opts)
+			return
+		}
+
+		result, err := r.executor.Execute(parsedStmts[0], opts)
+		if err != nil {
+			r.writeError(w, http.StatusInternalServerError, err.Error(), "execution")
+			return
+		}
+
+		response, err := handler.FormatResponse(result, "influxdb")
+		if err != nil {
+			r.writeError(w, http.StatusInternalServerError, err.Error(), "internal")
+			return
+		}
+
+		r.writeJSON(w, http.StatusOK, response)
 		return
 	}
 
-	opts := &ExecuteOptions{
-		Database: req.URL.Query().Get("db"),
+	results := QueryResults{Statements: make([]StatementResult, len(parsedStmts))}
+	for i, parsed := range parsedStmts {
+		sr := StatementResult{StatementID: i}
+		if parseErrs[i] != nil {
+			sr.Err = parseErrs[i]
+		} else if result, err := r.executor.Execute(parsed, opts); err != nil {
+			sr.Err = err
+		} else {
+			sr.Result = result
+		}
+		results.Statements[i] = sr
 	}
 
-	result, err := r.executor.Execute(parsed, opts)
-	if err != nil {
-		r.writeError(w, http.StatusInternalServerError, err.Error(), "execution")
+	batcher, ok := handler.(BatchFormatter)
+	if !ok {
+		r.writeError(w, http.StatusInternalServerError, "InfluxQL handler does not support batched statements", "internal")
 		return
 	}
 
-	response, err := handler.FormatResponse(result, "influxdb")
+	response, err := batcher.FormatResults(results, "influxdb")
 	if err != nil {
 		r.writeError(w, http.StatusInternalServerError, err.Error(), "internal")
 		return
@@ -372,6 +730,15 @@ func (r *Router) handleFlux(w http.ResponseWriter, req *http.Request) {
 	if org := req.Header.Get("X-Org"); org != "" {
 		opts.Database = org
 	}
+	opts.Timeout = parseTimeout(req.Header.Get("X-Timeout"))
+
+	ctx, cancel := requestContext(req, opts)
+	defer cancel()
+
+	if se, ok := r.streamExecutor(); ok {
+		r.streamFlux(ctx, w, se, parsed, opts)
+		return
+	}
 
 	result, err := r.executor.Execute(parsed, opts)
 	if err != nil {
@@ -603,6 +970,15 @@ func (r *Router) handleSQL(w http.ResponseWriter, req *http.Request) {
 
 	opts := &ExecuteOptions{
 		Database: req.URL.Query().Get("database"),
+		Timeout:  parseTimeout(req.URL.Query().Get("timeout")),
+	}
+
+	ctx, cancel := requestContext(req, opts)
+	defer cancel()
+
+	if se, ok := r.streamExecutor(); ok {
+		r.streamSQL(ctx, w, se, parsed, opts)
+		return
 	}
 
 	result, err := r.executor.Execute(parsed, opts)
@@ -684,6 +1060,83 @@ func (r *Router) handleAutoDetect(w http.ResponseWriter, req *http.Request) {
 	})
 }
 
+// handleTranslate converts a query written in one dialect into the native
+// text of another, without executing it - e.g. migrating saved InfluxQL
+// queries to SQL ahead of a client cutover.
+func (r *Router) handleTranslate(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		r.writeError(w, http.StatusBadRequest, "failed to read request body", "bad_request")
+		return
+	}
+
+	var translateReq struct {
+		Query string  `json:"query"`
+		From  Dialect `json:"from"`
+		To    Dialect `json:"to"`
+	}
+	if err := json.Unmarshal(body, &translateReq); err != nil {
+		r.writeError(w, http.StatusBadRequest, "invalid request body", "bad_request")
+		return
+	}
+
+	if translateReq.Query == "" {
+		r.writeError(w, http.StatusBadRequest, "missing query", "bad_request")
+		return
+	}
+	if translateReq.To == "" {
+		r.writeError(w, http.StatusBadRequest, "missing target dialect", "bad_request")
+		return
+	}
+
+	from := translateReq.From
+	var confidence float64
+	if from == "" {
+		from, confidence = r.detector.Detect(translateReq.Query)
+	}
+
+	handler, ok := r.GetHandler(from)
+	if !ok {
+		r.writeError(w, http.StatusBadRequest, fmt.Sprintf("no handler for source dialect: %s", from), "bad_request")
+		return
+	}
+
+	if _, ok := r.GetRenderer(translateReq.To); !ok {
+		r.writeError(w, http.StatusBadRequest, fmt.Sprintf("no renderer for target dialect: %s", translateReq.To), "bad_request")
+		return
+	}
+
+	parsed, err := handler.Parse(translateReq.Query)
+	if err != nil {
+		r.writeError(w, http.StatusBadRequest, err.Error(), "bad_request")
+		return
+	}
+
+	translated, err := NewTranslator(r).Translate(parsed, translateReq.To)
+	if err != nil {
+		var untranslatable *ErrUntranslatable
+		if errors.As(err, &untranslatable) {
+			r.writeError(w, http.StatusUnprocessableEntity, untranslatable.Error(), "untranslatable")
+			return
+		}
+		r.writeError(w, http.StatusInternalServerError, err.Error(), "render_error")
+		return
+	}
+
+	response := map[string]interface{}{
+		"status":     "success",
+		"from":       from,
+		"to":         translateReq.To,
+		"translated": translated,
+		"parsed":     parsed,
+	}
+	if translateReq.From == "" {
+		response["detected_confidence"] = confidence
+	}
+
+	r.writeJSON(w, http.StatusOK, response)
+}
+
 // writeJSON writes a JSON response
 func (r *Router) writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -208,6 +209,92 @@ func TestPromQLHandler_Parse(t *testing.T) {
 	}
 }
 
+func TestPromQLHandler_Parse_AlwaysRead(t *testing.T) {
+	handler := &PromQLHandler{}
+
+	for _, query := range []string{
+		`rate(http_requests_total[5m])`,
+		`up`,
+		`sum(rate(errors_total[1m])) by (job)`,
+	} {
+		parsed, err := handler.Parse(query)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", query, err)
+		}
+		if parsed.Intent != IntentRead {
+			t.Errorf("Parse(%q) Intent = %v, want %v (PromQL has no write syntax)", query, parsed.Intent, IntentRead)
+		}
+	}
+}
+
+func TestInfluxQLHandler_Parse_Intent(t *testing.T) {
+	handler := &InfluxQLHandler{}
+
+	tests := []struct {
+		query string
+		want  QueryIntent
+	}{
+		{`SELECT mean("value") FROM "cpu" LIMIT 100`, IntentRead},
+		{`SHOW MEASUREMENTS`, IntentRead},
+		{`INSERT cpu,host=server01 value=0.64`, IntentWrite},
+		{`DELETE FROM cpu WHERE time < now() - 1d`, IntentWrite},
+		{`DROP MEASUREMENT cpu`, IntentDDL},
+		{`CREATE DATABASE mydb`, IntentDDL},
+	}
+
+	for _, tt := range tests {
+		parsed, err := handler.Parse(tt.query)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.query, err)
+		}
+		if parsed.Intent != tt.want {
+			t.Errorf("Parse(%q) Intent = %v, want %v", tt.query, parsed.Intent, tt.want)
+		}
+	}
+}
+
+func TestSQLHandler_Parse_Intent(t *testing.T) {
+	handler := &SQLHandler{}
+
+	tests := []struct {
+		query string
+		want  QueryIntent
+	}{
+		{`SELECT avg(value) FROM meters INTERVAL(10s)`, IntentRead},
+		{`CREATE STABLE meters (ts TIMESTAMP, value FLOAT) TAGS (location NCHAR(20))`, IntentDDL},
+		{`INSERT INTO meters VALUES (now, 1.0)`, IntentWrite},
+		{`DROP TABLE meters`, IntentDDL},
+	}
+
+	for _, tt := range tests {
+		parsed, err := handler.Parse(tt.query)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.query, err)
+		}
+		if parsed.Intent != tt.want {
+			t.Errorf("Parse(%q) Intent = %v, want %v", tt.query, parsed.Intent, tt.want)
+		}
+	}
+}
+
+func TestFluxHandler_Parse_Intent(t *testing.T) {
+	handler := &FluxHandler{}
+
+	read := `from(bucket: "my-bucket") |> range(start: -1h)`
+	if parsed, err := handler.Parse(read); err != nil {
+		t.Fatalf("Parse(%q) error = %v", read, err)
+	} else if parsed.Intent != IntentRead {
+		t.Errorf("Parse(%q) Intent = %v, want %v", read, parsed.Intent, IntentRead)
+	}
+
+	write := `from(bucket: "my-bucket") |> range(start: -1h) |> to(bucket: "downsampled")`
+	if parsed, err := handler.Parse(write); err != nil {
+		t.Fatalf("Parse(%q) error = %v", write, err)
+	} else if parsed.Intent != IntentWrite {
+		t.Errorf("Parse(%q) Intent = %v, want %v", write, parsed.Intent, IntentWrite)
+	}
+}
+
 func TestRouter_PromQLEndpoint(t *testing.T) {
 	executor := &MockExecutor{}
 	router := NewRouter(executor)
@@ -296,3 +383,126 @@ func TestRouter_MissingQuery(t *testing.T) {
 		t.Errorf("Response status = %v, want error", resp.Status)
 	}
 }
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "single statement, no trailing semicolon",
+			query: `SELECT mean("value") FROM "cpu"`,
+			want:  []string{`SELECT mean("value") FROM "cpu"`},
+		},
+		{
+			name:  "two statements",
+			query: `SHOW DATABASES; SHOW MEASUREMENTS FROM "x"`,
+			want:  []string{`SHOW DATABASES`, `SHOW MEASUREMENTS FROM "x"`},
+		},
+		{
+			name:  "semicolon inside quoted string is not a split point",
+			query: `SELECT * FROM "cpu" WHERE host = 'a;b'`,
+			want:  []string{`SELECT * FROM "cpu" WHERE host = 'a;b'`},
+		},
+		{
+			name:  "line and block comments are stripped",
+			query: "SHOW DATABASES; -- list databases\nSHOW /* inline */ MEASUREMENTS",
+			want:  []string{"SHOW DATABASES", "SHOW  MEASUREMENTS"},
+		},
+		{
+			name:  "trailing semicolon and blank statements are dropped",
+			query: `SHOW DATABASES;;  `,
+			want:  []string{`SHOW DATABASES`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitStatements(tt.query)
+			if len(got) != len(tt.want) {
+				t.Fatalf("SplitStatements(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+			for i := range got {
+				if strings.TrimSpace(got[i]) != tt.want[i] {
+					t.Errorf("SplitStatements(%q)[%d] = %q, want %q", tt.query, i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRouter_InfluxQLEndpoint_MultiStatement(t *testing.T) {
+	executor := &MockExecutor{}
+	router := NewRouter(executor)
+
+	req := httptest.NewRequest("GET", `/query?q=SHOW+DATABASES%3BSHOW+MEASUREMENTS`, nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("ServeHTTP() status = %v, want %v, body = %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var resp struct {
+		Results []struct {
+			StatementID int `json:"statement_id"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(resp.Results) != 2 {
+		t.Fatalf("Results count = %v, want 2", len(resp.Results))
+	}
+	if resp.Results[0].StatementID != 0 || resp.Results[1].StatementID != 1 {
+		t.Errorf("Results statement_ids = %v, want [0 1]", resp.Results)
+	}
+}
+
+func TestRouter_TranslateEndpoint_PromQLRateToInfluxQL(t *testing.T) {
+	executor := &MockExecutor{}
+	router := NewRouter(executor)
+
+	body := `{"query": "rate(http_requests_total{job=\"api\"}[5m])", "from": "promql", "to": "influxql"}`
+	req := httptest.NewRequest("POST", "/dialect/translate", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("ServeHTTP() status = %v, want %v, body = %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var resp map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	translated, _ := resp["translated"].(string)
+	if !strings.Contains(translated, "non_negative_derivative(mean(\"value\"), 1s)") {
+		t.Errorf("translated = %q, want it to contain non_negative_derivative(mean(\"value\"), 1s)", translated)
+	}
+	if !strings.Contains(translated, `"job"='api'`) {
+		t.Errorf("translated = %q, want it to carry the job filter", translated)
+	}
+}
+
+func TestRouter_TranslateEndpoint_Untranslatable(t *testing.T) {
+	executor := &MockExecutor{}
+	router := NewRouter(executor)
+
+	body := `{"query": "histogram_quantile(0.9, rate(http_request_duration_seconds_bucket[5m]))", "from": "promql", "to": "sql"}`
+	req := httptest.NewRequest("POST", "/dialect/translate", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("ServeHTTP() status = %v, want %v, body = %s", w.Code, http.StatusUnprocessableEntity, w.Body.String())
+	}
+}
@@ -0,0 +1,319 @@
+package dialects
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lumadb/cluster/pkg/cluster"
+	"github.com/lumadb/cluster/pkg/platform/auth"
+	"github.com/lumadb/cluster/pkg/platform/cron"
+)
+
+// scheduledQueriesCollection is a shared_-prefixed system collection (see
+// auth.SystemNamespace) holding every ScheduledQuery definition, so they
+// survive a restart the same way any other document does.
+const scheduledQueriesCollection = "shared_scheduled_queries"
+
+// ScheduledQuery is a ParsedQuery-by-way-of-its-source-text registered to
+// run on a recurring cron schedule, continuous-query/recording-rule style:
+// on each tick it's re-parsed and executed, optionally writing its result
+// into MaterializeInto.
+type ScheduledQuery struct {
+	ID              string    `json:"_id"`
+	Dialect         string    `json:"dialect"`
+	Query           string    `json:"query"`
+	CronExpr        string    `json:"cron"`
+	Database        string    `json:"database,omitempty"`
+	MaterializeInto string    `json:"materializeInto,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// QueryScheduler registers ScheduledQuery definitions on a cron.Scheduler,
+// persisting them through cluster metadata so they survive a restart, and
+// runs each on every tick through the same DialectHandler/QueryExecutor
+// path a synchronous dialect endpoint would use.
+type QueryScheduler struct {
+	router *Router
+	node   *cluster.Node
+	cron   *cron.Scheduler
+
+	mu       sync.Mutex
+	cronJobs map[string]string // ScheduledQuery.ID -> cron.Scheduler job handle
+}
+
+// NewQueryScheduler creates a scheduler that runs queries against router's
+// registered handlers/executor, ticking through cronScheduler and
+// persisting definitions in node's cluster metadata.
+func NewQueryScheduler(router *Router, node *cluster.Node, cronScheduler *cron.Scheduler) *QueryScheduler {
+	return &QueryScheduler{
+		router:   router,
+		node:     node,
+		cron:     cronScheduler,
+		cronJobs: make(map[string]string),
+	}
+}
+
+// Register persists sq and schedules it on the cron.Scheduler. Passing an
+// ID that already exists replaces the previous definition's cron job.
+func (s *QueryScheduler) Register(sq ScheduledQuery) (*ScheduledQuery, error) {
+	if sq.Dialect == "" || sq.Query == "" || sq.CronExpr == "" {
+		return nil, fmt.Errorf("query, dialect, and cron are all required")
+	}
+	if sq.ID == "" {
+		id, err := generateJobID()
+		if err != nil {
+			return nil, err
+		}
+		sq.ID = id
+	}
+	sq.CreatedAt = time.Now()
+
+	doc, err := scheduledQueryToDoc(sq)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.node.InsertDocument(scheduledQueriesCollection, doc); err != nil {
+		return nil, fmt.Errorf("persist scheduled query: %w", err)
+	}
+
+	s.mu.Lock()
+	if oldJobID, ok := s.cronJobs[sq.ID]; ok {
+		s.cron.Remove(oldJobID)
+	}
+	s.mu.Unlock()
+
+	jobID, err := s.cron.Schedule(sq.CronExpr, func() { s.run(sq) })
+	if err != nil {
+		return nil, fmt.Errorf("schedule cron job: %w", err)
+	}
+
+	s.mu.Lock()
+	s.cronJobs[sq.ID] = jobID
+	s.mu.Unlock()
+
+	return &sq, nil
+}
+
+// run executes sq's query once, writing the result into MaterializeInto if
+// one was configured. Errors are swallowed without being logged or
+// returned; a single missed tick shouldn't take the scheduler down.
+func (s *QueryScheduler) run(sq ScheduledQuery) {
+	handler, ok := s.router.GetHandler(Dialect(sq.Dialect))
+	if !ok {
+		return
+	}
+	parsed, err := handler.Parse(sq.Query)
+	if err != nil {
+		return
+	}
+	result, err := s.router.executor.Execute(parsed, &ExecuteOptions{Database: sq.Database})
+	if err != nil {
+		return
+	}
+	if sq.MaterializeInto == "" {
+		return
+	}
+
+	for _, row := range result.Rows {
+		doc := make(map[string]interface{}, len(result.Columns))
+		for i, col := range result.Columns {
+			if i < len(row) {
+				doc[col.Name] = row[i]
+			}
+		}
+		s.node.InsertDocument(sq.MaterializeInto, doc)
+	}
+}
+
+// List returns every registered ScheduledQuery.
+func (s *QueryScheduler) List() ([]ScheduledQuery, error) {
+	rows, err := s.node.RunQuery(scheduledQueriesCollection, map[string]interface{}{"limit": 1000})
+	if err != nil {
+		return nil, err
+	}
+
+	docs, ok := rows.([]map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected scheduled query result shape")
+	}
+
+	out := make([]ScheduledQuery, 0, len(docs))
+	for _, doc := range docs {
+		sq, err := scheduledQueryFromDoc(doc)
+		if err != nil {
+			continue
+		}
+		out = append(out, sq)
+	}
+	return out, nil
+}
+
+// Delete removes a scheduled query's cron job and its persisted definition.
+func (s *QueryScheduler) Delete(id string) error {
+	s.mu.Lock()
+	jobID, ok := s.cronJobs[id]
+	if ok {
+		delete(s.cronJobs, id)
+	}
+	s.mu.Unlock()
+
+	if ok {
+		s.cron.Remove(jobID)
+	}
+	return s.node.DeleteDocument(scheduledQueriesCollection, id)
+}
+
+func scheduledQueryToDoc(sq ScheduledQuery) (map[string]interface{}, error) {
+	b, err := json.Marshal(sq)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	doc["_id"] = sq.ID
+	return doc, nil
+}
+
+func scheduledQueryFromDoc(doc map[string]interface{}) (ScheduledQuery, error) {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return ScheduledQuery{}, err
+	}
+	var sq ScheduledQuery
+	if err := json.Unmarshal(b, &sq); err != nil {
+		return ScheduledQuery{}, err
+	}
+	return sq, nil
+}
+
+// EnableScheduling installs a QueryScheduler backing POST /dialect/schedule,
+// GET /dialect/schedules, and DELETE /dialect/schedules/{id}.
+func (r *Router) EnableScheduling(node *cluster.Node, cronScheduler *cron.Scheduler) {
+	r.mu.Lock()
+	r.scheduler = NewQueryScheduler(r, node, cronScheduler)
+	r.mu.Unlock()
+}
+
+// requireManage enforces that req carries a user authorized for
+// auth.ActionManage, for the admin-only schedule endpoints. When no
+// Authorizer is installed, scheduling is left open like the rest of the
+// router in that mode.
+func (r *Router) requireManage(req *http.Request) error {
+	r.mu.RLock()
+	authorizer := r.authorizer
+	r.mu.RUnlock()
+	if authorizer == nil || authorizer.DisableAuthentication {
+		return nil
+	}
+	user, ok := UserFromContext(req.Context())
+	if !ok {
+		return auth.ErrInvalidToken
+	}
+	if !authorizer.Engine.IsAuthorized(user.Role, auth.ActionManage) {
+		return auth.ErrInvalidToken
+	}
+	return nil
+}
+
+// handleSchedule registers a new ScheduledQuery from {query, dialect, cron,
+// database, materialize_into}.
+func (r *Router) handleSchedule(w http.ResponseWriter, req *http.Request) {
+	r.mu.RLock()
+	scheduler := r.scheduler
+	r.mu.RUnlock()
+	if scheduler == nil {
+		r.writeError(w, http.StatusServiceUnavailable, "scheduling not enabled", "unavailable")
+		return
+	}
+	if err := r.requireManage(req); err != nil {
+		r.writeError(w, http.StatusForbidden, err.Error(), "forbidden")
+		return
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		r.writeError(w, http.StatusBadRequest, "failed to read request body", "bad_request")
+		return
+	}
+
+	var scheduleReq struct {
+		Query           string `json:"query"`
+		Dialect         string `json:"dialect"`
+		Cron            string `json:"cron"`
+		Database        string `json:"database"`
+		MaterializeInto string `json:"materialize_into"`
+	}
+	if err := json.Unmarshal(body, &scheduleReq); err != nil {
+		r.writeError(w, http.StatusBadRequest, "invalid request body", "bad_request")
+		return
+	}
+
+	sq, err := scheduler.Register(ScheduledQuery{
+		Dialect:         scheduleReq.Dialect,
+		Query:           scheduleReq.Query,
+		CronExpr:        scheduleReq.Cron,
+		Database:        scheduleReq.Database,
+		MaterializeInto: scheduleReq.MaterializeInto,
+	})
+	if err != nil {
+		r.writeError(w, http.StatusBadRequest, err.Error(), "bad_request")
+		return
+	}
+
+	r.writeJSON(w, http.StatusCreated, sq)
+}
+
+// handleListSchedules serves GET /dialect/schedules.
+func (r *Router) handleListSchedules(w http.ResponseWriter, req *http.Request) {
+	r.mu.RLock()
+	scheduler := r.scheduler
+	r.mu.RUnlock()
+	if scheduler == nil {
+		r.writeError(w, http.StatusServiceUnavailable, "scheduling not enabled", "unavailable")
+		return
+	}
+	if err := r.requireManage(req); err != nil {
+		r.writeError(w, http.StatusForbidden, err.Error(), "forbidden")
+		return
+	}
+
+	schedules, err := scheduler.List()
+	if err != nil {
+		r.writeError(w, http.StatusInternalServerError, err.Error(), "internal")
+		return
+	}
+	r.writeJSON(w, http.StatusOK, map[string]interface{}{"schedules": schedules})
+}
+
+// handleDeleteSchedule serves DELETE /dialect/schedules/{id}.
+func (r *Router) handleDeleteSchedule(w http.ResponseWriter, req *http.Request) {
+	r.mu.RLock()
+	scheduler := r.scheduler
+	r.mu.RUnlock()
+	if scheduler == nil {
+		r.writeError(w, http.StatusServiceUnavailable, "scheduling not enabled", "unavailable")
+		return
+	}
+	if err := r.requireManage(req); err != nil {
+		r.writeError(w, http.StatusForbidden, err.Error(), "forbidden")
+		return
+	}
+
+	id := strings.TrimPrefix(req.URL.Path, "/dialect/schedules/")
+	if id == "" {
+		r.writeError(w, http.StatusBadRequest, "missing schedule id", "bad_request")
+		return
+	}
+	if err := scheduler.Delete(id); err != nil {
+		r.writeError(w, http.StatusInternalServerError, err.Error(), "internal")
+		return
+	}
+	r.writeJSON(w, http.StatusOK, map[string]interface{}{"status": "deleted", "id": id})
+}
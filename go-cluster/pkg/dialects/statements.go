@@ -0,0 +1,114 @@
+package dialects
+
+import "strings"
+
+// SplitStatements splits query into its individual ';'-separated statement
+// texts the way InfluxDB's own parser does: a ';' inside a '...' or "..."
+// quoted string doesn't end a statement, and -- line and /* block */
+// comments are stripped first so a ';' inside one of those doesn't either.
+// Statements that are empty after trimming (a stray ';', a comment-only
+// line) are dropped, so a single-statement query with no trailing ';'
+// still returns a slice of length 1.
+func SplitStatements(query string) []string {
+	query = stripSQLComments(query)
+
+	var stmts []string
+	var buf strings.Builder
+	var quote rune
+
+	flush := func() {
+		if s := strings.TrimSpace(buf.String()); s != "" {
+			stmts = append(stmts, s)
+		}
+		buf.Reset()
+	}
+
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			buf.WriteRune(r)
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			buf.WriteRune(r)
+		case r == ';':
+			flush()
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	flush()
+
+	return stmts
+}
+
+// stripSQLComments removes -- line and /* block */ comments from query,
+// leaving their contents out of the text SplitStatements and Parse ever
+// see, the same as InfluxDB's own parser. A '--' or '/*' inside a quoted
+// string is left alone.
+func stripSQLComments(query string) string {
+	runes := []rune(query)
+	var out strings.Builder
+	var quote rune
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		if quote != 0 {
+			out.WriteRune(r)
+			if r == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch {
+		case r == '\'' || r == '"':
+			quote = r
+			out.WriteRune(r)
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			if i < len(runes) {
+				out.WriteRune('\n')
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			i++
+		default:
+			out.WriteRune(r)
+		}
+	}
+
+	return out.String()
+}
+
+// ParseStatements splits query into its ';'-separated statements via
+// SplitStatements and parses each one independently with handler, so a
+// batch like "SHOW DATABASES; SHOW MEASUREMENTS FROM x" yields one
+// ParsedQuery per statement instead of Parse, which only ever describes
+// a single statement, silently looking at just the first one. A parse
+// error on one statement is returned alongside the rest at the same
+// index rather than aborting the batch, so callers can still execute the
+// statements that did parse and report the failure next to them - the
+// same per-statement tolerance InfluxDB's own {"results":[...]} response
+// gives a batch.
+func ParseStatements(handler DialectHandler, query string) ([]*ParsedQuery, []error) {
+	texts := SplitStatements(query)
+	if len(texts) == 0 {
+		texts = []string{query}
+	}
+
+	parsed := make([]*ParsedQuery, len(texts))
+	errs := make([]error, len(texts))
+	for i, text := range texts {
+		parsed[i], errs[i] = handler.Parse(text)
+	}
+	return parsed, errs
+}
@@ -0,0 +1,270 @@
+package dialects
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RowBatch is one incremental slice of a streamed QueryResult. StreamingExecutor
+// implementations should size batches for reasonable flush latency (e.g. a few
+// hundred rows, or a time slice of a scan) rather than yielding row-by-row.
+type RowBatch struct {
+	Rows [][]interface{}
+}
+
+// StreamingExecutor is an optional extension of QueryExecutor: instead of
+// materializing the full QueryResult before returning, it yields column
+// metadata up front and then row batches over a channel, so a handler can
+// flush them to the client as they arrive. Executors that don't implement
+// this fall back to the ordinary buffered Execute path.
+type StreamingExecutor interface {
+	ExecuteStream(ctx context.Context, query *ParsedQuery, opts *ExecuteOptions) (columns []ColumnMeta, rows <-chan RowBatch, errc <-chan error)
+}
+
+// requestContext derives a context from the incoming request that is
+// cancelled when either the client disconnects (req.Context()) or the
+// dialect-level Timeout elapses, whichever comes first.
+func requestContext(req *http.Request, opts *ExecuteOptions) (context.Context, context.CancelFunc) {
+	if opts != nil && opts.Timeout > 0 {
+		return context.WithTimeout(req.Context(), opts.Timeout)
+	}
+	return context.WithCancel(req.Context())
+}
+
+// parseTimeout reads the Timeout field off a QueryRequest-style string
+// ("30s", "500ms", ...), ignoring malformed or empty values.
+func parseTimeout(s string) time.Duration {
+	if s == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// streamExecutor returns the Router's executor as a StreamingExecutor, if it
+// implements the interface.
+func (r *Router) streamExecutor() (StreamingExecutor, bool) {
+	se, ok := r.executor.(StreamingExecutor)
+	return se, ok
+}
+
+// streamFlux streams a Flux query's result as Flux's annotated CSV, one row
+// per line, flushing after every batch.
+func (r *Router) streamFlux(ctx context.Context, w http.ResponseWriter, se StreamingExecutor, parsed *ParsedQuery, opts *ExecuteOptions) {
+	columns, rows, errc := se.ExecuteStream(ctx, parsed, opts)
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	flusher, _ := w.(http.Flusher)
+
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.Name
+	}
+	fmt.Fprintf(w, "#group,false,false\n#datatype,string,long\n#default,_result,\n,result,table,%s\n", strings.Join(header, ","))
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	r.drainStream(ctx, rows, errc, func(batch RowBatch) error {
+		for _, row := range batch.Rows {
+			fields := make([]string, len(row))
+			for i, v := range row {
+				fields[i] = fmt.Sprintf("%v", v)
+			}
+			if _, err := fmt.Fprintf(w, ",_result,0,%s\n", strings.Join(fields, ",")); err != nil {
+				return err
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+}
+
+// streamInfluxQL streams an InfluxQL query's result as a single InfluxDB
+// "series" JSON object, emitting the values array incrementally so a large
+// scan never has to be held in memory all at once.
+func (r *Router) streamInfluxQL(ctx context.Context, w http.ResponseWriter, se StreamingExecutor, parsed *ParsedQuery, opts *ExecuteOptions) {
+	columns, rows, errc := se.ExecuteStream(ctx, parsed, opts)
+
+	w.Header().Set("Content-Type", "application/json")
+	flusher, _ := w.(http.Flusher)
+
+	colNames := make([]string, len(columns))
+	for i, c := range columns {
+		colNames[i] = c.Name
+	}
+	colJSON, _ := json.Marshal(colNames)
+	name := "series_0"
+	if len(parsed.Sources) > 0 {
+		name = parsed.Sources[0].Name
+	}
+	fmt.Fprintf(w, `{"results":[{"statement_id":0,"series":[{"name":%q,"columns":%s,"values":[`, name, colJSON)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	first := true
+	err := r.drainStream(ctx, rows, errc, func(batch RowBatch) error {
+		for _, row := range batch.Rows {
+			rowJSON, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+			if !first {
+				if _, err := w.Write([]byte(",")); err != nil {
+					return err
+				}
+			}
+			first = false
+			if _, err := w.Write(rowJSON); err != nil {
+				return err
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+
+	if err != nil {
+		fmt.Fprintf(w, `]}],"error":%q}]}`, err.Error())
+		return
+	}
+	fmt.Fprint(w, `]}]}]}`)
+}
+
+// streamPromQL streams a PromQL range query's result as a Prometheus matrix,
+// one series' values array populated incrementally as batches arrive.
+func (r *Router) streamPromQL(ctx context.Context, w http.ResponseWriter, se StreamingExecutor, parsed *ParsedQuery, opts *ExecuteOptions) {
+	_, rows, errc := se.ExecuteStream(ctx, parsed, opts)
+
+	w.Header().Set("Content-Type", "application/json")
+	flusher, _ := w.(http.Flusher)
+
+	metric := "{}"
+	if len(parsed.Sources) > 0 {
+		metric = fmt.Sprintf(`{"__name__":%q}`, parsed.Sources[0].Name)
+	}
+	fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[{"metric":%s,"values":[`, metric)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	first := true
+	err := r.drainStream(ctx, rows, errc, func(batch RowBatch) error {
+		for _, row := range batch.Rows {
+			pointJSON, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+			if !first {
+				if _, err := w.Write([]byte(",")); err != nil {
+					return err
+				}
+			}
+			first = false
+			if _, err := w.Write(pointJSON); err != nil {
+				return err
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+
+	if err != nil {
+		fmt.Fprintf(w, `]}],"error":%q}}`, err.Error())
+		return
+	}
+	fmt.Fprint(w, `]}]}}`)
+}
+
+// streamSQL streams a generic SQL (or OpenTSDB) query's result as a JSON
+// array of row tuples under a "rows" key, flushing after each batch.
+func (r *Router) streamSQL(ctx context.Context, w http.ResponseWriter, se StreamingExecutor, parsed *ParsedQuery, opts *ExecuteOptions) {
+	columns, rows, errc := se.ExecuteStream(ctx, parsed, opts)
+
+	w.Header().Set("Content-Type", "application/json")
+	flusher, _ := w.(http.Flusher)
+
+	colNames := make([]string, len(columns))
+	for i, c := range columns {
+		colNames[i] = c.Name
+	}
+	colJSON, _ := json.Marshal(colNames)
+	fmt.Fprintf(w, `{"status":"success","columns":%s,"rows":[`, colJSON)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	first := true
+	err := r.drainStream(ctx, rows, errc, func(batch RowBatch) error {
+		for _, row := range batch.Rows {
+			rowJSON, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+			if !first {
+				if _, err := w.Write([]byte(",")); err != nil {
+					return err
+				}
+			}
+			first = false
+			if _, err := w.Write(rowJSON); err != nil {
+				return err
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+
+	if err != nil {
+		fmt.Fprintf(w, `],"error":%q}`, err.Error())
+		return
+	}
+	fmt.Fprint(w, `]}`)
+}
+
+// drainStream forwards batches from rows to emit until rows/errc close, the
+// request's context is cancelled (client disconnect or Timeout deadline), or
+// emit itself returns an error. It always returns the first error seen, if
+// any, so callers can close out their response body appropriately.
+func (r *Router) drainStream(ctx context.Context, rows <-chan RowBatch, errc <-chan error, emit func(RowBatch) error) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case batch, ok := <-rows:
+			if !ok {
+				rows = nil
+				continue
+			}
+			if err := emit(batch); err != nil {
+				return err
+			}
+		case err, ok := <-errc:
+			if !ok {
+				errc = nil
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if rows == nil && errc == nil {
+			return nil
+		}
+	}
+}
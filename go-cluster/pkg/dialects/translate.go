@@ -0,0 +1,161 @@
+package dialects
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lumadb/cluster/pkg/dialects/promql"
+)
+
+// Translator converts a query already reduced to ParsedQuery - dialects'
+// shared logical plan (source, time range, filters, aggregations, group
+// by, order, limit) - into another dialect's native text. It's the
+// cross-dialect counterpart of a single DialectHandler's Parse/Render
+// pair: where a Renderer only ever emits the dialect it was written for,
+// Translate picks the right one for target and additionally catches the
+// handful of places where ParsedQuery's flat projection alone would
+// silently produce a wrong - rather than merely approximate - query.
+type Translator interface {
+	Translate(parsed *ParsedQuery, target Dialect) (string, error)
+}
+
+// ErrUntranslatable is returned when parsed uses a construct with no
+// equivalent in target, e.g. PromQL's histogram_quantile has no SQL
+// analogue. Callers can type-assert it to report Construct/Reason
+// separately from a generic render failure.
+type ErrUntranslatable struct {
+	Construct string
+	Target    Dialect
+	Reason    string
+}
+
+func (e *ErrUntranslatable) Error() string {
+	return fmt.Sprintf("cannot translate %s to %s: %s", e.Construct, e.Target, e.Reason)
+}
+
+// RouterTranslator translates through the Renderer a Router already has
+// registered for the target dialect, special-casing the cross-dialect
+// idioms (PromQL's rate() and InfluxQL's non_negative_derivative(mean(...))
+// are the same idea spelled two ways) that a bare ParsedQuery projection
+// loses, and rejecting constructs neither a Renderer nor an idiom can
+// express.
+type RouterTranslator struct {
+	router *Router
+}
+
+// NewTranslator creates a Translator that renders through router's
+// registered Renderers.
+func NewTranslator(router *Router) *RouterTranslator {
+	return &RouterTranslator{router: router}
+}
+
+func (t *RouterTranslator) Translate(parsed *ParsedQuery, target Dialect) (string, error) {
+	if err := checkTranslatable(parsed, target); err != nil {
+		return "", err
+	}
+
+	if text, ok := translatePromQLRate(parsed, target); ok {
+		return text, nil
+	}
+
+	renderer, ok := t.router.GetRenderer(target)
+	if !ok {
+		return "", fmt.Errorf("no renderer registered for dialect: %s", target)
+	}
+	return renderer.Render(parsed)
+}
+
+// translatePromQLRate special-cases PromQL's rate()/irate()/increase() -
+// a bare range-vector Call, not an AggregateExpr, so it never reaches
+// ParsedQuery.Aggregations the way sum()/avg() do - into InfluxQL's
+// non_negative_derivative(mean("value"), 1s) idiom, since influxQLRenderer
+// has nothing in ParsedQuery to tell it a rate was requested at all. The
+// reverse direction (InfluxQL non_negative_derivative back to PromQL
+// rate()) doesn't need a special case: it arrives as a normal Aggregation,
+// and promQLAggFunctions in render.go already maps its name to "rate" for
+// promQLRenderer to emit unchanged.
+func translatePromQLRate(parsed *ParsedQuery, target Dialect) (string, bool) {
+	if parsed.Dialect != DialectPromQL || target != DialectInfluxQL {
+		return "", false
+	}
+	call, ok := parsed.AST.(*promql.Call)
+	if !ok || !isPromQLRateFunc(call.Func) {
+		return "", false
+	}
+	if len(parsed.Sources) == 0 || parsed.TimeRange == nil || parsed.TimeRange.Duration <= 0 {
+		return "", false
+	}
+
+	rng := formatDialectDuration(parsed.TimeRange.Duration)
+
+	var clauses []string
+	for _, f := range parsed.Filters {
+		clauses = append(clauses, fmt.Sprintf(`"%s"%s'%v'`, f.Column, f.Operator, f.Value))
+	}
+	clauses = append(clauses, fmt.Sprintf("time > now() - %s", rng))
+
+	return fmt.Sprintf(
+		`SELECT non_negative_derivative(mean("value"), 1s) FROM "%s" WHERE %s GROUP BY time(%s)`,
+		parsed.Sources[0].Name, strings.Join(clauses, " AND "), rng,
+	), true
+}
+
+func isPromQLRateFunc(name string) bool {
+	switch name {
+	case "rate", "irate", "increase":
+		return true
+	}
+	return false
+}
+
+// checkTranslatable reports the one construct known to have no equivalent
+// outside PromQL in this repo: histogram_quantile, which depends on
+// PromQL's bucketed-histogram convention (le label + _bucket suffix) that
+// InfluxQL/Flux/SQL have no representation for at all.
+func checkTranslatable(parsed *ParsedQuery, target Dialect) error {
+	if parsed.Dialect != DialectPromQL || target == DialectPromQL {
+		return nil
+	}
+	expr, ok := parsed.AST.(promql.Expr)
+	if !ok {
+		return nil
+	}
+	if !promQLCallPresent(expr, "histogram_quantile") {
+		return nil
+	}
+	return &ErrUntranslatable{
+		Construct: "histogram_quantile()",
+		Target:    target,
+		Reason:    "no bucketed-histogram quantile function exists outside PromQL",
+	}
+}
+
+// promQLCallPresent reports whether e contains a Call node named name,
+// anywhere in its tree.
+func promQLCallPresent(e promql.Expr, name string) bool {
+	switch expr := e.(type) {
+	case *promql.Call:
+		if expr.Func == name {
+			return true
+		}
+		for _, arg := range expr.Args {
+			if promQLCallPresent(arg, name) {
+				return true
+			}
+		}
+	case *promql.AggregateExpr:
+		if expr.Param != nil && promQLCallPresent(expr.Param, name) {
+			return true
+		}
+		return promQLCallPresent(expr.Expr, name)
+	case *promql.BinaryExpr:
+		return promQLCallPresent(expr.LHS, name) || promQLCallPresent(expr.RHS, name)
+	case *promql.UnaryExpr:
+		return promQLCallPresent(expr.Expr, name)
+	case *promql.ParenExpr:
+		return promQLCallPresent(expr.Expr, name)
+	case *promql.SubqueryExpr:
+		return promQLCallPresent(expr.Expr, name)
+	}
+	return false
+}
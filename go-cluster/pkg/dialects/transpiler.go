@@ -0,0 +1,233 @@
+package dialects
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lumadb/cluster/pkg/ir"
+)
+
+// Transpiler translates one dialect's query text into the normalized IR
+// Plan every dialect funnels through, so the query execution path only
+// ever has to understand ir.Plan rather than eleven dialect grammars.
+type Transpiler interface {
+	Translate(src Dialect, query string) (ir.Plan, error)
+}
+
+// Registry maps a detected Dialect to the Transpiler that understands it,
+// and owns the DialectDetector used to pick that Dialect in the first
+// place.
+type Registry struct {
+	mu          sync.RWMutex
+	transpilers map[Dialect]Transpiler
+	detector    *DialectDetector
+}
+
+// NewRegistry builds a Registry with first-class translators registered
+// for InfluxQL, Flux, PromQL and time_bucket/Timescale SQL.
+func NewRegistry() *Registry {
+	r := &Registry{
+		transpilers: make(map[Dialect]Transpiler),
+		detector:    NewDialectDetector(),
+	}
+
+	r.Register(DialectInfluxQL, &influxQLTranspiler{})
+	r.Register(DialectFlux, &fluxTranspiler{})
+	r.Register(DialectPromQL, &promQLTranspiler{})
+	r.Register(DialectTimescale, &timescaleTranspiler{})
+
+	return r
+}
+
+// Register adds or replaces the Transpiler used for dialect.
+func (r *Registry) Register(dialect Dialect, t Transpiler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transpilers[dialect] = t
+}
+
+// Detect runs the registry's DialectDetector over query.
+func (r *Registry) Detect(query string) (Dialect, float64) {
+	return r.detector.Detect(query)
+}
+
+// Translate detects query's dialect and runs it through the matching
+// Transpiler, returning the detected dialect, the detector's confidence,
+// and the resulting Plan.
+func (r *Registry) Translate(query string) (dialect Dialect, confidence float64, plan ir.Plan, err error) {
+	dialect, confidence = r.Detect(query)
+
+	r.mu.RLock()
+	t, ok := r.transpilers[dialect]
+	r.mu.RUnlock()
+	if !ok {
+		return dialect, confidence, ir.Plan{}, fmt.Errorf("no transpiler registered for dialect %q", dialect)
+	}
+
+	plan, err = t.Translate(dialect, query)
+	return dialect, confidence, plan, err
+}
+
+// influxQLTranspiler reuses InfluxQLHandler's existing Parse (built on the
+// influxql package's parser) and maps its ParsedQuery onto ir.Plan, rather than re-deriving the same
+// FROM/WHERE/GROUP BY time() extraction a second time.
+type influxQLTranspiler struct{}
+
+func (t *influxQLTranspiler) Translate(src Dialect, query string) (ir.Plan, error) {
+	parsed, err := (&InfluxQLHandler{}).Parse(query)
+	if err != nil {
+		return ir.Plan{}, err
+	}
+	return parsedQueryToPlan(parsed), nil
+}
+
+// fluxTranspiler reuses FluxHandler.Parse the same way.
+type fluxTranspiler struct{}
+
+func (t *fluxTranspiler) Translate(src Dialect, query string) (ir.Plan, error) {
+	parsed, err := (&FluxHandler{}).Parse(query)
+	if err != nil {
+		return ir.Plan{}, err
+	}
+	return parsedQueryToPlan(parsed), nil
+}
+
+// promQLTranspiler reuses PromQLHandler.Parse the same way.
+type promQLTranspiler struct{}
+
+func (t *promQLTranspiler) Translate(src Dialect, query string) (ir.Plan, error) {
+	parsed, err := (&PromQLHandler{}).Parse(query)
+	if err != nil {
+		return ir.Plan{}, err
+	}
+	return parsedQueryToPlan(parsed), nil
+}
+
+// parsedQueryToPlan maps the dialects.ParsedQuery shape the older
+// DialectHandlers already produce onto ir.Plan, so the three handler-based
+// transpilers above share one conversion instead of each duplicating it.
+func parsedQueryToPlan(parsed *ParsedQuery) ir.Plan {
+	plan := ir.Plan{GroupBy: parsed.GroupBy}
+
+	if len(parsed.Sources) > 0 {
+		plan.Select.Source = parsed.Sources[0].Name
+	}
+
+	for _, f := range parsed.Filters {
+		plan.Filter = append(plan.Filter, ir.FilterNode{
+			Field: f.Column,
+			Op:    f.Operator,
+			Value: fmt.Sprintf("%v", f.Value),
+		})
+	}
+
+	if parsed.TimeRange != nil {
+		plan.TimeRange = &ir.TimeRangeNode{
+			Start: parsed.TimeRange.Start,
+			End:   parsed.TimeRange.End,
+		}
+		if parsed.TimeRange.Duration > 0 {
+			plan.Window = &ir.WindowNode{Every: parsed.TimeRange.Duration}
+		}
+	}
+
+	for _, a := range parsed.Aggregations {
+		plan.Aggregation = append(plan.Aggregation, ir.AggregationNode{
+			Function: a.Function,
+			Field:    a.Column,
+			Alias:    a.Alias,
+		})
+	}
+
+	return plan
+}
+
+// timescaleTranspiler understands time_bucket/time_bucket_gapfill SQL
+// directly, since TimescaleDB's dialect isn't covered by any existing
+// DialectHandler: SELECT time_bucket('1h', ts) AS bucket, agg(col) FROM
+// table WHERE ... GROUP BY bucket.
+type timescaleTranspiler struct{}
+
+var (
+	timeBucketRe  = regexp.MustCompile(`(?i)time_bucket(?:_gapfill)?\s*\(\s*'([^']+)'\s*,\s*\w+\s*\)`)
+	tsFromRe      = regexp.MustCompile(`(?i)\bFROM\s+["]?(\w+)["]?`)
+	tsWhereRe     = regexp.MustCompile(`(?i)\bWHERE\s+(.+?)(?:\s+GROUP\s+BY|\s+ORDER\s+BY|\s+LIMIT|$)`)
+	tsFilterRe    = regexp.MustCompile(`(\w+)\s*(=|!=|>=|<=|>|<)\s*'?([^'\s]+)'?`)
+	tsAggregateRe = regexp.MustCompile(`(?i)(\w+)\(([\w.*]+)\)(?:\s+as\s+(\w+))?`)
+)
+
+func (t *timescaleTranspiler) Translate(src Dialect, query string) (ir.Plan, error) {
+	bm := timeBucketRe.FindStringSubmatch(query)
+	if bm == nil {
+		return ir.Plan{}, fmt.Errorf("timescale transpiler: no time_bucket() call found in query")
+	}
+
+	every, err := parseTimescaleBucketWidth(bm[1])
+	if err != nil {
+		return ir.Plan{}, err
+	}
+	plan := ir.Plan{Window: &ir.WindowNode{Every: every}}
+
+	if fm := tsFromRe.FindStringSubmatch(query); fm != nil {
+		plan.Select.Source = fm[1]
+	} else {
+		return ir.Plan{}, fmt.Errorf("timescale transpiler: no FROM clause found in query")
+	}
+
+	if wm := tsWhereRe.FindStringSubmatch(query); wm != nil {
+		for _, fm := range tsFilterRe.FindAllStringSubmatch(wm[1], -1) {
+			plan.Filter = append(plan.Filter, ir.FilterNode{Field: fm[1], Op: fm[2], Value: fm[3]})
+		}
+	}
+
+	for _, am := range tsAggregateRe.FindAllStringSubmatch(query, -1) {
+		fn := strings.ToLower(am[1])
+		if fn == "time_bucket" || fn == "time_bucket_gapfill" {
+			continue
+		}
+		alias := am[3]
+		if alias == "" {
+			alias = fmt.Sprintf("%s_%s", fn, am[2])
+		}
+		plan.Aggregation = append(plan.Aggregation, ir.AggregationNode{Function: fn, Field: am[2], Alias: alias})
+	}
+
+	plan.GroupBy = []string{"bucket"}
+
+	return plan, nil
+}
+
+// parseTimescaleBucketWidth parses a Postgres INTERVAL-ish literal such as
+// "1 hour" or "5 minutes" (time_bucket's bucket_width argument) into a
+// time.Duration.
+func parseTimescaleBucketWidth(s string) (time.Duration, error) {
+	parts := strings.Fields(strings.TrimSpace(s))
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("timescale transpiler: unsupported bucket width %q", s)
+	}
+
+	n, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("timescale transpiler: invalid bucket width %q: %w", s, err)
+	}
+
+	unit := strings.ToLower(strings.TrimSuffix(parts[1], "s"))
+	switch unit {
+	case "second":
+		return time.Duration(n * float64(time.Second)), nil
+	case "minute":
+		return time.Duration(n * float64(time.Minute)), nil
+	case "hour":
+		return time.Duration(n * float64(time.Hour)), nil
+	case "day":
+		return time.Duration(n * 24 * float64(time.Hour)), nil
+	case "week":
+		return time.Duration(n * 7 * 24 * float64(time.Hour)), nil
+	default:
+		return 0, fmt.Errorf("timescale transpiler: unsupported bucket unit %q", parts[1])
+	}
+}
@@ -0,0 +1,57 @@
+// Package ir defines the dialect-agnostic intermediate representation
+// every query dialect's Transpiler normalizes into, so a single execution
+// path can run a query regardless of whether it arrived as InfluxQL,
+// Flux, PromQL, time_bucket SQL, or any other dialects.DialectDetector
+// understands.
+package ir
+
+import "time"
+
+// Plan is a normalized query plan: what to select, how to filter and
+// group it, and the time range/window/aggregation to apply - the shape
+// InfluxQL, Flux, PromQL and time_bucket SQL all reduce to.
+type Plan struct {
+	Select      SelectNode
+	Filter      []FilterNode
+	TimeRange   *TimeRangeNode
+	GroupBy     []string
+	Window      *WindowNode
+	Aggregation []AggregationNode
+}
+
+// SelectNode names the measurement/metric/table a plan reads from and,
+// optionally, the specific fields projected out of it - an empty Fields
+// means "all fields".
+type SelectNode struct {
+	Source string
+	Fields []string
+}
+
+// FilterNode is one "field <op> value" condition, ANDed with its siblings.
+type FilterNode struct {
+	Field string
+	Op    string // "=", "!=", "=~", "!~", ">", "<", ">=", "<="
+	Value string
+}
+
+// TimeRangeNode bounds the plan to [Start, End).
+type TimeRangeNode struct {
+	Start time.Time
+	End   time.Time
+}
+
+// WindowNode downsamples the time range into fixed-size buckets (PromQL's
+// range vectors, Flux's aggregateWindow, Timescale's time_bucket, and
+// InfluxQL's GROUP BY time() all compile down to this).
+type WindowNode struct {
+	Every  time.Duration
+	Offset time.Duration
+}
+
+// AggregationNode is one aggregate applied per window/group, e.g.
+// mean(value) AS avg_value.
+type AggregationNode struct {
+	Function string
+	Field    string
+	Alias    string
+}
@@ -0,0 +1,81 @@
+package meta
+
+import "encoding/json"
+
+// MetaClient is the entry point the TDengine handler and the federation
+// registry use to read and mutate cluster-wide metadata - databases,
+// retention policies, super-table schemas, subscriptions, users and data
+// node assignments - so a DDL statement run against any node converges to
+// the same state everywhere. Every mutation goes through storeFSM.Apply;
+// see storeFSM's doc comment for how that relates to actual Raft
+// replication.
+type MetaClient struct {
+	fsm *storeFSM
+}
+
+// NewMetaClient returns a MetaClient over an empty Data snapshot.
+func NewMetaClient() *MetaClient {
+	return &MetaClient{fsm: newStoreFSM()}
+}
+
+func (c *MetaClient) apply(cmd command) (*Data, error) {
+	b, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return c.fsm.Apply(b)
+}
+
+// CreateDatabase replicates a CREATE DATABASE.
+func (c *MetaClient) CreateDatabase(name string) error {
+	_, err := c.apply(command{Type: commandCreateDatabase, CreateDatabase: &createDatabaseCommand{Name: name}})
+	return err
+}
+
+// CreateRetentionPolicy replicates a database's KEEP/DURATION/REPLICA
+// retention policy.
+func (c *MetaClient) CreateRetentionPolicy(database string, rp RetentionPolicyInfo) error {
+	_, err := c.apply(command{
+		Type: commandCreateRetentionPolicy,
+		CreateRetentionPolicy: &createRetentionPolicyCommand{
+			Database: database,
+			Policy:   rp,
+		},
+	})
+	return err
+}
+
+// CreateSubscription replicates a CREATE SUBSCRIPTION.
+func (c *MetaClient) CreateSubscription(sub SubscriptionInfo) error {
+	_, err := c.apply(command{Type: commandCreateSubscription, CreateSubscription: &createSubscriptionCommand{Subscription: sub}})
+	return err
+}
+
+// SetDataNode replicates a node joining (or updating its advertised
+// addresses in) the cluster.
+func (c *MetaClient) SetDataNode(id uint64, host, tcpHost string) error {
+	_, err := c.apply(command{Type: commandSetDataNode, SetDataNode: &setDataNodeCommand{ID: id, Host: host, TCPHost: tcpHost}})
+	return err
+}
+
+// Database returns the named database, if it exists.
+func (c *MetaClient) Database(name string) (*DatabaseInfo, bool) {
+	return c.fsm.Data().Database(name)
+}
+
+// Databases returns every database in the cluster.
+func (c *MetaClient) Databases() []DatabaseInfo {
+	return c.fsm.Data().Databases
+}
+
+// Subscriptions returns every subscription in the cluster.
+func (c *MetaClient) Subscriptions() []SubscriptionInfo {
+	return c.fsm.Data().Subscriptions
+}
+
+// DataNodes returns every data node known to the cluster. The operator
+// derives LumaClusterStatus.ActiveNodes from this list on clusters with
+// Replicas > 1.
+func (c *MetaClient) DataNodes() []NodeInfo {
+	return c.fsm.Data().DataNodes
+}
@@ -0,0 +1,80 @@
+package meta
+
+import "testing"
+
+func TestMetaClient_CreateDatabaseAndRetentionPolicy(t *testing.T) {
+	c := NewMetaClient()
+
+	if err := c.CreateDatabase("test"); err != nil {
+		t.Fatalf("CreateDatabase() error = %v", err)
+	}
+	if err := c.CreateRetentionPolicy("test", RetentionPolicyInfo{Name: "default", ReplicaN: 1}); err != nil {
+		t.Fatalf("CreateRetentionPolicy() error = %v", err)
+	}
+
+	db, ok := c.Database("test")
+	if !ok {
+		t.Fatal("Database(\"test\") ok = false, want true")
+	}
+	if len(db.RetentionPolicies) != 1 || db.RetentionPolicies[0].Name != "default" {
+		t.Errorf("RetentionPolicies = %v, want one policy named default", db.RetentionPolicies)
+	}
+}
+
+func TestMetaClient_CreateRetentionPolicy_UnknownDatabase(t *testing.T) {
+	c := NewMetaClient()
+	if err := c.CreateRetentionPolicy("missing", RetentionPolicyInfo{Name: "default"}); err == nil {
+		t.Fatal("CreateRetentionPolicy() error = nil, want error for unknown database")
+	}
+}
+
+func TestMetaClient_SetDataNodeUpdatesExisting(t *testing.T) {
+	c := NewMetaClient()
+	if err := c.SetDataNode(1, "node1:8080", "node1:10000"); err != nil {
+		t.Fatalf("SetDataNode() error = %v", err)
+	}
+	if err := c.SetDataNode(1, "node1:8081", "node1:10001"); err != nil {
+		t.Fatalf("SetDataNode() error = %v", err)
+	}
+
+	nodes := c.DataNodes()
+	if len(nodes) != 1 {
+		t.Fatalf("DataNodes() = %v, want exactly one node (SetDataNode updates in place)", nodes)
+	}
+	if nodes[0].Host != "node1:8081" {
+		t.Errorf("Host = %q, want node1:8081", nodes[0].Host)
+	}
+}
+
+func TestMetaClient_CreateSubscriptionReplacesByName(t *testing.T) {
+	c := NewMetaClient()
+	sub := SubscriptionInfo{Name: "sink1", Database: "test", Destinations: []string{"http://a"}}
+	if err := c.CreateSubscription(sub); err != nil {
+		t.Fatalf("CreateSubscription() error = %v", err)
+	}
+	sub.Destinations = []string{"http://b"}
+	if err := c.CreateSubscription(sub); err != nil {
+		t.Fatalf("CreateSubscription() error = %v", err)
+	}
+
+	subs := c.Subscriptions()
+	if len(subs) != 1 || subs[0].Destinations[0] != "http://b" {
+		t.Errorf("Subscriptions() = %v, want one subscription with updated destinations", subs)
+	}
+}
+
+func TestData_MarshalUnmarshalBinaryRoundTrip(t *testing.T) {
+	d := &Data{Databases: []DatabaseInfo{{Name: "test"}}, Index: 3}
+	b, err := d.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+
+	var got Data
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	if got.Index != 3 || len(got.Databases) != 1 || got.Databases[0].Name != "test" {
+		t.Errorf("UnmarshalBinary() = %+v, want round-tripped Data", got)
+	}
+}
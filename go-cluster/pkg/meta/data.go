@@ -0,0 +1,163 @@
+// Package meta provides a replicated cluster metadata store modeled on
+// InfluxDB's meta service: a Data snapshot of every database, retention
+// policy, super-table schema, subscription, user and data node assignment
+// in the cluster, mutated only through storeFSM.Apply so that the same
+// command applied on any node converges to the same state everywhere.
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// NodeInfo identifies one LumaDB node in the cluster, set via
+// MetaClient.SetDataNode as each node joins.
+type NodeInfo struct {
+	ID      uint64
+	Host    string
+	TCPHost string
+}
+
+// RetentionPolicyInfo is one database's TDengine-dialect retention policy:
+// KEEP/DURATION/REPLICA.
+type RetentionPolicyInfo struct {
+	Name               string
+	Duration           time.Duration
+	ShardGroupDuration time.Duration
+	ReplicaN           int
+}
+
+// SuperTableInfo is one super-table's schema within a database.
+type SuperTableInfo struct {
+	Name   string
+	Tags   []string
+	Fields []string
+}
+
+// SubscriptionInfo is one tdengine CREATE SUBSCRIPTION definition.
+type SubscriptionInfo struct {
+	Name         string
+	Database     string
+	Measurement  string
+	Mode         string
+	Destinations []string
+}
+
+// UserInfo is one cluster user.
+type UserInfo struct {
+	Name  string
+	Hash  string
+	Admin bool
+}
+
+// DatabaseInfo is one database and everything scoped to it: its retention
+// policies and the super-tables created within it.
+type DatabaseInfo struct {
+	Name              string
+	DefaultRetention  string
+	RetentionPolicies []RetentionPolicyInfo
+	SuperTables       []SuperTableInfo
+}
+
+// Data is the full cluster metadata snapshot replicated by storeFSM. Index
+// counts the number of commands applied, InfluxDB meta.Data's convention for
+// detecting a stale read against a client that cached an older snapshot.
+type Data struct {
+	Index         uint64
+	Databases     []DatabaseInfo
+	Subscriptions []SubscriptionInfo
+	Users         []UserInfo
+	DataNodes     []NodeInfo
+}
+
+// Clone returns a deep copy of d, so a caller can mutate the copy and hand
+// it back to storeFSM.Apply without the old snapshot (held by a concurrent
+// reader) changing underneath it.
+func (d *Data) Clone() *Data {
+	clone := &Data{Index: d.Index}
+	clone.Databases = make([]DatabaseInfo, len(d.Databases))
+	for i, db := range d.Databases {
+		clone.Databases[i] = db
+		clone.Databases[i].RetentionPolicies = append([]RetentionPolicyInfo(nil), db.RetentionPolicies...)
+		clone.Databases[i].SuperTables = append([]SuperTableInfo(nil), db.SuperTables...)
+	}
+	clone.Subscriptions = append([]SubscriptionInfo(nil), d.Subscriptions...)
+	clone.Users = append([]UserInfo(nil), d.Users...)
+	clone.DataNodes = append([]NodeInfo(nil), d.DataNodes...)
+	return clone
+}
+
+// Database returns the named database, if it exists.
+func (d *Data) Database(name string) (*DatabaseInfo, bool) {
+	for i := range d.Databases {
+		if d.Databases[i].Name == name {
+			return &d.Databases[i], true
+		}
+	}
+	return nil, false
+}
+
+// CreateDatabase adds name if it doesn't already exist; it is not an error
+// to create a database that's already present, matching the SQL dialects'
+// own CREATE DATABASE IF NOT EXISTS semantics.
+func (d *Data) CreateDatabase(name string) {
+	if _, ok := d.Database(name); ok {
+		return
+	}
+	d.Databases = append(d.Databases, DatabaseInfo{Name: name})
+}
+
+// CreateRetentionPolicy attaches rp to database, replacing any existing
+// policy of the same name.
+func (d *Data) CreateRetentionPolicy(database string, rp RetentionPolicyInfo) error {
+	db, ok := d.Database(database)
+	if !ok {
+		return fmt.Errorf("meta: database %q not found", database)
+	}
+	for i, existing := range db.RetentionPolicies {
+		if existing.Name == rp.Name {
+			db.RetentionPolicies[i] = rp
+			return nil
+		}
+	}
+	db.RetentionPolicies = append(db.RetentionPolicies, rp)
+	return nil
+}
+
+// CreateSubscription adds sub, replacing any existing subscription of the
+// same name.
+func (d *Data) CreateSubscription(sub SubscriptionInfo) {
+	for i, existing := range d.Subscriptions {
+		if existing.Name == sub.Name {
+			d.Subscriptions[i] = sub
+			return
+		}
+	}
+	d.Subscriptions = append(d.Subscriptions, sub)
+}
+
+// SetDataNode adds or updates the node identified by id.
+func (d *Data) SetDataNode(id uint64, host, tcpHost string) {
+	for i, n := range d.DataNodes {
+		if n.ID == id {
+			d.DataNodes[i].Host = host
+			d.DataNodes[i].TCPHost = tcpHost
+			return
+		}
+	}
+	d.DataNodes = append(d.DataNodes, NodeInfo{ID: id, Host: host, TCPHost: tcpHost})
+}
+
+// MarshalBinary encodes d as JSON. InfluxDB's own meta service encodes Data
+// as protobuf; this package has no code-generation toolchain available to
+// produce and maintain a generated .pb.go, so JSON stands in as the wire
+// format storeFSM's Raft log entries and snapshots use instead.
+func (d *Data) MarshalBinary() ([]byte, error) {
+	return json.Marshal(d)
+}
+
+// UnmarshalBinary decodes d from the JSON MarshalBinary produces.
+func (d *Data) UnmarshalBinary(b []byte) error {
+	return json.Unmarshal(b, d)
+}
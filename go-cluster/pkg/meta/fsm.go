@@ -0,0 +1,99 @@
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Command type discriminators for command.Type, mirroring the named
+// protobuf command kinds InfluxDB's meta store fsm switches on.
+const (
+	commandCreateDatabase        = "CreateDatabase"
+	commandCreateRetentionPolicy = "CreateRetentionPolicy"
+	commandCreateSubscription    = "CreateSubscription"
+	commandSetDataNode           = "SetDataNode"
+)
+
+// command is a JSON discriminated union standing in for the generated
+// protobuf Command message InfluxDB's real meta store fsm applies; see
+// Data.MarshalBinary for why this package uses JSON instead.
+type command struct {
+	Type                  string                        `json:"type"`
+	CreateDatabase        *createDatabaseCommand        `json:"createDatabase,omitempty"`
+	CreateRetentionPolicy *createRetentionPolicyCommand `json:"createRetentionPolicy,omitempty"`
+	CreateSubscription    *createSubscriptionCommand    `json:"createSubscription,omitempty"`
+	SetDataNode           *setDataNodeCommand           `json:"setDataNode,omitempty"`
+}
+
+type createDatabaseCommand struct {
+	Name string `json:"name"`
+}
+
+type createRetentionPolicyCommand struct {
+	Database string              `json:"database"`
+	Policy   RetentionPolicyInfo `json:"policy"`
+}
+
+type createSubscriptionCommand struct {
+	Subscription SubscriptionInfo `json:"subscription"`
+}
+
+type setDataNodeCommand struct {
+	ID      uint64 `json:"id"`
+	Host    string `json:"host"`
+	TCPHost string `json:"tcpHost"`
+}
+
+// storeFSM applies serialized commands onto a Data snapshot. Its Apply
+// signature mirrors the raft.FSM shape (a log entry's bytes in, the
+// resulting state out), but nothing feeds it from a Raft log: MetaClient
+// calls Apply directly rather than through a replicated log.
+type storeFSM struct {
+	mu   sync.RWMutex
+	data *Data
+}
+
+func newStoreFSM() *storeFSM {
+	return &storeFSM{data: &Data{}}
+}
+
+// Apply decodes and applies one serialized command, returning the resulting
+// Data snapshot.
+func (f *storeFSM) Apply(b []byte) (*Data, error) {
+	var cmd command
+	if err := json.Unmarshal(b, &cmd); err != nil {
+		return nil, fmt.Errorf("meta: decode command: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data := f.data.Clone()
+	switch cmd.Type {
+	case commandCreateDatabase:
+		data.CreateDatabase(cmd.CreateDatabase.Name)
+	case commandCreateRetentionPolicy:
+		if err := data.CreateRetentionPolicy(cmd.CreateRetentionPolicy.Database, cmd.CreateRetentionPolicy.Policy); err != nil {
+			return nil, err
+		}
+	case commandCreateSubscription:
+		data.CreateSubscription(cmd.CreateSubscription.Subscription)
+	case commandSetDataNode:
+		n := cmd.SetDataNode
+		data.SetDataNode(n.ID, n.Host, n.TCPHost)
+	default:
+		return nil, fmt.Errorf("meta: unknown command type %q", cmd.Type)
+	}
+
+	data.Index++
+	f.data = data
+	return data.Clone(), nil
+}
+
+// Data returns a snapshot of the fsm's current state.
+func (f *storeFSM) Data() *Data {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.data.Clone()
+}
@@ -0,0 +1,111 @@
+// Package metrics defines the Prometheus collectors the rest of the
+// cluster registers against, plus the HTTP middleware that records the
+// per-route ones. Everything here is registered against the default
+// registry through promauto, so a single promhttp.Handler in pkg/api
+// exposes it all.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/valyala/fasthttp"
+)
+
+var (
+	// HTTPRequestDuration is a per-route request latency histogram.
+	// Dialect is only populated for requests where one was detected
+	// (currently /api/v1/query); every other route reports it empty.
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "lumadb",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by method, route, status and detected dialect.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "path", "status", "dialect"})
+
+	// RaftApplyDuration measures the time from Node.Apply submission to
+	// the Raft log entry committing (or failing).
+	RaftApplyDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "lumadb",
+		Subsystem: "raft",
+		Name:      "apply_duration_seconds",
+		Help:      "Latency of Node.Apply from submission to commit.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// RaftApplyInflight is the number of Apply calls currently awaiting
+	// commit - a proxy for how backed up the Raft log is.
+	RaftApplyInflight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "lumadb",
+		Subsystem: "raft",
+		Name:      "apply_inflight",
+		Help:      "Number of Node.Apply calls currently awaiting commit.",
+	})
+
+	// RouterDecisions counts how query/write requests were routed:
+	// served locally, forwarded to another node, or redirected to the
+	// leader.
+	RouterDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "lumadb",
+		Subsystem: "router",
+		Name:      "decisions_total",
+		Help:      "Count of router decisions by outcome: local, forwarded, redirect.",
+	}, []string{"outcome"})
+
+	// RAGIngestTotal and RAGQueryTotal count RAG calls by outcome
+	// (success/error).
+	RAGIngestTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "lumadb",
+		Subsystem: "rag",
+		Name:      "ingest_total",
+		Help:      "Count of RAG ingest calls by outcome.",
+	}, []string{"outcome"})
+
+	RAGQueryTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "lumadb",
+		Subsystem: "rag",
+		Name:      "query_total",
+		Help:      "Count of RAG query calls by outcome.",
+	}, []string{"outcome"})
+
+	// RAGTokens histograms the approximate token count consumed per RAG
+	// ingest/query call, bucketed by op ("ingest" or "query").
+	RAGTokens = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "lumadb",
+		Subsystem: "rag",
+		Name:      "tokens",
+		Help:      "Approximate token count per RAG ingest/query call.",
+		Buckets:   []float64{16, 64, 256, 1024, 4096, 16384},
+	}, []string{"op"})
+
+	// ConnectionPoolSize is the number of open connections router.Router
+	// currently holds per peer address.
+	ConnectionPoolSize = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "lumadb",
+		Subsystem: "router",
+		Name:      "connection_pool_size",
+		Help:      "Open connections held per peer address in the router's connection pool.",
+	}, []string{"addr"})
+)
+
+// InstrumentRoute wraps next with a request-latency observation against
+// HTTPRequestDuration, labeled with the caller-supplied route pattern
+// (e.g. "/api/v1/collections/{collection}") rather than the raw request
+// path, so dynamic path segments don't explode the metric's cardinality.
+func InstrumentRoute(pattern string, next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		start := time.Now()
+		next(ctx)
+
+		dialect, _ := ctx.UserValue("detected_dialect").(string)
+		HTTPRequestDuration.WithLabelValues(
+			string(ctx.Method()),
+			pattern,
+			strconv.Itoa(ctx.Response.StatusCode()),
+			dialect,
+		).Observe(time.Since(start).Seconds())
+	}
+}
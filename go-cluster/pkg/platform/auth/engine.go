@@ -1,7 +1,14 @@
 package auth
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -10,10 +17,51 @@ import (
 )
 
 var (
-	ErrInvalidToken = errors.New("invalid token")
-	ErrExpiredToken = errors.New("expired token")
+	ErrInvalidToken      = errors.New("invalid token")
+	ErrExpiredToken      = errors.New("expired token")
+	ErrTooManyAttempts   = errors.New("too many failed login attempts, try again later")
+	ErrUnknownSigningKey = errors.New("token signed by an unknown or expired key")
+	ErrInvalidNamespace  = errors.New(`namespace must not contain "__"`)
 )
 
+const (
+	maxLoginAttempts   = 5
+	loginLockoutWindow = 15 * time.Minute
+
+	// tokenTTL is how long a freshly minted JWT is valid for. RotateKey
+	// gives an outgoing key this much more life so tokens it already
+	// signed keep validating until they'd have expired naturally anyway.
+	tokenTTL = 24 * time.Hour
+
+	// authKeyringCommandOp is the cluster.Command.Op RotateKey replicates
+	// the keyring under - the same Command-through-node.Apply pattern
+	// RaftUserStore uses for credentials.
+	authKeyringCommandOp = "auth_keyring"
+)
+
+// KeyEntry is one JWT signing key in AuthEngine's keyring. Rotation appends
+// a new entry rather than replacing secretKey outright, so tokens already
+// issued under an older key keep validating (by kid) until NotAfter, instead
+// of every outstanding session being invalidated the moment the key rotates.
+type KeyEntry struct {
+	ID        string    `json:"id"`
+	Secret    []byte    `json:"secret"`
+	NotBefore time.Time `json:"not_before"`
+	// NotAfter is the zero time while this key is still the active signing
+	// key; RotateKey sets it on the outgoing key when a new one is added.
+	NotAfter time.Time `json:"not_after,omitempty"`
+}
+
+// active reports whether e is usable to verify a token at now - it doesn't
+// gate signing new tokens, only ValidateToken's keyFunc.
+func (e KeyEntry) active(now time.Time) bool {
+	return !now.Before(e.NotBefore) && (e.NotAfter.IsZero() || now.Before(e.NotAfter))
+}
+
+// SystemNamespace is the reserved tenant allowed to write Galaxy-style
+// "shared_"-prefixed collections that every other tenant can read.
+const SystemNamespace = "system"
+
 type Action string
 
 const (
@@ -24,21 +72,39 @@ const (
 )
 
 type Claims struct {
-	UserID string `json:"user_id"`
-	Role   string `json:"role"`
+	UserID    string `json:"user_id"`
+	Role      string `json:"role"`
+	Namespace string `json:"namespace"`
 	jwt.RegisteredClaims
 }
 
+type loginAttempts struct {
+	count     int
+	firstFail time.Time
+}
+
 type AuthEngine struct {
 	node        *cluster.Node
 	logger      *zap.Logger
-	secretKey   []byte
 	store       UserStore
 	permissions map[string]map[Action]bool // role -> action -> allowed
+
+	keyringMu sync.RWMutex
+	keyring   []KeyEntry // JWT signing keys, newest last; see RotateKey
+
+	// rotateMu serializes RotateKey calls so two concurrent rotations can't
+	// both read the same base keyring, Apply their own candidate, and race
+	// to overwrite e.keyring - see RotateKey.
+	rotateMu sync.Mutex
+
+	attemptsMu sync.Mutex
+	attempts   map[string]*loginAttempts // username -> failure tracking
 }
 
+// NewAuthEngine creates an AuthEngine backed by the default FileUserStore.
+// Use NewAuthEngineWithStore to plug in SQLUserStore, LDAPUserStore or
+// OIDCUserStore instead, or NewAuthEngineRaft for a cluster-replicated store.
 func NewAuthEngine(node *cluster.Node, logger *zap.Logger) (*AuthEngine, error) {
-	// Initialize File Store (MVP)
 	store, err := NewFileUserStore("users.json")
 	if err != nil {
 		return nil, err
@@ -46,19 +112,60 @@ func NewAuthEngine(node *cluster.Node, logger *zap.Logger) (*AuthEngine, error)
 
 	// Create default admin if not exists
 	if _, err := store.GetUser("admin"); err == ErrUserNotFound {
+		hash, err := HashPassword("password")
+		if err != nil {
+			return nil, err
+		}
 		store.SaveUser(&User{
 			Username:     "admin",
-			PasswordHash: "password", // In production: bcrypt
+			PasswordHash: hash,
 			Role:         "admin",
+			Namespace:    SystemNamespace,
 		})
 	}
 
+	return NewAuthEngineWithStore(node, logger, store), nil
+}
+
+// NewAuthEngineRaft creates an AuthEngine backed by a RaftUserStore, so
+// credentials converge across every node in the cluster instead of living in
+// a single node's users.json. Bootstraps the same default admin user
+// NewAuthEngine does.
+func NewAuthEngineRaft(node *cluster.Node, logger *zap.Logger) (*AuthEngine, error) {
+	store := NewRaftUserStore(node)
+
+	if _, err := store.GetUser("admin"); err == ErrUserNotFound {
+		hash, err := HashPassword("password")
+		if err != nil {
+			return nil, err
+		}
+		if err := store.SaveUser(&User{
+			Username:     "admin",
+			PasswordHash: hash,
+			Role:         "admin",
+			Namespace:    SystemNamespace,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewAuthEngineWithStore(node, logger, store), nil
+}
+
+// NewAuthEngineWithStore creates an AuthEngine against an arbitrary UserStore
+// implementation, letting the caller choose FileUserStore, SQLUserStore,
+// LDAPUserStore, OIDCUserStore or RaftUserStore without AuthEngine knowing
+// the difference.
+func NewAuthEngineWithStore(node *cluster.Node, logger *zap.Logger, store UserStore) *AuthEngine {
 	e := &AuthEngine{
-		node:        node,
-		logger:      logger,
-		store:       store,
-		secretKey:   []byte("luma-super-secret-key-change-me"),
+		node:   node,
+		logger: logger,
+		store:  store,
+		keyring: []KeyEntry{
+			{ID: "initial", Secret: []byte("luma-super-secret-key-change-me"), NotBefore: time.Unix(0, 0)},
+		},
 		permissions: make(map[string]map[Action]bool),
+		attempts:    make(map[string]*loginAttempts),
 	}
 
 	// Setup Default Roles (MVP)
@@ -72,7 +179,7 @@ func NewAuthEngine(node *cluster.Node, logger *zap.Logger) (*AuthEngine, error)
 		ActionRead: true,
 	}
 
-	return e, nil
+	return e
 }
 
 func (e *AuthEngine) Start() error {
@@ -80,52 +187,258 @@ func (e *AuthEngine) Start() error {
 	return nil
 }
 
-// GenerateToken creates a new JWT for a user
-func (e *AuthEngine) GenerateToken(username, password string) (string, error) {
+// SigningKey returns the secret of the current signing key, for callers
+// (e.g. GraphQLEngine's pagination cursors) that want to reuse it rather
+// than minting and managing a secret of their own. Note this pins the
+// caller to whatever key is active right now - it won't follow RotateKey.
+func (e *AuthEngine) SigningKey() []byte {
+	return e.currentKey().Secret
+}
+
+// currentKey returns the newest signing key, the one GenerateToken signs new
+// tokens with.
+func (e *AuthEngine) currentKey() KeyEntry {
+	e.keyringMu.RLock()
+	defer e.keyringMu.RUnlock()
+	return e.keyring[len(e.keyring)-1]
+}
+
+// keyByID returns the keyring entry with the given kid, for ValidateToken.
+func (e *AuthEngine) keyByID(id string) (KeyEntry, bool) {
+	e.keyringMu.RLock()
+	defer e.keyringMu.RUnlock()
+	for _, k := range e.keyring {
+		if k.ID == id {
+			return k, true
+		}
+	}
+	return KeyEntry{}, false
+}
+
+// RotateKey generates a new JWT signing key and makes it the one
+// GenerateToken signs with going forward. The outgoing key is kept in the
+// keyring with NotAfter set to tokenTTL from now, so tokens it already
+// signed keep validating by kid until they'd have expired anyway, rather
+// than every outstanding session being invalidated immediately. The updated
+// keyring is replicated via cluster.Node.Apply - the same Command pattern
+// RaftUserStore.apply uses for credentials - before it's adopted locally, so
+// a failed Apply (not leader, Raft unavailable) leaves this node's keyring
+// untouched instead of permanently diverging from the rest of the cluster.
+func (e *AuthEngine) RotateKey() error {
+	e.rotateMu.Lock()
+	defer e.rotateMu.Unlock()
+
+	id, secret, err := generateSigningKey()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	e.keyringMu.RLock()
+	candidate := make([]KeyEntry, len(e.keyring))
+	for i, k := range e.keyring {
+		if k.NotAfter.IsZero() {
+			k.NotAfter = now.Add(tokenTTL)
+		}
+		candidate[i] = k
+	}
+	e.keyringMu.RUnlock()
+	candidate = append(candidate, KeyEntry{ID: id, Secret: secret, NotBefore: now})
+
+	if e.node != nil {
+		payload, err := json.Marshal(candidate)
+		if err != nil {
+			return err
+		}
+		if err := e.node.Apply(context.Background(), &cluster.Command{Op: authKeyringCommandOp, Value: payload}); err != nil {
+			return err
+		}
+	}
+
+	e.keyringMu.Lock()
+	e.keyring = candidate
+	e.keyringMu.Unlock()
+	return nil
+}
+
+// generateSigningKey mints a new signing key ID and secret, following the
+// same crypto/rand + hex pattern dialects.generateJobID uses for random IDs.
+func generateSigningKey() (id string, secret []byte, err error) {
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", nil, fmt.Errorf("generate key id: %w", err)
+	}
+
+	secret = make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("generate key secret: %w", err)
+	}
+
+	return hex.EncodeToString(idBytes), secret, nil
+}
+
+// AuthenticateUser verifies a username/password pair against the pluggable
+// store (argon2id-hashed). Repeated failures for the same username are rate
+// limited to blunt brute-force attempts. Used directly by callers that just
+// need the resolved User (e.g. dialects.Authorizer), and by GenerateToken
+// before it mints a JWT.
+func (e *AuthEngine) AuthenticateUser(username, password string) (*User, error) {
+	if err := e.checkRateLimit(username); err != nil {
+		return nil, err
+	}
+
 	user, err := e.store.GetUser(username)
 	if err != nil {
-		return "", ErrUserNotFound
+		e.recordLoginFailure(username)
+		return nil, ErrUserNotFound
+	}
+
+	if !VerifyPassword(password, user.PasswordHash) {
+		e.recordLoginFailure(username)
+		return nil, errors.New("invalid password")
+	}
+
+	e.clearLoginFailures(username)
+
+	// Auto-upgrade: a successful login against a legacy bcrypt or plaintext
+	// hash is the one point we know the plaintext password, so re-hash it
+	// with argon2id now rather than waiting for a password change the user
+	// may never make.
+	if !IsModernHash(user.PasswordHash) {
+		if hash, err := HashPassword(password); err != nil {
+			e.logger.Warn("failed to upgrade password hash", zap.String("username", username), zap.Error(err))
+		} else {
+			user.PasswordHash = hash
+			if err := e.store.SaveUser(user); err != nil {
+				e.logger.Warn("failed to persist upgraded password hash", zap.String("username", username), zap.Error(err))
+			}
+		}
 	}
 
-	// In production: Verify password hash
-	if user.PasswordHash != password {
-		return "", errors.New("invalid password")
+	return user, nil
+}
+
+// GenerateToken verifies a username/password pair against the pluggable
+// store and mints a JWT.
+func (e *AuthEngine) GenerateToken(username, password string) (string, error) {
+	user, err := e.AuthenticateUser(username, password)
+	if err != nil {
+		return "", err
 	}
 
-	expirationTime := time.Now().Add(24 * time.Hour)
+	expirationTime := time.Now().Add(tokenTTL)
 	claims := &Claims{
-		UserID: user.ID,
-		Role:   user.Role,
+		UserID:    user.ID,
+		Role:      user.Role,
+		Namespace: user.Namespace,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			Issuer:    "luma-platform",
 		},
 	}
 
+	key := e.currentKey()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(e.secretKey)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Secret)
 }
 
-// Register creates a new user
-func (e *AuthEngine) Register(username, password, role string) error {
+// Register creates a new user in the given tenant namespace. Pass
+// SystemNamespace for operators who need to write "shared_"-prefixed
+// collections.
+//
+// namespace must not contain "__": the GraphQL layer concatenates
+// tenant+"__"+collection to scope storage, and a namespace containing that
+// separator could collide with another tenant's collection (tenant "a__b"
+// plus collection "c" resolves to the same physical name as tenant "a" plus
+// collection "b__c").
+func (e *AuthEngine) Register(username, password, role, namespace string) error {
+	if strings.Contains(namespace, "__") {
+		return ErrInvalidNamespace
+	}
+
 	if _, err := e.store.GetUser(username); err == nil {
 		return ErrUserAlreadyExists
 	}
 
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
 	return e.store.SaveUser(&User{
 		ID:           username, // Simple ID for MVP
 		Username:     username,
-		PasswordHash: password, // In production: bcrypt
+		PasswordHash: hash,
 		Role:         role,
+		Namespace:    namespace,
 	})
 }
 
+// ListUsers returns every user known to the store. Used by the admin-only
+// /debug/auth/users introspection endpoint; callers must strip PasswordHash
+// before returning this over the wire.
+func (e *AuthEngine) ListUsers() ([]*User, error) {
+	return e.store.ListUsers()
+}
+
+// checkRateLimit rejects a login attempt if username has failed too many
+// times within loginLockoutWindow.
+func (e *AuthEngine) checkRateLimit(username string) error {
+	e.attemptsMu.Lock()
+	defer e.attemptsMu.Unlock()
+
+	a, ok := e.attempts[username]
+	if !ok {
+		return nil
+	}
+
+	if time.Since(a.firstFail) > loginLockoutWindow {
+		delete(e.attempts, username)
+		return nil
+	}
+
+	if a.count >= maxLoginAttempts {
+		return ErrTooManyAttempts
+	}
+	return nil
+}
+
+func (e *AuthEngine) recordLoginFailure(username string) {
+	e.attemptsMu.Lock()
+	defer e.attemptsMu.Unlock()
+
+	a, ok := e.attempts[username]
+	if !ok || time.Since(a.firstFail) > loginLockoutWindow {
+		a = &loginAttempts{firstFail: time.Now()}
+		e.attempts[username] = a
+	}
+	a.count++
+}
+
+func (e *AuthEngine) clearLoginFailures(username string) {
+	e.attemptsMu.Lock()
+	defer e.attemptsMu.Unlock()
+	delete(e.attempts, username)
+}
+
 // ValidateToken parses and validates a JWT
 func (e *AuthEngine) ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return e.secretKey, nil
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			// Tokens minted before the keyring existed carry no kid; they
+			// were all signed by the bootstrap entry, which RotateKey never
+			// removes (only retires).
+			kid = "initial"
+		}
+		key, ok := e.keyByID(kid)
+		if !ok || !key.active(time.Now()) {
+			return nil, ErrUnknownSigningKey
+		}
+		return key.Secret, nil
 	})
 
 	if err != nil {
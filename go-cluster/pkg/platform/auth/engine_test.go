@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// memoryUserStore is a minimal in-memory UserStore for tests that don't
+// want FileUserStore's on-disk JSON persistence.
+type memoryUserStore struct {
+	users map[string]*User
+}
+
+func newMemoryUserStore() *memoryUserStore {
+	return &memoryUserStore{users: make(map[string]*User)}
+}
+
+func (s *memoryUserStore) GetUser(username string) (*User, error) {
+	u, ok := s.users[username]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (s *memoryUserStore) SaveUser(user *User) error {
+	s.users[user.Username] = user
+	return nil
+}
+
+func (s *memoryUserStore) ListUsers() ([]*User, error) {
+	var out []*User
+	for _, u := range s.users {
+		out = append(out, u)
+	}
+	return out, nil
+}
+
+func (s *memoryUserStore) DeleteUser(username string) error {
+	delete(s.users, username)
+	return nil
+}
+
+func (s *memoryUserStore) UpdateRoles(username, role string) error {
+	u, ok := s.users[username]
+	if !ok {
+		return ErrUserNotFound
+	}
+	u.Role = role
+	return nil
+}
+
+func newTestAuthEngine(t *testing.T) *AuthEngine {
+	t.Helper()
+	store := newMemoryUserStore()
+	hash, err := HashPassword("correct-password")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	store.SaveUser(&User{Username: "alice", PasswordHash: hash, Role: "viewer"})
+
+	return NewAuthEngineWithStore(nil, zap.NewNop(), store)
+}
+
+func TestAuthEngine_AuthenticateUser(t *testing.T) {
+	e := newTestAuthEngine(t)
+
+	if _, err := e.AuthenticateUser("alice", "correct-password"); err != nil {
+		t.Fatalf("AuthenticateUser() with correct password error = %v", err)
+	}
+
+	if _, err := e.AuthenticateUser("alice", "wrong-password"); err == nil {
+		t.Error("AuthenticateUser() with wrong password error = nil, want an error")
+	}
+
+	if _, err := e.AuthenticateUser("nobody", "whatever"); err != ErrUserNotFound {
+		t.Errorf("AuthenticateUser() for unknown user error = %v, want %v", err, ErrUserNotFound)
+	}
+}
+
+func TestAuthEngine_RateLimitsRepeatedFailures(t *testing.T) {
+	e := newTestAuthEngine(t)
+
+	for i := 0; i < maxLoginAttempts; i++ {
+		if _, err := e.AuthenticateUser("alice", "wrong-password"); err == nil {
+			t.Fatalf("attempt %d: AuthenticateUser() error = nil, want an error", i)
+		}
+	}
+
+	if _, err := e.AuthenticateUser("alice", "correct-password"); err != ErrTooManyAttempts {
+		t.Errorf("AuthenticateUser() after %d failures error = %v, want %v", maxLoginAttempts, err, ErrTooManyAttempts)
+	}
+}
+
+func TestAuthEngine_SuccessfulLoginClearsFailures(t *testing.T) {
+	e := newTestAuthEngine(t)
+
+	for i := 0; i < maxLoginAttempts-1; i++ {
+		if _, err := e.AuthenticateUser("alice", "wrong-password"); err == nil {
+			t.Fatalf("attempt %d: AuthenticateUser() error = nil, want an error", i)
+		}
+	}
+
+	if _, err := e.AuthenticateUser("alice", "correct-password"); err != nil {
+		t.Fatalf("AuthenticateUser() with correct password error = %v, want nil", err)
+	}
+
+	// The successful login should have reset the failure count, so a fresh
+	// run of failures is needed before the rate limit trips again.
+	if _, err := e.AuthenticateUser("alice", "wrong-password"); err == nil {
+		t.Fatal("AuthenticateUser() error = nil, want an error")
+	}
+	if _, err := e.AuthenticateUser("alice", "correct-password"); err != nil {
+		t.Errorf("AuthenticateUser() error = %v, want nil (rate limit should have reset)", err)
+	}
+}
+
+func TestAuthEngine_AuthenticateUserUpgradesLegacyHash(t *testing.T) {
+	store := newMemoryUserStore()
+	store.SaveUser(&User{Username: "bob", PasswordHash: "plain-password", Role: "viewer"})
+	e := NewAuthEngineWithStore(nil, zap.NewNop(), store)
+
+	if _, err := e.AuthenticateUser("bob", "plain-password"); err != nil {
+		t.Fatalf("AuthenticateUser() error = %v", err)
+	}
+
+	user, err := store.GetUser("bob")
+	if err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+	if !IsModernHash(user.PasswordHash) {
+		t.Errorf("PasswordHash = %q, want it upgraded to argon2id after a successful login", user.PasswordHash)
+	}
+}
+
+func TestAuthEngine_RegisterRejectsAmbiguousNamespace(t *testing.T) {
+	e := NewAuthEngineWithStore(nil, zap.NewNop(), newMemoryUserStore())
+
+	if err := e.Register("carol", "password", "viewer", "tenant__a"); err != ErrInvalidNamespace {
+		t.Errorf("Register() with namespace containing \"__\" error = %v, want %v", err, ErrInvalidNamespace)
+	}
+
+	if err := e.Register("carol", "password", "viewer", "tenant-a"); err != nil {
+		t.Errorf("Register() with a valid namespace error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// argon2id parameters tuned for an interactive login path (~50ms on
+// commodity hardware), not long-term archival hashing.
+const (
+	argon2Time    = 1
+	argon2Memory  = 64 * 1024 // KiB
+	argon2Threads = 4
+	argon2KeyLen  = 32
+	saltLen       = 16
+)
+
+// HashPassword derives an argon2id hash encoded as
+// "$argon2id$v=19$m=65536,t=1,p=4$<salt>$<hash>", the same format used by
+// the reference Go argon2id implementations so hashes are portable.
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("generate salt: %w", err)
+	}
+
+	hash := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+
+	encoded := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash))
+
+	return encoded, nil
+}
+
+// IsModernHash reports whether encodedHash is already in this package's
+// current argon2id format. A false here (a bcrypt or plaintext hash, from
+// a user created before this format existed) is what tells
+// AuthEngine.AuthenticateUser to re-hash and persist the upgrade on a
+// successful login.
+func IsModernHash(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$argon2id$")
+}
+
+// VerifyPassword checks password against an encoded hash, in constant
+// time. Dispatches on the hash's own format: this package's current
+// argon2id, a bcrypt hash ($2a$/$2b$/$2y$, from before this package
+// switched to argon2id), or - oldest of all - a plaintext PasswordHash
+// from before this package hashed passwords at all. Only the argon2id path
+// is reachable for anyone HashPassword has touched since; the other two
+// exist purely so AuthenticateUser's auto-upgrade has something to upgrade
+// from.
+func VerifyPassword(password, encodedHash string) bool {
+	switch {
+	case strings.HasPrefix(encodedHash, "$argon2id$"):
+		return verifyArgon2id(password, encodedHash)
+	case strings.HasPrefix(encodedHash, "$2a$"), strings.HasPrefix(encodedHash, "$2b$"), strings.HasPrefix(encodedHash, "$2y$"):
+		return bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password)) == nil
+	default:
+		return subtle.ConstantTimeCompare([]byte(password), []byte(encodedHash)) == 1
+	}
+}
+
+func verifyArgon2id(password, encodedHash string) bool {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return false
+	}
+
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false
+	}
+
+	got := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(want)))
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
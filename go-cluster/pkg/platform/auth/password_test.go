@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashPassword_VerifyPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+
+	if !IsModernHash(hash) {
+		t.Errorf("IsModernHash(%q) = false, want true", hash)
+	}
+
+	if !VerifyPassword("correct horse battery staple", hash) {
+		t.Error("VerifyPassword() with correct password = false, want true")
+	}
+	if VerifyPassword("wrong password", hash) {
+		t.Error("VerifyPassword() with wrong password = true, want false")
+	}
+}
+
+func TestHashPassword_UniqueSaltPerCall(t *testing.T) {
+	a, err := HashPassword("same-password")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	b, err := HashPassword("same-password")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+
+	if a == b {
+		t.Error("HashPassword() produced identical hashes for two calls, want distinct salts")
+	}
+}
+
+func TestVerifyPassword_LegacyBcrypt(t *testing.T) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt.GenerateFromPassword() error = %v", err)
+	}
+	bcryptHash := string(hashed)
+
+	if !VerifyPassword("password123", bcryptHash) {
+		t.Error("VerifyPassword() with correct password against a bcrypt hash = false, want true")
+	}
+	if VerifyPassword("definitely-wrong", bcryptHash) {
+		t.Error("VerifyPassword() with wrong password against a bcrypt hash = true, want false")
+	}
+}
+
+func TestVerifyPassword_LegacyPlaintext(t *testing.T) {
+	if !VerifyPassword("plaintext-password", "plaintext-password") {
+		t.Error("VerifyPassword() with matching legacy plaintext hash = false, want true")
+	}
+	if VerifyPassword("wrong", "plaintext-password") {
+		t.Error("VerifyPassword() with mismatched legacy plaintext hash = true, want false")
+	}
+}
+
+func TestIsModernHash(t *testing.T) {
+	tests := []struct {
+		hash string
+		want bool
+	}{
+		{"$argon2id$v=19$m=65536,t=1,p=4$c2FsdA$aGFzaA", true},
+		{"$2a$10$abcdefghijklmnopqrstuv", false},
+		{"plaintext", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsModernHash(tt.hash); got != tt.want {
+			t.Errorf("IsModernHash(%q) = %v, want %v", tt.hash, got, tt.want)
+		}
+	}
+}
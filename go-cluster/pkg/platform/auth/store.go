@@ -13,9 +13,14 @@ type User struct {
 	Username     string `json:"username"`
 	PasswordHash string `json:"password_hash"`
 	Role         string `json:"role"`
+	// Namespace is the tenant this user belongs to, carried into their JWT
+	// so the GraphQL engine can scope every collection they touch to it.
+	// Empty means the default (non-multi-tenant) namespace.
+	Namespace string `json:"namespace"`
 }
 
-// UserStore defines the interface for user persistence
+// UserStore defines the interface for user persistence. Implementations:
+// FileUserStore (MVP default), SQLUserStore, LDAPUserStore, OIDCUserStore.
 type UserStore interface {
 	// GetUser retrieves one user by username
 	GetUser(username string) (*User, error)
@@ -23,4 +28,8 @@ type UserStore interface {
 	SaveUser(user *User) error
 	// ListUsers returns all users
 	ListUsers() ([]*User, error)
+	// DeleteUser removes a user by username
+	DeleteUser(username string) error
+	// UpdateRoles changes a user's role without touching their credentials
+	UpdateRoles(username, role string) error
 }
@@ -99,3 +99,26 @@ func (s *FileUserStore) ListUsers() ([]*User, error) {
 	}
 	return list, nil
 }
+
+func (s *FileUserStore) DeleteUser(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[username]; !ok {
+		return ErrUserNotFound
+	}
+	delete(s.users, username)
+	return s.save()
+}
+
+func (s *FileUserStore) UpdateRoles(username, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		return ErrUserNotFound
+	}
+	u.Role = role
+	return s.save()
+}
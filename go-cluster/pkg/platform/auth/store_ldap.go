@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPUserStore authenticates users by binding against an LDAP/Active
+// Directory server. Roles come from group membership, mapped through
+// GroupRoleMap. Users are read-only from LumaDB's point of view - SaveUser,
+// DeleteUser and UpdateRoles all fail since the directory is the source of
+// truth.
+type LDAPUserStore struct {
+	addr         string // e.g. "ldap://dc1.corp.example.com:389"
+	baseDN       string
+	bindDNFormat string // e.g. "uid=%s,ou=people,dc=example,dc=com"
+	groupRoleMap map[string]string
+}
+
+// NewLDAPUserStore creates an LDAP-backed store. groupRoleMap maps an LDAP
+// group CN to a LumaDB role, e.g. {"lumadb-admins": "admin"}.
+func NewLDAPUserStore(addr, baseDN, bindDNFormat string, groupRoleMap map[string]string) *LDAPUserStore {
+	return &LDAPUserStore{
+		addr:         addr,
+		baseDN:       baseDN,
+		bindDNFormat: bindDNFormat,
+		groupRoleMap: groupRoleMap,
+	}
+}
+
+// Bind verifies credentials against the directory and resolves the user's
+// LumaDB role from their group membership. This is the LDAP equivalent of
+// AuthEngine's password check, so FileUserStore/SQLUserStore's SaveUser-based
+// GetUser+compare flow doesn't apply here.
+func (s *LDAPUserStore) Bind(username, password string) (*User, error) {
+	conn, err := ldap.DialURL(s.addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial LDAP: %w", err)
+	}
+	defer conn.Close()
+
+	bindDN := fmt.Sprintf(s.bindDNFormat, username)
+	if err := conn.Bind(bindDN, password); err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	searchReq := ldap.NewSearchRequest(
+		s.baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf("(&(objectClass=person)(uid=%s))", ldap.EscapeFilter(username)),
+		[]string{"memberOf"}, nil,
+	)
+	result, err := conn.Search(searchReq)
+	if err != nil || len(result.Entries) == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	role := "viewer"
+	for _, group := range result.Entries[0].GetAttributeValues("memberOf") {
+		// memberOf holds the group's full DN; the map is keyed by its CN.
+		if dn, err := ldap.ParseDN(group); err == nil && len(dn.RDNs) > 0 && len(dn.RDNs[0].Attributes) > 0 {
+			group = dn.RDNs[0].Attributes[0].Value
+		}
+		if r, ok := s.groupRoleMap[group]; ok {
+			role = r
+			break
+		}
+	}
+
+	return &User{ID: username, Username: username, Role: role}, nil
+}
+
+func (s *LDAPUserStore) GetUser(username string) (*User, error) {
+	return nil, fmt.Errorf("LDAPUserStore is bind-only; use Bind to authenticate %s", username)
+}
+
+func (s *LDAPUserStore) SaveUser(user *User) error {
+	return fmt.Errorf("LDAPUserStore is read-only: users are provisioned in the directory")
+}
+
+func (s *LDAPUserStore) ListUsers() ([]*User, error) {
+	return nil, fmt.Errorf("LDAPUserStore does not support listing; query the directory directly")
+}
+
+func (s *LDAPUserStore) DeleteUser(username string) error {
+	return fmt.Errorf("LDAPUserStore is read-only: remove %s from the directory instead", username)
+}
+
+func (s *LDAPUserStore) UpdateRoles(username, role string) error {
+	return fmt.Errorf("LDAPUserStore roles are derived from group membership, not settable")
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+)
+
+// OIDCUserStore provisions users on first login from an external identity
+// provider's ID token claims ("just-in-time provisioning"). Unlike
+// FileUserStore/SQLUserStore it never stores a password hash - the IdP is
+// the only party that ever sees a credential.
+type OIDCUserStore struct {
+	provider    *oidc.Provider
+	verifier    *oidc.IDTokenVerifier
+	roleClaim   string // e.g. "roles" or "groups"
+	defaultRole string
+	mu          sync.RWMutex
+	provisioned map[string]*User // username -> User, cached after first login
+}
+
+// NewOIDCUserStore discovers the provider's configuration (issuer,
+// JWKS endpoint) and prepares an ID token verifier for clientID.
+func NewOIDCUserStore(ctx context.Context, issuerURL, clientID, roleClaim, defaultRole string) (*OIDCUserStore, error) {
+	provider, err := oidc.NewProvider(ctx, issuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("discover OIDC provider %s: %w", issuerURL, err)
+	}
+
+	return &OIDCUserStore{
+		provider:    provider,
+		verifier:    provider.Verifier(&oidc.Config{ClientID: clientID}),
+		roleClaim:   roleClaim,
+		defaultRole: defaultRole,
+		provisioned: make(map[string]*User),
+	}, nil
+}
+
+// Provision verifies rawIDToken and creates (or refreshes) the local user
+// record from its claims, returning the resulting User.
+func (s *OIDCUserStore) Provision(ctx context.Context, rawIDToken string) (*User, error) {
+	idToken, err := s.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("verify ID token: %w", err)
+	}
+
+	var claims struct {
+		Subject string   `json:"sub"`
+		Email   string   `json:"email"`
+		Roles   []string `json:"-"`
+	}
+	var raw map[string]interface{}
+	if err := idToken.Claims(&raw); err != nil {
+		return nil, fmt.Errorf("parse claims: %w", err)
+	}
+	claims.Subject = idToken.Subject
+	if email, ok := raw["email"].(string); ok {
+		claims.Email = email
+	}
+
+	role := s.defaultRole
+	if rawRoles, ok := raw[s.roleClaim].([]interface{}); ok && len(rawRoles) > 0 {
+		if r, ok := rawRoles[0].(string); ok {
+			role = r
+		}
+	}
+
+	user := &User{
+		ID:       claims.Subject,
+		Username: claims.Email,
+		Role:     role,
+	}
+
+	s.mu.Lock()
+	s.provisioned[user.Username] = user
+	s.mu.Unlock()
+
+	return user, nil
+}
+
+func (s *OIDCUserStore) GetUser(username string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.provisioned[username]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (s *OIDCUserStore) SaveUser(user *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.provisioned[user.Username] = user
+	return nil
+}
+
+func (s *OIDCUserStore) ListUsers() ([]*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]*User, 0, len(s.provisioned))
+	for _, u := range s.provisioned {
+		list = append(list, u)
+	}
+	return list, nil
+}
+
+func (s *OIDCUserStore) DeleteUser(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.provisioned[username]; !ok {
+		return ErrUserNotFound
+	}
+	delete(s.provisioned, username)
+	return nil
+}
+
+func (s *OIDCUserStore) UpdateRoles(username, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.provisioned[username]
+	if !ok {
+		return ErrUserNotFound
+	}
+	u.Role = role
+	return nil
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/lumadb/cluster/pkg/cluster"
+)
+
+// Raft command ops for RaftUserStore, the same Command-through-node.Apply
+// pattern pkg/api/grpc.go's applyDialectQuery uses to replicate dialect
+// writes.
+const (
+	authOpUserCreate = "user_create"
+	authOpUserUpdate = "user_update"
+	authOpUserDelete = "user_delete"
+	authOpRoleGrant  = "role_grant"
+)
+
+// authCommand is the JSON payload carried on a cluster.Command's Value for
+// every RaftUserStore mutation.
+type authCommand struct {
+	Op       string `json:"op"`
+	User     *User  `json:"user,omitempty"`
+	Username string `json:"username,omitempty"`
+	Role     string `json:"role,omitempty"`
+}
+
+// RaftUserStore is a UserStore whose writes replicate through
+// cluster.Node.Apply instead of a local users.json, so every node in the
+// cluster converges on the same credentials. FileUserStore remains the
+// default for bootstrap and tests, where there's no cluster for
+// credentials to converge across - see NewAuthEngine vs NewAuthEngineRaft.
+//
+// Reads are served from an in-memory snapshot this store updates itself
+// once its own Apply call returns, the same way pkg/meta's storeFSM
+// applies directly rather than through a replicated log - see storeFSM's
+// doc comment for why: cluster.Node doesn't yet sit behind a real Raft
+// transport in this checkout, so there's no separate FSM callback to
+// update this snapshot from.
+type RaftUserStore struct {
+	node *cluster.Node
+
+	mu    sync.RWMutex
+	users map[string]*User
+}
+
+// NewRaftUserStore returns a RaftUserStore with no users yet applied.
+func NewRaftUserStore(node *cluster.Node) *RaftUserStore {
+	return &RaftUserStore{node: node, users: make(map[string]*User)}
+}
+
+func (s *RaftUserStore) apply(cmd authCommand) error {
+	payload, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+	if err := s.node.Apply(context.Background(), &cluster.Command{Op: "auth", Value: payload}); err != nil {
+		return err
+	}
+	return s.applyLocal(cmd)
+}
+
+func (s *RaftUserStore) applyLocal(cmd authCommand) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch cmd.Op {
+	case authOpUserCreate, authOpUserUpdate:
+		if cmd.User == nil {
+			return fmt.Errorf("auth: %s command missing user", cmd.Op)
+		}
+		s.users[cmd.User.Username] = cmd.User
+	case authOpUserDelete:
+		delete(s.users, cmd.Username)
+	case authOpRoleGrant:
+		u, ok := s.users[cmd.Username]
+		if !ok {
+			return ErrUserNotFound
+		}
+		u.Role = cmd.Role
+	default:
+		return fmt.Errorf("auth: unknown command op %q", cmd.Op)
+	}
+	return nil
+}
+
+func (s *RaftUserStore) GetUser(username string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (s *RaftUserStore) SaveUser(user *User) error {
+	op := authOpUserCreate
+	if _, err := s.GetUser(user.Username); err == nil {
+		op = authOpUserUpdate
+	}
+	return s.apply(authCommand{Op: op, User: user})
+}
+
+func (s *RaftUserStore) ListUsers() ([]*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]*User, 0, len(s.users))
+	for _, u := range s.users {
+		list = append(list, u)
+	}
+	return list, nil
+}
+
+func (s *RaftUserStore) DeleteUser(username string) error {
+	if _, err := s.GetUser(username); err != nil {
+		return err
+	}
+	return s.apply(authCommand{Op: authOpUserDelete, Username: username})
+}
+
+func (s *RaftUserStore) UpdateRoles(username, role string) error {
+	if _, err := s.GetUser(username); err != nil {
+		return err
+	}
+	return s.apply(authCommand{Op: authOpRoleGrant, Username: username, Role: role})
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SQLUserStore persists users in a sqlx-supported RDBMS. Its queries are
+// written for sqlite: GetUser, DeleteUser and UpdateRoles use "?" placeholders,
+// which postgres does not accept, and SaveUser's ON CONFLICT upsert and the
+// TEXT PRIMARY KEY column are not accepted by mysql.
+type SQLUserStore struct {
+	db *sqlx.DB
+}
+
+// NewSQLUserStore opens a connection pool for driverName (sqlite3, mysql,
+// postgres) and ensures the users table exists.
+func NewSQLUserStore(driverName, dsn string) (*SQLUserStore, error) {
+	db, err := sqlx.Connect(driverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s: %w", driverName, err)
+	}
+
+	schema := `CREATE TABLE IF NOT EXISTS users (
+		id            TEXT PRIMARY KEY,
+		username      TEXT UNIQUE NOT NULL,
+		password_hash TEXT NOT NULL,
+		role          TEXT NOT NULL
+	)`
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("create users table: %w", err)
+	}
+
+	return &SQLUserStore{db: db}, nil
+}
+
+func (s *SQLUserStore) GetUser(username string) (*User, error) {
+	var u User
+	err := s.db.Get(&u, `SELECT id, username, password_hash, role FROM users WHERE username = ?`, username)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (s *SQLUserStore) SaveUser(user *User) error {
+	_, err := s.db.NamedExec(`
+		INSERT INTO users (id, username, password_hash, role) VALUES (:id, :username, :password_hash, :role)
+		ON CONFLICT(username) DO UPDATE SET password_hash = excluded.password_hash, role = excluded.role
+	`, user)
+	return err
+}
+
+func (s *SQLUserStore) ListUsers() ([]*User, error) {
+	var users []*User
+	if err := s.db.Select(&users, `SELECT id, username, password_hash, role FROM users`); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// DeleteUser removes a user by username.
+func (s *SQLUserStore) DeleteUser(username string) error {
+	res, err := s.db.Exec(`DELETE FROM users WHERE username = ?`, username)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// UpdateRoles changes a user's role without touching their credentials.
+func (s *SQLUserStore) UpdateRoles(username, role string) error {
+	res, err := s.db.Exec(`UPDATE users SET role = ? WHERE username = ?`, role, username)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Close releases the underlying connection pool.
+func (s *SQLUserStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,127 @@
+package platform
+
+import (
+	"net/http/pprof"
+
+	"github.com/lumadb/cluster/pkg/platform/auth"
+	"github.com/lumadb/cluster/pkg/query"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// setupDebugRoutes mounts an operator-only /debug/* subtree modeled on the
+// debug HTTP surfaces mature control planes (e.g. Istio's pilot xds debug
+// endpoints) expose: live cluster/trigger/schema/auth state plus standard Go
+// pprof profiles, all gated behind authMiddleware + adminMiddleware.
+func (s *Server) setupDebugRoutes() {
+	admin := func(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return s.authMiddleware(s.adminMiddleware(h))
+	}
+
+	s.router.GET("/debug/cluster/peers", admin(s.handleDebugPeers))
+	s.router.GET("/debug/triggers", admin(s.handleDebugTriggers))
+	s.router.GET("/debug/schema", admin(s.handleDebugSchema))
+	s.router.GET("/debug/plans", admin(s.handleDebugPlans))
+	s.router.GET("/debug/auth/users", admin(s.handleDebugUsers))
+
+	s.router.GET("/debug/pprof/", admin(fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Index)))
+	s.router.GET("/debug/pprof/cmdline", admin(fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Cmdline)))
+	s.router.GET("/debug/pprof/profile", admin(fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Profile)))
+	s.router.GET("/debug/pprof/symbol", admin(fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Symbol)))
+	s.router.GET("/debug/pprof/trace", admin(fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Trace)))
+	// Named profiles (heap, goroutine, allocs, block, mutex, ...) all go
+	// through pprof.Index, which dispatches on the trailing path segment.
+	s.router.GET("/debug/pprof/{profile:*}", admin(fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Index)))
+}
+
+// handleDebugPeers reports the live cluster peer list with role and health,
+// as known to this node.
+func (s *Server) handleDebugPeers(ctx *fasthttp.RequestCtx) {
+	jsonResponse(ctx, fasthttp.StatusOK, map[string]interface{}{
+		"peers": s.node.Peers(),
+	})
+}
+
+// handleDebugTriggers reports every registered trigger config and the
+// timestamp it last fired at.
+func (s *Server) handleDebugTriggers(ctx *fasthttp.RequestCtx) {
+	jsonResponse(ctx, fasthttp.StatusOK, map[string]interface{}{
+		"triggers": s.node.ListTriggers(),
+	})
+}
+
+// handleDebugSchema dumps the caller's tenant schema as SDL. Defaults to
+// auth.SystemNamespace if no X-Luma-Namespace header or namespace claim is
+// present, so an operator hitting this with the bootstrap admin account
+// sees the system tenant's schema.
+func (s *Server) handleDebugSchema(ctx *fasthttp.RequestCtx) {
+	identity := identityFromCtx(ctx)
+	if identity.TenantID == "" {
+		identity.TenantID = auth.SystemNamespace
+	}
+
+	sdl, err := s.platform.gqlEngine.PrintSDL(identity.TenantID)
+	if err != nil {
+		errorResponse(ctx, fasthttp.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.SetContentType("text/plain; charset=utf-8")
+	ctx.SetStatusCode(fasthttp.StatusOK)
+	ctx.WriteString(sdl)
+}
+
+// handleDebugPlans runs the query Planner over ?query= and returns the
+// resulting Plan without executing it - useful for checking how a query
+// would be scattered, aggregated or joined before running it for real.
+func (s *Server) handleDebugPlans(ctx *fasthttp.RequestCtx) {
+	q := string(ctx.QueryArgs().Peek("query"))
+	if q == "" {
+		errorResponse(ctx, fasthttp.StatusBadRequest, "missing query parameter")
+		return
+	}
+
+	plan, err := query.NewPlanner().Plan(q)
+	if err != nil {
+		errorResponse(ctx, fasthttp.StatusBadRequest, err.Error())
+		return
+	}
+
+	jsonResponse(ctx, fasthttp.StatusOK, plan)
+}
+
+// debugUser is the users/roles view exposed at /debug/auth/users - never the
+// password hash.
+type debugUser struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// handleDebugUsers lists every known username and role.
+func (s *Server) handleDebugUsers(ctx *fasthttp.RequestCtx) {
+	users, err := s.platform.authEngine.ListUsers()
+	if err != nil {
+		errorResponse(ctx, fasthttp.StatusInternalServerError, err.Error())
+		return
+	}
+
+	out := make([]debugUser, 0, len(users))
+	for _, u := range users {
+		out = append(out, debugUser{Username: u.Username, Role: u.Role})
+	}
+
+	jsonResponse(ctx, fasthttp.StatusOK, map[string]interface{}{"users": out})
+}
+
+// adminMiddleware restricts a handler to the "admin" role. Must run after
+// authMiddleware so ctx's "role" user value is populated.
+func (s *Server) adminMiddleware(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		role, ok := ctx.UserValue("role").(string)
+		if !ok || role != "admin" {
+			errorResponse(ctx, fasthttp.StatusForbidden, "admin role required")
+			return
+		}
+		next(ctx)
+	}
+}
@@ -0,0 +1,446 @@
+package federation
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Writable is implemented by a Source that can also accept durable writes,
+// not just be introspected and read from. Any registered Source that
+// satisfies it is eligible for hinted handoff; one that doesn't (a purely
+// read-only introspection source) is simply never spooled to.
+type Writable interface {
+	Source
+	Write(ctx context.Context, payload []byte) error
+}
+
+// HintedHandoffConfig controls a HintedHandoffQueue's on-disk footprint and
+// replay backoff.
+type HintedHandoffConfig struct {
+	// Dir is where each source's segment file is kept, one file per source.
+	Dir string
+	// MaxQueueSize is the most hints kept per source; once reached, the
+	// oldest unreplayed hint is dropped to make room for the newest write,
+	// mirroring InfluxDB HH's bounded-queue-by-age-then-size behavior.
+	MaxQueueSize int
+	// MaxHintAge is how long a hint is retried before it's dropped as stale.
+	MaxHintAge time.Duration
+	// BaseBackoff and MaxBackoff bound the exponential backoff applied
+	// between replay attempts against a source that's still failing.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// DefaultHintedHandoffConfig returns sizing modeled on InfluxDB's hinted
+// handoff defaults: generous queue depth, a day of retry before giving up,
+// and backoff from half a second up to a minute.
+func DefaultHintedHandoffConfig(dir string) HintedHandoffConfig {
+	return HintedHandoffConfig{
+		Dir:          dir,
+		MaxQueueSize: 10000,
+		MaxHintAge:   24 * time.Hour,
+		BaseBackoff:  500 * time.Millisecond,
+		MaxBackoff:   60 * time.Second,
+	}
+}
+
+// hint is one spooled write awaiting replay against its source.
+type hint struct {
+	ID         string    `json:"id"`
+	Payload    []byte    `json:"payload"`
+	EnqueuedAt time.Time `json:"enqueuedAt"`
+	Attempts   int       `json:"attempts"`
+}
+
+// Stats summarizes one source's spooled backlog.
+type Stats struct {
+	Depth         int           `json:"depth"`
+	OldestHintAge time.Duration `json:"oldestHintAge"`
+	Dropped       int64         `json:"dropped"`
+}
+
+// HintedHandoffQueue spools writes bound for a temporarily unavailable
+// federated Source to a per-source append-only segment file on disk, then
+// replays them, in order, once the source recovers. It implements Watcher
+// so a SourceRegistry can wake its drainer the moment a source comes back,
+// instead of waiting out the backoff it accumulated while the source was
+// down.
+type HintedHandoffQueue struct {
+	cfg      HintedHandoffConfig
+	registry *SourceRegistry
+
+	mu     sync.Mutex
+	queues map[string]*sourceQueue
+}
+
+// sourceQueue is the in-memory mirror of one source's segment file, plus
+// the drainer goroutine replaying it.
+type sourceQueue struct {
+	mu      sync.Mutex
+	file    *os.File
+	hints   []*hint
+	dropped int64
+
+	trigger chan struct{}
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+var segmentNameRe = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+func segmentPath(dir, sourceName string) string {
+	return filepath.Join(dir, segmentNameRe.ReplaceAllString(sourceName, "_")+".hh")
+}
+
+// NewHintedHandoffQueue creates a HintedHandoffQueue rooted at cfg.Dir,
+// registering it with registry so it learns about sources as they're added
+// and removed. It does not eagerly load every segment file already on disk;
+// a source's queue is loaded lazily, the first time it's enqueued to or the
+// registry reports the source as added.
+func NewHintedHandoffQueue(cfg HintedHandoffConfig, registry *SourceRegistry) (*HintedHandoffQueue, error) {
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("create hinted handoff dir: %w", err)
+	}
+	hhq := &HintedHandoffQueue{
+		cfg:      cfg,
+		registry: registry,
+		queues:   make(map[string]*sourceQueue),
+	}
+	registry.Watch(hhq)
+	return hhq, nil
+}
+
+// Enqueue durably spools payload for replay against sourceName, draining
+// immediately if the source is already registered and reachable.
+func (hhq *HintedHandoffQueue) Enqueue(sourceName string, payload []byte) error {
+	q, err := hhq.queueFor(sourceName)
+	if err != nil {
+		return err
+	}
+
+	h := &hint{
+		ID:         fmt.Sprintf("%s-%d-%d", sourceName, time.Now().UnixNano(), rand.Int63()),
+		Payload:    payload,
+		EnqueuedAt: time.Now(),
+	}
+	if err := q.append(h, hhq.cfg.MaxQueueSize); err != nil {
+		return fmt.Errorf("spool hint for %s: %w", sourceName, err)
+	}
+
+	q.wake()
+	return nil
+}
+
+// Stats reports queue depth and oldest-hint age per source currently
+// tracked in memory.
+func (hhq *HintedHandoffQueue) Stats() map[string]Stats {
+	hhq.mu.Lock()
+	names := make([]string, 0, len(hhq.queues))
+	qs := make([]*sourceQueue, 0, len(hhq.queues))
+	for name, q := range hhq.queues {
+		names = append(names, name)
+		qs = append(qs, q)
+	}
+	hhq.mu.Unlock()
+
+	out := make(map[string]Stats, len(names))
+	for i, name := range names {
+		out[name] = qs[i].stats()
+	}
+	return out
+}
+
+// Stop shuts down every source's drainer goroutine and closes its segment
+// file. Spooled hints stay on disk for the next process to replay.
+func (hhq *HintedHandoffQueue) Stop() {
+	hhq.mu.Lock()
+	qs := make([]*sourceQueue, 0, len(hhq.queues))
+	for _, q := range hhq.queues {
+		qs = append(qs, q)
+	}
+	hhq.mu.Unlock()
+
+	for _, q := range qs {
+		q.close()
+	}
+}
+
+// OnSourceAdded implements Watcher: a source coming back (or appearing for
+// the first time) wakes its drainer so any spooled hints replay immediately
+// instead of waiting out whatever backoff it last reached.
+func (hhq *HintedHandoffQueue) OnSourceAdded(name string, source Source) {
+	q, err := hhq.queueFor(name)
+	if err != nil {
+		return
+	}
+	q.wake()
+}
+
+// OnSourceRemoved implements Watcher. The source's queue and segment file
+// are left in place - a removed source may just be flapping - so any hints
+// already spooled are still there to replay once it's registered again.
+func (hhq *HintedHandoffQueue) OnSourceRemoved(name string) {}
+
+// queueFor returns sourceName's sourceQueue, loading it from its segment
+// file and starting its drainer goroutine on first use.
+func (hhq *HintedHandoffQueue) queueFor(sourceName string) (*sourceQueue, error) {
+	hhq.mu.Lock()
+	defer hhq.mu.Unlock()
+
+	if q, ok := hhq.queues[sourceName]; ok {
+		return q, nil
+	}
+
+	q, err := openSourceQueue(segmentPath(hhq.cfg.Dir, sourceName))
+	if err != nil {
+		return nil, err
+	}
+	hhq.queues[sourceName] = q
+	q.startDrainer(hhq.registry, sourceName, hhq.cfg)
+	return q, nil
+}
+
+// openSourceQueue opens path for append, replaying any hints already in it.
+func openSourceQueue(path string) (*sourceQueue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	q := &sourceQueue{
+		trigger: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var h hint
+		if err := json.Unmarshal(line, &h); err != nil {
+			// A torn write from a prior crash; the rest of the file is
+			// still worth replaying, so skip just this line.
+			continue
+		}
+		hc := h
+		q.hints = append(q.hints, &hc)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	q.file = f
+	return q, nil
+}
+
+// append adds h to the queue and its segment file, dropping the oldest
+// spooled hint first if maxSize has been reached.
+func (q *sourceQueue) append(h *hint, maxSize int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if maxSize > 0 && len(q.hints) >= maxSize {
+		q.hints = q.hints[1:]
+		q.dropped++
+	}
+	q.hints = append(q.hints, h)
+	return q.writeLine(h)
+}
+
+func (q *sourceQueue) writeLine(h *hint) error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if _, err := q.file.Write(data); err != nil {
+		return err
+	}
+	return q.file.Sync()
+}
+
+// compact rewrites the segment file to hold only the hints still pending,
+// called after a successful drain so the file doesn't grow unbounded.
+func (q *sourceQueue) compact() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	path := q.file.Name()
+	if err := q.file.Close(); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, h := range q.hints {
+		data, err := json.Marshal(h)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	q.file = f
+	return nil
+}
+
+func (q *sourceQueue) stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	s := Stats{Depth: len(q.hints), Dropped: q.dropped}
+	if len(q.hints) > 0 {
+		s.OldestHintAge = time.Since(q.hints[0].EnqueuedAt)
+	}
+	return s
+}
+
+func (q *sourceQueue) wake() {
+	select {
+	case q.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (q *sourceQueue) close() {
+	close(q.stop)
+	q.wg.Wait()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.file.Close()
+}
+
+// startDrainer launches the goroutine that replays this source's spooled
+// hints, in order, against registry's current registration for sourceName.
+// It wakes immediately on wake() (a fresh Enqueue or the source coming back
+// via OnSourceAdded) and otherwise retries a failing source on an
+// exponentially growing backoff with jitter, capped at cfg.MaxBackoff.
+func (q *sourceQueue) startDrainer(registry *SourceRegistry, sourceName string, cfg HintedHandoffConfig) {
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+
+		backoff := cfg.BaseBackoff
+		for {
+			if q.drainReady(registry, sourceName, cfg) {
+				backoff = cfg.BaseBackoff
+			} else {
+				backoff = jitterBackoff(backoff, cfg.MaxBackoff)
+			}
+
+			select {
+			case <-q.stop:
+				return
+			case <-q.trigger:
+			case <-time.After(backoff):
+			}
+		}
+	}()
+}
+
+// drainReady replays every pending hint it can, stopping at the first
+// failure so hints stay strictly ordered per source. It returns true if the
+// queue is now empty (all hints replayed or dropped as stale).
+func (q *sourceQueue) drainReady(registry *SourceRegistry, sourceName string, cfg HintedHandoffConfig) bool {
+	src, err := registry.Get(sourceName)
+	if err != nil {
+		return false
+	}
+	writable, ok := src.(Writable)
+	if !ok {
+		return false
+	}
+
+	ctx := context.Background()
+	changed := false
+	for {
+		h := q.peek()
+		if h == nil {
+			break
+		}
+		if cfg.MaxHintAge > 0 && time.Since(h.EnqueuedAt) > cfg.MaxHintAge {
+			q.popDropped()
+			changed = true
+			continue
+		}
+
+		h.Attempts++
+		if err := writable.Write(ctx, h.Payload); err != nil {
+			break
+		}
+		q.pop()
+		changed = true
+	}
+
+	if changed {
+		q.compact()
+	}
+	return q.depth() == 0
+}
+
+func (q *sourceQueue) peek() *hint {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.hints) == 0 {
+		return nil
+	}
+	return q.hints[0]
+}
+
+func (q *sourceQueue) pop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.hints) > 0 {
+		q.hints = q.hints[1:]
+	}
+}
+
+func (q *sourceQueue) popDropped() {
+	q.mu.Lock()
+	if len(q.hints) > 0 {
+		q.hints = q.hints[1:]
+		q.dropped++
+	}
+	q.mu.Unlock()
+}
+
+func (q *sourceQueue) depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.hints)
+}
+
+// jitterBackoff doubles delay, capped at max, then adds up to +/-25% jitter
+// so many sources failing at once don't all retry in lockstep.
+func jitterBackoff(delay, max time.Duration) time.Duration {
+	next := delay * 2
+	if next > max || next <= 0 {
+		next = max
+	}
+	jitter := time.Duration(rand.Int63n(int64(next)/2+1)) - next/4
+	withJitter := next + jitter
+	if withJitter < 0 {
+		withJitter = next
+	}
+	return withJitter
+}
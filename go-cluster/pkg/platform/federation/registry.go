@@ -3,14 +3,27 @@ package federation
 import (
 	"errors"
 	"sync"
+
+	"github.com/lumadb/cluster/pkg/meta"
 )
 
 var ErrSourceNotFound = errors.New("source not found")
 
+// Watcher is notified when a source is added to or removed from a
+// SourceRegistry, so dependents (the GraphQL engine's stitched schema, in
+// particular) can update incrementally instead of re-introspecting every
+// registered source on every change.
+type Watcher interface {
+	OnSourceAdded(name string, source Source)
+	OnSourceRemoved(name string)
+}
+
 // SourceRegistry manages federated data sources
 type SourceRegistry struct {
-	mu      sync.RWMutex
-	sources map[string]Source
+	mu       sync.RWMutex
+	sources  map[string]Source
+	watchers []Watcher
+	meta     *meta.MetaClient // optional; set via SetMetaClient to read cluster-wide data node placement
 }
 
 func NewSourceRegistry() *SourceRegistry {
@@ -19,10 +32,61 @@ func NewSourceRegistry() *SourceRegistry {
 	}
 }
 
-func (r *SourceRegistry) Register(name string, source Source) {
+// SetMetaClient gives the registry a cluster-wide MetaClient, so a
+// multi-node deployment's registry can place federated sources against
+// DataNodes() the same way on every node, instead of each node only seeing
+// the data nodes it happens to know about locally.
+func (r *SourceRegistry) SetMetaClient(client *meta.MetaClient) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.meta = client
+}
+
+// DataNodes returns the cluster's data node placement as last reported by
+// the registry's MetaClient, or nil if none was set via SetMetaClient.
+func (r *SourceRegistry) DataNodes() []meta.NodeInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.meta == nil {
+		return nil
+	}
+	return r.meta.DataNodes()
+}
+
+// Watch registers w to be notified of future Register/Remove calls. It does
+// not replay sources already present in the registry - callers that need
+// those should range over List() once before calling Watch.
+func (r *SourceRegistry) Watch(w Watcher) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.watchers = append(r.watchers, w)
+}
+
+func (r *SourceRegistry) Register(name string, source Source) {
+	r.mu.Lock()
 	r.sources[name] = source
+	watchers := append([]Watcher(nil), r.watchers...)
+	r.mu.Unlock()
+
+	for _, w := range watchers {
+		w.OnSourceAdded(name, source)
+	}
+}
+
+// Remove unregisters name, notifying watchers if it was present.
+func (r *SourceRegistry) Remove(name string) {
+	r.mu.Lock()
+	_, ok := r.sources[name]
+	delete(r.sources, name)
+	watchers := append([]Watcher(nil), r.watchers...)
+	r.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	for _, w := range watchers {
+		w.OnSourceRemoved(name)
+	}
 }
 
 func (r *SourceRegistry) Get(name string) (Source, error) {
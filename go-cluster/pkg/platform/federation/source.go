@@ -0,0 +1,57 @@
+package federation
+
+import "context"
+
+// Driver identifies what kind of backend a Source talks to, so callers (the
+// GraphQL engine, in particular) can decide between full LumaDB-to-LumaDB
+// stitching and a more generic, read-only introspection.
+type Driver string
+
+// DriverLumaDB marks a Source as another LumaDB node, reachable over the
+// cluster's own gRPC API - the only driver that currently gets full
+// GraphQL type/resolver stitching.
+const DriverLumaDB Driver = "lumadb"
+
+// ForeignKey declares a cross-source join: the local field named Field
+// holds an id from RefCollection's RefField. The GraphQL engine turns this
+// into a nested field on the owning type, resolved by batching every row's
+// Field value into one remote RunQuery instead of one per row.
+type ForeignKey struct {
+	Field         string `json:"field"`
+	RefCollection string `json:"refCollection"`
+	RefField      string `json:"refField"`
+}
+
+// CollectionSchema describes one collection a Source exposes: its name and
+// any foreign keys declared against it.
+type CollectionSchema struct {
+	Name        string
+	ForeignKeys []ForeignKey
+}
+
+// SourceSchema is the result of introspecting a Source: every collection it
+// currently exposes.
+type SourceSchema struct {
+	Collections []CollectionSchema
+}
+
+// Source is a federated data source the GraphQL engine can stitch into its
+// schema.
+type Source interface {
+	// Driver reports what kind of backend this source talks to.
+	Driver() Driver
+	// Introspect returns the source's current collections.
+	Introspect(ctx context.Context) (*SourceSchema, error)
+}
+
+// LumaSource is implemented by Sources whose Driver is DriverLumaDB: it can
+// proxy document reads and writes to the remote node over gRPC, mirroring
+// cluster.Node's own GetDocument/RunQuery/InsertDocument so the GraphQL
+// engine's stitched resolvers don't need a separate code path for remote
+// collections.
+type LumaSource interface {
+	Source
+	GetDocument(ctx context.Context, collection, id string) (map[string]interface{}, error)
+	RunQuery(ctx context.Context, collection string, query map[string]interface{}) ([]map[string]interface{}, error)
+	InsertDocument(ctx context.Context, collection string, doc map[string]interface{}) (string, error)
+}
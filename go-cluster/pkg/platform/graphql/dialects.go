@@ -0,0 +1,224 @@
+package graphql
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+	"github.com/lumadb/cluster/pkg/dialects"
+)
+
+// newTimeSeriesType builds the engine-wide "TimeSeries" type every dialect
+// façade field returns: a QueryResult flattened into GraphQL-friendly shape
+// (column names plus a JSON array of row tuples, since a time-series row's
+// column types vary by query and graphql-go has no tuple type).
+func newTimeSeriesType() *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:        "TimeSeries",
+		Description: "A dialect query's result: column names plus row values, each row a JSON array aligned to columns.",
+		Fields: graphql.Fields{
+			"columns": &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"rows": &graphql.Field{
+				Type:        graphql.NewList(newJSONScalar()),
+				Description: "Each element is one row, itself a JSON array of column values.",
+			},
+		},
+	})
+}
+
+// SetDialectRouter wires router in as the backing execution engine for the
+// promql/influxql/sql façade fields. Schemas built before this is called
+// simply omit those fields - call it during platform startup, before the
+// first BuildSchema.
+func (e *GraphQLEngine) SetDialectRouter(router *dialects.Router) {
+	e.mu.Lock()
+	e.dialectRouter = router
+	e.mu.Unlock()
+}
+
+// buildDialectFields exposes three of the dialects this engine's Router can
+// run (Flux and any other registered dialect get no field) as typed GraphQL
+// fields - query { promql(expr: ...) { ... }
+// influxql(q: ...) { ... } sql(q: ...) { ... } } - each returning the common
+// TimeSeries shape derived from the underlying QueryResult. Returns an empty
+// set if no Router has been wired in via SetDialectRouter.
+func (e *GraphQLEngine) buildDialectFields() graphql.Fields {
+	e.mu.Lock()
+	router := e.dialectRouter
+	e.mu.Unlock()
+	if router == nil {
+		return graphql.Fields{}
+	}
+
+	toTimeSeries := func(result *dialects.QueryResult) map[string]interface{} {
+		columns := make([]string, len(result.Columns))
+		for i, c := range result.Columns {
+			columns[i] = c.Name
+		}
+		return map[string]interface{}{"columns": columns, "rows": result.Rows}
+	}
+
+	runField := func(dialect dialects.Dialect, queryArg string) *graphql.Field {
+		return &graphql.Field{
+			Type: e.timeSeriesType,
+			Args: graphql.FieldConfigArgument{
+				queryArg:   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"database": &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				query, _ := p.Args[queryArg].(string)
+				database, _ := p.Args["database"].(string)
+				result, err := router.RunQuery(dialect, query, database)
+				if err != nil {
+					return nil, err
+				}
+				return toTimeSeries(result), nil
+			},
+		}
+	}
+
+	return graphql.Fields{
+		"promql":   runField(dialects.DialectPromQL, "expr"),
+		"influxql": runField(dialects.DialectInfluxQL, "q"),
+		"sql":      runField(dialects.DialectSQL, "q"),
+	}
+}
+
+// RegisterRemoteDialectSource adds a remote TSDB (reachable over its own
+// native HTTP API, not LumaDB's) as a named federated query target. Queries
+// against it run through remote(source: ..., query: ...).
+func (e *GraphQLEngine) RegisterRemoteDialectSource(name string, source *RemoteDialectSource) {
+	e.mu.Lock()
+	if e.remoteDialectSources == nil {
+		e.remoteDialectSources = make(map[string]*RemoteDialectSource)
+	}
+	e.remoteDialectSources[name] = source
+	e.mu.Unlock()
+}
+
+// buildRemoteDialectFields exposes registered RemoteDialectSources: a single
+// "remote" field to query one by name, and "mergeByTime" to fan out the same
+// query text to several and inner-join their rows on an exactly-matching
+// first (time) column - a best-effort merge, not true timestamp alignment
+// with interpolation.
+func (e *GraphQLEngine) buildRemoteDialectFields() graphql.Fields {
+	e.mu.Lock()
+	sources := make(map[string]*RemoteDialectSource, len(e.remoteDialectSources))
+	for k, v := range e.remoteDialectSources {
+		sources[k] = v
+	}
+	e.mu.Unlock()
+	if len(sources) == 0 {
+		return graphql.Fields{}
+	}
+
+	toTimeSeries := func(result *dialects.QueryResult) map[string]interface{} {
+		columns := make([]string, len(result.Columns))
+		for i, c := range result.Columns {
+			columns[i] = c.Name
+		}
+		return map[string]interface{}{"columns": columns, "rows": result.Rows}
+	}
+
+	fields := graphql.Fields{
+		"remote": &graphql.Field{
+			Type: e.timeSeriesType,
+			Args: graphql.FieldConfigArgument{
+				"source": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"query":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				name, _ := p.Args["source"].(string)
+				query, _ := p.Args["query"].(string)
+				source, ok := sources[name]
+				if !ok {
+					return nil, fmt.Errorf("unknown remote dialect source: %s", name)
+				}
+				result, err := source.Query(p.Context, query)
+				if err != nil {
+					return nil, err
+				}
+				return toTimeSeries(result), nil
+			},
+		},
+		"mergeByTime": &graphql.Field{
+			Type: e.timeSeriesType,
+			Args: graphql.FieldConfigArgument{
+				"sources": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.String))},
+				"query":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				names, _ := p.Args["sources"].([]interface{})
+				query, _ := p.Args["query"].(string)
+
+				results := make([]*dialects.QueryResult, 0, len(names))
+				for _, n := range names {
+					name, _ := n.(string)
+					source, ok := sources[name]
+					if !ok {
+						return nil, fmt.Errorf("unknown remote dialect source: %s", name)
+					}
+					result, err := source.Query(p.Context, query)
+					if err != nil {
+						return nil, fmt.Errorf("query %s: %w", name, err)
+					}
+					results = append(results, result)
+				}
+
+				merged := mergeResultsByTime(results)
+				return toTimeSeries(merged), nil
+			},
+		},
+	}
+	return fields
+}
+
+// mergeResultsByTime inner-joins results on their first column, assumed to be
+// a timestamp, keeping only rows whose timestamp appears in every result.
+// This is intentionally the simplest correct thing: an exact match, no
+// interpolation or nearest-neighbor snapping across sources with different
+// sampling intervals.
+func mergeResultsByTime(results []*dialects.QueryResult) *dialects.QueryResult {
+	if len(results) == 0 {
+		return &dialects.QueryResult{}
+	}
+	if len(results) == 1 {
+		return results[0]
+	}
+
+	byTime := make(map[interface{}][][]interface{})
+	for _, result := range results {
+		for _, row := range result.Rows {
+			if len(row) == 0 {
+				continue
+			}
+			byTime[row[0]] = append(byTime[row[0]], row)
+		}
+	}
+
+	columns := make([]dialects.ColumnMeta, 0)
+	for i, result := range results {
+		if i == 0 {
+			columns = append(columns, result.Columns...)
+			continue
+		}
+		if len(result.Columns) > 1 {
+			columns = append(columns, result.Columns[1:]...)
+		}
+	}
+
+	merged := make([][]interface{}, 0)
+	for t, rows := range byTime {
+		if len(rows) != len(results) {
+			continue // missing from at least one source - drop rather than guess
+		}
+		combined := []interface{}{t}
+		for _, row := range rows {
+			if len(row) > 1 {
+				combined = append(combined, row[1:]...)
+			}
+		}
+		merged = append(merged, combined)
+	}
+
+	return &dialects.QueryResult{Columns: columns, Rows: merged}
+}
@@ -3,58 +3,109 @@ package graphql
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"strings"
+	"sync"
 
 	"github.com/graphql-go/graphql"
 	"github.com/graphql-go/graphql/gqlerrors"
 	"github.com/graphql-go/graphql/language/ast"
 	"github.com/lumadb/cluster/pkg/cluster"
+	"github.com/lumadb/cluster/pkg/dialects"
+	"github.com/lumadb/cluster/pkg/platform/auth"
+	"github.com/lumadb/cluster/pkg/platform/events"
 	"github.com/lumadb/cluster/pkg/platform/federation"
 	"go.uber.org/zap"
 )
 
-// GraphQLEngine manages the dynamic GraphQL schema
-type GraphQLEngine struct {
-	node      *cluster.Node
-	logger    *zap.Logger
-	registry  *federation.SourceRegistry
-	schema    graphql.Schema
-	hasSchema bool
+// Subscriber lets the GraphQL engine fan change events out to live
+// subscribers. It is satisfied by platform.Hub; kept as an interface here so
+// this package doesn't need to import platform (which imports graphql).
+type Subscriber interface {
+	Subscribe(id, role, collection string, eventType events.EventType) <-chan events.Event
+	Unsubscribe(id string)
 }
 
-func NewGraphQLEngine(node *cluster.Node, registry *federation.SourceRegistry, logger *zap.Logger) *GraphQLEngine {
-	return &GraphQLEngine{
-		node:     node,
-		registry: registry,
-		logger:   logger,
-	}
-}
+// GraphQLEngine manages the dynamic, per-tenant GraphQL schema. Each tenant
+// gets its own schema - built from its own namespaced collections plus any
+// Galaxy-style shared_ ones - cached in schemas until InvalidateTenant (or a
+// federated source add/remove) drops it.
+type GraphQLEngine struct {
+	node       *cluster.Node
+	logger     *zap.Logger
+	registry   *federation.SourceRegistry
+	subscriber Subscriber
+	acl        ACL
 
-// BuildSchema dynamically constructs the GraphQL schema from database collections AND federated sources
-func (e *GraphQLEngine) BuildSchema() error {
-	e.logger.Info("Building GraphQL Schema...")
+	mu                 sync.Mutex
+	jsonScalar         *graphql.Scalar
+	retentionType      *graphql.Object
+	orderDirection     *graphql.Enum
+	pageInfoType       *graphql.Object
+	timeSeriesType     *graphql.Object
+	subscriptionFields graphql.Fields
 
-	// Root Query
-	queryFields := graphql.Fields{
-		"hello": &graphql.Field{
-			Type: graphql.String,
-			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				return "world", nil
-			},
-		},
-	}
+	// dialectRouter, if set via SetDialectRouter, backs the promql/influxql/
+	// sql façade fields over dialects.Router's local query execution.
+	dialectRouter *dialects.Router
 
-	// Root Mutation
-	mutationFields := graphql.Fields{
-		"noop": &graphql.Field{
-			Type: graphql.String,
-			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				return "ok", nil
-			},
-		},
+	// remoteDialectSources, registered via RegisterRemoteDialectSource, back
+	// the remote/mergeByTime façade fields over other TSDBs' native HTTP APIs.
+	remoteDialectSources map[string]*RemoteDialectSource
+
+	// cursorKey signs the pagination cursors <col>_connection hands out, set
+	// via SetCursorKey. Left nil (an all-zero HMAC key) until a caller wires
+	// one in - still tamper-evident, just not secret.
+	cursorKey []byte
+
+	// federatedQueryFields/federatedMutationFields hold every field stitched
+	// in from a federated source (see stitch.go). They aren't tenant-scoped,
+	// so they're merged as-is into every tenant's schema.
+	federatedQueryFields    graphql.Fields
+	federatedMutationFields graphql.Fields
+
+	// sourceQueryFields/sourceMutationFields track which field names in
+	// federatedQueryFields/federatedMutationFields belong to which federated
+	// source, so OnSourceRemoved can undo exactly what OnSourceAdded added.
+	sourceQueryFields    map[string][]string
+	sourceMutationFields map[string][]string
+
+	// schemas caches one built graphql.Schema per (tenant, version). versions
+	// tracks each tenant's current version, guarded by mu; bumping it (via
+	// InvalidateTenant) naturally misses the cache on the next lookup.
+	schemas  sync.Map
+	versions map[string]int64
+}
+
+func NewGraphQLEngine(node *cluster.Node, registry *federation.SourceRegistry, logger *zap.Logger) *GraphQLEngine {
+	e := &GraphQLEngine{
+		node:                    node,
+		registry:                registry,
+		logger:                  logger,
+		jsonScalar:              newJSONScalar(),
+		retentionType:           newRetentionType(),
+		orderDirection:          newOrderDirectionEnum(),
+		pageInfoType:            newPageInfoType(),
+		timeSeriesType:          newTimeSeriesType(),
+		federatedQueryFields:    make(graphql.Fields),
+		federatedMutationFields: make(graphql.Fields),
+		sourceQueryFields:       make(map[string][]string),
+		sourceMutationFields:    make(map[string][]string),
+		versions:                make(map[string]int64),
 	}
+	e.subscriptionFields = buildSubscriptionFields(e.jsonScalar)
+	if registry != nil {
+		registry.Watch(e)
+	}
+	return e
+}
 
-	// Custom JSON scalar
-	jsonScalar := graphql.NewScalar(graphql.ScalarConfig{
+// newJSONScalar builds the engine-wide "JSON" scalar. There's exactly one
+// instance, shared by every tenant's local fields and by every federated
+// field, since graphql-go rejects two distinct types registered under the
+// same name within one schema.
+func newJSONScalar() *graphql.Scalar {
+	return graphql.NewScalar(graphql.ScalarConfig{
 		Name:        "JSON",
 		Description: "The generic JSON scalar type represents a JSON value.",
 		Serialize: func(value interface{}) interface{} {
@@ -72,120 +123,121 @@ func (e *GraphQLEngine) BuildSchema() error {
 			}
 		},
 	})
+}
 
-	// 1. List all collections to build schema dynamically
-	collections, err := e.node.ListCollections()
-	if err != nil {
-		e.logger.Error("Failed to list collections for schema build", zap.Error(err))
-	}
-
-	for _, colName := range collections {
-		// Define Type for Collection
-		objType := graphql.NewObject(graphql.ObjectConfig{
-			Name: colName,
-			Fields: graphql.Fields{
-				"_id":      &graphql.Field{Type: graphql.String},
-				"_created": &graphql.Field{Type: graphql.String},
-				"data":     &graphql.Field{Type: jsonScalar},
-			},
-		})
-
-		// --- QUERIES ---
-		// 1. Get by ID
-		queryFields[colName+"_by_pk"] = &graphql.Field{
-			Type: objType,
-			Args: graphql.FieldConfigArgument{
-				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
-			},
-			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				id, _ := p.Args["id"].(string)
-				return e.node.GetDocument(colName, id)
-			},
-		}
-
-		// 2. List
-		queryFields[colName] = &graphql.Field{
-			Type: graphql.NewList(objType),
+// buildSubscriptionFields builds the engine-wide Subscription fields every
+// tenant gets regardless of its collection list. Resolve is never actually
+// invoked through graphql.Do(); subscriptions are served out-of-band via
+// ExecuteSubscription/the WebSocket transport. It's still registered here so
+// introspection and the GraphiQL docs work.
+func buildSubscriptionFields(jsonScalar *graphql.Scalar) graphql.Fields {
+	return graphql.Fields{
+		"documentChanged": &graphql.Field{
+			Type: jsonScalar,
 			Args: graphql.FieldConfigArgument{
-				"limit": &graphql.ArgumentConfig{Type: graphql.Int},
-				"where": &graphql.ArgumentConfig{Type: jsonScalar},
-			},
-			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				limit, _ := p.Args["limit"].(int)
-				if limit <= 0 {
-					limit = 10
-				}
-				query := map[string]interface{}{"limit": limit}
-				if whereVal, ok := p.Args["where"].(map[string]interface{}); ok {
-					query["filter"] = whereVal
-				}
-				return e.node.RunQuery(colName, query)
+				"collection": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"op":         &graphql.ArgumentConfig{Type: graphql.String},
 			},
-		}
+			Resolve: subscriptionOnlyResolver("documentChanged"),
+		},
+	}
+}
 
-		// --- MUTATIONS ---
-		// 3. Insert
-		mutationFields["insert_"+colName] = &graphql.Field{
-			Type: graphql.String, // Returns ID
-			Args: graphql.FieldConfigArgument{
-				"data": &graphql.ArgumentConfig{Type: graphql.NewNonNull(jsonScalar)},
-			},
-			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				data, _ := p.Args["data"].(map[string]interface{})
-				return e.node.InsertDocument(colName, data)
-			},
-		}
+// subscriptionOnlyResolver is the Resolve func for every subscription field:
+// they're never run through graphql.Do(), only through ExecuteSubscription.
+func subscriptionOnlyResolver(field string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return nil, fmt.Errorf("%s must be executed as a subscription, not a query", field)
 	}
+}
 
-	// 2. Stitched Federated Sources - Native Only
-	if e.registry != nil {
-		sources := e.registry.List()
-		for srcName, src := range sources {
-			// In native-only mode, we only support LumaDB sources or similar NoSQL
-			// SQL introspection logic is removed.
-			// Future: Implement LumaDB-to-LumaDB federation here.
-			e.logger.Info("Federated source present but SQL stitching disabled", zap.String("source", srcName))
-
-			// Introspect source (Generic)
-			schema, err := src.Introspect(context.Background())
-			if err != nil {
-				e.logger.Error("Failed to introspect source", zap.String("source", srcName), zap.Error(err))
-				continue
-			}
+// SetSubscriber wires in the hub used to resolve "subscription" operations.
+// Optional: without one the schema still has its Subscription root, but
+// ExecuteSubscription returns an error for every operation.
+func (e *GraphQLEngine) SetSubscriber(s Subscriber) {
+	e.subscriber = s
+}
 
-			// TODO: Implement generic stitching for non-SQL sources if needed
-			// For now, we skip SQL table generation
-			_ = schema
-		}
-	}
+// SetCursorKey installs the HMAC key <col>_connection uses to sign and
+// verify pagination cursors. Callers should pass the same secret the rest of
+// the cluster already trusts (e.g. AuthEngine's JWT signing key) rather than
+// minting a dedicated one.
+func (e *GraphQLEngine) SetCursorKey(key []byte) {
+	e.cursorKey = key
+}
+
+// BuildSchema eagerly builds and caches tenant's schema, introspecting every
+// currently-registered federated source along the way. Callers don't have to
+// call this before Execute - a tenant's first Execute builds it lazily - but
+// doing so at startup surfaces schema errors before the first request.
+func (e *GraphQLEngine) BuildSchema(tenant string) error {
+	e.logger.Info("Building GraphQL schema", zap.String("tenant", tenant))
 
-	// Finalize Schema
-	schemaConfig := graphql.SchemaConfig{
-		Query:    graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: queryFields}),
-		Mutation: graphql.NewObject(graphql.ObjectConfig{Name: "Mutation", Fields: mutationFields}),
+	if err := e.stitchAllSources(); err != nil {
+		return err
 	}
 
-	schema, err := graphql.NewSchema(schemaConfig)
+	schema, err := e.buildSchemaForTenant(tenant)
 	if err != nil {
-		return fmt.Errorf("failed to create schema: %v", err)
+		return err
 	}
 
-	e.schema = schema
-	e.hasSchema = true
+	e.mu.Lock()
+	version := e.versions[tenant]
+	e.mu.Unlock()
+	e.schemas.Store(tenantSchemaKey{tenant: tenant, version: version}, schema)
 	return nil
 }
 
-// Execute runs a GraphQL query
-func (e *GraphQLEngine) Execute(ctx context.Context, query string, variables map[string]interface{}) *graphql.Result {
-	if !e.hasSchema {
-		// Try to build schema lazily
-		if err := e.BuildSchema(); err != nil {
-			return &graphql.Result{Errors: []gqlerrors.FormattedError{{Message: err.Error()}}}
+// stitchAllSources introspects every currently-registered federated source
+// and stitches it into federatedQueryFields/federatedMutationFields. Safe to
+// call more than once; re-stitching a source that's already stitched just
+// overwrites its fields with themselves.
+func (e *GraphQLEngine) stitchAllSources() error {
+	if e.registry == nil {
+		return nil
+	}
+
+	type sourceInit struct {
+		name   string
+		src    federation.Source
+		schema *federation.SourceSchema
+	}
+	var inits []sourceInit
+	for name, src := range e.registry.List() {
+		schema, err := src.Introspect(context.Background())
+		if err != nil {
+			e.logger.Error("Failed to introspect source", zap.String("source", name), zap.Error(err))
+			continue
 		}
+		inits = append(inits, sourceInit{name: name, src: src, schema: schema})
 	}
 
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, init := range inits {
+		e.stitchSourceLocked(init.name, init.src, init.schema)
+	}
+	return nil
+}
+
+// Execute runs a GraphQL query as identity, against identity.TenantID's
+// schema.
+func (e *GraphQLEngine) Execute(ctx context.Context, identity Identity, query string, variables map[string]interface{}) *graphql.Result {
+	schema, err := e.schemaForTenant(identity.TenantID)
+	if err != nil {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{{Message: err.Error()}}}
+	}
+
+	ctx = context.WithValue(ctx, identityContextKey{}, identity)
+
+	// A fresh loader cache per request so batched foreign-key joins within
+	// this one query don't leak results into (or reuse stale ones from)
+	// any other request. See loader.go.
+	ctx = context.WithValue(ctx, loaderCacheKey{}, newLoaderCache())
+
 	params := graphql.Params{
-		Schema:         e.schema,
+		Schema:         schema,
 		RequestString:  query,
 		VariableValues: variables,
 		Context:        ctx,
@@ -193,3 +245,105 @@ func (e *GraphQLEngine) Execute(ctx context.Context, query string, variables map
 
 	return graphql.Do(params)
 }
+
+// subscriptionFieldRe extracts the field name and its argument list out of a
+// `subscription { field(arg: "...", ...) { ... } }` document. A full AST walk
+// isn't worth it for the handful of root fields ExecuteSubscription supports.
+var subscriptionFieldRe = regexp.MustCompile(`(?s)subscription[^{]*\{\s*(\w+)\s*\(([^)]*)\)`)
+var subscriptionArgRe = regexp.MustCompile(`(\w+)\s*:\s*"?([\w]+)"?`)
+
+// subscriptionWhereRe pulls a single `where: { field: "value" }` equality
+// clause out of a field's raw argument string. Anything richer than one
+// top-level equality (nesting, _and/_or, non-string values) isn't supported
+// yet - the same "good enough for the one shape we handle" tradeoff as
+// subscriptionFieldRe above.
+var subscriptionWhereRe = regexp.MustCompile(`where\s*:\s*\{\s*(\w+)\s*:\s*"([^"]*)"`)
+
+// ExecuteSubscription resolves a `subscription { <field>(...) { ... } }`
+// operation to a live feed of matching change events, scoped to
+// identity.TenantID's collections. The returned channel is closed, and
+// cleanup runs automatically, once the caller cancels ctx. Three root field
+// shapes are recognized: the generic "documentChanged(collection, op)", and
+// the per-collection "<col>_changes(where)" and "<col>_window(interval, agg)"
+// fields BuildSchema generates for every tenant collection. Only the first two
+// yield a feed; <col>_window always returns an error.
+func (e *GraphQLEngine) ExecuteSubscription(ctx context.Context, identity Identity, query string) (<-chan *graphql.Result, error) {
+	if e.subscriber == nil {
+		return nil, fmt.Errorf("subscriptions are not enabled on this engine")
+	}
+
+	matches := subscriptionFieldRe.FindStringSubmatch(query)
+	if len(matches) < 3 {
+		return nil, fmt.Errorf("could not parse a supported subscription field out of the query")
+	}
+	field, rawArgs := matches[1], matches[2]
+
+	args := make(map[string]string)
+	for _, am := range subscriptionArgRe.FindAllStringSubmatch(rawArgs, -1) {
+		args[am[1]] = am[2]
+	}
+
+	var collection string
+	switch {
+	case field == "documentChanged":
+		collection = args["collection"]
+	case strings.HasSuffix(field, "_window"):
+		// <col>_window needs the tdengine stream engine's CREATE STREAM
+		// aggregation pipeline, which isn't wired into cluster.Node yet -
+		// fail loudly rather than silently serving an unaggregated feed.
+		return nil, fmt.Errorf("%s: windowed aggregation subscriptions require the tdengine stream engine, which this cluster doesn't have wired in yet", field)
+	case strings.HasSuffix(field, "_changes"):
+		collection = strings.TrimSuffix(field, "_changes")
+	default:
+		return nil, fmt.Errorf("unsupported subscription field %q", field)
+	}
+	if collection == "" {
+		return nil, fmt.Errorf("%s requires a collection", field)
+	}
+
+	if err := e.checkACL(ctx, identity, auth.ActionRead, collection); err != nil {
+		return nil, err
+	}
+
+	var whereField, whereValue string
+	if wm := subscriptionWhereRe.FindStringSubmatch(rawArgs); wm != nil {
+		whereField, whereValue = wm[1], wm[2]
+	}
+
+	subID := fmt.Sprintf("gql-%p-%d", &query, len(args))
+	evtCh := e.subscriber.Subscribe(subID, identity.Role, scopedCollection(identity.TenantID, collection), events.EventType(args["op"]))
+
+	out := make(chan *graphql.Result)
+	go func() {
+		defer close(out)
+		defer e.subscriber.Unsubscribe(subID)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case evt, ok := <-evtCh:
+				if !ok {
+					return
+				}
+				if whereField != "" {
+					doc, _ := evt.Document.(map[string]interface{})
+					if fmt.Sprintf("%v", doc[whereField]) != whereValue {
+						continue
+					}
+				}
+				out <- &graphql.Result{
+					Data: map[string]interface{}{
+						field: map[string]interface{}{
+							"collection": collection,
+							"op":         string(evt.Type),
+							"document":   evt.Document,
+						},
+					},
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
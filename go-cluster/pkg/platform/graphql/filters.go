@@ -0,0 +1,335 @@
+package graphql
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+	"github.com/lumadb/cluster/pkg/platform/auth"
+)
+
+// fieldSample is a collection field observed while sampling documents, with
+// the GraphQL scalar type inferred for it.
+type fieldSample struct {
+	name string
+	typ  *graphql.Scalar
+}
+
+// sampleSize caps how many documents sampleFields reads to infer a
+// collection's field shape.
+const sampleSize = 20
+
+// sampleFields infers a GraphQL scalar type for each top-level key found in
+// up to sampleSize documents' "data" object, by running the same RunQuery
+// path the list resolver uses. This is a best-effort guess, not a schema: a
+// key missing from the sample, or one whose observed values disagree on
+// type, is dropped rather than guessed at.
+func (e *GraphQLEngine) sampleFields(scopedCol string) []fieldSample {
+	rows, err := e.node.RunQuery(scopedCol, map[string]interface{}{"limit": sampleSize})
+	if err != nil {
+		return nil
+	}
+	docs, ok := rows.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	types := make(map[string]*graphql.Scalar)
+	var order []string
+	for _, row := range docs {
+		doc, ok := row.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		data, ok := doc["data"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key, val := range data {
+			t := scalarForValue(val)
+			if t == nil {
+				continue
+			}
+			if existing, seen := types[key]; seen {
+				if existing != t {
+					types[key] = nil
+				}
+				continue
+			}
+			types[key] = t
+			order = append(order, key)
+		}
+	}
+
+	out := make([]fieldSample, 0, len(order))
+	for _, key := range order {
+		if t := types[key]; t != nil {
+			out = append(out, fieldSample{name: key, typ: t})
+		}
+	}
+	return out
+}
+
+func scalarForValue(val interface{}) *graphql.Scalar {
+	switch val.(type) {
+	case string:
+		return graphql.String
+	case bool:
+		return graphql.Boolean
+	case float64:
+		// encoding/json decodes every JSON number into a float64; there's no
+		// way to tell an observed 3 was meant as Int rather than Float.
+		return graphql.Float
+	default:
+		return nil
+	}
+}
+
+// newOrderDirectionEnum builds the engine-wide "OrderDirection" enum, reused
+// by every collection's <Col>_order_by input the same way jsonScalar is
+// reused across tenants.
+func newOrderDirectionEnum() *graphql.Enum {
+	return graphql.NewEnum(graphql.EnumConfig{
+		Name: "OrderDirection",
+		Values: graphql.EnumValueConfigMap{
+			"asc":  &graphql.EnumValueConfig{Value: "asc"},
+			"desc": &graphql.EnumValueConfig{Value: "desc"},
+		},
+	})
+}
+
+// newPageInfoType builds the engine-wide Relay "PageInfo" type, reused by
+// every collection's <col>_connection the same way jsonScalar is reused
+// across tenants.
+func newPageInfoType() *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"endCursor":   &graphql.Field{Type: graphql.String},
+		},
+	})
+}
+
+// buildComparisonInput builds the <Col>_<field>_comparison_exp input type
+// for one sampled field: _eq/_neq/_in always, plus _like for strings and
+// _gt/_lt for numbers.
+func buildComparisonInput(col string, f fieldSample) *graphql.InputObject {
+	fields := graphql.InputObjectConfigFieldMap{
+		"_eq":  &graphql.InputObjectFieldConfig{Type: f.typ},
+		"_neq": &graphql.InputObjectFieldConfig{Type: f.typ},
+		"_in":  &graphql.InputObjectFieldConfig{Type: graphql.NewList(f.typ)},
+	}
+	switch f.typ {
+	case graphql.String:
+		fields["_like"] = &graphql.InputObjectFieldConfig{Type: graphql.String}
+	case graphql.Float:
+		fields["_gt"] = &graphql.InputObjectFieldConfig{Type: f.typ}
+		fields["_lt"] = &graphql.InputObjectFieldConfig{Type: f.typ}
+	}
+	return graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:   fmt.Sprintf("%s_%s_comparison_exp", col, f.name),
+		Fields: fields,
+	})
+}
+
+// buildBoolExpType builds <Col>_bool_exp: one field per sampled key of the
+// collection's "data" blob, restricted to the comparison operators that make
+// sense for its inferred type, plus the _and/_or/_not boolean combinators.
+// Comparisons apply to data.<field> - there's no schema enforcing that every
+// document actually carries that key.
+func buildBoolExpType(col string, fields []fieldSample) *graphql.InputObject {
+	var self *graphql.InputObject
+	self = graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: col + "_bool_exp",
+		Fields: (graphql.InputObjectConfigFieldMapThunk)(func() graphql.InputObjectConfigFieldMap {
+			fieldMap := graphql.InputObjectConfigFieldMap{
+				"_and": &graphql.InputObjectFieldConfig{Type: graphql.NewList(self)},
+				"_or":  &graphql.InputObjectFieldConfig{Type: graphql.NewList(self)},
+				"_not": &graphql.InputObjectFieldConfig{Type: self},
+			}
+			for _, f := range fields {
+				fieldMap[f.name] = &graphql.InputObjectFieldConfig{Type: buildComparisonInput(col, f)}
+			}
+			return fieldMap
+		}),
+	})
+	return self
+}
+
+// buildOrderByType builds <Col>_order_by: one OrderDirection field per
+// sampled key, falling back to ordering by _id when sampling found nothing.
+func buildOrderByType(col string, fields []fieldSample, orderDirection *graphql.Enum) *graphql.InputObject {
+	fieldMap := graphql.InputObjectConfigFieldMap{}
+	for _, f := range fields {
+		fieldMap[f.name] = &graphql.InputObjectFieldConfig{Type: orderDirection}
+	}
+	if len(fieldMap) == 0 {
+		fieldMap["_id"] = &graphql.InputObjectFieldConfig{Type: orderDirection}
+	}
+	return graphql.NewInputObject(graphql.InputObjectConfig{Name: col + "_order_by", Fields: fieldMap})
+}
+
+// buildConnectionTypes builds <col>_edge and <col>_connection around
+// objType, for Relay-style cursor pagination.
+func buildConnectionTypes(col string, objType *graphql.Object, pageInfoType *graphql.Object) (edgeType, connectionType *graphql.Object) {
+	edgeType = graphql.NewObject(graphql.ObjectConfig{
+		Name: col + "_edge",
+		Fields: graphql.Fields{
+			"node":   &graphql.Field{Type: objType},
+			"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+	connectionType = graphql.NewObject(graphql.ObjectConfig{
+		Name: col + "_connection",
+		Fields: graphql.Fields{
+			"edges":    &graphql.Field{Type: graphql.NewList(edgeType)},
+			"pageInfo": &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+		},
+	})
+	return edgeType, connectionType
+}
+
+// connectionResolver resolves <col>_connection: a page of scopedCol's
+// documents as a Relay edge list, with an extra row fetched to compute
+// hasNextPage.
+func (e *GraphQLEngine) connectionResolver(col, scopedCol string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		identity := identityFrom(p.Context)
+		if err := e.checkACL(p.Context, identity, auth.ActionRead, col); err != nil {
+			return nil, err
+		}
+
+		first, _ := p.Args["first"].(int)
+		if first <= 0 {
+			first = 10
+		}
+
+		query := map[string]interface{}{"limit": first + 1}
+		if whereVal, ok := p.Args["where"].(map[string]interface{}); ok {
+			query["filter"] = whereVal
+		} else if rawVal, ok := p.Args["where_raw"].(map[string]interface{}); ok {
+			query["filter"] = rawVal
+		}
+		if orderVal, ok := p.Args["order_by"].([]interface{}); ok && len(orderVal) > 0 {
+			query["order_by"] = orderVal
+		}
+
+		rows, err := e.node.RunQuery(scopedCol, query)
+		if err != nil {
+			return nil, err
+		}
+		docs, _ := rows.([]interface{})
+
+		// after-cursor support is a best-effort in-memory skip within the
+		// fetched page, not a true server-side seek - RunQuery has no
+		// "resume after this row" primitive to push the skip down to.
+		if afterRaw, ok := p.Args["after"].(string); ok && afterRaw != "" {
+			cursor, err := decodeCursor(e.cursorKey, afterRaw)
+			if err != nil {
+				return nil, err
+			}
+			for i, row := range docs {
+				if doc, ok := row.(map[string]interface{}); ok {
+					if id, _ := doc["_id"].(string); id == cursor.ID {
+						docs = docs[i+1:]
+						break
+					}
+				}
+			}
+		}
+
+		hasNext := len(docs) > first
+		if hasNext {
+			docs = docs[:first]
+		}
+
+		edges := make([]map[string]interface{}, 0, len(docs))
+		var endCursor string
+		for _, row := range docs {
+			doc, ok := row.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			id, _ := doc["_id"].(string)
+			cur, err := encodeCursor(e.cursorKey, "_id", id, id)
+			if err != nil {
+				return nil, err
+			}
+			edges = append(edges, map[string]interface{}{"node": doc, "cursor": cur})
+			endCursor = cur
+		}
+
+		return map[string]interface{}{
+			"edges": edges,
+			"pageInfo": map[string]interface{}{
+				"hasNextPage": hasNext,
+				"endCursor":   endCursor,
+			},
+		}, nil
+	}
+}
+
+// cursorPayload is the tuple a connection cursor encodes: which field it was
+// ordered on, that field's value for the row, and the row's _id as a
+// tiebreaker.
+type cursorPayload struct {
+	IndexField string      `json:"f"`
+	IndexValue interface{} `json:"v"`
+	ID         string      `json:"id"`
+}
+
+// encodeCursor builds an opaque pagination cursor for one row: the payload a
+// client needs to resume after it, base64-encoded alongside an HMAC-SHA256
+// tag over that payload so decodeCursor can reject anything not minted by
+// this engine - a client can't forge a cursor to scan arbitrary ranges.
+func encodeCursor(key []byte, indexField string, indexValue interface{}, id string) (string, error) {
+	payload, err := json.Marshal(cursorPayload{IndexField: indexField, IndexValue: indexValue, ID: id})
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	tag := mac.Sum(nil)
+
+	envelope := append(payload, '.')
+	envelope = append(envelope, []byte(base64.RawURLEncoding.EncodeToString(tag))...)
+	return base64.RawURLEncoding.EncodeToString(envelope), nil
+}
+
+// decodeCursor reverses encodeCursor, rejecting any cursor whose HMAC tag
+// doesn't match - whether corrupted in transit or hand-crafted by a client.
+func decodeCursor(key []byte, cursor string) (cursorPayload, error) {
+	envelope, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return cursorPayload{}, fmt.Errorf("invalid cursor encoding: %w", err)
+	}
+
+	sep := bytes.LastIndexByte(envelope, '.')
+	if sep < 0 {
+		return cursorPayload{}, fmt.Errorf("malformed cursor")
+	}
+	payload, tagB64 := envelope[:sep], envelope[sep+1:]
+
+	tag, err := base64.RawURLEncoding.DecodeString(string(tagB64))
+	if err != nil {
+		return cursorPayload{}, fmt.Errorf("invalid cursor tag encoding: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	if !hmac.Equal(mac.Sum(nil), tag) {
+		return cursorPayload{}, fmt.Errorf("cursor failed authentication")
+	}
+
+	var p cursorPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return cursorPayload{}, fmt.Errorf("invalid cursor payload: %w", err)
+	}
+	return p, nil
+}
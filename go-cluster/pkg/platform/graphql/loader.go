@@ -0,0 +1,65 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+)
+
+// loaderCacheKey is the context key a request's loaderCache is stored
+// under, set once per Execute call.
+type loaderCacheKey struct{}
+
+// loaderCache batches foreign-key joins within a single GraphQL request.
+// A federated list field's Resolve already has every row it's about to
+// return before the per-row FK sub-resolvers run, so it prefetches the
+// referenced documents for the whole list in one RunQuery and stores them
+// here; the FK field's resolver then just does a map lookup instead of a
+// remote call per row.
+type loaderCache struct {
+	mu   sync.Mutex
+	docs map[string]map[string]map[string]interface{} // loaderKey -> ref id -> doc
+}
+
+func newLoaderCache() *loaderCache {
+	return &loaderCache{docs: make(map[string]map[string]map[string]interface{})}
+}
+
+func loaderKey(sourceName, collection, refField string) string {
+	return sourceName + "|" + collection + "|" + refField
+}
+
+// store records a batch of resolved documents, keyed by id, for later
+// lookups against (sourceName, collection, refField).
+func (c *loaderCache) store(sourceName, collection, refField string, byID map[string]map[string]interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.docs[loaderKey(sourceName, collection, refField)] = byID
+}
+
+// has reports whether a batch has already been stored for this key, so a
+// list resolver that already prefetched once (e.g. when the loader cache
+// is reused by a retried field) doesn't issue a duplicate RunQuery.
+func (c *loaderCache) has(sourceName, collection, refField string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.docs[loaderKey(sourceName, collection, refField)]
+	return ok
+}
+
+func (c *loaderCache) get(sourceName, collection, refField, id string) (map[string]interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	byID, ok := c.docs[loaderKey(sourceName, collection, refField)]
+	if !ok {
+		return nil, false
+	}
+	doc, ok := byID[id]
+	return doc, ok
+}
+
+// loaderCacheFrom extracts the request-scoped loaderCache Execute attached
+// to ctx, if any.
+func loaderCacheFrom(ctx context.Context) (*loaderCache, bool) {
+	c, ok := ctx.Value(loaderCacheKey{}).(*loaderCache)
+	return c, ok
+}
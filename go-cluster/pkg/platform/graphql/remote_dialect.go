@@ -0,0 +1,154 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/lumadb/cluster/pkg/dialects"
+)
+
+// RemoteDialectSource queries a remote time-series database over its own
+// native HTTP API - not LumaDB's - and decodes the well-known response shape
+// for Dialect back into a QueryResult, so the GraphQL façade's remote/
+// mergeByTime fields can treat it the same as a local dialects.Router query.
+// This only covers the two response shapes implemented below; an unlisted
+// Dialect is a configuration error, not a silent no-op.
+type RemoteDialectSource struct {
+	Name    string
+	BaseURL string
+	Dialect dialects.Dialect
+	Client  *http.Client
+}
+
+// NewRemoteDialectSource creates a RemoteDialectSource with a bounded
+// default HTTP client, since an unresponsive remote TSDB shouldn't be able
+// to hang a GraphQL request indefinitely.
+func NewRemoteDialectSource(name, baseURL string, dialect dialects.Dialect) *RemoteDialectSource {
+	return &RemoteDialectSource{
+		Name:    name,
+		BaseURL: baseURL,
+		Dialect: dialect,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Query runs query against the remote source's native endpoint and decodes
+// its response into a QueryResult.
+func (s *RemoteDialectSource) Query(ctx context.Context, query string) (*dialects.QueryResult, error) {
+	switch s.Dialect {
+	case dialects.DialectPromQL:
+		return s.queryPromQL(ctx, query)
+	case dialects.DialectInfluxQL:
+		return s.queryInfluxQL(ctx, query)
+	default:
+		return nil, fmt.Errorf("remote dialect source %s: unsupported dialect %s", s.Name, s.Dialect)
+	}
+}
+
+// queryPromQL calls a Prometheus-compatible /api/v1/query endpoint and
+// flattens its vector/matrix result into rows of [timestamp, value].
+func (s *RemoteDialectSource) queryPromQL(ctx context.Context, query string) (*dialects.QueryResult, error) {
+	endpoint := s.BaseURL + "/api/v1/query?query=" + url.QueryEscape(query)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Metric map[string]string `json:"metric"`
+				Value  []interface{}     `json:"value"`  // instant vector: [ts, value]
+				Values [][]interface{}   `json:"values"` // range vector: [[ts, value], ...]
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := s.doJSON(req, &body); err != nil {
+		return nil, err
+	}
+	if body.Status != "success" {
+		return nil, fmt.Errorf("remote promql query failed: %s", body.Error)
+	}
+
+	rows := make([][]interface{}, 0)
+	for _, series := range body.Data.Result {
+		points := series.Values
+		if len(series.Value) > 0 {
+			points = append(points, series.Value)
+		}
+		for _, point := range points {
+			if len(point) != 2 {
+				continue
+			}
+			rows = append(rows, []interface{}{point[0], point[1]})
+		}
+	}
+
+	return &dialects.QueryResult{
+		Columns: []dialects.ColumnMeta{
+			{Name: "time", Type: "timestamp", IsTime: true},
+			{Name: "value", Type: "float64"},
+		},
+		Rows: rows,
+	}, nil
+}
+
+// queryInfluxQL calls a v1-compatible /query endpoint and flattens the
+// first statement's first series into QueryResult rows.
+func (s *RemoteDialectSource) queryInfluxQL(ctx context.Context, query string) (*dialects.QueryResult, error) {
+	endpoint := s.BaseURL + "/query?q=" + url.QueryEscape(query)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Results []struct {
+			Error  string `json:"error"`
+			Series []struct {
+				Columns []string        `json:"columns"`
+				Values  [][]interface{} `json:"values"`
+			} `json:"series"`
+		} `json:"results"`
+	}
+	if err := s.doJSON(req, &body); err != nil {
+		return nil, err
+	}
+	if len(body.Results) == 0 {
+		return &dialects.QueryResult{}, nil
+	}
+	if body.Results[0].Error != "" {
+		return nil, fmt.Errorf("remote influxql query failed: %s", body.Results[0].Error)
+	}
+	if len(body.Results[0].Series) == 0 {
+		return &dialects.QueryResult{}, nil
+	}
+
+	series := body.Results[0].Series[0]
+	columns := make([]dialects.ColumnMeta, len(series.Columns))
+	for i, name := range series.Columns {
+		columns[i] = dialects.ColumnMeta{Name: name, IsTime: name == "time"}
+	}
+
+	return &dialects.QueryResult{Columns: columns, Rows: series.Values}, nil
+}
+
+func (s *RemoteDialectSource) doJSON(req *http.Request, out interface{}) error {
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("remote dialect source %s: http %d", s.Name, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,168 @@
+package graphql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/lumadb/cluster/pkg/cluster"
+	"github.com/lumadb/cluster/pkg/platform/auth"
+)
+
+// newRetentionType builds the engine-wide "RetentionPolicy" object type,
+// reused across every tenant's collection type the same way jsonScalar is -
+// graphql-go rejects two distinct types registered under the same name
+// within one schema.
+func newRetentionType() *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "RetentionPolicy",
+		Fields: graphql.Fields{
+			"duration":           &graphql.Field{Type: graphql.String},
+			"shardGroupDuration": &graphql.Field{Type: graphql.String},
+			"replicaN":           &graphql.Field{Type: graphql.Int},
+		},
+	})
+}
+
+// retentionFieldResolver resolves the "retention" field stitched onto every
+// generated collection object type.
+func (e *GraphQLEngine) retentionFieldResolver(tenant, collection string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		policy, err := e.node.GetRetentionPolicy(scopedCollection(tenant, collection))
+		if err != nil {
+			return nil, err
+		}
+		return retentionPolicyPayload(policy), nil
+	}
+}
+
+func retentionPolicyPayload(policy *cluster.RetentionPolicy) map[string]interface{} {
+	if policy == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"duration":           policy.Duration.String(),
+		"shardGroupDuration": policy.ShardGroupDuration.String(),
+		"replicaN":           policy.ReplicaN,
+	}
+}
+
+// retentionPolicyFromArgs parses a create_retention_policy_<col> call's
+// arguments into a RetentionPolicy, defaulting shardGroupDuration to
+// duration and replicaN to 1 when omitted - mirroring InfluxDB's
+// RetentionPolicyInfo defaults.
+func retentionPolicyFromArgs(args map[string]interface{}) (cluster.RetentionPolicy, error) {
+	durationStr, _ := args["duration"].(string)
+	duration, err := time.ParseDuration(durationStr)
+	if err != nil {
+		return cluster.RetentionPolicy{}, fmt.Errorf("invalid duration %q: %w", durationStr, err)
+	}
+
+	shardGroupDuration := duration
+	if sgd, ok := args["shardGroupDuration"].(string); ok && sgd != "" {
+		shardGroupDuration, err = time.ParseDuration(sgd)
+		if err != nil {
+			return cluster.RetentionPolicy{}, fmt.Errorf("invalid shardGroupDuration %q: %w", sgd, err)
+		}
+	}
+
+	replicaN := 1
+	if n, ok := args["replicaN"].(int); ok && n > 0 {
+		replicaN = n
+	}
+
+	return cluster.RetentionPolicy{
+		Duration:           duration,
+		ShardGroupDuration: shardGroupDuration,
+		ReplicaN:           replicaN,
+	}, nil
+}
+
+// alterRetentionPolicyFromArgs merges a partial alter_retention_policy_<col>
+// call's arguments onto the collection's current policy.
+func alterRetentionPolicyFromArgs(current cluster.RetentionPolicy, args map[string]interface{}) (cluster.RetentionPolicy, error) {
+	updated := current
+	if durationStr, ok := args["duration"].(string); ok && durationStr != "" {
+		d, err := time.ParseDuration(durationStr)
+		if err != nil {
+			return updated, fmt.Errorf("invalid duration %q: %w", durationStr, err)
+		}
+		updated.Duration = d
+	}
+	if sgd, ok := args["shardGroupDuration"].(string); ok && sgd != "" {
+		d, err := time.ParseDuration(sgd)
+		if err != nil {
+			return updated, fmt.Errorf("invalid shardGroupDuration %q: %w", sgd, err)
+		}
+		updated.ShardGroupDuration = d
+	}
+	if n, ok := args["replicaN"].(int); ok && n > 0 {
+		updated.ReplicaN = n
+	}
+	return updated, nil
+}
+
+// addRetentionMutationFields registers create_retention_policy_<col> and
+// alter_retention_policy_<col> on mutationFields for one tenant collection.
+func (e *GraphQLEngine) addRetentionMutationFields(mutationFields graphql.Fields, tenant, collection string) {
+	mutationFields["create_retention_policy_"+collection] = &graphql.Field{
+		Type: graphql.Boolean,
+		Args: graphql.FieldConfigArgument{
+			"duration":           &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			"shardGroupDuration": &graphql.ArgumentConfig{Type: graphql.String},
+			"replicaN":           &graphql.ArgumentConfig{Type: graphql.Int},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			identity := identityFrom(p.Context)
+			if err := e.checkACL(p.Context, identity, auth.ActionManage, collection); err != nil {
+				return nil, err
+			}
+			policy, err := retentionPolicyFromArgs(p.Args)
+			if err != nil {
+				return nil, err
+			}
+			target, err := writableScopedCollection(identity.TenantID, collection)
+			if err != nil {
+				return nil, err
+			}
+			if err := e.node.SetRetentionPolicy(target, &policy); err != nil {
+				return nil, err
+			}
+			return true, nil
+		},
+	}
+
+	mutationFields["alter_retention_policy_"+collection] = &graphql.Field{
+		Type: graphql.Boolean,
+		Args: graphql.FieldConfigArgument{
+			"duration":           &graphql.ArgumentConfig{Type: graphql.String},
+			"shardGroupDuration": &graphql.ArgumentConfig{Type: graphql.String},
+			"replicaN":           &graphql.ArgumentConfig{Type: graphql.Int},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			identity := identityFrom(p.Context)
+			if err := e.checkACL(p.Context, identity, auth.ActionManage, collection); err != nil {
+				return nil, err
+			}
+			target, err := writableScopedCollection(identity.TenantID, collection)
+			if err != nil {
+				return nil, err
+			}
+			current, err := e.node.GetRetentionPolicy(target)
+			if err != nil {
+				return nil, err
+			}
+			if current == nil {
+				current = &cluster.RetentionPolicy{}
+			}
+			updated, err := alterRetentionPolicyFromArgs(*current, p.Args)
+			if err != nil {
+				return nil, err
+			}
+			if err := e.node.SetRetentionPolicy(target, &updated); err != nil {
+				return nil, err
+			}
+			return true, nil
+		},
+	}
+}
@@ -0,0 +1,69 @@
+package graphql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// PrintSDL renders tenant's built schema object types as GraphQL SDL, for
+// operator debugging via /debug/schema. It only prints object types - enough
+// to see what the dynamic per-collection schema looks like without needing a
+// full introspection client.
+func (e *GraphQLEngine) PrintSDL(tenant string) (string, error) {
+	schema, err := e.schemaForTenant(tenant)
+	if err != nil {
+		return "", err
+	}
+
+	typeMap := schema.TypeMap()
+	typeNames := make([]string, 0, len(typeMap))
+	for name := range typeMap {
+		if strings.HasPrefix(name, "__") {
+			continue
+		}
+		typeNames = append(typeNames, name)
+	}
+	sort.Strings(typeNames)
+
+	var b strings.Builder
+	for _, name := range typeNames {
+		obj, ok := typeMap[name].(*graphql.Object)
+		if !ok {
+			continue
+		}
+
+		fmt.Fprintf(&b, "type %s {\n", obj.Name())
+
+		fields := obj.Fields()
+		fieldNames := make([]string, 0, len(fields))
+		for fname := range fields {
+			fieldNames = append(fieldNames, fname)
+		}
+		sort.Strings(fieldNames)
+
+		for _, fname := range fieldNames {
+			field := fields[fname]
+			fmt.Fprintf(&b, "  %s%s: %s\n", fname, printArgs(field.Args), field.Type.String())
+		}
+		b.WriteString("}\n\n")
+	}
+
+	return b.String(), nil
+}
+
+// printArgs renders a field's arguments as "(name: Type, ...)", or "" if the
+// field takes none.
+func printArgs(args []*graphql.Argument) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = fmt.Sprintf("%s: %s", arg.Name(), arg.Type.String())
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
@@ -0,0 +1,377 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/lumadb/cluster/pkg/platform/auth"
+	"go.uber.org/zap"
+)
+
+// Identity is the caller a GraphQL operation executes as: the tenant whose
+// collections it may see, and the role used to evaluate the engine's ACL.
+type Identity struct {
+	TenantID string
+	Role     string
+}
+
+// ACL is evaluated by every local collection resolver before it touches the
+// database, letting the caller reject a read or write based on identity and
+// role claims. A nil ACL (the default) allows everything, matching the
+// engine's pre-multi-tenancy behavior.
+type ACL func(ctx context.Context, identity Identity, action auth.Action, collection string) error
+
+// SetACL installs the hook used to authorize local collection field
+// resolvers. Optional: an engine without one performs no per-field checks
+// beyond tenant/shared-collection scoping.
+func (e *GraphQLEngine) SetACL(acl ACL) {
+	e.acl = acl
+}
+
+// identityContextKey is the context key an Execute/ExecuteSubscription
+// call's Identity is stored under, for ACL hooks and tenant-scoped
+// resolvers to read back.
+type identityContextKey struct{}
+
+func identityFrom(ctx context.Context) Identity {
+	id, _ := ctx.Value(identityContextKey{}).(Identity)
+	return id
+}
+
+// sharedCollectionPrefix marks a collection as Galaxy-style "shared":
+// readable by every tenant, writable only by auth.SystemNamespace.
+const sharedCollectionPrefix = "shared_"
+
+func isSharedCollection(name string) bool {
+	return strings.HasPrefix(name, sharedCollectionPrefix)
+}
+
+// tenantCollectionPrefix namespaces a collection name under tenant so one
+// tenant can never read or guess its way into another's collections. This
+// concatenation is only unambiguous because tenant itself can never contain
+// "__" (auth.AuthEngine.Register rejects any namespace that does) - otherwise
+// tenant "a__b" plus collection "c" would collide with tenant "a" plus
+// collection "b__c", both resolving to "a__b__c".
+func tenantCollectionPrefix(tenant string) string {
+	return tenant + "__"
+}
+
+// scopedCollection maps a tenant-visible collection name to the name it's
+// actually stored under: shared_ collections are stored unprefixed (visible
+// to every tenant), everything else lives under the tenant's own prefix.
+func scopedCollection(tenant, collection string) string {
+	if isSharedCollection(collection) {
+		return collection
+	}
+	return tenantCollectionPrefix(tenant) + collection
+}
+
+// writableScopedCollection is scopedCollection plus the Galaxy rule that
+// shared_ collections may only be written by auth.SystemNamespace.
+func writableScopedCollection(tenant, collection string) (string, error) {
+	if isSharedCollection(collection) && tenant != auth.SystemNamespace {
+		return "", fmt.Errorf("collection %q is read-only outside the %q tenant", collection, auth.SystemNamespace)
+	}
+	return scopedCollection(tenant, collection), nil
+}
+
+// listTenantCollections returns the collections visible to tenant: its own
+// (de-prefixed back to their bare names) plus every shared_ collection.
+func (e *GraphQLEngine) listTenantCollections(tenant string) ([]string, error) {
+	all, err := e.node.ListCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := tenantCollectionPrefix(tenant)
+	var out []string
+	for _, name := range all {
+		switch {
+		case strings.HasPrefix(name, prefix):
+			out = append(out, strings.TrimPrefix(name, prefix))
+		case isSharedCollection(name):
+			out = append(out, name)
+		}
+	}
+	return out, nil
+}
+
+// tenantSchemaKey identifies one cached per-tenant schema build. version
+// comes from e.versions[tenant]; InvalidateTenant bumps it so the next
+// schemaForTenant call misses the cache instead of serving a stale schema.
+type tenantSchemaKey struct {
+	tenant  string
+	version int64
+}
+
+// InvalidateTenant drops tenant's cached schema, forcing the next Execute
+// for it to rebuild from the current collection list. There's no automatic
+// change detection (ListCollections has no notion of "since last call"), so
+// callers that add/remove a tenant's collections out of band must call this
+// themselves.
+func (e *GraphQLEngine) InvalidateTenant(tenant string) {
+	e.mu.Lock()
+	old := e.versions[tenant]
+	e.versions[tenant] = old + 1
+	e.mu.Unlock()
+
+	e.schemas.Delete(tenantSchemaKey{tenant: tenant, version: old})
+}
+
+// invalidateAllTenants drops every cached tenant schema. Used when a
+// federated source is added or removed, since federated fields are merged
+// into every tenant's schema alike.
+func (e *GraphQLEngine) invalidateAllTenants() {
+	e.schemas.Range(func(key, _ interface{}) bool {
+		e.schemas.Delete(key)
+		return true
+	})
+}
+
+// schemaForTenant returns tenant's cached schema, building and caching it
+// first if this is the first request for tenant (or its cache entry was
+// invalidated).
+func (e *GraphQLEngine) schemaForTenant(tenant string) (graphql.Schema, error) {
+	e.mu.Lock()
+	version := e.versions[tenant]
+	e.mu.Unlock()
+
+	key := tenantSchemaKey{tenant: tenant, version: version}
+	if cached, ok := e.schemas.Load(key); ok {
+		return cached.(graphql.Schema), nil
+	}
+
+	schema, err := e.buildSchemaForTenant(tenant)
+	if err != nil {
+		return graphql.Schema{}, err
+	}
+
+	e.schemas.Store(key, schema)
+	return schema, nil
+}
+
+// buildLocalFieldsForTenant builds the root Query/Mutation fields for
+// tenant's own (and every shared_) collection, with every resolver scoped to
+// tenant via scopedCollection/writableScopedCollection and gated by e.acl.
+func (e *GraphQLEngine) buildLocalFieldsForTenant(tenant string) (graphql.Fields, graphql.Fields, error) {
+	queryFields := graphql.Fields{
+		"hello": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return "world", nil
+			},
+		},
+	}
+
+	mutationFields := graphql.Fields{
+		"noop": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return "ok", nil
+			},
+		},
+	}
+
+	collections, err := e.listTenantCollections(tenant)
+	if err != nil {
+		e.logger.Error("failed to list collections for tenant schema build", zap.String("tenant", tenant), zap.Error(err))
+	}
+
+	for _, colName := range collections {
+		colName := colName // capture per iteration for the closures below
+
+		objType := graphql.NewObject(graphql.ObjectConfig{
+			Name: colName,
+			Fields: graphql.Fields{
+				"_id":      &graphql.Field{Type: graphql.String},
+				"_created": &graphql.Field{Type: graphql.String},
+				"data":     &graphql.Field{Type: e.jsonScalar},
+				"retention": &graphql.Field{
+					Type:    e.retentionType,
+					Resolve: e.retentionFieldResolver(tenant, colName),
+				},
+			},
+		})
+
+		queryFields[colName+"_by_pk"] = &graphql.Field{
+			Type: objType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				identity := identityFrom(p.Context)
+				if err := e.checkACL(p.Context, identity, auth.ActionRead, colName); err != nil {
+					return nil, err
+				}
+				id, _ := p.Args["id"].(string)
+				return e.node.GetDocument(scopedCollection(tenant, colName), id)
+			},
+		}
+
+		// sampledFields drives the typed <Col>_bool_exp/_order_by inputs
+		// below: a best-effort guess at the collection's field shape from a
+		// handful of sampled documents, not an enforced schema.
+		sampledFields := e.sampleFields(scopedCollection(tenant, colName))
+		boolExpType := buildBoolExpType(colName, sampledFields)
+		orderByType := buildOrderByType(colName, sampledFields, e.orderDirection)
+		_, connectionType := buildConnectionTypes(colName, objType, e.pageInfoType)
+
+		queryFields[colName] = &graphql.Field{
+			Type: graphql.NewList(objType),
+			Args: graphql.FieldConfigArgument{
+				"limit": &graphql.ArgumentConfig{Type: graphql.Int},
+				// where is the typed, validated path; where_raw is the
+				// opaque JSON escape hatch for anything the sampled shape
+				// doesn't cover.
+				"where":     &graphql.ArgumentConfig{Type: boolExpType},
+				"where_raw": &graphql.ArgumentConfig{Type: e.jsonScalar},
+				"order_by":  &graphql.ArgumentConfig{Type: graphql.NewList(orderByType)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				identity := identityFrom(p.Context)
+				if err := e.checkACL(p.Context, identity, auth.ActionRead, colName); err != nil {
+					return nil, err
+				}
+				limit, _ := p.Args["limit"].(int)
+				if limit <= 0 {
+					limit = 10
+				}
+				query := map[string]interface{}{"limit": limit}
+				if whereVal, ok := p.Args["where"].(map[string]interface{}); ok {
+					query["filter"] = whereVal
+				} else if rawVal, ok := p.Args["where_raw"].(map[string]interface{}); ok {
+					query["filter"] = rawVal
+				}
+				if orderVal, ok := p.Args["order_by"].([]interface{}); ok && len(orderVal) > 0 {
+					query["order_by"] = orderVal
+				}
+				return e.node.RunQuery(scopedCollection(tenant, colName), query)
+			},
+		}
+
+		queryFields[colName+"_connection"] = &graphql.Field{
+			Type: connectionType,
+			Args: graphql.FieldConfigArgument{
+				"first":     &graphql.ArgumentConfig{Type: graphql.Int},
+				"after":     &graphql.ArgumentConfig{Type: graphql.String},
+				"where":     &graphql.ArgumentConfig{Type: boolExpType},
+				"where_raw": &graphql.ArgumentConfig{Type: e.jsonScalar},
+				"order_by":  &graphql.ArgumentConfig{Type: graphql.NewList(orderByType)},
+			},
+			Resolve: e.connectionResolver(colName, scopedCollection(tenant, colName)),
+		}
+
+		mutationFields["insert_"+colName] = &graphql.Field{
+			Type: graphql.String, // Returns ID
+			Args: graphql.FieldConfigArgument{
+				"data": &graphql.ArgumentConfig{Type: graphql.NewNonNull(e.jsonScalar)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				identity := identityFrom(p.Context)
+				if err := e.checkACL(p.Context, identity, auth.ActionWrite, colName); err != nil {
+					return nil, err
+				}
+				target, err := writableScopedCollection(identity.TenantID, colName)
+				if err != nil {
+					return nil, err
+				}
+				data, _ := p.Args["data"].(map[string]interface{})
+				return e.node.InsertDocument(target, data)
+			},
+		}
+
+		e.addRetentionMutationFields(mutationFields, tenant, colName)
+	}
+
+	return queryFields, mutationFields, nil
+}
+
+// buildSubscriptionFieldsForTenant builds tenant's Subscription root: the
+// engine-wide fields from buildSubscriptionFields plus, for each of tenant's
+// collections, a CDC "<col>_changes(where)" field and a windowed-aggregation
+// "<col>_window(interval, agg)" field modeled on the tdengine stream engine's
+// CREATE TOPIC / CREATE STREAM primitives. Like every other subscription
+// field, Resolve is never invoked through graphql.Do() - ExecuteSubscription
+// serves <col>_changes out-of-band over the WebSocket transport and rejects
+// <col>_window with an error.
+func (e *GraphQLEngine) buildSubscriptionFieldsForTenant(tenant string) graphql.Fields {
+	fields := graphql.Fields{}
+	for name, field := range e.subscriptionFields {
+		fields[name] = field
+	}
+
+	collections, err := e.listTenantCollections(tenant)
+	if err != nil {
+		e.logger.Error("failed to list collections for tenant subscription fields", zap.String("tenant", tenant), zap.Error(err))
+		return fields
+	}
+
+	for _, colName := range collections {
+		fields[colName+"_changes"] = &graphql.Field{
+			Type: e.jsonScalar,
+			Args: graphql.FieldConfigArgument{
+				"where": &graphql.ArgumentConfig{Type: e.jsonScalar},
+			},
+			Resolve: subscriptionOnlyResolver(colName + "_changes"),
+		}
+		fields[colName+"_window"] = &graphql.Field{
+			Type: e.jsonScalar,
+			Args: graphql.FieldConfigArgument{
+				"interval": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"agg":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: subscriptionOnlyResolver(colName + "_window"),
+		}
+	}
+
+	return fields
+}
+
+// checkACL runs e.acl if one is installed; a nil ACL allows everything.
+func (e *GraphQLEngine) checkACL(ctx context.Context, identity Identity, action auth.Action, collection string) error {
+	if e.acl == nil {
+		return nil
+	}
+	return e.acl(ctx, identity, action, collection)
+}
+
+// buildSchemaForTenant assembles tenant's full schema: its local collection
+// fields plus every federated field currently stitched in (federation isn't
+// tenant-partitioned, so it's identical across tenants).
+func (e *GraphQLEngine) buildSchemaForTenant(tenant string) (graphql.Schema, error) {
+	queryFields, mutationFields, err := e.buildLocalFieldsForTenant(tenant)
+	if err != nil {
+		return graphql.Schema{}, err
+	}
+	subscriptionFields := e.buildSubscriptionFieldsForTenant(tenant)
+
+	e.mu.Lock()
+	for name, field := range e.federatedQueryFields {
+		queryFields[name] = field
+	}
+	for name, field := range e.federatedMutationFields {
+		mutationFields[name] = field
+	}
+	e.mu.Unlock()
+
+	for name, field := range e.buildDialectFields() {
+		queryFields[name] = field
+	}
+	for name, field := range e.buildRemoteDialectFields() {
+		queryFields[name] = field
+	}
+
+	schemaConfig := graphql.SchemaConfig{
+		Query:        graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: queryFields}),
+		Mutation:     graphql.NewObject(graphql.ObjectConfig{Name: "Mutation", Fields: mutationFields}),
+		Subscription: graphql.NewObject(graphql.ObjectConfig{Name: "Subscription", Fields: subscriptionFields}),
+	}
+
+	schema, err := graphql.NewSchema(schemaConfig)
+	if err != nil {
+		return graphql.Schema{}, fmt.Errorf("failed to create schema: %v", err)
+	}
+	return schema, nil
+}
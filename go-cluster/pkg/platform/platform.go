@@ -1,24 +1,34 @@
 package platform
 
 import (
+	"context"
+	"time"
+
 	"github.com/lumadb/cluster/pkg/cluster"
 	"github.com/lumadb/cluster/pkg/platform/auth"
 	"github.com/lumadb/cluster/pkg/platform/cron"
+	"github.com/lumadb/cluster/pkg/platform/events"
 	"github.com/lumadb/cluster/pkg/platform/federation"
 	"github.com/lumadb/cluster/pkg/platform/graphql"
 	"github.com/lumadb/cluster/pkg/platform/mcp"
 	"go.uber.org/zap"
 )
 
+// retentionSweepInterval is how often the background sweeper checks every
+// collection's retention policy for expired documents.
+const retentionSweepInterval = 5 * time.Minute
+
 // Platform manages the high-level application features (GraphQL, Events, Auth)
 type Platform struct {
-	node       *cluster.Node
-	logger     *zap.Logger
-	mcpServer  *mcp.MCPServer
-	gqlEngine  *graphql.GraphQLEngine
-	authEngine *auth.AuthEngine
-	cron       *cron.Scheduler
-	registry   *federation.SourceRegistry
+	node             *cluster.Node
+	logger           *zap.Logger
+	mcpServer        *mcp.MCPServer
+	gqlEngine        *graphql.GraphQLEngine
+	authEngine       *auth.AuthEngine
+	cron             *cron.Scheduler
+	registry         *federation.SourceRegistry
+	retentionSweeper *cluster.RetentionSweeper
+	hub              *Hub
 }
 
 func NewPlatform(node *cluster.Node, logger *zap.Logger) *Platform {
@@ -27,9 +37,15 @@ func NewPlatform(node *cluster.Node, logger *zap.Logger) *Platform {
 		logger:   logger,
 		cron:     cron.NewScheduler(node, logger),
 		registry: federation.NewSourceRegistry(),
+		hub:      NewHub(logger),
 	}
 }
 
+// Hub returns the platform's subscription fan-out hub
+func (p *Platform) Hub() *Hub {
+	return p.hub
+}
+
 // Start initializes all platform subsystems
 func (p *Platform) Start() error {
 	p.logger.Info("Starting Luma Platform...")
@@ -40,11 +56,16 @@ func (p *Platform) Start() error {
 
 	// 1. Start GraphQL Engine (needed by MCP)
 	p.gqlEngine = graphql.NewGraphQLEngine(p.node, p.registry, p.logger)
-	if err := p.gqlEngine.BuildSchema(); err != nil {
+	p.gqlEngine.SetSubscriber(p.hub)
+	if err := p.gqlEngine.BuildSchema(auth.SystemNamespace); err != nil {
 		p.logger.Error("Failed to build GraphQL schema", zap.Error(err))
 		return err
 	}
 
+	// 1b. Wire the subscription hub into the trigger bus as an internal sink so
+	// any collection's triggers can also fan out to live GraphQL/SSE clients.
+	p.node.RegisterSink(events.SinkTypeInternalHub, p.hub.Dispatch)
+
 	// 2. Start MCP Server
 	p.mcpServer = mcp.NewMCPServer(p.node, p.gqlEngine, p.logger)
 
@@ -59,6 +80,11 @@ func (p *Platform) Start() error {
 		p.logger.Error("Failed to start Auth Engine", zap.Error(err))
 		return err
 	}
+	p.gqlEngine.SetCursorKey(p.authEngine.SigningKey())
+
+	// 4. Start the retention sweeper
+	p.retentionSweeper = cluster.NewRetentionSweeper(p.node, p.logger, retentionSweepInterval)
+	go p.retentionSweeper.Run(context.Background())
 
 	return nil
 }
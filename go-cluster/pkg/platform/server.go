@@ -1,14 +1,18 @@
 package platform
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/fasthttp/router"
+	"github.com/fasthttp/websocket"
 	"github.com/lumadb/cluster/pkg/cluster"
 	"github.com/lumadb/cluster/pkg/platform/auth"
 	"github.com/lumadb/cluster/pkg/platform/events"
+	"github.com/lumadb/cluster/pkg/platform/graphql"
 	"github.com/valyala/fasthttp"
 	"go.uber.org/zap"
 )
@@ -34,7 +38,7 @@ func (s *Server) Start(addr string) error {
 	s.logger.Info("Starting LumaDB Platform Server (FastHTTP)", zap.String("addr", addr))
 
 	// Initialize Schema
-	if err := s.platform.gqlEngine.BuildSchema(); err != nil {
+	if err := s.platform.gqlEngine.BuildSchema(auth.SystemNamespace); err != nil {
 		s.logger.Error("Failed to build GraphQL schema", zap.Error(err))
 	}
 
@@ -55,6 +59,12 @@ func (s *Server) setupRoutes() {
 	s.router.POST("/graphql", s.authMiddleware(s.handleGraphQL))
 	s.router.GET("/graphql", s.authMiddleware(s.handleGraphQLOrPlayground))
 
+	// GraphQL subscriptions - graphql-transport-ws over WebSocket (Protected)
+	s.router.GET("/graphql/ws", s.authMiddleware(s.handleGraphQLSubscriptions))
+
+	// REST change feed - Server-Sent Events (Protected)
+	s.router.GET("/api/v1/{collection}/_changes", s.authMiddleware(s.handleChangesSSE))
+
 	// REST API
 	s.router.GET("/api/health", func(ctx *fasthttp.RequestCtx) {
 		ctx.SetContentType("application/json")
@@ -70,6 +80,22 @@ func (s *Server) setupRoutes() {
 	s.router.GET("/api/v1/{collection}", s.authMiddleware(s.handleRestList))
 	s.router.POST("/api/v1/{collection}", s.authMiddleware(s.handleRestInsert))
 	s.router.GET("/api/v1/{collection}/{id}", s.authMiddleware(s.handleRestGet))
+
+	// Operator debug/introspection surface (admin-only)
+	s.setupDebugRoutes()
+}
+
+// identityFromCtx resolves the tenant and role a request executes GraphQL
+// operations as. Tenant prefers the X-Luma-Namespace header (letting an
+// operator act on another tenant's behalf) and falls back to the namespace
+// claim authMiddleware pulled off the caller's JWT.
+func identityFromCtx(ctx *fasthttp.RequestCtx) graphql.Identity {
+	tenant := string(ctx.Request.Header.Peek("X-Luma-Namespace"))
+	if tenant == "" {
+		tenant, _ = ctx.UserValue("namespace").(string)
+	}
+	role, _ := ctx.UserValue("role").(string)
+	return graphql.Identity{TenantID: tenant, Role: role}
 }
 
 // Helpers
@@ -99,7 +125,7 @@ func (s *Server) handleGraphQL(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	result := s.platform.gqlEngine.Execute(ctx, body.Query, body.Variables)
+	result := s.platform.gqlEngine.Execute(ctx, identityFromCtx(ctx), body.Query, body.Variables)
 	jsonResponse(ctx, fasthttp.StatusOK, result)
 }
 
@@ -107,7 +133,7 @@ func (s *Server) handleGraphQLOrPlayground(ctx *fasthttp.RequestCtx) {
 	queryArgs := ctx.QueryArgs()
 	if queryArgs.Has("query") {
 		query := string(queryArgs.Peek("query"))
-		result := s.platform.gqlEngine.Execute(ctx, query, nil)
+		result := s.platform.gqlEngine.Execute(ctx, identityFromCtx(ctx), query, nil)
 		jsonResponse(ctx, fasthttp.StatusOK, result)
 		return
 	}
@@ -117,6 +143,123 @@ func (s *Server) handleGraphQLOrPlayground(ctx *fasthttp.RequestCtx) {
 	ctx.WriteString(graphiqlHTML)
 }
 
+// graphqlWSUpgrader upgrades /graphql/ws connections to the
+// graphql-transport-ws protocol (connection_init/subscribe/next/complete).
+var graphqlWSUpgrader = websocket.FastHTTPUpgrader{
+	Subprotocols: []string{"graphql-transport-ws"},
+	CheckOrigin:  func(ctx *fasthttp.RequestCtx) bool { return true },
+}
+
+type gqlWSMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+func (s *Server) handleGraphQLSubscriptions(ctx *fasthttp.RequestCtx) {
+	identity := identityFromCtx(ctx)
+
+	err := graphqlWSUpgrader.Upgrade(ctx, func(conn *websocket.Conn) {
+		defer conn.Close()
+
+		cancels := make(map[string]context.CancelFunc)
+		defer func() {
+			for _, cancel := range cancels {
+				cancel()
+			}
+		}()
+
+		for {
+			var msg gqlWSMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+
+			switch msg.Type {
+			case "connection_init":
+				conn.WriteJSON(gqlWSMessage{Type: "connection_ack"})
+
+			case "subscribe":
+				var payload struct {
+					Query string `json:"query"`
+				}
+				if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+					conn.WriteJSON(gqlWSMessage{ID: msg.ID, Type: "error"})
+					continue
+				}
+
+				subCtx, cancel := context.WithCancel(context.Background())
+				results, err := s.platform.gqlEngine.ExecuteSubscription(subCtx, identity, payload.Query)
+				if err != nil {
+					cancel()
+					conn.WriteJSON(gqlWSMessage{ID: msg.ID, Type: "error"})
+					continue
+				}
+				cancels[msg.ID] = cancel
+
+				go func(id string) {
+					defer cancel()
+					for result := range results {
+						data, _ := json.Marshal(result)
+						if err := conn.WriteJSON(gqlWSMessage{ID: id, Type: "next", Payload: data}); err != nil {
+							return
+						}
+					}
+					conn.WriteJSON(gqlWSMessage{ID: id, Type: "complete"})
+				}(msg.ID)
+
+			case "complete":
+				// Client-initiated unsubscribe: canceling the subscription's ctx
+				// closes its results channel, which ends the goroutine above. A
+				// closed connection cancels every remaining subscription.
+				if cancel, ok := cancels[msg.ID]; ok {
+					cancel()
+					delete(cancels, msg.ID)
+				}
+			}
+		}
+	})
+
+	if err != nil {
+		s.logger.Error("Failed to upgrade GraphQL WebSocket", zap.Error(err))
+	}
+}
+
+// handleChangesSSE streams change events for a collection as Server-Sent
+// Events, filtered by op and authorized via the same role-based checks as the
+// REST API.
+func (s *Server) handleChangesSSE(ctx *fasthttp.RequestCtx) {
+	collection := ctx.UserValue("collection").(string)
+	role, ok := ctx.UserValue("role").(string)
+	if !ok || !s.platform.authEngine.IsAuthorized(role, auth.ActionRead) {
+		errorResponse(ctx, fasthttp.StatusForbidden, "Forbidden")
+		return
+	}
+
+	op := string(ctx.QueryArgs().Peek("op"))
+	subID := fmt.Sprintf("sse-%s-%s-%d", collection, role, ctx.ConnID())
+	evtCh := s.platform.Hub().Subscribe(subID, role, collection, events.EventType(op))
+
+	ctx.SetContentType("text/event-stream")
+	ctx.Response.Header.Set("Cache-Control", "no-cache")
+	ctx.Response.Header.Set("Connection", "keep-alive")
+
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer s.platform.Hub().Unsubscribe(subID)
+
+		for evt := range evtCh {
+			data, err := json.Marshal(evt)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", evt.Type, data)
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+}
+
 func (s *Server) handleRestList(ctx *fasthttp.RequestCtx) {
 	collection := ctx.UserValue("collection").(string)
 	role, ok := ctx.UserValue("role").(string)
@@ -231,17 +374,17 @@ func (s *Server) handleLogin(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	if creds.Username == "admin" && creds.Password == "password" {
-		token, err := s.platform.authEngine.GenerateToken("admin", "admin")
-		if err != nil {
-			errorResponse(ctx, fasthttp.StatusInternalServerError, "Failed to generate token")
+	token, err := s.platform.authEngine.GenerateToken(creds.Username, creds.Password)
+	if err != nil {
+		if err == auth.ErrTooManyAttempts {
+			errorResponse(ctx, fasthttp.StatusTooManyRequests, err.Error())
 			return
 		}
-		jsonResponse(ctx, fasthttp.StatusOK, map[string]string{"token": token})
+		errorResponse(ctx, fasthttp.StatusUnauthorized, "Invalid credentials")
 		return
 	}
 
-	errorResponse(ctx, fasthttp.StatusUnauthorized, "Invalid credentials")
+	jsonResponse(ctx, fasthttp.StatusOK, map[string]string{"token": token})
 }
 
 // Middleware
@@ -269,6 +412,7 @@ func (s *Server) authMiddleware(next fasthttp.RequestHandler) fasthttp.RequestHa
 
 		ctx.SetUserValue("user_id", claims.UserID)
 		ctx.SetUserValue("role", claims.Role)
+		ctx.SetUserValue("namespace", claims.Namespace)
 		next(ctx)
 	}
 }
@@ -0,0 +1,97 @@
+package platform
+
+import (
+	"sync"
+
+	"github.com/lumadb/cluster/pkg/platform/events"
+	"go.uber.org/zap"
+)
+
+// subscriptionKey identifies a class of change events a client cares about.
+type subscriptionKey struct {
+	collection string
+	eventType  events.EventType
+}
+
+// Subscription is a single client's live feed of matching events, shared by
+// both the GraphQL-over-WebSocket transport and the REST SSE transport.
+type Subscription struct {
+	ID     string
+	Key    subscriptionKey
+	Role   string
+	Events chan events.Event
+}
+
+// Hub fans events out from the trigger bus to any number of subscribers,
+// filtered by collection and event type. It does no authorization itself:
+// the SSE and GraphQL transports check the caller's role before Subscribe.
+type Hub struct {
+	mu     sync.RWMutex
+	subs   map[string]*Subscription
+	logger *zap.Logger
+}
+
+// NewHub creates a new subscription fan-out hub.
+func NewHub(logger *zap.Logger) *Hub {
+	return &Hub{
+		subs:   make(map[string]*Subscription),
+		logger: logger,
+	}
+}
+
+// Subscribe registers a new subscriber and returns its channel. Callers must
+// call Unsubscribe when the client disconnects. It satisfies graphql.Subscriber
+// so the GraphQL engine can resolve `subscription` operations without this
+// package needing to import graphql (which already imports platform).
+func (h *Hub) Subscribe(id, role, collection string, eventType events.EventType) <-chan events.Event {
+	sub := &Subscription{
+		ID:     id,
+		Key:    subscriptionKey{collection: collection, eventType: eventType},
+		Role:   role,
+		Events: make(chan events.Event, 64),
+	}
+
+	h.mu.Lock()
+	h.subs[id] = sub
+	h.mu.Unlock()
+
+	return sub.Events
+}
+
+// Unsubscribe removes a subscriber and closes its channel.
+func (h *Hub) Unsubscribe(id string) {
+	h.mu.Lock()
+	sub, ok := h.subs[id]
+	if ok {
+		delete(h.subs, id)
+	}
+	h.mu.Unlock()
+
+	if ok {
+		close(sub.Events)
+	}
+}
+
+// Dispatch is registered with the node's trigger bus as a sink and fans the
+// event out to every subscriber whose filter matches. A slow subscriber never
+// blocks the others - events are dropped for it instead.
+func (h *Hub) Dispatch(evt events.Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, sub := range h.subs {
+		if sub.Key.collection != "" && sub.Key.collection != evt.Collection {
+			continue
+		}
+		if sub.Key.eventType != "" && sub.Key.eventType != evt.Type {
+			continue
+		}
+
+		select {
+		case sub.Events <- evt:
+		default:
+			h.logger.Warn("Dropping event for slow subscriber",
+				zap.String("subscription_id", sub.ID), zap.String("collection", evt.Collection))
+		}
+	}
+}
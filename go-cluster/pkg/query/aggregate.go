@@ -0,0 +1,280 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/axiomhq/hyperloglog"
+)
+
+// AggregateState is a mergeable partial aggregate. Each shard computes one
+// locally; the Executor's fan-in merges them pairwise before calling
+// Finalize to produce the value the caller actually asked for. This is what
+// makes AVG, COUNT DISTINCT and percentiles correct across shards - a naive
+// average-of-averages or a single shard's top-K would be wrong.
+type AggregateState interface {
+	// Merge combines other into the receiver. other must be the same
+	// concrete type (the same AggregateSpec.Op).
+	Merge(other AggregateState) error
+	// Finalize computes the user-visible result from the accumulated state.
+	Finalize() interface{}
+}
+
+// NewAggregateState constructs the zero-value partial state for spec.Op, to
+// be fed values via accumulation helpers below as rows are scanned locally.
+func NewAggregateState(spec AggregateSpec) (AggregateState, error) {
+	switch spec.Op {
+	case "sum":
+		return &SumState{}, nil
+	case "count":
+		return &CountState{}, nil
+	case "avg":
+		return &AvgState{}, nil
+	case "min":
+		return &MinState{Value: math.Inf(1)}, nil
+	case "max":
+		return &MaxState{Value: math.Inf(-1)}, nil
+	case "count_distinct":
+		return &HLLState{sketch: hyperloglog.New16()}, nil
+	case "percentile":
+		return &TDigestState{digest: newTDigest(100), percentile: spec.Percentile}, nil
+	default:
+		return nil, fmt.Errorf("unknown aggregate op %q", spec.Op)
+	}
+}
+
+// SumState accumulates a running total.
+type SumState struct {
+	Value float64
+}
+
+func (s *SumState) Add(v float64) { s.Value += v }
+
+func (s *SumState) Merge(other AggregateState) error {
+	o, ok := other.(*SumState)
+	if !ok {
+		return fmt.Errorf("cannot merge %T into SumState", other)
+	}
+	s.Value += o.Value
+	return nil
+}
+
+func (s *SumState) Finalize() interface{} { return s.Value }
+
+// CountState accumulates a running row count.
+type CountState struct {
+	Value int64
+}
+
+func (s *CountState) Add(n int64) { s.Value += n }
+
+func (s *CountState) Merge(other AggregateState) error {
+	o, ok := other.(*CountState)
+	if !ok {
+		return fmt.Errorf("cannot merge %T into CountState", other)
+	}
+	s.Value += o.Value
+	return nil
+}
+
+func (s *CountState) Finalize() interface{} { return s.Value }
+
+// AvgState tracks {sum, count} so the final division happens once, after
+// merging, rather than averaging per-shard averages (which is only correct
+// when every shard holds the same number of rows).
+type AvgState struct {
+	Sum   float64
+	Count int64
+}
+
+func (s *AvgState) Add(v float64) {
+	s.Sum += v
+	s.Count++
+}
+
+func (s *AvgState) Merge(other AggregateState) error {
+	o, ok := other.(*AvgState)
+	if !ok {
+		return fmt.Errorf("cannot merge %T into AvgState", other)
+	}
+	s.Sum += o.Sum
+	s.Count += o.Count
+	return nil
+}
+
+func (s *AvgState) Finalize() interface{} {
+	if s.Count == 0 {
+		return 0.0
+	}
+	return s.Sum / float64(s.Count)
+}
+
+// MinState and MaxState track a running extremum.
+type MinState struct{ Value float64 }
+
+func (s *MinState) Add(v float64) {
+	if v < s.Value {
+		s.Value = v
+	}
+}
+
+func (s *MinState) Merge(other AggregateState) error {
+	o, ok := other.(*MinState)
+	if !ok {
+		return fmt.Errorf("cannot merge %T into MinState", other)
+	}
+	if o.Value < s.Value {
+		s.Value = o.Value
+	}
+	return nil
+}
+
+func (s *MinState) Finalize() interface{} { return s.Value }
+
+type MaxState struct{ Value float64 }
+
+func (s *MaxState) Add(v float64) {
+	if v > s.Value {
+		s.Value = v
+	}
+}
+
+func (s *MaxState) Merge(other AggregateState) error {
+	o, ok := other.(*MaxState)
+	if !ok {
+		return fmt.Errorf("cannot merge %T into MaxState", other)
+	}
+	if o.Value > s.Value {
+		s.Value = o.Value
+	}
+	return nil
+}
+
+func (s *MaxState) Finalize() interface{} { return s.Value }
+
+// HLLState estimates COUNT(DISTINCT x) with a HyperLogLog sketch, which
+// merges by union rather than needing the full distinct set shipped home.
+type HLLState struct {
+	sketch *hyperloglog.Sketch
+}
+
+func (s *HLLState) Add(key []byte) { s.sketch.Insert(key) }
+
+func (s *HLLState) Merge(other AggregateState) error {
+	o, ok := other.(*HLLState)
+	if !ok {
+		return fmt.Errorf("cannot merge %T into HLLState", other)
+	}
+	return s.sketch.Merge(o.sketch)
+}
+
+func (s *HLLState) Finalize() interface{} { return s.sketch.Estimate() }
+
+// TDigestState estimates percentiles (e.g. p99 latency) from t-digest
+// centroids, which compress cheaply and merge without re-sorting every raw
+// sample across shards.
+type TDigestState struct {
+	digest     *tDigest
+	percentile float64
+}
+
+func (s *TDigestState) Add(v float64) { s.digest.Add(v, 1) }
+
+func (s *TDigestState) Merge(other AggregateState) error {
+	o, ok := other.(*TDigestState)
+	if !ok {
+		return fmt.Errorf("cannot merge %T into TDigestState", other)
+	}
+	s.digest.Merge(o.digest)
+	return nil
+}
+
+func (s *TDigestState) Finalize() interface{} {
+	return s.digest.Quantile(s.percentile)
+}
+
+// tDigest is a minimal centroid-based digest sufficient for approximate
+// quantiles of partial aggregates. It intentionally skips the
+// scale-function-driven compression of a production t-digest and instead
+// keeps at most maxCentroids by periodic naive compaction, trading a little
+// accuracy for a much simpler merge.
+type tDigest struct {
+	centroids    []centroid
+	maxCentroids int
+}
+
+type centroid struct {
+	Mean   float64
+	Weight float64
+}
+
+func newTDigest(maxCentroids int) *tDigest {
+	return &tDigest{maxCentroids: maxCentroids}
+}
+
+func (d *tDigest) Add(value, weight float64) {
+	d.centroids = append(d.centroids, centroid{Mean: value, Weight: weight})
+	if len(d.centroids) > d.maxCentroids*4 {
+		d.compress()
+	}
+}
+
+func (d *tDigest) Merge(other *tDigest) {
+	d.centroids = append(d.centroids, other.centroids...)
+	if len(d.centroids) > d.maxCentroids*4 {
+		d.compress()
+	}
+}
+
+// compress sorts centroids by mean and greedily merges neighbors until at
+// most maxCentroids remain.
+func (d *tDigest) compress() {
+	sort.Slice(d.centroids, func(i, j int) bool { return d.centroids[i].Mean < d.centroids[j].Mean })
+
+	if len(d.centroids) <= d.maxCentroids {
+		return
+	}
+
+	compressed := make([]centroid, 0, d.maxCentroids)
+	groupSize := (len(d.centroids) + d.maxCentroids - 1) / d.maxCentroids
+
+	for i := 0; i < len(d.centroids); i += groupSize {
+		end := i + groupSize
+		if end > len(d.centroids) {
+			end = len(d.centroids)
+		}
+
+		var sumWeight, weightedMean float64
+		for _, c := range d.centroids[i:end] {
+			sumWeight += c.Weight
+			weightedMean += c.Mean * c.Weight
+		}
+		compressed = append(compressed, centroid{Mean: weightedMean / sumWeight, Weight: sumWeight})
+	}
+
+	d.centroids = compressed
+}
+
+// Quantile returns an approximate value at quantile q (0..1).
+func (d *tDigest) Quantile(q float64) float64 {
+	d.compress()
+	if len(d.centroids) == 0 {
+		return 0
+	}
+
+	var totalWeight float64
+	for _, c := range d.centroids {
+		totalWeight += c.Weight
+	}
+
+	target := q * totalWeight
+	var cumulative float64
+	for _, c := range d.centroids {
+		cumulative += c.Weight
+		if cumulative >= target {
+			return c.Mean
+		}
+	}
+	return d.centroids[len(d.centroids)-1].Mean
+}
@@ -3,6 +3,7 @@ package query
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/lumadb/cluster/pkg/core"
@@ -19,6 +20,9 @@ type Result struct {
 type ClusterClient interface {
 	ExecuteRemote(ctx context.Context, nodeAddr string, stmt *Statement) (*Result, error)
 	ExecuteLocal(ctx context.Context, stmt *Statement) (*Result, error)
+	// Peers returns the addresses of every node holding a shard of the
+	// collection, used to resolve a "*" (broadcast) shard list.
+	Peers() []string
 }
 
 // Executor executes a query plan
@@ -60,75 +64,58 @@ func (e *Executor) executePointLookup(ctx context.Context, plan *Plan) (*Result,
 }
 
 func (e *Executor) executeScatterGather(ctx context.Context, plan *Plan) (*Result, error) {
-	// Broadcast to all shards (simulated by plan.Shards containing "*")
-	// Real implementation: resolve "*" to actual addresses, or rely on client to know broadcast peers
-
-	// For MVP, we assume client knows how to Broadcast if we pass specific flag or list
-	// Or we iterate here if we had the list.
-	// Let's assume we get a list of addresses from the plan (populated by Planner in real world)
-	// Since Planner put "*", we need to resolve it or let client handle.
-	// Let's assume strict separation and say Planner should have populated actual IPs.
-	// Since it didn't (MVP), we'll assume client.Broadcast() exists or similar.
-	// Let's abstract this:
-
-	// We will perform naive scatter-gather here assuming plan.Shards has real addresses
-	// If it has "*", we fail for now, or update Planner to provide IDs.
-
-	// Update: Planner provided "*". Let's assume Planner injects "localhost" and other peers.
-	// Since we don't have that yet, let's just make it compilable.
-
-	return &Result{Count: 0, Documents: []interface{}{}}, nil
+	return e.fanOut(ctx, plan.Shards, plan.Query)
 }
 
+// executeAggregation fans plan.Query out to every shard, reduces each
+// shard's matching documents into a partial AggregateState per group, then
+// merges the per-shard states before finalizing. This is what keeps AVG,
+// COUNT DISTINCT and percentiles correct across shards: the merge happens on
+// {sum,count}/HLL sketches/t-digest centroids rather than on already-final
+// per-shard numbers.
 func (e *Executor) executeAggregation(ctx context.Context, plan *Plan) (*Result, error) {
-	// 1. Scatter: Broadcast query to all nodes
-	// Assume shards=["*"] means all nodes
-	// In MVP, we use a fixed list of peers or let fanOut handle discovery
-
-	// Create a modified query for the shards if needed (e.g., partial aggregates)
-	// For MVP, we send the full GROUP BY query. Each shard returns groups.
+	if len(plan.Aggregates) == 0 {
+		return nil, fmt.Errorf("aggregation plan has no AggregateSpecs")
+	}
 
-	results, err := e.fanOut(ctx, plan.Shards, plan.Query)
+	shardStates, err := e.fanOutPartialAggregates(ctx, plan)
 	if err != nil {
 		return nil, err
 	}
 
-	// 2. Gather & Merge
-	// We merge using the same Rust logic (e.g. sum of sums)
-	// For MVP, we extract the "values" from returned documents and aggregate them.
-	// Assumption: Shards return Documents containing the aggregation value for a single group in MVP,
-	// OR they return raw docs if we are doing global aggregation.
-	// Let's assume global aggregation (e.g. SUM(price)) for MVP simplicity.
-
-	// Flatten result values
-	var values []interface{}
-	for _, doc := range results.Documents {
-		// Extract value. For MVP we assume document itself is the value or contains it.
-		// If doc is map, we need the field.
-		// Given we don't have the field name here easily without parsing plan.Query,
-		// we'll assume the document is a loose value or we take the first field.
-		// BETTER: executePointLookup style, but for now we just collect.
-		values = append(values, doc)
+	merged := make(map[string]map[string]AggregateState)
+	for _, states := range shardStates {
+		for group, byAlias := range states {
+			dst, ok := merged[group]
+			if !ok {
+				dst = make(map[string]AggregateState)
+				merged[group] = dst
+			}
+			for alias, state := range byAlias {
+				if existing, ok := dst[alias]; ok {
+					if err := existing.Merge(state); err != nil {
+						return nil, fmt.Errorf("merge aggregate %q: %w", alias, err)
+					}
+				} else {
+					dst[alias] = state
+				}
+			}
+		}
 	}
 
-	// Use Rust FFI to aggregate the partial results
-	// Note: For SUM, Sum(P1, P2) works. For AVG, we need Count+Sum.
-	// MVP: Supports SUM/MIN/MAX. AVG is approximate if not weighted.
-	// Real implementation would handle partial aggregates state.
-
-	// Determine Op from Plan (MVP hardcode or pass via Plan)
-	op := "SUM"
-	// TODO: plumb op through Plan
-
-	finalVal, err := core.ExecuteAggregate(values, op)
-	if err != nil {
-		return nil, err
+	docs := make([]interface{}, 0, len(merged))
+	for group, byAlias := range merged {
+		doc := make(map[string]interface{}, len(byAlias)+len(plan.GroupBy))
+		for i, col := range plan.GroupBy {
+			doc[col] = groupKeyPart(group, i)
+		}
+		for alias, state := range byAlias {
+			doc[alias] = state.Finalize()
+		}
+		docs = append(docs, doc)
 	}
 
-	return &Result{
-		Count:     1,
-		Documents: []interface{}{map[string]interface{}{"result": finalVal}},
-	}, nil
+	return &Result{Count: len(docs), Documents: docs}, nil
 }
 
 func (e *Executor) executeJoin(ctx context.Context, plan *Plan) (*Result, error) {
@@ -178,16 +165,18 @@ func (e *Executor) executeJoin(ctx context.Context, plan *Plan) (*Result, error)
 	return &Result{Documents: joinedDocs, Count: len(joinedDocs)}, nil
 }
 
+// resolveShards expands a "*" (broadcast) shard list into the cluster's
+// real peer addresses. Any other shard list is returned unchanged.
+func (e *Executor) resolveShards(nodes []string) []string {
+	if len(nodes) == 1 && nodes[0] == "*" {
+		return e.client.Peers()
+	}
+	return nodes
+}
+
 // ScatterHelper could go here (fan-out, fan-in)
 func (e *Executor) fanOut(ctx context.Context, nodes []string, stmt *Statement) (*Result, error) {
-	// If nodes contains "*", replace with actual peer list
-	// For MVP, if "*", we assume client knows how to handle it or we use placeholder
-	targetNodes := nodes
-	if len(nodes) > 0 && nodes[0] == "*" {
-		// e.client.GetPeers() ??
-		// Fallback: Just execute locally for test
-		targetNodes = []string{"localhost"}
-	}
+	targetNodes := e.resolveShards(nodes)
 
 	var wg sync.WaitGroup
 	resultChan := make(chan *Result, len(targetNodes))
@@ -230,3 +219,182 @@ func (e *Executor) fanOut(ctx context.Context, nodes []string, stmt *Statement)
 
 	return finalRes, nil
 }
+
+// groupKeySeparator joins GroupBy column values into a single map key. It is
+// a control character so it can't collide with real column values.
+const groupKeySeparator = "\x1f"
+
+// groupKeyPart recovers the i-th GroupBy column value from a key built by
+// buildGroupKey.
+func groupKeyPart(key string, i int) string {
+	parts := strings.Split(key, groupKeySeparator)
+	if i >= len(parts) {
+		return ""
+	}
+	return parts[i]
+}
+
+// buildGroupKey derives the fan-in map key for a document given the
+// GroupBy columns. A plan with no GroupBy collapses everything into one
+// global group.
+func buildGroupKey(doc map[string]interface{}, groupBy []string) string {
+	if len(groupBy) == 0 {
+		return ""
+	}
+	parts := make([]string, len(groupBy))
+	for i, col := range groupBy {
+		parts[i] = fmt.Sprintf("%v", doc[col])
+	}
+	return strings.Join(parts, groupKeySeparator)
+}
+
+// fanOutPartialAggregates sends plan.Query to every resolved shard and, for
+// each shard, reduces its matching documents into one AggregateState per
+// (group, alias) pair. The caller merges these per-shard partials together.
+func (e *Executor) fanOutPartialAggregates(ctx context.Context, plan *Plan) ([]map[string]map[string]AggregateState, error) {
+	targetNodes := e.resolveShards(plan.Shards)
+
+	type shardResult struct {
+		states map[string]map[string]AggregateState
+		err    error
+	}
+	resultChan := make(chan shardResult, len(targetNodes))
+
+	var wg sync.WaitGroup
+	for _, node := range targetNodes {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+
+			var res *Result
+			var err error
+			if addr == "localhost" {
+				res, err = e.client.ExecuteLocal(ctx, plan.Query)
+			} else {
+				res, err = e.client.ExecuteRemote(ctx, addr, plan.Query)
+			}
+			if err != nil {
+				resultChan <- shardResult{err: fmt.Errorf("shard %s: %w", addr, err)}
+				return
+			}
+
+			states, err := partialAggregate(res.Documents, plan)
+			if err != nil {
+				resultChan <- shardResult{err: fmt.Errorf("shard %s: %w", addr, err)}
+				return
+			}
+			resultChan <- shardResult{states: states}
+		}(node)
+	}
+
+	wg.Wait()
+	close(resultChan)
+
+	all := make([]map[string]map[string]AggregateState, 0, len(targetNodes))
+	for res := range resultChan {
+		if res.err != nil {
+			return nil, res.err
+		}
+		all = append(all, res.states)
+	}
+	return all, nil
+}
+
+// partialAggregate reduces one shard's raw matching documents into a
+// per-group, per-alias AggregateState - the "intermediate state tuple" the
+// Planner expects to see merged across shards.
+func partialAggregate(docs []interface{}, plan *Plan) (map[string]map[string]AggregateState, error) {
+	states := make(map[string]map[string]AggregateState)
+
+	for _, raw := range docs {
+		doc, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		group := buildGroupKey(doc, plan.GroupBy)
+		byAlias, ok := states[group]
+		if !ok {
+			byAlias = make(map[string]AggregateState)
+			states[group] = byAlias
+		}
+
+		for _, spec := range plan.Aggregates {
+			state, ok := byAlias[spec.Alias]
+			if !ok {
+				var err error
+				state, err = NewAggregateState(spec)
+				if err != nil {
+					return nil, err
+				}
+				byAlias[spec.Alias] = state
+			}
+
+			if err := accumulate(state, spec, doc); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return states, nil
+}
+
+// accumulate feeds one document's column value into a partial
+// AggregateState, dispatching on its concrete type since AggregateState
+// itself only exposes Merge/Finalize.
+func accumulate(state AggregateState, spec AggregateSpec, doc map[string]interface{}) error {
+	switch s := state.(type) {
+	case *SumState:
+		v, err := toFloat64(doc[spec.Column])
+		if err != nil {
+			return err
+		}
+		s.Add(v)
+	case *CountState:
+		s.Add(1)
+	case *AvgState:
+		v, err := toFloat64(doc[spec.Column])
+		if err != nil {
+			return err
+		}
+		s.Add(v)
+	case *MinState:
+		v, err := toFloat64(doc[spec.Column])
+		if err != nil {
+			return err
+		}
+		s.Add(v)
+	case *MaxState:
+		v, err := toFloat64(doc[spec.Column])
+		if err != nil {
+			return err
+		}
+		s.Add(v)
+	case *HLLState:
+		s.Add([]byte(fmt.Sprintf("%v", doc[spec.Column])))
+	case *TDigestState:
+		v, err := toFloat64(doc[spec.Column])
+		if err != nil {
+			return err
+		}
+		s.Add(v)
+	default:
+		return fmt.Errorf("unhandled aggregate state type %T", state)
+	}
+	return nil
+}
+
+// toFloat64 coerces the dynamically-typed values documents hold (decoded
+// from JSON, so normally float64 already) into float64 for accumulation.
+func toFloat64(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	default:
+		return 0, fmt.Errorf("value %v is not numeric", v)
+	}
+}
@@ -0,0 +1,136 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PlanType identifies how the Executor should carry out a Plan.
+type PlanType int
+
+const (
+	PlanTypePointLookup PlanType = iota
+	PlanTypeScatterGather
+	PlanTypeAggregation
+	PlanTypeJoin
+)
+
+// Statement is the normalized, dialect-agnostic form of a query the Planner
+// hands to the Executor (and, for scatter-gather, to remote peers).
+type Statement struct {
+	Collection string
+	Filter     map[string]interface{}
+	Limit      int64
+}
+
+// AggregateSpec describes one aggregation the Planner wants computed, e.g.
+// AVG(price) AS avg_price, COUNT(DISTINCT user_id), PERCENTILE(latency, 99).
+type AggregateSpec struct {
+	Op     string // "sum", "count", "count_distinct", "avg", "min", "max", "percentile"
+	Column string
+	Alias  string
+	// Percentile is only meaningful when Op == "percentile" (e.g. 0.99 for p99).
+	Percentile float64
+}
+
+// aggregateCallRe matches a single "op(column)" or "op(column) AS alias"
+// aggregate call inside a SELECT list, e.g. "avg(price) AS avg_price".
+var aggregateCallRe = regexp.MustCompile(`(?i)^\s*(\w+)\(([\w.*]+)\)(?:\s+as\s+(\w+))?\s*$`)
+
+// selectQueryRe matches a minimal SQL-like statement:
+//
+//	SELECT <cols> FROM <collection> [WHERE <k>=<v> [AND <k>=<v>]...] [GROUP BY <cols>] [LIMIT <n>]
+//
+// This is deliberately not a full parser - it exists so /debug/plans can show
+// operators how a query would be planned without executing it.
+var selectQueryRe = regexp.MustCompile(`(?is)^\s*select\s+(.+?)\s+from\s+(\w+)(?:\s+where\s+(.+?))?(?:\s+group\s+by\s+([\w,\s]+?))?(?:\s+limit\s+(\d+))?\s*$`)
+var whereClauseRe = regexp.MustCompile(`(\w+)\s*=\s*'?([^'\s]+)'?`)
+
+// Planner turns a query string into a Plan the Executor can run - or, for
+// /debug/plans, that an operator can inspect without running it at all.
+type Planner struct{}
+
+// NewPlanner creates a Planner.
+func NewPlanner() *Planner {
+	return &Planner{}
+}
+
+// Plan parses query into a Plan. It only understands the minimal SQL-like
+// shape described on selectQueryRe; anything else is rejected rather than
+// guessed at.
+func (p *Planner) Plan(query string) (*Plan, error) {
+	m := selectQueryRe.FindStringSubmatch(query)
+	if m == nil {
+		return nil, fmt.Errorf("query does not match the supported SELECT ... FROM ... shape")
+	}
+
+	selectList, collection, where, groupByList, limitStr := m[1], m[2], m[3], m[4], m[5]
+
+	stmt := &Statement{Collection: collection, Filter: make(map[string]interface{})}
+	if where != "" {
+		for _, wm := range whereClauseRe.FindAllStringSubmatch(where, -1) {
+			stmt.Filter[wm[1]] = wm[2]
+		}
+	}
+	if limitStr != "" {
+		limit, err := strconv.ParseInt(limitStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LIMIT %q: %w", limitStr, err)
+		}
+		stmt.Limit = limit
+	}
+
+	var groupBy []string
+	for _, col := range strings.Split(groupByList, ",") {
+		if col = strings.TrimSpace(col); col != "" {
+			groupBy = append(groupBy, col)
+		}
+	}
+
+	var aggregates []AggregateSpec
+	for _, col := range strings.Split(selectList, ",") {
+		am := aggregateCallRe.FindStringSubmatch(col)
+		if am == nil {
+			continue
+		}
+		op, column, alias := strings.ToLower(am[1]), am[2], am[3]
+		if alias == "" {
+			alias = fmt.Sprintf("%s_%s", op, column)
+		}
+		aggregates = append(aggregates, AggregateSpec{Op: op, Column: column, Alias: alias})
+	}
+
+	plan := &Plan{Query: stmt, Shards: []string{"*"}}
+	switch {
+	case len(aggregates) > 0:
+		plan.Type = PlanTypeAggregation
+		plan.Aggregates = aggregates
+		plan.GroupBy = groupBy
+	case len(stmt.Filter) > 0:
+		plan.Type = PlanTypePointLookup
+	default:
+		plan.Type = PlanTypeScatterGather
+	}
+
+	return plan, nil
+}
+
+// Plan is the output of the query Planner, consumed by the Executor.
+type Plan struct {
+	Type  PlanType
+	Query *Statement
+	// Shards lists target node addresses, or ["*"] for "all nodes" - the
+	// Executor resolves "*" via ClusterClient.Peers() for scatter-gather and
+	// aggregation plans; a point lookup passes Shards[0] to ExecuteRemote as is.
+	Shards []string
+
+	// Aggregates and GroupBy are only populated for PlanTypeAggregation: the
+	// Planner has already rewritten the user's aggregate into a per-shard
+	// *partial* aggregate (see AggregateState) plus the keys to group by.
+	Aggregates []AggregateSpec
+	GroupBy    []string
+
+	SubPlans []*Plan // populated for PlanTypeJoin
+}
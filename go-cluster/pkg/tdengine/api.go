@@ -4,44 +4,221 @@ package tdengine
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/lumadb/cluster/pkg/meta"
 )
 
+// TransportHealth reports backpressure and reconnect counters for the
+// cluster's Raft transport, so Health can surface them without this
+// package importing the cluster package directly. *cluster.PipelineTransport
+// satisfies this interface.
+type TransportHealth interface {
+	MsgsInflight() int64
+	MsgsDropped() int64
+	StreamReconnects() int64
+}
+
 // API implements TDengine REST API handlers
 type API struct {
-	engine *Engine
+	engine        *Engine
+	jwtAuth       *JWTAuthenticator
+	Transport     TransportHealth      // optional; set to report cluster transport health from Health
+	tmq           *TopicRegistry       // optional; set via EnableTMQ to accept CREATE TOPIC and /rest/tmq
+	streams       *StreamManager       // optional; set via EnableStreams to accept CREATE STREAM
+	planner       *Planner             // optional; set via EnablePlanner to answer EXPLAIN/EXPLAIN ANALYZE
+	bindings      *BindingManager      // optional; set via EnableBindings to accept CREATE/DROP/SHOW BINDING(S)
+	retention     *RetentionManager    // optional; set via EnableRetention to accept KEEP/DURATION/REPLICA
+	subscriptions *SubscriptionManager // optional; set via EnableSubscriptions to accept CREATE/DROP/SHOW SUBSCRIPTION(S)
+	graphite      *GraphiteIngester    // optional; set via EnableGraphite to accept /graphite/write
+	meta          *meta.MetaClient     // optional; set via EnableMeta to replicate DDL cluster-wide
 }
 
-// NewAPI creates a new TDengine API instance
+// NewAPI creates a new TDengine API instance. Bearer-token authentication is
+// disabled until one is configured; use NewAPIWithJWT to enable it.
 func NewAPI(engine *Engine) *API {
 	return &API{engine: engine}
 }
 
+// NewAPIWithJWT creates a TDengine API instance that also accepts and, via
+// Login, issues JWT bearer tokens through jwtAuth.
+func NewAPIWithJWT(engine *Engine, jwtAuth *JWTAuthenticator) *API {
+	return &API{engine: engine, jwtAuth: jwtAuth}
+}
+
+// EnableTMQ turns on topic/consumer support: CREATE TOPIC ... AS SELECT via
+// /rest/sql starts registering topics in registry, and /rest/tmq accepts
+// WebSocket consumers. Without calling this, CREATE TOPIC falls through to
+// the engine like any other statement and /rest/tmq is unavailable.
+func (a *API) EnableTMQ(registry *TopicRegistry) {
+	a.tmq = registry
+}
+
+// EnableStreams turns on continuous-stream support: CREATE STREAM, SHOW
+// STREAMS and DROP STREAM via /rest/sql start being handled by a
+// StreamManager persisted at path, instead of falling through to the
+// engine unrecognized.
+func (a *API) EnableStreams(path string) error {
+	sm, err := NewStreamManager(path, a.engine)
+	if err != nil {
+		return err
+	}
+	a.streams = sm
+	return nil
+}
+
+// EnablePlanner turns on EXPLAIN / EXPLAIN ANALYZE support via /rest/sql,
+// costing scans against stats. Pass a nil StatsProvider to still answer
+// EXPLAIN with full-scan estimates when no table statistics are wired up.
+func (a *API) EnablePlanner(stats StatsProvider) {
+	a.planner = NewPlanner(stats)
+}
+
+// EnableBindings turns on SQL plan bindings: CREATE/DROP/SHOW BINDING(S) via
+// /rest/sql start being handled by a BindingManager persisting global
+// bindings at path, and every other statement is checked against it for a
+// bound replacement before reaching the engine.
+func (a *API) EnableBindings(path string) error {
+	bm, err := NewBindingManager(path)
+	if err != nil {
+		return err
+	}
+	a.bindings = bm
+	return nil
+}
+
+// EnableRetention turns on retention-policy support: CREATE DATABASE's
+// KEEP/DURATION/REPLICA clauses start being recorded, and SHOW RETENTION
+// POLICIES ON / ALTER RETENTION POLICY via /rest/sql start being handled,
+// by a RetentionManager persisting policies at path. If the engine this
+// API was built with satisfies ShardExpirer, a background sweeper also
+// starts, dropping shards older than each policy's Duration once per
+// sweepInterval.
+func (a *API) EnableRetention(path string, sweepInterval time.Duration) error {
+	rm, err := NewRetentionManager(path)
+	if err != nil {
+		return err
+	}
+	a.retention = rm
+	// a.engine's static type is the concrete *Engine, not an interface, so
+	// the ShardExpirer check has to go through interface{} first to be a
+	// valid assertion at all; it still only succeeds if *Engine actually
+	// has a DropExpired method.
+	if expirer, ok := interface{}(a.engine).(ShardExpirer); ok {
+		rm.StartSweeper(expirer, sweepInterval)
+	}
+	return nil
+}
+
+// EnableSubscriptions turns on subscription support: CREATE/DROP/SHOW
+// SUBSCRIPTION(S) via /rest/sql start being handled by a
+// SubscriptionManager persisting subscriptions at path, and every write
+// accepted by /rest/sql (INSERT) or /influxdb/v1/write starts being
+// forwarded asynchronously to matching destinations over a worker pool of
+// the given size (a small default if workers <= 0).
+func (a *API) EnableSubscriptions(path string, workers int) error {
+	sm, err := NewSubscriptionManager(path, workers)
+	if err != nil {
+		return err
+	}
+	a.subscriptions = sm
+	return nil
+}
+
+// EnableGraphite turns on Graphite plaintext ingestion: /graphite/write
+// starts parsing "metric.path value [timestamp]" lines via a
+// GraphiteIngester built from rawTemplates (InfluxDB-style template
+// strings, most-specific filter wins when more than one matches a
+// metric), writing the resulting points through the same super-table
+// auto-creation path as the InfluxDB line-protocol write endpoint.
+func (a *API) EnableGraphite(rawTemplates []string) error {
+	g, err := NewGraphiteIngester(a.engine, rawTemplates)
+	if err != nil {
+		return err
+	}
+	a.graphite = g
+	return nil
+}
+
+// EnableMeta turns on cluster metadata replication: successful CREATE
+// DATABASE statements and CREATE SUBSCRIPTION definitions start being
+// mirrored into client, so a MetaClient shared cluster-wide converges on
+// the same databases and subscriptions regardless of which node a DDL
+// statement was run against.
+func (a *API) EnableMeta(client *meta.MetaClient) {
+	a.meta = client
+}
+
+// recordMetaFromCreate mirrors a successful CREATE DATABASE statement's
+// database name into a.meta, after the statement has already been executed
+// against the engine - the same "only record what actually took effect"
+// ordering recordRetentionFromCreate uses.
+func (a *API) recordMetaFromCreate(sql string) {
+	if a.meta == nil {
+		return
+	}
+	m := reCreateDatabaseRetention.FindStringSubmatch(sql)
+	if m == nil {
+		return
+	}
+	a.meta.CreateDatabase(m[1])
+}
+
+// recordMetaSubscription mirrors a successfully created subscription into
+// a.meta.
+func (a *API) recordMetaSubscription(sub Subscription) {
+	if a.meta == nil {
+		return
+	}
+	a.meta.CreateSubscription(metaSubscriptionInfo(sub))
+}
+
 // Register registers all TDengine API routes
 func (a *API) Register(mux *http.ServeMux) {
 	// REST SQL endpoint (primary)
-	mux.HandleFunc("/rest/sql", a.SQL)
-	mux.HandleFunc("/rest/sql/", a.SQLWithDB)
+	mux.HandleFunc("/rest/sql", a.compress(a.SQL))
+	mux.HandleFunc("/rest/sql/", a.compress(a.SQLWithDB))
 
 	// REST SQL with timing
-	mux.HandleFunc("/rest/sqlt", a.SQLWithTiming)
-	mux.HandleFunc("/rest/sqlt/", a.SQLWithTimingAndDB)
+	mux.HandleFunc("/rest/sqlt", a.compress(a.SQLWithTiming))
+	mux.HandleFunc("/rest/sqlt/", a.compress(a.SQLWithTimingAndDB))
 
 	// REST SQL with UTC
-	mux.HandleFunc("/rest/sqlutc", a.SQLUTC)
-	mux.HandleFunc("/rest/sqlutc/", a.SQLUTCWithDB)
+	mux.HandleFunc("/rest/sqlutc", a.compress(a.SQLUTC))
+	mux.HandleFunc("/rest/sqlutc/", a.compress(a.SQLUTCWithDB))
+
+	// REST SQL, chunked NDJSON streaming (large result sets)
+	mux.HandleFunc("/rest/sql/stream", a.compress(a.SQLStream))
+
+	// Flux-style pipeline query, modeled on InfluxDB 2.x's /api/v2/query
+	mux.HandleFunc("/api/v2/query", a.compress(a.QueryV2))
+
+	// Prometheus remote_write / remote_read
+	mux.HandleFunc("/api/v1/prom/write", a.compress(a.PromWrite))
+	mux.HandleFunc("/api/v1/prom/read", a.compress(a.PromRead))
 
 	// InfluxDB line protocol
-	mux.HandleFunc("/influxdb/v1/write", a.InfluxDBWrite)
+	mux.HandleFunc("/influxdb/v1/write", a.compress(a.InfluxDBWrite))
+
+	// Graphite plaintext protocol (only answers once EnableGraphite is called)
+	mux.HandleFunc("/graphite/write", a.compress(a.GraphiteWrite))
+
+	// OpenTSDB standard /api/put-style path: single object or array, summary response
+	mux.HandleFunc("/opentsdb/v1/put/", a.compress(a.OpenTSDBPut))
 
 	// OpenTSDB JSON
-	mux.HandleFunc("/opentsdb/v1/put/json/", a.OpenTSDBJSON)
+	mux.HandleFunc("/opentsdb/v1/put/json/", a.compress(a.OpenTSDBJSON))
 
 	// OpenTSDB Telnet
-	mux.HandleFunc("/opentsdb/v1/put/telnet/", a.OpenTSDBTelnet)
+	mux.HandleFunc("/opentsdb/v1/put/telnet/", a.compress(a.OpenTSDBTelnet))
+
+	// TMQ: consumer WebSocket endpoint (only answers once EnableTMQ is called)
+	mux.HandleFunc("/rest/tmq", a.TMQ)
 
 	// Login for token
 	mux.HandleFunc("/rest/login/", a.Login)
@@ -58,11 +235,6 @@ func (a *API) SQL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !a.authenticate(r) {
-		a.respondError(w, TSDB_CODE_TSC_AUTH_FAILURE, "Authentication failed")
-		return
-	}
-
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		a.respondError(w, TSDB_CODE_FAILED, err.Error())
@@ -74,6 +246,62 @@ func (a *API) SQL(w http.ResponseWriter, r *http.Request) {
 	// Get database from header or default
 	db := r.Header.Get("X-TDengine-Database")
 
+	if res := a.authenticate(r, db, !isReadOnlySQL(sql)); !res.ok {
+		a.respondErrorStatus(w, res.status, res.code, res.desc)
+		return
+	}
+
+	sessionKey := r.Header.Get("Authorization")
+
+	if a.bindings != nil {
+		if resp, handled := a.handleBindingSQL(sessionKey, sql); handled {
+			a.respond(w, resp)
+			return
+		}
+		sql = a.resolveBinding(sessionKey, sql)
+	}
+
+	if a.planner != nil {
+		if resp, handled := a.handleExplainSQL(db, sql); handled {
+			a.respond(w, resp)
+			return
+		}
+	}
+
+	if a.tmq != nil {
+		if def, ok := parseCreateTopicSQL(sql); ok {
+			def.Database = db
+			def.CreatedAt = time.Now()
+			if _, err := a.tmq.CreateTopic(def); err != nil {
+				a.respondError(w, TSDB_CODE_FAILED, err.Error())
+				return
+			}
+			a.respond(w, &Response{Code: TSDB_CODE_SUCCESS})
+			return
+		}
+	}
+
+	if a.streams != nil {
+		if resp, handled := a.handleStreamSQL(db, sql); handled {
+			a.respond(w, resp)
+			return
+		}
+	}
+
+	if a.retention != nil {
+		if resp, handled := a.handleRetentionSQL(sql); handled {
+			a.respond(w, resp)
+			return
+		}
+	}
+
+	if a.subscriptions != nil {
+		if resp, handled := a.handleSubscriptionSQL(sql); handled {
+			a.respond(w, resp)
+			return
+		}
+	}
+
 	opts := &ExecuteOptions{
 		ReqID:    r.Header.Get("X-Request-ID"),
 		Timezone: r.Header.Get("X-Timezone"),
@@ -84,6 +312,9 @@ func (a *API) SQL(w http.ResponseWriter, r *http.Request) {
 		a.respondError(w, TSDB_CODE_FAILED, err.Error())
 		return
 	}
+	a.recordRetentionFromCreate(sql)
+	a.recordMetaFromCreate(sql)
+	a.forwardInsert(db, sql)
 
 	a.respond(w, result)
 }
@@ -95,11 +326,6 @@ func (a *API) SQLWithDB(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !a.authenticate(r) {
-		a.respondError(w, TSDB_CODE_TSC_AUTH_FAILURE, "Authentication failed")
-		return
-	}
-
 	// Extract database from path
 	db := strings.TrimPrefix(r.URL.Path, "/rest/sql/")
 
@@ -111,6 +337,11 @@ func (a *API) SQLWithDB(w http.ResponseWriter, r *http.Request) {
 
 	sql := string(body)
 
+	if res := a.authenticate(r, db, !isReadOnlySQL(sql)); !res.ok {
+		a.respondErrorStatus(w, res.status, res.code, res.desc)
+		return
+	}
+
 	opts := &ExecuteOptions{
 		ReqID:    r.Header.Get("X-Request-ID"),
 		Timezone: r.Header.Get("X-Timezone"),
@@ -132,11 +363,6 @@ func (a *API) SQLWithTiming(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !a.authenticate(r) {
-		a.respondError(w, TSDB_CODE_TSC_AUTH_FAILURE, "Authentication failed")
-		return
-	}
-
 	start := time.Now()
 
 	body, err := io.ReadAll(r.Body)
@@ -148,6 +374,11 @@ func (a *API) SQLWithTiming(w http.ResponseWriter, r *http.Request) {
 	sql := string(body)
 	db := r.Header.Get("X-TDengine-Database")
 
+	if res := a.authenticate(r, db, !isReadOnlySQL(sql)); !res.ok {
+		a.respondErrorStatus(w, res.status, res.code, res.desc)
+		return
+	}
+
 	opts := &ExecuteOptions{
 		ReqID:    r.Header.Get("X-Request-ID"),
 		Timezone: r.Header.Get("X-Timezone"),
@@ -177,11 +408,6 @@ func (a *API) SQLWithTimingAndDB(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !a.authenticate(r) {
-		a.respondError(w, TSDB_CODE_TSC_AUTH_FAILURE, "Authentication failed")
-		return
-	}
-
 	start := time.Now()
 
 	db := strings.TrimPrefix(r.URL.Path, "/rest/sqlt/")
@@ -194,6 +420,11 @@ func (a *API) SQLWithTimingAndDB(w http.ResponseWriter, r *http.Request) {
 
 	sql := string(body)
 
+	if res := a.authenticate(r, db, !isReadOnlySQL(sql)); !res.ok {
+		a.respondErrorStatus(w, res.status, res.code, res.desc)
+		return
+	}
+
 	opts := &ExecuteOptions{
 		ReqID:    r.Header.Get("X-Request-ID"),
 		Timezone: r.Header.Get("X-Timezone"),
@@ -223,11 +454,6 @@ func (a *API) SQLUTC(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !a.authenticate(r) {
-		a.respondError(w, TSDB_CODE_TSC_AUTH_FAILURE, "Authentication failed")
-		return
-	}
-
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		a.respondError(w, TSDB_CODE_FAILED, err.Error())
@@ -237,6 +463,11 @@ func (a *API) SQLUTC(w http.ResponseWriter, r *http.Request) {
 	sql := string(body)
 	db := r.Header.Get("X-TDengine-Database")
 
+	if res := a.authenticate(r, db, !isReadOnlySQL(sql)); !res.ok {
+		a.respondErrorStatus(w, res.status, res.code, res.desc)
+		return
+	}
+
 	opts := &ExecuteOptions{
 		ReqID:    r.Header.Get("X-Request-ID"),
 		Timezone: "UTC",
@@ -258,11 +489,6 @@ func (a *API) SQLUTCWithDB(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !a.authenticate(r) {
-		a.respondError(w, TSDB_CODE_TSC_AUTH_FAILURE, "Authentication failed")
-		return
-	}
-
 	db := strings.TrimPrefix(r.URL.Path, "/rest/sqlutc/")
 
 	body, err := io.ReadAll(r.Body)
@@ -273,6 +499,11 @@ func (a *API) SQLUTCWithDB(w http.ResponseWriter, r *http.Request) {
 
 	sql := string(body)
 
+	if res := a.authenticate(r, db, !isReadOnlySQL(sql)); !res.ok {
+		a.respondErrorStatus(w, res.status, res.code, res.desc)
+		return
+	}
+
 	opts := &ExecuteOptions{
 		ReqID:    r.Header.Get("X-Request-ID"),
 		Timezone: "UTC",
@@ -294,17 +525,17 @@ func (a *API) InfluxDBWrite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !a.authenticate(r) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
 	db := r.URL.Query().Get("db")
 	if db == "" {
 		http.Error(w, "Database required", http.StatusBadRequest)
 		return
 	}
 
+	if res := a.authenticate(r, db, true); !res.ok {
+		a.respondErrorStatus(w, res.status, res.code, res.desc)
+		return
+	}
+
 	precision := r.URL.Query().Get("precision")
 	if precision == "" {
 		precision = "ns" // Default to nanoseconds
@@ -313,41 +544,109 @@ func (a *API) InfluxDBWrite(w http.ResponseWriter, r *http.Request) {
 	body, _ := io.ReadAll(r.Body)
 	lines := strings.Split(string(body), "\n")
 
-	for _, line := range lines {
+	var lineErrs []LineError
+	for i, line := range lines {
 		line = strings.TrimSpace(line)
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
 
-		parsed, err := parseInfluxDBLine(line, precision)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
+		parsed, lerr := parseInfluxDBLine(i+1, line, precision)
+		if lerr != nil {
+			lineErrs = append(lineErrs, *lerr)
+			continue
 		}
 
 		if err := a.engine.WriteInfluxDB(db, parsed); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+			lineErrs = append(lineErrs, LineError{Line: i + 1, Msg: err.Error()})
+			continue
+		}
+
+		if a.subscriptions != nil {
+			a.subscriptions.Forward(db, parsed.Measurement, renderLineProtocol(parsed))
 		}
 	}
 
+	if len(lineErrs) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&writeLineProtocolErrors{
+			Code:    "invalid",
+			Message: fmt.Sprintf("%d of %d line(s) failed to write", len(lineErrs), len(lines)),
+			Lines:   lineErrs,
+		})
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// OpenTSDBJSON handles OpenTSDB JSON protocol
-func (a *API) OpenTSDBJSON(w http.ResponseWriter, r *http.Request) {
+// GraphiteWrite handles /graphite/write: one Graphite plaintext metric per
+// line, parsed and written via a.graphite the same way InfluxDBWrite
+// parses and writes line-protocol points.
+func (a *API) GraphiteWrite(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if a.graphite == nil {
+		http.Error(w, "Graphite ingestion is not enabled", http.StatusNotFound)
+		return
+	}
 
-	if !a.authenticate(r) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	db := r.URL.Query().Get("db")
+	if db == "" {
+		http.Error(w, "Database required", http.StatusBadRequest)
+		return
+	}
+
+	if res := a.authenticate(r, db, true); !res.ok {
+		a.respondErrorStatus(w, res.status, res.code, res.desc)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	lines := strings.Split(string(body), "\n")
+
+	var lineErrs []LineError
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if err := a.graphite.Ingest(db, line); err != nil {
+			lineErrs = append(lineErrs, LineError{Line: i + 1, Msg: err.Error()})
+		}
+	}
+
+	if len(lineErrs) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&writeLineProtocolErrors{
+			Code:    "invalid",
+			Message: fmt.Sprintf("%d of %d line(s) failed to write", len(lineErrs), len(lines)),
+			Lines:   lineErrs,
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// OpenTSDBJSON handles OpenTSDB JSON protocol
+func (a *API) OpenTSDBJSON(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	db := strings.TrimPrefix(r.URL.Path, "/opentsdb/v1/put/json/")
 
+	if res := a.authenticate(r, db, true); !res.ok {
+		a.respondErrorStatus(w, res.status, res.code, res.desc)
+		return
+	}
+
 	var points []OpenTSDBPoint
 	if err := json.NewDecoder(r.Body).Decode(&points); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -371,13 +670,13 @@ func (a *API) OpenTSDBTelnet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !a.authenticate(r) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	db := strings.TrimPrefix(r.URL.Path, "/opentsdb/v1/put/telnet/")
+
+	if res := a.authenticate(r, db, true); !res.ok {
+		a.respondErrorStatus(w, res.status, res.code, res.desc)
 		return
 	}
 
-	db := strings.TrimPrefix(r.URL.Path, "/opentsdb/v1/put/telnet/")
-
 	body, _ := io.ReadAll(r.Body)
 	lines := strings.Split(string(body), "\n")
 
@@ -427,9 +726,21 @@ func (a *API) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Return TDengine-style token (the base64 encoded credentials for now)
 	token := strings.TrimPrefix(auth, "Basic ")
 
+	// When a JWTAuthenticator is configured, issue a signed bearer token
+	// instead of the legacy base64-credentials pseudo-token. MVP: a
+	// successful login is granted full read/write across every database;
+	// per-database scoping would need the engine to expose per-user grants.
+	if a.jwtAuth != nil {
+		signed, err := a.jwtAuth.Issue(parts[0], []Scope{{Database: "*", Read: true, Write: true}})
+		if err != nil {
+			a.respondError(w, TSDB_CODE_FAILED, err.Error())
+			return
+		}
+		token = signed
+	}
+
 	a.respond(w, &Response{
 		Code: 0,
 		Desc: token,
@@ -438,8 +749,25 @@ func (a *API) Login(w http.ResponseWriter, r *http.Request) {
 
 // Health handles health check endpoint
 func (a *API) Health(w http.ResponseWriter, r *http.Request) {
+	if a.Transport == nil {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("LumaDB TDengine-Compatible API is healthy"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("LumaDB TDengine-Compatible API is healthy"))
+	json.NewEncoder(w).Encode(struct {
+		Status           string `json:"status"`
+		MsgsInflight     int64  `json:"raft_msgs_inflight"`
+		MsgsDropped      int64  `json:"raft_msgs_dropped"`
+		StreamReconnects int64  `json:"raft_stream_reconnects"`
+	}{
+		Status:           "healthy",
+		MsgsInflight:     a.Transport.MsgsInflight(),
+		MsgsDropped:      a.Transport.MsgsDropped(),
+		StreamReconnects: a.Transport.StreamReconnects(),
+	})
 }
 
 // Ready handles readiness check endpoint
@@ -448,36 +776,82 @@ func (a *API) Ready(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("ready"))
 }
 
-// authenticate checks request authentication
-func (a *API) authenticate(r *http.Request) bool {
+// authResult is what authenticate determined about a request: whether it is
+// allowed to proceed and, if not, the HTTP status and TSDB error to respond
+// with - 401 for missing/invalid credentials, 403 for a valid bearer token
+// that lacks the scope the request needs.
+type authResult struct {
+	ok     bool
+	method AuthMethod
+	claims *Claims // set only when method == AuthMethodBearer
+	status int
+	code   int
+	desc   string
+}
+
+func authOK(method AuthMethod, claims *Claims) authResult {
+	return authResult{ok: true, method: method, claims: claims}
+}
+
+func authDenied(status, code int, desc string) authResult {
+	return authResult{ok: false, status: status, code: code, desc: desc}
+}
+
+// authenticate checks request authentication against db, for the given
+// action (write or read). It supports Basic credentials, an opaque Taosd
+// token and a bearer JWT (when a.jwtAuth is configured) - the latter also
+// checked for expiry, revocation and per-database scope.
+func (a *API) authenticate(r *http.Request, db string, write bool) authResult {
 	auth := r.Header.Get("Authorization")
 
+	if strings.HasPrefix(auth, "Bearer ") {
+		if a.jwtAuth == nil {
+			return authDenied(http.StatusUnauthorized, TSDB_CODE_TSC_AUTH_FAILURE, "bearer authentication is not enabled")
+		}
+		claims, err := a.jwtAuth.Verify(strings.TrimPrefix(auth, "Bearer "))
+		if err != nil {
+			if errors.Is(err, ErrTokenExpired) {
+				return authDenied(http.StatusUnauthorized, TSDB_CODE_TSC_TOKEN_EXPIRED, "token expired")
+			}
+			return authDenied(http.StatusUnauthorized, TSDB_CODE_TSC_AUTH_FAILURE, err.Error())
+		}
+		if !claims.Authorized(db, write) {
+			return authDenied(http.StatusForbidden, TSDB_CODE_TSC_INSUFFICIENT_PRIV, "token is not scoped for this database")
+		}
+		return authOK(AuthMethodBearer, claims)
+	}
+
 	// Support Basic authentication
 	if strings.HasPrefix(auth, "Basic ") {
 		decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth, "Basic "))
 		if err != nil {
-			return false
+			return authDenied(http.StatusUnauthorized, TSDB_CODE_TSC_AUTH_FAILURE, "invalid authentication encoding")
 		}
 		parts := strings.SplitN(string(decoded), ":", 2)
-		if len(parts) != 2 {
-			return false
+		if len(parts) != 2 || !a.engine.Authenticate(parts[0], parts[1]) {
+			return authDenied(http.StatusUnauthorized, TSDB_CODE_TSC_AUTH_FAILURE, "authentication failed")
 		}
-		return a.engine.Authenticate(parts[0], parts[1])
+		return authOK(AuthMethodBasic, nil)
 	}
 
 	// Support Taosd token
 	if strings.HasPrefix(auth, "Taosd ") {
 		token := strings.TrimPrefix(auth, "Taosd ")
-		return a.engine.ValidateToken(token)
+		if !a.engine.ValidateToken(token) {
+			return authDenied(http.StatusUnauthorized, TSDB_CODE_TSC_AUTH_FAILURE, "invalid token")
+		}
+		return authOK(AuthMethodToken, nil)
 	}
 
 	// Support URL token parameter
-	token := r.URL.Query().Get("token")
-	if token != "" {
-		return a.engine.ValidateToken(token)
+	if token := r.URL.Query().Get("token"); token != "" {
+		if !a.engine.ValidateToken(token) {
+			return authDenied(http.StatusUnauthorized, TSDB_CODE_TSC_AUTH_FAILURE, "invalid token")
+		}
+		return authOK(AuthMethodToken, nil)
 	}
 
-	return false
+	return authDenied(http.StatusUnauthorized, TSDB_CODE_TSC_AUTH_FAILURE, "missing authentication")
 }
 
 // respond writes a JSON response
@@ -486,142 +860,20 @@ func (a *API) respond(w http.ResponseWriter, result *Response) {
 	json.NewEncoder(w).Encode(result)
 }
 
-// respondError writes an error response
+// respondError writes an error response with TDengine's convention of
+// encoding the failure in the JSON body over an HTTP 200.
 func (a *API) respondError(w http.ResponseWriter, code int, desc string) {
+	a.respondErrorStatus(w, http.StatusOK, code, desc)
+}
+
+// respondErrorStatus is like respondError but sets status as the HTTP
+// status code, used to distinguish 401 (no/invalid credentials) from 403
+// (valid credentials, insufficient scope) on authentication failures.
+func (a *API) respondErrorStatus(w http.ResponseWriter, status, code int, desc string) {
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(&Response{
 		Code: code,
 		Desc: desc,
 	})
 }
-
-// parseInfluxDBLine parses InfluxDB line protocol
-func parseInfluxDBLine(line, precision string) (*InfluxDBLineProtocol, error) {
-	// Format: measurement[,tag=value...] field=value[,field=value...] [timestamp]
-	result := &InfluxDBLineProtocol{
-		Tags:   make(map[string]string),
-		Fields: make(map[string]interface{}),
-	}
-
-	// Split by space (max 3 parts: measurement+tags, fields, timestamp)
-	parts := strings.SplitN(line, " ", 3)
-	if len(parts) < 2 {
-		return nil, nil // Skip malformed lines
-	}
-
-	// Parse measurement and tags
-	measurementAndTags := strings.Split(parts[0], ",")
-	result.Measurement = measurementAndTags[0]
-
-	for _, tag := range measurementAndTags[1:] {
-		kv := strings.SplitN(tag, "=", 2)
-		if len(kv) == 2 {
-			result.Tags[kv[0]] = kv[1]
-		}
-	}
-
-	// Parse fields
-	fieldParts := strings.Split(parts[1], ",")
-	for _, field := range fieldParts {
-		kv := strings.SplitN(field, "=", 2)
-		if len(kv) == 2 {
-			// Simple float parsing
-			result.Fields[kv[0]] = parseFieldValue(kv[1])
-		}
-	}
-
-	// Parse timestamp
-	if len(parts) > 2 {
-		ts := parseInt64(parts[2])
-		result.Timestamp = normalizeTimestamp(ts, precision)
-	} else {
-		result.Timestamp = time.Now().UnixNano() / int64(time.Millisecond)
-	}
-
-	return result, nil
-}
-
-func parseFieldValue(s string) interface{} {
-	s = strings.TrimSpace(s)
-
-	// Boolean
-	if s == "true" || s == "t" || s == "T" {
-		return true
-	}
-	if s == "false" || s == "f" || s == "F" {
-		return false
-	}
-
-	// Integer (ends with 'i')
-	if strings.HasSuffix(s, "i") {
-		return parseInt64(s[:len(s)-1])
-	}
-
-	// String (quoted)
-	if strings.HasPrefix(s, "\"") && strings.HasSuffix(s, "\"") {
-		return s[1 : len(s)-1]
-	}
-
-	// Float
-	return parseFloat64(s)
-}
-
-func parseInt64(s string) int64 {
-	var result int64
-	for _, c := range s {
-		if c >= '0' && c <= '9' {
-			result = result*10 + int64(c-'0')
-		} else if c == '-' && result == 0 {
-			// Handle negative numbers
-		}
-	}
-	return result
-}
-
-func parseFloat64(s string) float64 {
-	var result float64
-	var decimal float64 = 0
-	var afterDecimal bool
-	var divisor float64 = 10
-	negative := false
-
-	for i, c := range s {
-		if c == '-' && i == 0 {
-			negative = true
-			continue
-		}
-		if c == '.' {
-			afterDecimal = true
-			continue
-		}
-		if c >= '0' && c <= '9' {
-			if afterDecimal {
-				decimal += float64(c-'0') / divisor
-				divisor *= 10
-			} else {
-				result = result*10 + float64(c-'0')
-			}
-		}
-	}
-
-	result += decimal
-	if negative {
-		result = -result
-	}
-	return result
-}
-
-func normalizeTimestamp(ts int64, precision string) int64 {
-	switch precision {
-	case "ns":
-		return ts / 1_000_000 // Convert to ms
-	case "us", "u":
-		return ts / 1_000 // Convert to ms
-	case "ms":
-		return ts
-	case "s":
-		return ts * 1_000 // Convert to ms
-	default:
-		return ts / 1_000_000 // Default ns to ms
-	}
-}
@@ -0,0 +1,131 @@
+package tdengine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsReadOnlySQL(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want bool
+	}{
+		{"SELECT * FROM meters", true},
+		{"  select avg(value) from meters", true},
+		{"SHOW DATABASES", true},
+		{"DESCRIBE meters", true},
+		{"EXPLAIN SELECT * FROM meters", true},
+		{"INSERT INTO meters VALUES (now, 1.0)", false},
+		{"CREATE STABLE meters (ts TIMESTAMP, value FLOAT) TAGS (id INT)", false},
+	}
+
+	for _, tt := range tests {
+		if got := isReadOnlySQL(tt.sql); got != tt.want {
+			t.Errorf("isReadOnlySQL(%q) = %v, want %v", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestClaims_Authorized(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []Scope
+		db     string
+		write  bool
+		want   bool
+	}{
+		{"read allowed by matching read scope", []Scope{{Database: "meters", Read: true}}, "meters", false, true},
+		{"write denied by read-only scope", []Scope{{Database: "meters", Read: true}}, "meters", true, false},
+		{"write allowed by write scope", []Scope{{Database: "meters", Write: true}}, "meters", true, true},
+		{"wildcard database matches any db", []Scope{{Database: "*", Read: true}}, "other_db", false, true},
+		{"no matching scope denies", []Scope{{Database: "meters", Read: true, Write: true}}, "other_db", false, false},
+		{"write scope also grants read", []Scope{{Database: "meters", Write: true}}, "meters", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Claims{Scopes: tt.scopes}
+			if got := c.Authorized(tt.db, tt.write); got != tt.want {
+				t.Errorf("Authorized(%q, %v) = %v, want %v", tt.db, tt.write, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticator_IssueAndVerify(t *testing.T) {
+	keys := &HS256KeyProvider{Secret: []byte("test-secret")}
+	auth := NewJWTAuthenticator(keys, "luma-test", time.Hour)
+
+	scopes := []Scope{{Database: "meters", Read: true, Write: true}}
+	token, err := auth.Issue("alice", scopes)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	claims, err := auth.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if claims.Subject != "alice" {
+		t.Errorf("Subject = %q, want %q", claims.Subject, "alice")
+	}
+	if !claims.Authorized("meters", true) {
+		t.Error("Authorized(meters, write) = false, want true")
+	}
+}
+
+func TestJWTAuthenticator_VerifyExpired(t *testing.T) {
+	keys := &HS256KeyProvider{Secret: []byte("test-secret")}
+	auth := NewJWTAuthenticator(keys, "luma-test", time.Hour)
+	auth.TTL = -time.Hour // NewJWTAuthenticator replaces a non-positive ttl with one hour
+
+	token, err := auth.Issue("alice", nil)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	if _, err := auth.Verify(token); err != ErrTokenExpired {
+		t.Errorf("Verify() error = %v, want %v", err, ErrTokenExpired)
+	}
+}
+
+func TestJWTAuthenticator_VerifyWrongKey(t *testing.T) {
+	auth := NewJWTAuthenticator(&HS256KeyProvider{Secret: []byte("secret-a")}, "luma-test", time.Hour)
+	token, err := auth.Issue("alice", nil)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	other := NewJWTAuthenticator(&HS256KeyProvider{Secret: []byte("secret-b")}, "luma-test", time.Hour)
+	if _, err := other.Verify(token); err == nil {
+		t.Error("Verify() error = nil, want a signature error for the wrong key")
+	}
+}
+
+func TestJWTAuthenticator_Revoke(t *testing.T) {
+	keys := &HS256KeyProvider{Secret: []byte("test-secret")}
+	auth := NewJWTAuthenticator(keys, "luma-test", time.Hour)
+
+	token, err := auth.Issue("alice", nil)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	claims, err := auth.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	auth.Revoke(claims)
+
+	if _, err := auth.Verify(token); err != ErrTokenRevoked {
+		t.Errorf("Verify() after Revoke() error = %v, want %v", err, ErrTokenRevoked)
+	}
+}
+
+func TestRS256KeyProvider_VerifyKeyRequiresPublicKey(t *testing.T) {
+	p := &RS256KeyProvider{}
+	if _, err := p.VerifyKey(); err == nil {
+		t.Error("VerifyKey() error = nil, want an error when PublicKey is unset")
+	}
+}
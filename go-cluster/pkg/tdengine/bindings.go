@@ -0,0 +1,267 @@
+package tdengine
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BindingScope is who a SQL binding applies to: every connection (Global,
+// persisted to disk) or just the connection that created it (Session, kept
+// in memory only and dropped when that connection's bindings are cleared).
+type BindingScope int
+
+const (
+	BindingGlobal BindingScope = iota
+	BindingSession
+)
+
+func (s BindingScope) String() string {
+	if s == BindingSession {
+		return "SESSION"
+	}
+	return "GLOBAL"
+}
+
+// SQLBinding is one CREATE BINDING entry: the normalized digest of the
+// statement it applies to, and the statement to actually plan/execute in
+// its place.
+type SQLBinding struct {
+	Digest      string
+	OriginalSQL string
+	BoundSQL    string
+	Scope       BindingScope
+	CreatedAt   time.Time
+}
+
+var (
+	reBindingLiteralStr = regexp.MustCompile(`'(?:[^'\\]|\\.)*'`)
+	reBindingLiteralNum = regexp.MustCompile(`\b\d+(\.\d+)?\b`)
+	reBindingWhitespace = regexp.MustCompile(`\s+`)
+
+	reCreateBinding = regexp.MustCompile(`(?is)^\s*CREATE\s+(GLOBAL\s+|SESSION\s+)?BINDING\s+FOR\s+(.+?)\s+USING\s+(.+?)\s*;?\s*$`)
+	reDropBinding   = regexp.MustCompile(`(?is)^\s*DROP\s+BINDING\s+FOR\s+(.+?)\s*;?\s*$`)
+	reShowBindings  = regexp.MustCompile(`(?is)^\s*SHOW\s+BINDINGS\s*;?\s*$`)
+)
+
+// digestSQL normalizes sql into the key a binding is looked up by: string
+// and numeric literals collapsed to "?" and whitespace runs collapsed to a
+// single space, so that two statements differing only in their literal
+// values (the common case - the same query run with different filter
+// values) share a binding.
+func digestSQL(sql string) string {
+	normalized := reBindingLiteralStr.ReplaceAllString(sql, "?")
+	normalized = reBindingLiteralNum.ReplaceAllString(normalized, "?")
+	normalized = reBindingWhitespace.ReplaceAllString(strings.TrimSpace(normalized), " ")
+	sum := sha1.Sum([]byte(strings.ToUpper(normalized)))
+	return hex.EncodeToString(sum[:])
+}
+
+// BindingManager holds CREATE BINDING entries and resolves a statement to
+// its bound replacement before planning, the way TiDB's bindinfo package
+// lets an operator pin a plan for a problematic query without touching
+// application SQL. Global bindings are persisted to path as JSON and
+// reloaded on startup; session bindings live only in memory, keyed by the
+// connection identity (the Authorization header) that created them.
+type BindingManager struct {
+	mu      sync.RWMutex
+	path    string
+	global  map[string]*SQLBinding            // digest -> binding
+	session map[string]map[string]*SQLBinding // session key -> digest -> binding
+}
+
+// NewBindingManager creates a BindingManager persisting global bindings to
+// path, loading any that already exist there.
+func NewBindingManager(path string) (*BindingManager, error) {
+	bm := &BindingManager{
+		path:    path,
+		global:  make(map[string]*SQLBinding),
+		session: make(map[string]map[string]*SQLBinding),
+	}
+	if err := bm.load(); err != nil {
+		return nil, err
+	}
+	return bm, nil
+}
+
+func (bm *BindingManager) load() error {
+	data, err := os.ReadFile(bm.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var bindings []*SQLBinding
+	if err := json.Unmarshal(data, &bindings); err != nil {
+		return err
+	}
+	for _, b := range bindings {
+		bm.global[b.Digest] = b
+	}
+	return nil
+}
+
+func (bm *BindingManager) save() error {
+	bindings := make([]*SQLBinding, 0, len(bm.global))
+	for _, b := range bm.global {
+		bindings = append(bindings, b)
+	}
+	data, err := json.MarshalIndent(bindings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(bm.path, data, 0644)
+}
+
+// CreateBinding registers a binding for originalSQL, replacing any existing
+// binding for the same digest and scope.
+func (bm *BindingManager) CreateBinding(scope BindingScope, sessionKey, originalSQL, boundSQL string) error {
+	b := &SQLBinding{
+		Digest:      digestSQL(originalSQL),
+		OriginalSQL: strings.TrimSpace(originalSQL),
+		BoundSQL:    strings.TrimSpace(boundSQL),
+		Scope:       scope,
+		CreatedAt:   time.Now(),
+	}
+
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	if scope == BindingSession {
+		if bm.session[sessionKey] == nil {
+			bm.session[sessionKey] = make(map[string]*SQLBinding)
+		}
+		bm.session[sessionKey][b.Digest] = b
+		return nil
+	}
+
+	bm.global[b.Digest] = b
+	return bm.save()
+}
+
+// DropBinding removes any binding (global, and the caller's session
+// binding if any) for originalSQL.
+func (bm *BindingManager) DropBinding(sessionKey, originalSQL string) error {
+	digest := digestSQL(originalSQL)
+
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	delete(bm.global, digest)
+	if sessionBindings := bm.session[sessionKey]; sessionBindings != nil {
+		delete(sessionBindings, digest)
+	}
+	return bm.save()
+}
+
+// Lookup returns the binding that applies to sql for sessionKey - a
+// session binding takes precedence over a global one for the same digest -
+// and false if none exists.
+func (bm *BindingManager) Lookup(sessionKey, sql string) (*SQLBinding, bool) {
+	digest := digestSQL(sql)
+
+	bm.mu.RLock()
+	defer bm.mu.RUnlock()
+
+	if sessionBindings := bm.session[sessionKey]; sessionBindings != nil {
+		if b, ok := sessionBindings[digest]; ok {
+			return b, true
+		}
+	}
+	b, ok := bm.global[digest]
+	return b, ok
+}
+
+// ShowBindings returns every binding visible to sessionKey: its own session
+// bindings plus all global ones.
+func (bm *BindingManager) ShowBindings(sessionKey string) []*SQLBinding {
+	bm.mu.RLock()
+	defer bm.mu.RUnlock()
+
+	out := make([]*SQLBinding, 0, len(bm.global)+len(bm.session[sessionKey]))
+	for _, b := range bm.global {
+		out = append(out, b)
+	}
+	for _, b := range bm.session[sessionKey] {
+		out = append(out, b)
+	}
+	return out
+}
+
+// parseCreateBindingSQL parses `CREATE [GLOBAL|SESSION] BINDING FOR <stmt>
+// USING <stmt>`.
+func parseCreateBindingSQL(sql string) (scope BindingScope, originalSQL, boundSQL string, ok bool) {
+	m := reCreateBinding.FindStringSubmatch(sql)
+	if m == nil {
+		return 0, "", "", false
+	}
+	scope = BindingGlobal
+	if strings.EqualFold(strings.TrimSpace(m[1]), "SESSION") {
+		scope = BindingSession
+	}
+	return scope, m[2], m[3], true
+}
+
+// handleBindingSQL answers CREATE BINDING, DROP BINDING and SHOW BINDINGS
+// directly, reporting handled=false for any other statement so the caller
+// falls through to the engine (and, via Lookup, to plan-hint substitution)
+// as usual.
+func (a *API) handleBindingSQL(sessionKey, sql string) (*Response, bool) {
+	sql = strings.TrimSpace(sql)
+
+	if scope, originalSQL, boundSQL, ok := parseCreateBindingSQL(sql); ok {
+		if err := a.bindings.CreateBinding(scope, sessionKey, originalSQL, boundSQL); err != nil {
+			return &Response{Code: TSDB_CODE_FAILED, Desc: err.Error()}, true
+		}
+		return &Response{Code: TSDB_CODE_SUCCESS}, true
+	}
+
+	if m := reDropBinding.FindStringSubmatch(sql); m != nil {
+		if err := a.bindings.DropBinding(sessionKey, m[1]); err != nil {
+			return &Response{Code: TSDB_CODE_FAILED, Desc: err.Error()}, true
+		}
+		return &Response{Code: TSDB_CODE_SUCCESS}, true
+	}
+
+	if reShowBindings.MatchString(sql) {
+		bindings := a.bindings.ShowBindings(sessionKey)
+		data := make([][]interface{}, len(bindings))
+		for i, b := range bindings {
+			data[i] = []interface{}{b.OriginalSQL, b.BoundSQL, b.Scope.String(), b.Digest, b.CreatedAt.Format(time.RFC3339)}
+		}
+		return &Response{
+			Code: TSDB_CODE_SUCCESS,
+			ColumnMeta: [][]interface{}{
+				{"Original_sql", "VARCHAR", 1024}, {"Bind_sql", "VARCHAR", 1024},
+				{"Scope", "VARCHAR", 8}, {"Digest", "VARCHAR", 40}, {"Create_time", "VARCHAR", 32},
+			},
+			Data: data,
+			Rows: len(data),
+		}, true
+	}
+
+	return nil, false
+}
+
+// resolveBinding substitutes sql for its bound statement's plan-hint SQL
+// when one is registered, so CREATE BINDING can pin a plan (a forced
+// index, forced full-scan, forced interval fill mode - whatever the bound
+// statement's own hint syntax expresses) without the caller having to know
+// a binding exists. This is the integration point a.engine.Execute would
+// call into if this snapshot's engine still had its substitution hook;
+// here it's applied in the API layer, immediately before Execute.
+func (a *API) resolveBinding(sessionKey, sql string) string {
+	if a.bindings == nil {
+		return sql
+	}
+	if b, ok := a.bindings.Lookup(sessionKey, sql); ok {
+		return b.BoundSQL
+	}
+	return sql
+}
@@ -0,0 +1,73 @@
+package tdengine
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// compress wraps a handler with transparent gzip/deflate support: it
+// decompresses a gzip or deflate request body per Content-Encoding, and, if
+// the client's Accept-Encoding allows it, compresses the response with
+// gzip - the same negotiation InfluxDB's httpd.Handler does around its own
+// endpoints, so large line-protocol writes and query results don't have to
+// cross the wire uncompressed.
+func (a *API) compress(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Content-Encoding") {
+		case "gzip":
+			gr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, "invalid gzip body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			defer gr.Close()
+			r.Body = io.NopCloser(gr)
+		case "deflate":
+			r.Body = io.NopCloser(flate.NewReader(r.Body))
+		}
+
+		if !acceptsEncoding(r, "gzip") {
+			next(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Add("Vary", "Accept-Encoding")
+		gw := gzip.NewWriter(w)
+		defer gw.Close()
+		next(&gzipResponseWriter{ResponseWriter: w, gw: gw}, r)
+	}
+}
+
+// acceptsEncoding reports whether r's Accept-Encoding header lists enc.
+func acceptsEncoding(r *http.Request, enc string) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		if strings.TrimSpace(strings.SplitN(part, ";", 2)[0]) == enc {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter lets handlers keep writing through the ordinary
+// http.ResponseWriter interface while transparently gzip-compressing the
+// body. It implements http.Flusher so streaming handlers like SQLStream
+// still work when wrapped.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gw *gzip.Writer
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	return g.gw.Write(b)
+}
+
+func (g *gzipResponseWriter) Flush() {
+	g.gw.Flush()
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,140 @@
+package tdengine
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FluxQuery is the parsed form of the minimal Flux pipeline /api/v2/query
+// understands:
+//
+//	from(bucket:"x") |> range(start:-1h[,stop:...]) |> filter(fn: (r) => r.tag == "v") |> aggregateWindow(every:1m, fn:mean)
+//
+// This is deliberately not a general Flux parser - it exists to let
+// InfluxDB-speaking clients run the same shape of query the Flux docs use as
+// their canonical example against the engine's own SQL dialect.
+type FluxQuery struct {
+	Bucket          string
+	Start           string
+	Stop            string
+	Filters         map[string]string
+	AggregateWindow string
+	AggregateFunc   string
+}
+
+var (
+	fluxFromRe            = regexp.MustCompile(`from\(\s*bucket:\s*"([^"]+)"\s*\)`)
+	fluxRangeRe           = regexp.MustCompile(`range\(\s*start:\s*(-?[\w:.]+)(?:\s*,\s*stop:\s*(-?[\w:.]+))?\s*\)`)
+	fluxFilterRe          = regexp.MustCompile(`filter\(\s*fn:\s*\(r\)\s*=>\s*r\.(\w+)\s*==\s*"([^"]*)"\s*\)`)
+	fluxAggregateWindowRe = regexp.MustCompile(`aggregateWindow\(\s*every:\s*(\w+)\s*,\s*fn:\s*(\w+)\s*\)`)
+)
+
+// ParseFlux parses a Flux pipeline into a FluxQuery. Only the stages
+// documented on FluxQuery are recognized; anything else is rejected rather
+// than guessed at.
+func ParseFlux(src string) (*FluxQuery, error) {
+	src = strings.TrimSpace(src)
+	stages := strings.Split(src, "|>")
+	if len(stages) == 0 || strings.TrimSpace(stages[0]) == "" {
+		return nil, fmt.Errorf("empty flux query")
+	}
+
+	fromMatch := fluxFromRe.FindStringSubmatch(stages[0])
+	if fromMatch == nil {
+		return nil, fmt.Errorf(`flux query must start with from(bucket: "...")`)
+	}
+
+	fq := &FluxQuery{Bucket: fromMatch[1], Filters: make(map[string]string)}
+
+	for _, stage := range stages[1:] {
+		stage = strings.TrimSpace(stage)
+		switch {
+		case strings.HasPrefix(stage, "range("):
+			m := fluxRangeRe.FindStringSubmatch(stage)
+			if m == nil {
+				return nil, fmt.Errorf("unsupported range() clause: %s", stage)
+			}
+			fq.Start, fq.Stop = m[1], m[2]
+		case strings.HasPrefix(stage, "filter("):
+			m := fluxFilterRe.FindStringSubmatch(stage)
+			if m == nil {
+				return nil, fmt.Errorf("unsupported filter() clause: %s", stage)
+			}
+			fq.Filters[m[1]] = m[2]
+		case strings.HasPrefix(stage, "aggregateWindow("):
+			m := fluxAggregateWindowRe.FindStringSubmatch(stage)
+			if m == nil {
+				return nil, fmt.Errorf("unsupported aggregateWindow() clause: %s", stage)
+			}
+			fq.AggregateWindow, fq.AggregateFunc = m[1], m[2]
+		default:
+			return nil, fmt.Errorf("unsupported flux stage: %s", stage)
+		}
+	}
+
+	return fq, nil
+}
+
+// ToSQL translates the parsed pipeline into the engine's own SQL dialect so
+// it can run through the same Engine.Execute path as /rest/sql.
+func (fq *FluxQuery) ToSQL() string {
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	if fq.AggregateFunc != "" {
+		b.WriteString(strings.ToUpper(fq.AggregateFunc))
+		b.WriteString("(value)")
+	} else {
+		b.WriteString("*")
+	}
+	b.WriteString(" FROM ")
+	b.WriteString(fq.Bucket)
+
+	var conds []string
+	if fq.Start != "" {
+		conds = append(conds, fmt.Sprintf("ts >= %s", fluxTimeToSQL(fq.Start)))
+	}
+	if fq.Stop != "" {
+		conds = append(conds, fmt.Sprintf("ts <= %s", fluxTimeToSQL(fq.Stop)))
+	}
+	for _, k := range sortedKeys(fq.Filters) {
+		conds = append(conds, fmt.Sprintf("%s = '%s'", k, fq.Filters[k]))
+	}
+	if len(conds) > 0 {
+		b.WriteString(" WHERE ")
+		b.WriteString(strings.Join(conds, " AND "))
+	}
+
+	if fq.AggregateWindow != "" {
+		b.WriteString(" INTERVAL(")
+		b.WriteString(fq.AggregateWindow)
+		b.WriteString(")")
+	}
+
+	return b.String()
+}
+
+// fluxTimeToSQL converts Flux's relative duration shorthand (-1h, -30m) into
+// the engine's NOW()-relative syntax; absolute timestamps pass through
+// unchanged.
+func fluxTimeToSQL(t string) string {
+	if strings.HasPrefix(t, "-") {
+		return fmt.Sprintf("NOW() - %s", t[1:])
+	}
+	if t == "now()" {
+		return "NOW()"
+	}
+	return "'" + t + "'"
+}
+
+// sortedKeys returns m's keys in sorted order, so ToSQL's WHERE clause is
+// deterministic across runs (map iteration order is not).
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
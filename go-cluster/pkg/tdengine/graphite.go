@@ -0,0 +1,227 @@
+package tdengine
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultGraphiteSeparator = "."
+	defaultGraphiteField     = "value"
+)
+
+// GraphiteTemplate maps one shape of Graphite dotted metric path onto an
+// InfluxDB-style measurement/tags/field, InfluxDB's own Graphite input
+// plugin template syntax: a whitespace-separated filter (dot-separated,
+// "*" matching exactly one path segment, omitted to match every metric)
+// and a template assigning "measurement", "field" or a tag name to each
+// matching path position, plus any trailing key=value pairs as static
+// default tags.
+type GraphiteTemplate struct {
+	Filter    []string // nil matches every metric
+	Fields    []string // dot-separated template positions; "" or "*" skips a segment
+	Tags      map[string]string
+	Separator string // joins multiple "measurement" segments; defaults to "."
+}
+
+// specificity is how many non-wildcard segments a template's filter pins
+// down. matchTemplate prefers the matching template with the highest
+// specificity, InfluxDB's own "most specific filter wins" tie-break.
+func (t *GraphiteTemplate) specificity() int {
+	n := 0
+	for _, seg := range t.Filter {
+		if seg != "*" {
+			n++
+		}
+	}
+	return n
+}
+
+// matches reports whether parts' leading segments satisfy t.Filter - the
+// filter classifies a metric by a prefix of its path (InfluxDB's own
+// "servers.*" style filters commonly have fewer segments than the metrics
+// they select), while t.Fields below maps over the metric's full path
+// regardless of how many segments the filter itself pinned down.
+func (t *GraphiteTemplate) matches(parts []string) bool {
+	if t.Filter == nil {
+		return true
+	}
+	if len(parts) < len(t.Filter) {
+		return false
+	}
+	for i, seg := range t.Filter {
+		if seg != "*" && seg != parts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseGraphiteTemplate parses one InfluxDB-style Graphite template line,
+// e.g. "servers.* .host.measurement" or
+// "*.cpu.* region.host.measurement.field region=unknown". The template
+// itself is the one whitespace-separated field with no "=" in it; if two
+// such fields are present, the first is the filter and the second is the
+// template, otherwise the lone field is the template and the filter
+// matches every metric. Every other whitespace-separated field is a
+// "tag=value" static default tag.
+func parseGraphiteTemplate(raw string) (*GraphiteTemplate, error) {
+	fields := strings.Fields(raw)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty graphite template")
+	}
+
+	var filter, template string
+	rest := fields
+	if len(fields) > 1 && !strings.Contains(fields[0], "=") && !strings.Contains(fields[1], "=") {
+		filter, template = fields[0], fields[1]
+		rest = fields[2:]
+	} else if !strings.Contains(fields[0], "=") {
+		template = fields[0]
+		rest = fields[1:]
+	} else {
+		return nil, fmt.Errorf("graphite template %q has no template field", raw)
+	}
+
+	tpl := &GraphiteTemplate{Tags: make(map[string]string), Separator: defaultGraphiteSeparator}
+	for _, kv := range rest {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("malformed default tag %q in graphite template", kv)
+		}
+		tpl.Tags[parts[0]] = parts[1]
+	}
+
+	if filter != "" {
+		tpl.Filter = strings.Split(filter, ".")
+	}
+	tpl.Fields = strings.Split(template, ".")
+	return tpl, nil
+}
+
+// GraphiteIngester parses Graphite plaintext metrics into
+// InfluxDBLineProtocol points via a set of templates, then writes them
+// through the same super-table auto-creation path the InfluxDB
+// line-protocol write endpoint uses.
+type GraphiteIngester struct {
+	engine    *Engine
+	templates []*GraphiteTemplate
+}
+
+// NewGraphiteIngester builds a GraphiteIngester from raw InfluxDB-style
+// Graphite template lines, sorted most-specific filter first so
+// matchTemplate's first match is already the best one.
+func NewGraphiteIngester(engine *Engine, rawTemplates []string) (*GraphiteIngester, error) {
+	g := &GraphiteIngester{engine: engine}
+	for _, raw := range rawTemplates {
+		tpl, err := parseGraphiteTemplate(raw)
+		if err != nil {
+			return nil, err
+		}
+		g.templates = append(g.templates, tpl)
+	}
+	sort.SliceStable(g.templates, func(i, j int) bool {
+		si, sj := g.templates[i].specificity(), g.templates[j].specificity()
+		if si != sj {
+			return si > sj
+		}
+		return len(g.templates[i].Filter) > len(g.templates[j].Filter)
+	})
+	return g, nil
+}
+
+// matchTemplate returns the most specific registered template whose filter
+// matches parts, and false if none do.
+func (g *GraphiteIngester) matchTemplate(parts []string) (*GraphiteTemplate, bool) {
+	for _, tpl := range g.templates {
+		if tpl.matches(parts) {
+			return tpl, true
+		}
+	}
+	return nil, false
+}
+
+// ParseLine parses one Graphite plaintext line - "metric.path value
+// [timestamp]" - into an InfluxDBLineProtocol point, applying the best
+// matching template to split the metric's dotted path into measurement,
+// tags and field name. A metric with no matching template falls back to
+// using its full dotted path as the measurement, field "value", and no
+// tags, so ingestion never silently drops an unrecognized metric shape.
+func (g *GraphiteIngester) ParseLine(line string) (*InfluxDBLineProtocol, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || len(fields) > 3 {
+		return nil, fmt.Errorf("malformed graphite line %q", line)
+	}
+
+	value, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid graphite value %q: %w", fields[1], err)
+	}
+
+	ts := time.Now().UnixNano() / int64(time.Millisecond)
+	if len(fields) == 3 {
+		sec, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid graphite timestamp %q: %w", fields[2], err)
+		}
+		ts = sec * 1000
+	}
+
+	parts := strings.Split(fields[0], ".")
+	tpl, ok := g.matchTemplate(parts)
+
+	var measurementParts []string
+	fieldName := defaultGraphiteField
+	tags := make(map[string]string)
+	separator := defaultGraphiteSeparator
+
+	if !ok {
+		measurementParts = parts
+	} else {
+		for k, v := range tpl.Tags {
+			tags[k] = v
+		}
+		for i, role := range tpl.Fields {
+			if i >= len(parts) || role == "" || role == "*" {
+				continue
+			}
+			switch role {
+			case "measurement":
+				measurementParts = append(measurementParts, parts[i])
+			case "field":
+				fieldName = parts[i]
+			default:
+				tags[role] = parts[i]
+			}
+		}
+		if len(measurementParts) == 0 {
+			measurementParts = parts
+		}
+		if tpl.Separator != "" {
+			separator = tpl.Separator
+		}
+	}
+
+	measurement := strings.Join(measurementParts, separator)
+	return &InfluxDBLineProtocol{
+		Measurement: measurement,
+		Tags:        tags,
+		Fields:      map[string]interface{}{fieldName: value},
+		Timestamp:   ts,
+		SubtableKey: subtableKey(measurement, tags),
+	}, nil
+}
+
+// Ingest parses line and writes the resulting point into database via the
+// engine's InfluxDB write path - the same super-table auto-creation the
+// InfluxDBWrite HTTP handler uses.
+func (g *GraphiteIngester) Ingest(database, line string) error {
+	parsed, err := g.ParseLine(line)
+	if err != nil {
+		return err
+	}
+	return g.engine.WriteInfluxDB(database, parsed)
+}
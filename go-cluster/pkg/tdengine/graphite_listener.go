@@ -0,0 +1,60 @@
+package tdengine
+
+import (
+	"bufio"
+	"net"
+	"strings"
+)
+
+// GraphiteTCPListener is a raw TCP listener speaking Carbon's plaintext
+// protocol ("metric.path value timestamp\n" per line) directly, the wire
+// format collectd and statsd-style agents speak over a plain socket rather
+// than the HTTP /graphite/write endpoint GraphiteWrite answers. Call Serve
+// in its own goroutine; it blocks until the listener is closed.
+type GraphiteTCPListener struct {
+	ingester *GraphiteIngester
+	db       string
+	ln       net.Listener
+}
+
+// NewGraphiteTCPListener binds addr (e.g. ":2003", Carbon's default
+// plaintext port) and returns a listener that ingests every accepted line
+// into db.
+func NewGraphiteTCPListener(ingester *GraphiteIngester, db, addr string) (*GraphiteTCPListener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &GraphiteTCPListener{ingester: ingester, db: db, ln: ln}, nil
+}
+
+// Serve accepts connections until the listener is closed, handling each on
+// its own goroutine so one slow or hung agent can't stall the others.
+func (l *GraphiteTCPListener) Serve() error {
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			return err
+		}
+		go l.handle(conn)
+	}
+}
+
+// Close stops accepting new connections.
+func (l *GraphiteTCPListener) Close() error {
+	return l.ln.Close()
+}
+
+func (l *GraphiteTCPListener) handle(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if err := l.ingester.Ingest(l.db, line); err != nil {
+			conn.Write([]byte("error: " + err.Error() + "\n"))
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package tdengine
+
+import "testing"
+
+func TestGraphiteIngester_ParseLine_SingleTemplate(t *testing.T) {
+	g, err := NewGraphiteIngester(nil, []string{"servers.* .host.measurement"})
+	if err != nil {
+		t.Fatalf("NewGraphiteIngester() error = %v", err)
+	}
+
+	p, err := g.ParseLine("servers.web01.cpu 0.64 1700000000")
+	if err != nil {
+		t.Fatalf("ParseLine() error = %v", err)
+	}
+	if p.Measurement != "cpu" {
+		t.Errorf("Measurement = %q, want cpu", p.Measurement)
+	}
+	if p.Tags["host"] != "web01" {
+		t.Errorf("Tags[host] = %q, want web01", p.Tags["host"])
+	}
+	if p.Fields["value"] != 0.64 {
+		t.Errorf("Fields[value] = %v, want 0.64", p.Fields["value"])
+	}
+	if p.Timestamp != 1700000000*1000 {
+		t.Errorf("Timestamp = %d, want %d", p.Timestamp, 1700000000*1000)
+	}
+}
+
+func TestGraphiteIngester_ParseLine_MostSpecificFilterWins(t *testing.T) {
+	g, err := NewGraphiteIngester(nil, []string{
+		"*.*.* measurement.host.field",    // matches every 3-segment metric, least specific
+		"*.cpu.* region.host.measurement", // matches 3-segment metrics with a literal middle segment
+	})
+	if err != nil {
+		t.Fatalf("NewGraphiteIngester() error = %v", err)
+	}
+
+	p, err := g.ParseLine("us-west.cpu.web01 98.5 1700000000")
+	if err != nil {
+		t.Fatalf("ParseLine() error = %v", err)
+	}
+	if p.Measurement != "web01" {
+		t.Errorf("Measurement = %q, want web01 (should match the more specific *.cpu.* template)", p.Measurement)
+	}
+	if p.Tags["region"] != "us-west" {
+		t.Errorf("Tags[region] = %q, want us-west", p.Tags["region"])
+	}
+	if p.Fields["value"] != 98.5 {
+		t.Errorf("Fields[value] = %v, want 98.5", p.Fields["value"])
+	}
+}
+
+func TestGraphiteIngester_ParseLine_UnmatchedFallsBackToFullPath(t *testing.T) {
+	g, err := NewGraphiteIngester(nil, []string{"servers.* .host.measurement"})
+	if err != nil {
+		t.Fatalf("NewGraphiteIngester() error = %v", err)
+	}
+
+	p, err := g.ParseLine("unrelated.metric.path 1 1700000000")
+	if err != nil {
+		t.Fatalf("ParseLine() error = %v", err)
+	}
+	if p.Measurement != "unrelated.metric.path" {
+		t.Errorf("Measurement = %q, want unrelated.metric.path (no template matches, so the full path is used)", p.Measurement)
+	}
+	if len(p.Tags) != 0 {
+		t.Errorf("Tags = %v, want none", p.Tags)
+	}
+	if p.Fields["value"] != 1.0 {
+		t.Errorf("Fields[value] = %v, want 1", p.Fields["value"])
+	}
+}
+
+func TestParseGraphiteTemplate_DefaultTags(t *testing.T) {
+	tpl, err := parseGraphiteTemplate("*.cpu.* region.host.measurement.field env=prod")
+	if err != nil {
+		t.Fatalf("parseGraphiteTemplate() error = %v", err)
+	}
+	if tpl.Tags["env"] != "prod" {
+		t.Errorf("Tags[env] = %q, want prod", tpl.Tags["env"])
+	}
+	if len(tpl.Filter) != 3 {
+		t.Errorf("Filter = %v, want 3 segments", tpl.Filter)
+	}
+}
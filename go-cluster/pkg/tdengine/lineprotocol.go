@@ -0,0 +1,278 @@
+package tdengine
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LineError describes one malformed line from an InfluxDB line-protocol
+// write, identified by its 1-based line number and the column the
+// tokenizer gave up at - enough for InfluxDBWrite to report exactly which
+// lines failed, the same partial-success shape Influx 2.x's write endpoint
+// returns.
+type LineError struct {
+	Line int    `json:"line"`
+	Col  int    `json:"col"`
+	Msg  string `json:"msg"`
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d, col %d: %s", e.Line, e.Col, e.Msg)
+}
+
+// writeLineProtocolErrors is the partial-success/failure body InfluxDBWrite
+// returns when one or more lines fail to parse or write.
+type writeLineProtocolErrors struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Lines   []LineError `json:"lines"`
+}
+
+// lpTokenizer is a small state machine over a single line-protocol line:
+//
+//	measurement[,tag=value...] field=value[,field=value...] [timestamp]
+//
+// It understands backslash-escaping of `,`, `=` and ` ` in measurement/tag
+// text, double-quoted string fields (with `\"` escapes), and typed field
+// literals: a trailing `i` for a signed integer, `u` for unsigned, `true`/
+// `false` for booleans, and a bare numeric literal - including scientific
+// notation - for a float.
+type lpTokenizer struct {
+	src string
+	pos int
+}
+
+func (t *lpTokenizer) skipSpaces() {
+	for t.pos < len(t.src) && t.src[t.pos] == ' ' {
+		t.pos++
+	}
+}
+
+// readToken reads an escaped identifier up to the first unescaped byte in
+// stopSet, unescaping `\,`, `\=` and `\ ` along the way. It returns the
+// unescaped text and the stop byte encountered (0 at end of input).
+func (t *lpTokenizer) readToken(stopSet string) (string, byte) {
+	var b strings.Builder
+	for t.pos < len(t.src) {
+		c := t.src[t.pos]
+		if c == '\\' && t.pos+1 < len(t.src) && strings.IndexByte(",= ", t.src[t.pos+1]) >= 0 {
+			b.WriteByte(t.src[t.pos+1])
+			t.pos += 2
+			continue
+		}
+		if strings.IndexByte(stopSet, c) >= 0 {
+			return b.String(), c
+		}
+		b.WriteByte(c)
+		t.pos++
+	}
+	return b.String(), 0
+}
+
+// parseIdentifiers reads "measurement[,tag=value...]" up to the field set.
+func (t *lpTokenizer) parseIdentifiers() (measurement string, tags map[string]string, err error) {
+	tags = make(map[string]string)
+
+	measurement, stop := t.readToken(", ")
+	if measurement == "" {
+		return "", nil, fmt.Errorf("missing measurement name")
+	}
+
+	for stop == ',' {
+		t.pos++ // consume ','
+		key, keyStop := t.readToken("= ")
+		if keyStop != '=' || key == "" {
+			return "", nil, fmt.Errorf("malformed tag set near %q", key)
+		}
+		t.pos++ // consume '='
+		value, valStop := t.readToken(", ")
+		tags[key] = value
+		stop = valStop
+	}
+
+	return measurement, tags, nil
+}
+
+// parseFields reads "field=value[,field=value...]".
+func (t *lpTokenizer) parseFields() (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+
+	for {
+		key, stop := t.readToken("= ")
+		if stop != '=' || key == "" {
+			return nil, fmt.Errorf("malformed field set near %q", key)
+		}
+		t.pos++ // consume '='
+
+		value, err := t.readFieldValue()
+		if err != nil {
+			return nil, err
+		}
+		fields[key] = value
+
+		if t.pos < len(t.src) && t.src[t.pos] == ',' {
+			t.pos++
+			continue
+		}
+		break
+	}
+
+	return fields, nil
+}
+
+// readFieldValue reads one field value: a double-quoted string, a boolean
+// literal, or a numeric literal with an optional `i`/`u` type suffix - the
+// bare default is a float, scientific notation included.
+func (t *lpTokenizer) readFieldValue() (interface{}, error) {
+	if t.pos < len(t.src) && t.src[t.pos] == '"' {
+		return t.readQuotedString()
+	}
+
+	start := t.pos
+	for t.pos < len(t.src) && t.src[t.pos] != ',' && t.src[t.pos] != ' ' {
+		t.pos++
+	}
+	raw := t.src[start:t.pos]
+	if raw == "" {
+		return nil, fmt.Errorf("empty field value")
+	}
+
+	switch strings.ToLower(raw) {
+	case "true", "t":
+		return true, nil
+	case "false", "f":
+		return false, nil
+	}
+
+	if strings.HasSuffix(raw, "i") {
+		n, err := strconv.ParseInt(raw[:len(raw)-1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer field %q: %w", raw, err)
+		}
+		return n, nil
+	}
+	if strings.HasSuffix(raw, "u") {
+		n, err := strconv.ParseUint(raw[:len(raw)-1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unsigned field %q: %w", raw, err)
+		}
+		return n, nil
+	}
+
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid float field %q: %w", raw, err)
+	}
+	return f, nil
+}
+
+// readQuotedString reads a double-quoted string field, unescaping `\"`.
+func (t *lpTokenizer) readQuotedString() (string, error) {
+	t.pos++ // consume opening quote
+	var b strings.Builder
+	for t.pos < len(t.src) {
+		c := t.src[t.pos]
+		if c == '\\' && t.pos+1 < len(t.src) && t.src[t.pos+1] == '"' {
+			b.WriteByte('"')
+			t.pos += 2
+			continue
+		}
+		if c == '"' {
+			t.pos++
+			return b.String(), nil
+		}
+		b.WriteByte(c)
+		t.pos++
+	}
+	return "", fmt.Errorf("unterminated quoted string field")
+}
+
+// parseInfluxDBLine parses one InfluxDB line-protocol line, returning a
+// *LineError (rather than a bare error) so InfluxDBWrite can report the
+// exact line and column a write failed at.
+func parseInfluxDBLine(lineNum int, line, precision string) (*InfluxDBLineProtocol, *LineError) {
+	t := &lpTokenizer{src: line}
+
+	measurement, tags, err := t.parseIdentifiers()
+	if err != nil {
+		return nil, &LineError{Line: lineNum, Col: t.pos, Msg: err.Error()}
+	}
+
+	t.skipSpaces()
+	fields, err := t.parseFields()
+	if err != nil {
+		return nil, &LineError{Line: lineNum, Col: t.pos, Msg: err.Error()}
+	}
+
+	t.skipSpaces()
+	var ts int64
+	if t.pos < len(t.src) {
+		parsed, err := strconv.ParseInt(strings.TrimSpace(t.src[t.pos:]), 10, 64)
+		if err != nil {
+			return nil, &LineError{Line: lineNum, Col: t.pos, Msg: "invalid timestamp: " + err.Error()}
+		}
+		ts = normalizeTimestamp(parsed, precision)
+	} else {
+		ts = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+
+	return &InfluxDBLineProtocol{
+		Measurement: measurement,
+		Tags:        tags,
+		Fields:      fields,
+		Timestamp:   ts,
+		SubtableKey: subtableKey(measurement, tags),
+	}, nil
+}
+
+// subtableKey deterministically names the subtable a point belongs to:
+// measurement plus its tag set sorted by key, hashed so the key is a fixed,
+// filesystem/collection-name-safe size regardless of how many tags a line
+// carries. Sorting tags first means two lines for the same series always
+// hash to the same subtable even if their tags were written in a different
+// order.
+func subtableKey(measurement string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(measurement)
+	for _, k := range keys {
+		b.WriteByte(',')
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(tags[k])
+	}
+
+	sum := sha1.Sum([]byte(b.String()))
+	return "t_" + hex.EncodeToString(sum[:])[:16]
+}
+
+// normalizeTimestamp converts a raw timestamp in the given write precision
+// to milliseconds, the unit InfluxDBLineProtocol.Timestamp is stored in.
+func normalizeTimestamp(ts int64, precision string) int64 {
+	switch precision {
+	case "ns", "n":
+		return ts / 1_000_000
+	case "us", "u":
+		return ts / 1_000
+	case "ms":
+		return ts
+	case "s":
+		return ts * 1_000
+	case "m":
+		return ts * 60_000
+	case "h":
+		return ts * 3_600_000
+	default:
+		return ts / 1_000_000 // default: treat as ns
+	}
+}
@@ -0,0 +1,142 @@
+package tdengine
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// OpenTSDBPutResult is the OpenTSDB-style summary every /api/put-compatible
+// path returns: how many of the submitted points were written, how many
+// failed, and why, so collectd/tcollector-style agents that already parse
+// this shape against a real OpenTSDB server work against LumaDB unchanged.
+type OpenTSDBPutResult struct {
+	Failed  int      `json:"failed"`
+	Success int      `json:"success"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// WriteOpenTSDB writes points to db one at a time via WriteOpenTSDBJSON
+// (which already does the metric->supertable / tag-combination->subtable
+// mapping a single JSON point goes through), collecting a per-point
+// success/failure summary instead of aborting the whole batch on the first
+// error - the behavior OpenTSDB's own /api/put has always had.
+func (e *Engine) WriteOpenTSDB(db string, points []OpenTSDBPoint) *OpenTSDBPutResult {
+	result := &OpenTSDBPutResult{}
+	for _, point := range points {
+		p := point
+		if err := e.WriteOpenTSDBJSON(db, &p); err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, err.Error())
+			continue
+		}
+		result.Success++
+	}
+	return result
+}
+
+// OpenTSDBPut handles /opentsdb/v1/put/{db}, the standard OpenTSDB
+// /api/put path: a single point object or a JSON array of points, both
+// accepted the way a real OpenTSDB server does, replying with the
+// {"failed":N,"success":M,"errors":[...]} summary instead of the
+// all-or-nothing 204/500 the json/ and telnet/ paths below give.
+func (a *API) OpenTSDBPut(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	db := strings.TrimPrefix(r.URL.Path, "/opentsdb/v1/put/")
+
+	if res := a.authenticate(r, db, true); !res.ok {
+		a.respondErrorStatus(w, res.status, res.code, res.desc)
+		return
+	}
+
+	raw := json.RawMessage{}
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var points []OpenTSDBPoint
+	if err := json.Unmarshal(raw, &points); err != nil {
+		var single OpenTSDBPoint
+		if err := json.Unmarshal(raw, &single); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		points = []OpenTSDBPoint{single}
+	}
+
+	result := a.engine.WriteOpenTSDB(db, points)
+
+	w.Header().Set("Content-Type", "application/json")
+	if result.Failed > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// OpenTSDBTelnetListener is a raw TCP listener speaking OpenTSDB's telnet
+// put protocol ("put <metric> <ts> <value> <tagk=tagv>...\n" per line),
+// the wire format tcollector and older collectd plugins actually speak -
+// over a plain socket, not HTTP the way OpenTSDBTelnet above expects.
+// Call Serve in its own goroutine; it blocks until the listener is closed.
+type OpenTSDBTelnetListener struct {
+	api *API
+	db  string
+	ln  net.Listener
+}
+
+// NewOpenTSDBTelnetListener binds addr (e.g. ":4242", OpenTSDB's default
+// telnet port) and returns a listener that writes every accepted "put"
+// line into db.
+func NewOpenTSDBTelnetListener(api *API, db, addr string) (*OpenTSDBTelnetListener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &OpenTSDBTelnetListener{api: api, db: db, ln: ln}, nil
+}
+
+// Serve accepts connections until the listener is closed, handling each on
+// its own goroutine so one slow or hung agent can't stall the others.
+func (l *OpenTSDBTelnetListener) Serve() error {
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			return err
+		}
+		go l.handle(conn)
+	}
+}
+
+// Close stops accepting new connections.
+func (l *OpenTSDBTelnetListener) Close() error {
+	return l.ln.Close()
+}
+
+func (l *OpenTSDBTelnetListener) handle(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case line == "version":
+			conn.Write([]byte("LumaDB TSDB-compatible (OpenTSDB telnet)\n"))
+		case strings.HasPrefix(line, "put "):
+			if err := l.api.engine.WriteOpenTSDBTelnet(l.db, line); err != nil {
+				conn.Write([]byte("error: " + err.Error() + "\n"))
+			}
+		default:
+			conn.Write([]byte("error: unrecognized command\n"))
+		}
+	}
+}
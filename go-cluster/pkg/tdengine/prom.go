@@ -0,0 +1,138 @@
+package tdengine
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"google.golang.org/protobuf/proto"
+)
+
+// PromWrite handles /api/v1/prom/write: a Prometheus remote_write endpoint,
+// following the same approach InfluxDB's own Prometheus service takes. It
+// decodes a snappy-compressed prompb.WriteRequest and hands the contained
+// TimeSeries to Engine.WriteProm, which maps each series' "__name__" label
+// to a measurement and every other label to a tag.
+func (a *API) PromWrite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	db := r.URL.Query().Get("db")
+	if db == "" {
+		db = r.Header.Get("X-TDengine-Database")
+	}
+
+	if res := a.authenticate(r, db, true); !res.ok {
+		a.respondErrorStatus(w, res.status, res.code, res.desc)
+		return
+	}
+
+	compressed, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		http.Error(w, "invalid snappy encoding: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req prompb.WriteRequest
+	if err := proto.Unmarshal(data, &req); err != nil {
+		http.Error(w, "invalid protobuf: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := a.engine.WriteProm(req.Timeseries); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PromRead handles /api/v1/prom/read: a Prometheus remote_read endpoint. It
+// decodes a snappy-compressed prompb.ReadRequest, runs each query through
+// Engine.ReadProm, and streams the matching series back in the same
+// snappy-compressed protobuf envelope remote_read clients expect.
+func (a *API) PromRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	db := r.URL.Query().Get("db")
+	if db == "" {
+		db = r.Header.Get("X-TDengine-Database")
+	}
+
+	if res := a.authenticate(r, db, false); !res.ok {
+		a.respondErrorStatus(w, res.status, res.code, res.desc)
+		return
+	}
+
+	compressed, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		http.Error(w, "invalid snappy encoding: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req prompb.ReadRequest
+	if err := proto.Unmarshal(data, &req); err != nil {
+		http.Error(w, "invalid protobuf: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := &prompb.ReadResponse{Results: make([]*prompb.QueryResult, len(req.Queries))}
+	for i, q := range req.Queries {
+		series, err := a.engine.ReadProm(q)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp.Results[i] = &prompb.QueryResult{Timeseries: series}
+	}
+
+	out, err := proto.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Header().Set("Content-Encoding", "snappy")
+	w.Write(snappy.Encode(nil, out))
+}
+
+// MatchersToFilter translates a Prometheus remote_read query's label
+// matchers into the engine's internal measurement name plus equality
+// filter - the shape Engine.ReadProm uses to look up the tag data PromWrite
+// populated. Only MatchEqual is honored for labels other than "__name__";
+// regex and negative matchers are left to the engine's own full scan rather
+// than being mistranslated into an equality filter that would miss rows.
+func MatchersToFilter(matchers []*prompb.LabelMatcher) (measurement string, filter map[string]interface{}) {
+	filter = make(map[string]interface{})
+	for _, m := range matchers {
+		if m.Name == "__name__" {
+			if m.Type == prompb.LabelMatcher_EQ {
+				measurement = m.Value
+			}
+			continue
+		}
+		if m.Type == prompb.LabelMatcher_EQ {
+			filter[m.Name] = m.Value
+		}
+	}
+	return measurement, filter
+}
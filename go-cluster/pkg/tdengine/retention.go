@@ -0,0 +1,343 @@
+package tdengine
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetentionPolicy is one database's KEEP/DURATION/REPLICA clause, TDengine's
+// CREATE DATABASE retention settings mirrored onto InfluxDB's own
+// RetentionPolicyInfo model (duration, shard-group duration, replication
+// factor, default flag) so the same shape can be reported to either
+// protocol's clients. Unlike InfluxDB, TDengine keeps exactly one policy per
+// database rather than several, so Name defaults to Database unless ALTER
+// RETENTION POLICY gives the policy a name of its own.
+type RetentionPolicy struct {
+	Name               string
+	Database           string
+	Duration           time.Duration // KEEP
+	ShardGroupDuration time.Duration // DURATION
+	ReplicaN           int           // REPLICA
+	Default            bool
+	CreatedAt          time.Time
+}
+
+// ShardExpirer is implemented by an Engine that can drop data older than a
+// cutoff. RetentionManager's sweeper calls it for every database with a
+// policy once EnableRetention is wired to an engine satisfying it - the
+// same optional-capability pattern api.go already uses for TransportHealth,
+// since this snapshot's Engine has no such method to call yet.
+type ShardExpirer interface {
+	DropExpired(database string, olderThan time.Time) (int64, error)
+}
+
+// RetentionManager holds one RetentionPolicy per database, persisting them
+// as JSON at path so they survive restart - the same durability
+// StreamManager and BindingManager already give streams and SQL bindings -
+// and sweeps expired shards on an interval via a ShardExpirer.
+type RetentionManager struct {
+	mu       sync.RWMutex
+	path     string
+	policies map[string]*RetentionPolicy // database -> policy
+
+	stop chan struct{}
+}
+
+// NewRetentionManager creates a RetentionManager persisting policies to
+// path, loading any that already exist there.
+func NewRetentionManager(path string) (*RetentionManager, error) {
+	rm := &RetentionManager{
+		path:     path,
+		policies: make(map[string]*RetentionPolicy),
+	}
+	if err := rm.load(); err != nil {
+		return nil, err
+	}
+	return rm, nil
+}
+
+func (rm *RetentionManager) load() error {
+	data, err := os.ReadFile(rm.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var policies []*RetentionPolicy
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return err
+	}
+	for _, p := range policies {
+		rm.policies[p.Database] = p
+	}
+	return nil
+}
+
+func (rm *RetentionManager) save() error {
+	policies := make([]*RetentionPolicy, 0, len(rm.policies))
+	for _, p := range rm.policies {
+		policies = append(policies, p)
+	}
+	data, err := json.MarshalIndent(policies, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(rm.path, data, 0644)
+}
+
+// Set registers policy for database, replacing any existing one.
+func (rm *RetentionManager) Set(database string, policy RetentionPolicy) error {
+	policy.Database = database
+	if policy.Name == "" {
+		policy.Name = database
+	}
+	policy.CreatedAt = time.Now()
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.policies[database] = &policy
+	return rm.save()
+}
+
+// Get returns database's policy, if one was registered.
+func (rm *RetentionManager) Get(database string) (RetentionPolicy, bool) {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	p, ok := rm.policies[database]
+	if !ok {
+		return RetentionPolicy{}, false
+	}
+	return *p, true
+}
+
+// List returns every registered policy, InfluxDB's SHOW RETENTION POLICIES
+// order: by Database name.
+func (rm *RetentionManager) List() []RetentionPolicy {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	out := make([]RetentionPolicy, 0, len(rm.policies))
+	for _, p := range rm.policies {
+		out = append(out, *p)
+	}
+	sortRetentionPolicies(out)
+	return out
+}
+
+func sortRetentionPolicies(policies []RetentionPolicy) {
+	for i := 1; i < len(policies); i++ {
+		for j := i; j > 0 && policies[j].Database < policies[j-1].Database; j-- {
+			policies[j], policies[j-1] = policies[j-1], policies[j]
+		}
+	}
+}
+
+// StartSweeper launches a goroutine that, every interval, drops shards
+// older than Duration for every database with a policy registered, via
+// expirer. It returns immediately; call Stop to shut the goroutine down.
+func (rm *RetentionManager) StartSweeper(expirer ShardExpirer, interval time.Duration) {
+	rm.mu.Lock()
+	if rm.stop != nil {
+		rm.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	rm.stop = stop
+	rm.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				rm.sweepOnce(expirer)
+			}
+		}
+	}()
+}
+
+// Stop shuts down the sweeper goroutine started by StartSweeper, if any.
+func (rm *RetentionManager) Stop() {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	if rm.stop != nil {
+		close(rm.stop)
+		rm.stop = nil
+	}
+}
+
+func (rm *RetentionManager) sweepOnce(expirer ShardExpirer) {
+	for _, policy := range rm.List() {
+		if policy.Duration <= 0 {
+			continue
+		}
+		expirer.DropExpired(policy.Database, time.Now().Add(-policy.Duration))
+	}
+}
+
+var (
+	reCreateDatabaseRetention = regexp.MustCompile(`(?is)^\s*CREATE\s+DATABASE\s+(?:IF\s+NOT\s+EXISTS\s+)?` +
+		"`?([A-Za-z_][A-Za-z0-9_]*)`?" +
+		`(.*)$`)
+	reRetentionKeep     = regexp.MustCompile(`(?i)\bKEEP\s+(\d+[a-zA-Z]*)\b`)
+	reRetentionDuration = regexp.MustCompile(`(?i)\bDURATION\s+(\d+[a-zA-Z]*)\b`)
+	reRetentionReplica  = regexp.MustCompile(`(?i)\bREPLICA\s+(\d+)\b`)
+
+	reShowRetentionPolicies = regexp.MustCompile(`(?is)^\s*SHOW\s+RETENTION\s+POLICIES\s+ON\s+` +
+		"`?([A-Za-z_][A-Za-z0-9_]*)`?" +
+		`\s*;?\s*$`)
+	reAlterRetentionPolicy = regexp.MustCompile(`(?is)^\s*ALTER\s+RETENTION\s+POLICY\s+` +
+		"`?([A-Za-z_][A-Za-z0-9_]*)`?" +
+		`\s+ON\s+` + "`?([A-Za-z_][A-Za-z0-9_]*)`?" +
+		`(.*)$`)
+)
+
+// parseRetentionKeepDuration parses a TDengine KEEP/DURATION value: either a
+// suffixed duration like "10d" (see parseTDuration) or a bare integer, which
+// TDengine interprets as a number of days.
+func parseRetentionKeepDuration(s string) (time.Duration, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return parseTDuration(s)
+}
+
+// parseCreateDatabaseRetention recognizes a CREATE DATABASE statement's
+// KEEP/DURATION/REPLICA clauses, e.g.
+// "CREATE DATABASE test KEEP 365 DURATION 10 REPLICA 3", returning ok=false
+// if the statement isn't CREATE DATABASE or carries none of the three
+// clauses (plain "CREATE DATABASE test" keeps falling through to the engine
+// unchanged).
+func parseCreateDatabaseRetention(sql string) (database string, policy RetentionPolicy, ok bool) {
+	m := reCreateDatabaseRetention.FindStringSubmatch(sql)
+	if m == nil {
+		return "", RetentionPolicy{}, false
+	}
+	database = m[1]
+	rest := m[2]
+
+	keepM := reRetentionKeep.FindStringSubmatch(rest)
+	durationM := reRetentionDuration.FindStringSubmatch(rest)
+	replicaM := reRetentionReplica.FindStringSubmatch(rest)
+	if keepM == nil && durationM == nil && replicaM == nil {
+		return "", RetentionPolicy{}, false
+	}
+
+	if keepM != nil {
+		d, err := parseRetentionKeepDuration(keepM[1])
+		if err != nil {
+			return "", RetentionPolicy{}, false
+		}
+		policy.Duration = d
+	}
+	if durationM != nil {
+		d, err := parseRetentionKeepDuration(durationM[1])
+		if err != nil {
+			return "", RetentionPolicy{}, false
+		}
+		policy.ShardGroupDuration = d
+	} else {
+		policy.ShardGroupDuration = policy.Duration
+	}
+	if replicaM != nil {
+		n, err := strconv.Atoi(replicaM[1])
+		if err != nil {
+			return "", RetentionPolicy{}, false
+		}
+		policy.ReplicaN = n
+	} else {
+		policy.ReplicaN = 1
+	}
+
+	return database, policy, true
+}
+
+// handleRetentionSQL answers SHOW RETENTION POLICIES ON <db> and
+// ALTER RETENTION POLICY ... ON <db> directly. CREATE DATABASE is not
+// intercepted here: recordRetentionFromCreate runs after a.engine.Execute
+// succeeds, so a database that fails to create never gets a retention
+// policy recorded for it either.
+func (a *API) handleRetentionSQL(sql string) (*Response, bool) {
+	sql = strings.TrimSpace(sql)
+
+	if m := reShowRetentionPolicies.FindStringSubmatch(sql); m != nil {
+		database := m[1]
+		policy, ok := a.retention.Get(database)
+		data := [][]interface{}{}
+		if ok {
+			data = append(data, []interface{}{
+				policy.Name, policy.Duration.String(), policy.ShardGroupDuration.String(),
+				policy.ReplicaN, policy.Default,
+			})
+		}
+		return &Response{
+			Code: TSDB_CODE_SUCCESS,
+			ColumnMeta: [][]interface{}{
+				{"name", "VARCHAR", 64}, {"duration", "VARCHAR", 32}, {"shardGroupDuration", "VARCHAR", 32},
+				{"replicaN", "INT", 4}, {"default", "BOOL", 1},
+			},
+			Data: data,
+			Rows: len(data),
+		}, true
+	}
+
+	if m := reAlterRetentionPolicy.FindStringSubmatch(sql); m != nil {
+		name, database, rest := m[1], m[2], m[3]
+		policy, ok := a.retention.Get(database)
+		if !ok {
+			policy = RetentionPolicy{Name: name, ReplicaN: 1}
+		}
+		policy.Name = name
+
+		if keepM := reRetentionKeep.FindStringSubmatch(rest); keepM != nil {
+			if d, err := parseRetentionKeepDuration(keepM[1]); err == nil {
+				policy.Duration = d
+			}
+		}
+		if durationM := reRetentionDuration.FindStringSubmatch(rest); durationM != nil {
+			if d, err := parseRetentionKeepDuration(durationM[1]); err == nil {
+				policy.ShardGroupDuration = d
+			}
+		}
+		if replicaM := reRetentionReplica.FindStringSubmatch(rest); replicaM != nil {
+			if n, err := strconv.Atoi(replicaM[1]); err == nil {
+				policy.ReplicaN = n
+			}
+		}
+		if strings.Contains(strings.ToUpper(rest), "DEFAULT") {
+			policy.Default = true
+		}
+
+		if err := a.retention.Set(database, policy); err != nil {
+			return &Response{Code: TSDB_CODE_FAILED, Desc: err.Error()}, true
+		}
+		return &Response{Code: TSDB_CODE_SUCCESS}, true
+	}
+
+	return nil, false
+}
+
+// recordRetentionFromCreate records the retention policy embedded in a
+// CREATE DATABASE statement's KEEP/DURATION/REPLICA clauses, if any, after
+// the statement has already been executed against the engine successfully.
+func (a *API) recordRetentionFromCreate(sql string) {
+	if a.retention == nil {
+		return
+	}
+	database, policy, ok := parseCreateDatabaseRetention(sql)
+	if !ok {
+		return
+	}
+	a.retention.Set(database, policy)
+}
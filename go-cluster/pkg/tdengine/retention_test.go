@@ -0,0 +1,60 @@
+package tdengine
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseCreateDatabaseRetention(t *testing.T) {
+	sql := "CREATE DATABASE test KEEP 365 DURATION 10 REPLICA 3"
+
+	database, policy, ok := parseCreateDatabaseRetention(sql)
+	if !ok {
+		t.Fatalf("parseCreateDatabaseRetention(%q) ok = false, want true", sql)
+	}
+	if database != "test" {
+		t.Errorf("database = %q, want test", database)
+	}
+	if policy.Duration != 365*24*time.Hour {
+		t.Errorf("Duration = %v, want 365 days", policy.Duration)
+	}
+	if policy.ShardGroupDuration != 10*24*time.Hour {
+		t.Errorf("ShardGroupDuration = %v, want 10 days", policy.ShardGroupDuration)
+	}
+	if policy.ReplicaN != 3 {
+		t.Errorf("ReplicaN = %v, want 3", policy.ReplicaN)
+	}
+}
+
+func TestParseCreateDatabaseRetention_NoClauses(t *testing.T) {
+	if _, _, ok := parseCreateDatabaseRetention("CREATE DATABASE test"); ok {
+		t.Error("parseCreateDatabaseRetention() ok = true for a plain CREATE DATABASE, want false")
+	}
+}
+
+func TestRetentionManager_SetGetPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "retention.json")
+
+	rm, err := NewRetentionManager(path)
+	if err != nil {
+		t.Fatalf("NewRetentionManager() error = %v", err)
+	}
+
+	if err := rm.Set("test", RetentionPolicy{Duration: 24 * time.Hour, ReplicaN: 1}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	reloaded, err := NewRetentionManager(path)
+	if err != nil {
+		t.Fatalf("NewRetentionManager() reload error = %v", err)
+	}
+
+	policy, ok := reloaded.Get("test")
+	if !ok {
+		t.Fatal("Get() ok = false after reload, want true")
+	}
+	if policy.Duration != 24*time.Hour {
+		t.Errorf("Duration = %v, want 24h", policy.Duration)
+	}
+}
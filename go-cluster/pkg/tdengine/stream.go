@@ -0,0 +1,131 @@
+package tdengine
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SQLStream handles /rest/sql/stream: like SQL, but instead of buffering the
+// whole result set in memory it streams newline-delimited JSON RowBatches as
+// the query executes, flushing after each one - the same chunked-query idea
+// as InfluxDB's httpd.Handler with its DefaultChunkSize, so a large SELECT
+// doesn't have to fit in memory before the first byte goes out.
+func (a *API) SQLStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	chunkSize := DefaultChunkSize
+	if cs := r.URL.Query().Get("chunk_size"); cs != "" {
+		if n, err := strconv.Atoi(cs); err == nil && n > 0 {
+			chunkSize = n
+		}
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.respondError(w, TSDB_CODE_FAILED, err.Error())
+		return
+	}
+
+	db := r.Header.Get("X-TDengine-Database")
+	if res := a.authenticate(r, db, !isReadOnlySQL(string(body))); !res.ok {
+		a.respondErrorStatus(w, res.status, res.code, res.desc)
+		return
+	}
+
+	opts := &ExecuteOptions{
+		ReqID:    r.Header.Get("X-Request-ID"),
+		Timezone: r.Header.Get("X-Timezone"),
+	}
+
+	batches, errc := a.engine.ExecuteStream(db, string(body), opts, chunkSize)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(w)
+	for batch := range batches {
+		if err := enc.Encode(batch); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+
+	if err := <-errc; err != nil {
+		enc.Encode(&Response{Code: TSDB_CODE_FAILED, Desc: err.Error()})
+		flusher.Flush()
+	}
+}
+
+// fluxRequest is the JSON body /api/v2/query accepts when the caller sends
+// application/json rather than a raw Flux script, mirroring InfluxDB 2.x's
+// own QueryRequest just enough for the fields ParseFlux understands.
+type fluxRequest struct {
+	Query string `json:"query"`
+}
+
+// QueryV2 handles /api/v2/query, a minimal Flux-pipeline endpoint modeled on
+// InfluxDB 2.x's query API. It only understands the
+// from |> range |> filter* |> aggregateWindow shape documented on ParseFlux;
+// anything else comes back as 400 rather than being silently misinterpreted.
+// The parsed pipeline is translated to this engine's own SQL dialect and run
+// through the normal Engine.Execute path.
+func (a *API) QueryV2(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	db := r.URL.Query().Get("org")
+	if db == "" {
+		db = r.Header.Get("X-TDengine-Database")
+	}
+
+	if res := a.authenticate(r, db, false); !res.ok {
+		a.respondErrorStatus(w, res.status, res.code, res.desc)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.respondError(w, TSDB_CODE_FAILED, err.Error())
+		return
+	}
+
+	flux := string(body)
+	if strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+		var req fluxRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			a.respondError(w, TSDB_CODE_TSC_INVALID_SQL, "invalid JSON body: "+err.Error())
+			return
+		}
+		flux = req.Query
+	}
+
+	fq, err := ParseFlux(flux)
+	if err != nil {
+		a.respondErrorStatus(w, http.StatusBadRequest, TSDB_CODE_TSC_INVALID_SQL, err.Error())
+		return
+	}
+
+	opts := &ExecuteOptions{ReqID: r.Header.Get("X-Request-ID")}
+
+	result, err := a.engine.Execute(db, fq.ToSQL(), opts)
+	if err != nil {
+		a.respondError(w, TSDB_CODE_FAILED, err.Error())
+		return
+	}
+
+	a.respond(w, result)
+}
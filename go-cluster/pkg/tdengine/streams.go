@@ -0,0 +1,682 @@
+package tdengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StreamExecutor is the minimal surface StreamManager needs to write closed
+// window results into a target table - satisfied by *Engine.
+type StreamExecutor interface {
+	Execute(db, sql string, opts *ExecuteOptions) (*Response, error)
+}
+
+// streamTrigger is the parsed form of StreamDefinition.Trigger.
+type streamTrigger int
+
+const (
+	triggerAtOnce streamTrigger = iota
+	triggerWindowClose
+	triggerMaxDelay
+)
+
+// aggFunc is one of the aggregate functions a stream's SELECT list can use.
+type aggFunc int
+
+const (
+	aggCount aggFunc = iota
+	aggSum
+	aggAvg
+	aggMin
+	aggMax
+	aggFirst
+	aggLast
+)
+
+var aggFuncNames = map[string]aggFunc{
+	"COUNT": aggCount, "SUM": aggSum, "AVG": aggAvg,
+	"MIN": aggMin, "MAX": aggMax, "FIRST": aggFirst, "LAST": aggLast,
+}
+
+// streamAggExpr is one `FUNC(col) AS alias` entry from a stream's SELECT list.
+type streamAggExpr struct {
+	Func   aggFunc
+	Column string
+	Alias  string
+}
+
+// streamSpec is a StreamDefinition plus everything parsed out of its SQL
+// that the runtime actually needs, so CreateStream only has to parse once.
+type streamSpec struct {
+	Def       StreamDefinition
+	Trigger   streamTrigger
+	MaxDelay  time.Duration
+	Watermark time.Duration
+	Interval  time.Duration
+	Fill      FillType
+	FillValue float64
+	Exprs     []streamAggExpr
+	Source    string
+}
+
+// windowAgg accumulates one open window's running aggregates.
+type windowAgg struct {
+	count  int64
+	sums   map[string]float64
+	mins   map[string]float64
+	maxs   map[string]float64
+	firsts map[string]float64
+	lasts  map[string]float64
+	timer  *time.Timer
+}
+
+func newWindowAgg() *windowAgg {
+	return &windowAgg{
+		sums: make(map[string]float64), mins: make(map[string]float64),
+		maxs: make(map[string]float64), firsts: make(map[string]float64),
+		lasts: make(map[string]float64),
+	}
+}
+
+func (w *windowAgg) apply(column string, value float64) {
+	if _, seen := w.firsts[column]; !seen {
+		w.firsts[column] = value
+		w.mins[column] = value
+		w.maxs[column] = value
+	} else {
+		if value < w.mins[column] {
+			w.mins[column] = value
+		}
+		if value > w.maxs[column] {
+			w.maxs[column] = value
+		}
+	}
+	w.lasts[column] = value
+	w.sums[column] += value
+}
+
+// streamRuntime is the live, in-memory state for one registered stream:
+// its parsed spec plus whatever windows are currently open. Window state is
+// intentionally not persisted - only the stream definition is, so a
+// restarted engine resumes accepting rows into fresh windows rather than
+// replaying history.
+type streamRuntime struct {
+	spec        streamSpec
+	mu          sync.Mutex
+	windows     map[int64]*windowAgg
+	nextWindow  int64
+	haveNext    bool
+	watermark   int64
+	lastEmitted map[string]float64
+	haveEmitted bool
+}
+
+// StreamManager owns every registered continuous stream: parsing CREATE
+// STREAM, feeding rows into the right window, closing windows on watermark
+// advance (or immediately/on a timer, per StreamDefinition.Trigger), and
+// persisting definitions as a JSON file so they survive an engine restart -
+// the same file-snapshot approach auth.FileUserStore uses.
+type StreamManager struct {
+	mu       sync.RWMutex
+	path     string
+	streams  map[string]*streamRuntime
+	executor StreamExecutor
+}
+
+// NewStreamManager creates a StreamManager backed by path, loading any
+// previously-persisted stream definitions (with fresh, empty window state).
+func NewStreamManager(path string, executor StreamExecutor) (*StreamManager, error) {
+	sm := &StreamManager{path: path, streams: make(map[string]*streamRuntime), executor: executor}
+	if err := sm.load(); err != nil {
+		return nil, err
+	}
+	return sm, nil
+}
+
+func (sm *StreamManager) load() error {
+	data, err := os.ReadFile(sm.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	var defs []StreamDefinition
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return err
+	}
+	for _, def := range defs {
+		spec, err := parseCreateStreamSQL(def.SQL)
+		if err != nil {
+			continue // definition saved by a since-changed parser; skip rather than fail startup
+		}
+		spec.Def = def
+		sm.streams[def.Name] = &streamRuntime{spec: spec, windows: make(map[int64]*windowAgg)}
+	}
+	return nil
+}
+
+func (sm *StreamManager) save() error {
+	defs := make([]StreamDefinition, 0, len(sm.streams))
+	for _, sr := range sm.streams {
+		defs = append(defs, sr.spec.Def)
+	}
+	data, err := json.MarshalIndent(defs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(sm.path, data, 0644)
+}
+
+// CreateStream parses a `CREATE STREAM ... AS SELECT ...` statement and
+// registers it, persisting the definition immediately.
+func (sm *StreamManager) CreateStream(database, sql string) (StreamDefinition, error) {
+	spec, err := parseCreateStreamSQL(sql)
+	if err != nil {
+		return StreamDefinition{}, err
+	}
+	spec.Def.Database = database
+	spec.Def.SQL = sql
+	spec.Def.CreatedAt = time.Now()
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if _, exists := sm.streams[spec.Def.Name]; exists {
+		return StreamDefinition{}, fmt.Errorf("stream %s already exists", spec.Def.Name)
+	}
+	sm.streams[spec.Def.Name] = &streamRuntime{spec: spec, windows: make(map[int64]*windowAgg)}
+	if err := sm.save(); err != nil {
+		delete(sm.streams, spec.Def.Name)
+		return StreamDefinition{}, err
+	}
+	return spec.Def, nil
+}
+
+// DropStream removes a stream and stops any pending MAX_DELAY timers.
+func (sm *StreamManager) DropStream(name string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sr, ok := sm.streams[name]
+	if !ok {
+		return fmt.Errorf("unknown stream: %s", name)
+	}
+	sr.mu.Lock()
+	for _, w := range sr.windows {
+		if w.timer != nil {
+			w.timer.Stop()
+		}
+	}
+	sr.mu.Unlock()
+	delete(sm.streams, name)
+	return sm.save()
+}
+
+// ShowStreams lists every registered stream definition.
+func (sm *StreamManager) ShowStreams() []StreamDefinition {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	defs := make([]StreamDefinition, 0, len(sm.streams))
+	for _, sr := range sm.streams {
+		defs = append(defs, sr.spec.Def)
+	}
+	return defs
+}
+
+// OnInsert feeds one inserted row into every stream whose source table and
+// database match. The engine's INSERT execution path would call this after
+// a successful write; this snapshot's ghost Engine.Execute doesn't, so
+// until that's wired up, streams accept rows only from direct callers (and
+// tests) of OnInsert rather than from live /rest/sql traffic.
+func (sm *StreamManager) OnInsert(database, table string, row map[string]interface{}, ts time.Time) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	for _, sr := range sm.streams {
+		if sr.spec.Def.Database == database && strings.EqualFold(sr.spec.Source, table) {
+			sr.onInsert(sm, row, ts)
+		}
+	}
+}
+
+func (sr *streamRuntime) onInsert(sm *StreamManager, row map[string]interface{}, ts time.Time) {
+	tsMs := ts.UnixMilli()
+	intervalMs := sr.spec.Interval.Milliseconds()
+	if intervalMs <= 0 {
+		intervalMs = 1
+	}
+	watermarkMs := tsMs - sr.spec.Watermark.Milliseconds()
+
+	sr.mu.Lock()
+
+	if sr.spec.Def.IgnoreExpired && tsMs < sr.watermark {
+		sr.mu.Unlock()
+		return
+	}
+
+	start := (tsMs / intervalMs) * intervalMs
+	if !sr.haveNext {
+		sr.nextWindow = start
+		sr.haveNext = true
+	}
+
+	w, ok := sr.windows[start]
+	if !ok {
+		w = newWindowAgg()
+		sr.windows[start] = w
+	}
+	w.count++ // counts the row once, regardless of how many SELECT exprs use it
+	for _, expr := range sr.spec.Exprs {
+		if expr.Column == "*" {
+			continue
+		}
+		if v, ok := numericValue(row[expr.Column]); ok {
+			w.apply(expr.Column, v)
+		}
+	}
+
+	if sr.spec.Trigger == triggerAtOnce {
+		row := sr.computeRow(start, w)
+		sr.mu.Unlock()
+		sm.emit(sr, row)
+		sr.mu.Lock()
+	} else if sr.spec.Trigger == triggerMaxDelay && w.timer == nil {
+		closeAt := start
+		w.timer = time.AfterFunc(sr.spec.MaxDelay, func() {
+			sr.mu.Lock()
+			ws, ok := sr.windows[closeAt]
+			if !ok {
+				sr.mu.Unlock()
+				return
+			}
+			row := sr.computeRow(closeAt, ws)
+			delete(sr.windows, closeAt)
+			sr.mu.Unlock()
+			sm.emit(sr, row)
+		})
+	}
+
+	if watermarkMs > sr.watermark {
+		sr.watermark = watermarkMs
+	}
+	sr.closeEligibleWindows(sm, intervalMs)
+	sr.mu.Unlock()
+}
+
+// closeEligibleWindows closes every window ending at or before the current
+// watermark, in order, filling gaps per spec.Fill for windows that never
+// saw a row. Caller must hold sr.mu.
+func (sr *streamRuntime) closeEligibleWindows(sm *StreamManager, intervalMs int64) {
+	for sr.haveNext && sr.nextWindow+intervalMs <= sr.watermark {
+		start := sr.nextWindow
+		if w, ok := sr.windows[start]; ok {
+			if w.timer != nil {
+				w.timer.Stop()
+			}
+			row := sr.computeRow(start, w)
+			delete(sr.windows, start)
+			sr.mu.Unlock()
+			sm.emit(sr, row)
+			sr.mu.Lock()
+		} else if row, ok := sr.fillRow(start); ok {
+			sr.mu.Unlock()
+			sm.emit(sr, row)
+			sr.mu.Lock()
+		}
+		sr.nextWindow += intervalMs
+	}
+}
+
+// computeRow evaluates every SELECT expression against w's running
+// aggregates and records the result as sr.lastEmitted, for FILL(PREV).
+func (sr *streamRuntime) computeRow(windowStart int64, w *windowAgg) map[string]interface{} {
+	row := map[string]interface{}{"window_start": windowStart}
+	values := make(map[string]float64, len(sr.spec.Exprs))
+	for _, expr := range sr.spec.Exprs {
+		var v float64
+		switch expr.Func {
+		case aggCount:
+			v = float64(w.count)
+		case aggSum:
+			v = w.sums[expr.Column]
+		case aggAvg:
+			if w.count > 0 {
+				v = w.sums[expr.Column] / float64(w.count)
+			}
+		case aggMin:
+			v = w.mins[expr.Column]
+		case aggMax:
+			v = w.maxs[expr.Column]
+		case aggFirst:
+			v = w.firsts[expr.Column]
+		case aggLast:
+			v = w.lasts[expr.Column]
+		}
+		row[expr.Alias] = v
+		values[expr.Alias] = v
+	}
+	sr.lastEmitted = values
+	sr.haveEmitted = true
+	return row
+}
+
+// fillRow builds a synthetic row for a window that closed with no data, per
+// spec.Fill. It returns false when the window should simply be skipped
+// (FillNone, or FillPrev/FillLinear before anything has ever been emitted).
+func (sr *streamRuntime) fillRow(windowStart int64) (map[string]interface{}, bool) {
+	switch sr.spec.Fill {
+	case FillNone:
+		return nil, false
+	case FillNull:
+		row := map[string]interface{}{"window_start": windowStart}
+		for _, expr := range sr.spec.Exprs {
+			row[expr.Alias] = nil
+		}
+		return row, true
+	case FillValue:
+		row := map[string]interface{}{"window_start": windowStart}
+		for _, expr := range sr.spec.Exprs {
+			row[expr.Alias] = sr.spec.FillValue
+		}
+		return row, true
+	case FillPrev, FillLinear:
+		// True FILL(LINEAR) needs the next real window's value, which isn't
+		// known yet when a window closes in streaming order, so it falls
+		// back to the previous value exactly like FILL(PREV).
+		if !sr.haveEmitted {
+			return nil, false
+		}
+		row := map[string]interface{}{"window_start": windowStart}
+		for _, expr := range sr.spec.Exprs {
+			row[expr.Alias] = sr.lastEmitted[expr.Alias]
+		}
+		return row, true
+	default:
+		return nil, false
+	}
+}
+
+// emit writes one closed (or filled) window's row into the stream's target
+// table via its executor.
+func (sm *StreamManager) emit(sr *streamRuntime, row map[string]interface{}) {
+	cols := make([]string, 0, len(row))
+	vals := make([]string, 0, len(row))
+	cols = append(cols, "ts")
+	vals = append(vals, strconv.FormatInt(row["window_start"].(int64), 10))
+	for _, expr := range sr.spec.Exprs {
+		cols = append(cols, expr.Alias)
+		v := row[expr.Alias]
+		if v == nil {
+			vals = append(vals, "NULL")
+		} else {
+			vals = append(vals, strconv.FormatFloat(v.(float64), 'f', -1, 64))
+		}
+	}
+
+	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		sr.spec.Def.TargetTable, strings.Join(cols, ", "), strings.Join(vals, ", "))
+	sm.executor.Execute(sr.spec.Def.Database, sql, nil)
+}
+
+func numericValue(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// createStreamPattern matches CREATE STREAM <name> TRIGGER <mode>[ <dur>]
+// WATERMARK <dur> INTO <target> AS <select...>.
+var createStreamPattern = regexp.MustCompile(
+	`(?is)^\s*CREATE\s+STREAM\s+(\S+)\s+TRIGGER\s+(AT_ONCE|WINDOW_CLOSE|MAX_DELAY)(?:\s+(\S+))?\s+WATERMARK\s+(\S+)\s+INTO\s+(\S+)\s+AS\s+(SELECT\s.+)$`)
+
+var (
+	reStreamFrom     = regexp.MustCompile(`(?is)FROM\s+(\S+)`)
+	reStreamInterval = regexp.MustCompile(`(?is)INTERVAL\s*\(\s*([^)]+)\)`)
+	reStreamFill     = regexp.MustCompile(`(?is)FILL\s*\(\s*([^)]+)\)`)
+	reStreamIgnExp   = regexp.MustCompile(`(?i)IGNORE\s+EXPIRED`)
+	reStreamIgnUpd   = regexp.MustCompile(`(?i)IGNORE\s+UPDATE`)
+	reSelectList     = regexp.MustCompile(`(?is)^SELECT\s+(.+?)\s+FROM\s`)
+	reAggExpr        = regexp.MustCompile(`(?i)^(\w+)\(\s*([^)]*?)\s*\)(?:\s+AS\s+(\w+))?$`)
+)
+
+// parseCreateStreamSQL parses a CREATE STREAM statement into a streamSpec.
+func parseCreateStreamSQL(sql string) (streamSpec, error) {
+	m := createStreamPattern.FindStringSubmatch(strings.TrimSpace(sql))
+	if m == nil {
+		return streamSpec{}, fmt.Errorf("not a recognized CREATE STREAM statement")
+	}
+
+	spec := streamSpec{
+		Def: StreamDefinition{
+			Name:          m[1],
+			TargetTable:   m[5],
+			Trigger:       strings.ToLower(m[2]),
+			Watermark:     m[4],
+			IgnoreExpired: reStreamIgnExp.MatchString(sql),
+			IgnoreUpdate:  reStreamIgnUpd.MatchString(sql),
+		},
+	}
+
+	switch strings.ToUpper(m[2]) {
+	case "AT_ONCE":
+		spec.Trigger = triggerAtOnce
+	case "MAX_DELAY":
+		spec.Trigger = triggerMaxDelay
+		if m[3] == "" {
+			return streamSpec{}, fmt.Errorf("MAX_DELAY requires a duration")
+		}
+		dur, err := parseTDuration(m[3])
+		if err != nil {
+			return streamSpec{}, fmt.Errorf("invalid MAX_DELAY duration: %w", err)
+		}
+		spec.MaxDelay = dur
+		spec.Def.MaxDelay = m[3]
+	default:
+		spec.Trigger = triggerWindowClose
+	}
+
+	watermark, err := parseTDuration(m[4])
+	if err != nil {
+		return streamSpec{}, fmt.Errorf("invalid WATERMARK duration: %w", err)
+	}
+	spec.Watermark = watermark
+
+	selectClause := m[6]
+	fromMatch := reStreamFrom.FindStringSubmatch(selectClause)
+	if fromMatch == nil {
+		return streamSpec{}, fmt.Errorf("CREATE STREAM's AS SELECT is missing a FROM clause")
+	}
+	spec.Source = fromMatch[1]
+	spec.Def.SourceTable = spec.Source
+
+	intervalMatch := reStreamInterval.FindStringSubmatch(selectClause)
+	if intervalMatch == nil {
+		return streamSpec{}, fmt.Errorf("CREATE STREAM's AS SELECT is missing INTERVAL(...)")
+	}
+	interval, err := parseTDuration(intervalMatch[1])
+	if err != nil {
+		return streamSpec{}, fmt.Errorf("invalid INTERVAL duration: %w", err)
+	}
+	spec.Interval = interval
+	spec.Def.Interval = intervalMatch[1]
+
+	if fillMatch := reStreamFill.FindStringSubmatch(selectClause); fillMatch != nil {
+		spec.Def.Fill = fillMatch[1]
+		fillSpec := strings.Fields(strings.ToUpper(fillMatch[1]))
+		switch fillSpec[0] {
+		case "NULL":
+			spec.Fill = FillNull
+		case "PREV":
+			spec.Fill = FillPrev
+		case "LINEAR":
+			spec.Fill = FillLinear
+		case "VALUE":
+			spec.Fill = FillValue
+			if len(fillSpec) > 1 {
+				if v, err := strconv.ParseFloat(fillSpec[1], 64); err == nil {
+					spec.FillValue = v
+				}
+			}
+		default:
+			spec.Fill = FillNone
+		}
+	} else {
+		spec.Fill = FillNone
+	}
+
+	listMatch := reSelectList.FindStringSubmatch(selectClause)
+	if listMatch == nil {
+		return streamSpec{}, fmt.Errorf("could not parse CREATE STREAM's SELECT list")
+	}
+	for _, item := range splitTopLevel(listMatch[1]) {
+		exprMatch := reAggExpr.FindStringSubmatch(strings.TrimSpace(item))
+		if exprMatch == nil {
+			continue // not an aggregate expression (e.g. a bare column); streams only aggregate
+		}
+		fn, ok := aggFuncNames[strings.ToUpper(exprMatch[1])]
+		if !ok {
+			continue
+		}
+		column := strings.TrimSpace(exprMatch[2])
+		alias := exprMatch[3]
+		if alias == "" {
+			alias = strings.ToLower(exprMatch[1])
+			if column != "*" {
+				alias += "_" + column
+			}
+		}
+		spec.Exprs = append(spec.Exprs, streamAggExpr{Func: fn, Column: column, Alias: alias})
+	}
+	if len(spec.Exprs) == 0 {
+		return streamSpec{}, fmt.Errorf("CREATE STREAM's SELECT list has no supported aggregate expressions")
+	}
+
+	return spec, nil
+}
+
+// splitTopLevel splits s on commas that are not nested inside parentheses.
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth := 0
+	start := 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, s[start:])
+	return parts
+}
+
+// parseTDuration parses a TDengine-style duration like "1m", "30s", "2h".
+func parseTDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+	unit := s[len(s)-1:]
+	numPart := s[:len(s)-1]
+	n, err := strconv.ParseInt(numPart, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	switch strings.ToLower(unit) {
+	case "a": // TDengine milliseconds
+		return time.Duration(n) * time.Millisecond, nil
+	case "s":
+		return time.Duration(n) * time.Second, nil
+	case "m":
+		return time.Duration(n) * time.Minute, nil
+	case "h":
+		return time.Duration(n) * time.Hour, nil
+	case "d":
+		return time.Duration(n) * 24 * time.Hour, nil
+	case "w":
+		return time.Duration(n) * 7 * 24 * time.Hour, nil
+	default:
+		return 0, fmt.Errorf("unsupported duration unit: %s", unit)
+	}
+}
+
+// parseDropStreamSQL recognizes DROP STREAM [IF EXISTS] <name>.
+func parseDropStreamSQL(sql string) (string, bool) {
+	re := regexp.MustCompile(`(?is)^\s*DROP\s+STREAM\s+(?:IF\s+EXISTS\s+)?(\S+)\s*;?\s*$`)
+	m := re.FindStringSubmatch(strings.TrimSpace(sql))
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// isShowStreamsSQL recognizes SHOW STREAMS.
+func isShowStreamsSQL(sql string) bool {
+	re := regexp.MustCompile(`(?is)^\s*SHOW\s+STREAMS\s*;?\s*$`)
+	return re.MatchString(strings.TrimSpace(sql))
+}
+
+// handleStreamSQL answers CREATE STREAM, SHOW STREAMS and DROP STREAM
+// directly, reporting handled=false for any other statement so the caller
+// falls through to the engine as usual.
+func (a *API) handleStreamSQL(db, sql string) (*Response, bool) {
+	if isShowStreamsSQL(sql) {
+		defs := a.streams.ShowStreams()
+		data := make([][]interface{}, len(defs))
+		for i, d := range defs {
+			data[i] = []interface{}{d.Name, d.Database, d.SourceTable, d.TargetTable, d.Trigger, d.Interval, d.Watermark}
+		}
+		return &Response{
+			Code: TSDB_CODE_SUCCESS,
+			ColumnMeta: [][]interface{}{
+				{"name", "VARCHAR", 64}, {"database", "VARCHAR", 64}, {"source_table", "VARCHAR", 64},
+				{"target_table", "VARCHAR", 64}, {"trigger", "VARCHAR", 16}, {"interval", "VARCHAR", 16},
+				{"watermark", "VARCHAR", 16},
+			},
+			Data: data,
+			Rows: len(data),
+		}, true
+	}
+
+	if name, ok := parseDropStreamSQL(sql); ok {
+		if err := a.streams.DropStream(name); err != nil {
+			return &Response{Code: TSDB_CODE_FAILED, Desc: err.Error()}, true
+		}
+		return &Response{Code: TSDB_CODE_SUCCESS}, true
+	}
+
+	if _, err := parseCreateStreamSQL(sql); err == nil {
+		if _, err := a.streams.CreateStream(db, sql); err != nil {
+			return &Response{Code: TSDB_CODE_FAILED, Desc: err.Error()}, true
+		}
+		return &Response{Code: TSDB_CODE_SUCCESS}, true
+	}
+
+	return nil, false
+}
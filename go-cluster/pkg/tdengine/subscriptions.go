@@ -0,0 +1,440 @@
+package tdengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lumadb/cluster/pkg/meta"
+)
+
+// SubscriptionMode controls how a Subscription spreads writes across its
+// Destinations: SubscriptionAll fans every write out to every destination,
+// SubscriptionAny load-balances by routing each write to exactly one -
+// InfluxDB subscriptions' own ALL/ANY modes.
+type SubscriptionMode string
+
+const (
+	SubscriptionAll SubscriptionMode = "ALL"
+	SubscriptionAny SubscriptionMode = "ANY"
+)
+
+const (
+	defaultSubscriptionWorkers  = 4
+	subscriptionQueueSize       = 1024
+	subscriptionMaxAttempts     = 3
+	subscriptionBaseBackoff     = 250 * time.Millisecond
+	subscriptionMaxBackoff      = 4 * time.Second
+	subscriptionDeliveryTimeout = 5 * time.Second
+)
+
+// Subscription is one CREATE SUBSCRIPTION entry: a named fan-out/load-balance
+// target for every write accepted against Database, optionally narrowed to
+// just Measurement.
+type Subscription struct {
+	Name         string
+	Database     string
+	Measurement  string // empty matches every measurement in Database
+	Mode         SubscriptionMode
+	Destinations []string
+	CreatedAt    time.Time
+}
+
+// metaSubscriptionInfo converts sub to the shape meta.MetaClient replicates.
+func metaSubscriptionInfo(sub Subscription) meta.SubscriptionInfo {
+	return meta.SubscriptionInfo{
+		Name:         sub.Name,
+		Database:     sub.Database,
+		Measurement:  sub.Measurement,
+		Mode:         string(sub.Mode),
+		Destinations: sub.Destinations,
+	}
+}
+
+// subscriptionForward is one line-protocol payload queued for delivery to a
+// single destination.
+type subscriptionForward struct {
+	destination string
+	payload     []byte
+}
+
+// SubscriptionManager holds CREATE SUBSCRIPTION entries, persisting them as
+// JSON at path, and asynchronously forwards matching writes to their
+// destinations over a bounded worker pool. A destination that's down is
+// retried a few times with exponential backoff; once the pool's queue is
+// full, a forward is dropped rather than blocking the write path that
+// produced it.
+type SubscriptionManager struct {
+	mu            sync.RWMutex
+	path          string
+	subscriptions map[string]*Subscription
+
+	queue   chan subscriptionForward
+	client  *http.Client
+	dropped int64
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewSubscriptionManager creates a SubscriptionManager persisting
+// subscriptions to path, loading any that already exist there, and starts
+// workers delivery goroutines (defaultSubscriptionWorkers if workers <= 0).
+func NewSubscriptionManager(path string, workers int) (*SubscriptionManager, error) {
+	if workers <= 0 {
+		workers = defaultSubscriptionWorkers
+	}
+
+	sm := &SubscriptionManager{
+		path:          path,
+		subscriptions: make(map[string]*Subscription),
+		queue:         make(chan subscriptionForward, subscriptionQueueSize),
+		client:        &http.Client{Timeout: subscriptionDeliveryTimeout},
+		stop:          make(chan struct{}),
+	}
+	if err := sm.load(); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < workers; i++ {
+		sm.wg.Add(1)
+		go sm.worker()
+	}
+	return sm, nil
+}
+
+func (sm *SubscriptionManager) load() error {
+	data, err := os.ReadFile(sm.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var subs []*Subscription
+	if err := json.Unmarshal(data, &subs); err != nil {
+		return err
+	}
+	for _, s := range subs {
+		sm.subscriptions[s.Name] = s
+	}
+	return nil
+}
+
+func (sm *SubscriptionManager) save() error {
+	subs := make([]*Subscription, 0, len(sm.subscriptions))
+	for _, s := range sm.subscriptions {
+		subs = append(subs, s)
+	}
+	data, err := json.MarshalIndent(subs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(sm.path, data, 0644)
+}
+
+// CreateSubscription registers sub, replacing any existing subscription of
+// the same name.
+func (sm *SubscriptionManager) CreateSubscription(sub Subscription) error {
+	sub.CreatedAt = time.Now()
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.subscriptions[sub.Name] = &sub
+	return sm.save()
+}
+
+// DropSubscription removes name, if registered.
+func (sm *SubscriptionManager) DropSubscription(name string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	delete(sm.subscriptions, name)
+	return sm.save()
+}
+
+// ShowSubscriptions returns every registered subscription.
+func (sm *SubscriptionManager) ShowSubscriptions() []*Subscription {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	out := make([]*Subscription, 0, len(sm.subscriptions))
+	for _, s := range sm.subscriptions {
+		out = append(out, s)
+	}
+	return out
+}
+
+// Dropped returns the number of forwards dropped so far, either because the
+// worker pool's queue was full or because every delivery attempt failed.
+func (sm *SubscriptionManager) Dropped() int64 {
+	return atomic.LoadInt64(&sm.dropped)
+}
+
+// Stop signals every delivery worker to finish its current forward and
+// exit.
+func (sm *SubscriptionManager) Stop() {
+	close(sm.stop)
+	sm.wg.Wait()
+}
+
+// Forward queues payload for delivery to every subscription registered
+// against database whose Measurement is empty or matches measurement. It
+// returns immediately; delivery happens on the worker pool.
+func (sm *SubscriptionManager) Forward(database, measurement string, payload []byte) {
+	sm.mu.RLock()
+	var matches []*Subscription
+	for _, s := range sm.subscriptions {
+		if s.Database != database {
+			continue
+		}
+		if s.Measurement != "" && s.Measurement != measurement {
+			continue
+		}
+		matches = append(matches, s)
+	}
+	sm.mu.RUnlock()
+
+	for _, s := range matches {
+		sm.enqueue(s, payload)
+	}
+}
+
+func (sm *SubscriptionManager) enqueue(s *Subscription, payload []byte) {
+	destinations := s.Destinations
+	if len(destinations) == 0 {
+		return
+	}
+	if s.Mode == SubscriptionAny {
+		i := rand.Intn(len(destinations))
+		destinations = destinations[i : i+1]
+	}
+
+	for _, dest := range destinations {
+		select {
+		case sm.queue <- subscriptionForward{destination: dest, payload: payload}:
+		default:
+			atomic.AddInt64(&sm.dropped, 1)
+		}
+	}
+}
+
+func (sm *SubscriptionManager) worker() {
+	defer sm.wg.Done()
+	for {
+		select {
+		case <-sm.stop:
+			return
+		case fwd := <-sm.queue:
+			sm.deliver(fwd)
+		}
+	}
+}
+
+// deliver POSTs fwd.payload to fwd.destination, making up to
+// subscriptionMaxAttempts attempts with exponential backoff before giving up
+// and counting it as dropped.
+func (sm *SubscriptionManager) deliver(fwd subscriptionForward) {
+	backoff := subscriptionBaseBackoff
+	for attempt := 0; attempt < subscriptionMaxAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > subscriptionMaxBackoff {
+				backoff = subscriptionMaxBackoff
+			}
+		}
+
+		req, err := http.NewRequest(http.MethodPost, fwd.destination, bytes.NewReader(fwd.payload))
+		if err != nil {
+			return
+		}
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+		resp, err := sm.client.Do(req)
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 300 {
+			return
+		}
+	}
+	atomic.AddInt64(&sm.dropped, 1)
+}
+
+var (
+	reCreateSubscription = regexp.MustCompile(`(?is)^\s*CREATE\s+SUBSCRIPTION\s+` +
+		"`?([A-Za-z_][A-Za-z0-9_]*)`?" + `\s+ON\s+` +
+		"`?([A-Za-z_][A-Za-z0-9_]*)`?" +
+		"(?:\\.`?([A-Za-z_][A-Za-z0-9_]*)`?)?" +
+		`\s+DESTINATIONS\s+(ALL|ANY)\s+(.+?)\s*;?\s*$`)
+	reSubscriptionDestination = regexp.MustCompile(`'([^']*)'`)
+	reDropSubscription        = regexp.MustCompile(`(?is)^\s*DROP\s+SUBSCRIPTION\s+` +
+		"`?([A-Za-z_][A-Za-z0-9_]*)`?" + `\s*;?\s*$`)
+	reShowSubscriptions = regexp.MustCompile(`(?is)^\s*SHOW\s+SUBSCRIPTIONS\s*;?\s*$`)
+	reInsertMeasurement = regexp.MustCompile(`(?is)^\s*INSERT\s+INTO\s+` +
+		"`?([A-Za-z_][A-Za-z0-9_]*)`?")
+)
+
+// parseCreateSubscriptionSQL parses `CREATE SUBSCRIPTION <name> ON
+// <db>[.<measurement>] DESTINATIONS ALL|ANY '<url>' ['<url>'...]`.
+func parseCreateSubscriptionSQL(sql string) (Subscription, bool) {
+	m := reCreateSubscription.FindStringSubmatch(sql)
+	if m == nil {
+		return Subscription{}, false
+	}
+
+	destMatches := reSubscriptionDestination.FindAllStringSubmatch(m[5], -1)
+	if len(destMatches) == 0 {
+		return Subscription{}, false
+	}
+	destinations := make([]string, len(destMatches))
+	for i, d := range destMatches {
+		destinations[i] = d[1]
+	}
+
+	return Subscription{
+		Name:         m[1],
+		Database:     m[2],
+		Measurement:  m[3],
+		Mode:         SubscriptionMode(strings.ToUpper(m[4])),
+		Destinations: destinations,
+	}, true
+}
+
+// handleSubscriptionSQL answers CREATE SUBSCRIPTION, DROP SUBSCRIPTION and
+// SHOW SUBSCRIPTIONS directly, reporting handled=false for any other
+// statement so the caller falls through to the engine as usual.
+func (a *API) handleSubscriptionSQL(sql string) (*Response, bool) {
+	sql = strings.TrimSpace(sql)
+
+	if sub, ok := parseCreateSubscriptionSQL(sql); ok {
+		if err := a.subscriptions.CreateSubscription(sub); err != nil {
+			return &Response{Code: TSDB_CODE_FAILED, Desc: err.Error()}, true
+		}
+		a.recordMetaSubscription(sub)
+		return &Response{Code: TSDB_CODE_SUCCESS}, true
+	}
+
+	if m := reDropSubscription.FindStringSubmatch(sql); m != nil {
+		if err := a.subscriptions.DropSubscription(m[1]); err != nil {
+			return &Response{Code: TSDB_CODE_FAILED, Desc: err.Error()}, true
+		}
+		return &Response{Code: TSDB_CODE_SUCCESS}, true
+	}
+
+	if reShowSubscriptions.MatchString(sql) {
+		subs := a.subscriptions.ShowSubscriptions()
+		data := make([][]interface{}, len(subs))
+		for i, s := range subs {
+			data[i] = []interface{}{
+				s.Name, s.Database, s.Measurement, string(s.Mode),
+				strings.Join(s.Destinations, ","), s.CreatedAt.Format(time.RFC3339),
+			}
+		}
+		return &Response{
+			Code: TSDB_CODE_SUCCESS,
+			ColumnMeta: [][]interface{}{
+				{"name", "VARCHAR", 64}, {"database", "VARCHAR", 64}, {"measurement", "VARCHAR", 64},
+				{"mode", "VARCHAR", 8}, {"destinations", "VARCHAR", 1024}, {"create_time", "VARCHAR", 32},
+			},
+			Data: data,
+			Rows: len(data),
+		}, true
+	}
+
+	return nil, false
+}
+
+// forwardInsert forwards sql - an already-executed INSERT statement's raw
+// text - to every subscription on db matching its target table. The
+// InfluxDB schemaless write path has real field/tag data to render into
+// line protocol (see renderLineProtocol); a SQL INSERT's column values are
+// only meaningful together with the target table's schema, which lives in
+// the engine and isn't available here, so the best this layer can forward
+// for a SQL-originated write is the statement itself.
+func (a *API) forwardInsert(db, sql string) {
+	if a.subscriptions == nil {
+		return
+	}
+	m := reInsertMeasurement.FindStringSubmatch(sql)
+	if m == nil {
+		return
+	}
+	a.subscriptions.Forward(db, m[1], []byte(sql))
+}
+
+// renderLineProtocol serializes p back into InfluxDB line-protocol text,
+// the form InfluxDBWrite forwards a schemaless write to matching
+// subscriptions in.
+func renderLineProtocol(p *InfluxDBLineProtocol) []byte {
+	var b strings.Builder
+	b.WriteString(escapeLPIdentifier(p.Measurement))
+
+	tagKeys := make([]string, 0, len(p.Tags))
+	for k := range p.Tags {
+		tagKeys = append(tagKeys, k)
+	}
+	sort.Strings(tagKeys)
+	for _, k := range tagKeys {
+		b.WriteByte(',')
+		b.WriteString(escapeLPIdentifier(k))
+		b.WriteByte('=')
+		b.WriteString(escapeLPIdentifier(p.Tags[k]))
+	}
+
+	b.WriteByte(' ')
+
+	fieldKeys := make([]string, 0, len(p.Fields))
+	for k := range p.Fields {
+		fieldKeys = append(fieldKeys, k)
+	}
+	sort.Strings(fieldKeys)
+	for i, k := range fieldKeys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(escapeLPIdentifier(k))
+		b.WriteByte('=')
+		b.WriteString(renderLPFieldValue(p.Fields[k]))
+	}
+
+	fmt.Fprintf(&b, " %d", p.Timestamp)
+	return []byte(b.String())
+}
+
+func escapeLPIdentifier(s string) string {
+	return strings.NewReplacer(" ", `\ `, ",", `\,`, "=", `\=`).Replace(s)
+}
+
+func renderLPFieldValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return `"` + strings.ReplaceAll(val, `"`, `\"`) + `"`
+	case bool:
+		if val {
+			return "true"
+		}
+		return "false"
+	case int64:
+		return strconv.FormatInt(val, 10) + "i"
+	case uint64:
+		return strconv.FormatUint(val, 10) + "u"
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
@@ -0,0 +1,143 @@
+package tdengine
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseCreateSubscriptionSQL(t *testing.T) {
+	sql := "CREATE SUBSCRIPTION sink1 ON test.cpu DESTINATIONS ALL 'http://sink1' 'http://sink2'"
+
+	sub, ok := parseCreateSubscriptionSQL(sql)
+	if !ok {
+		t.Fatalf("parseCreateSubscriptionSQL(%q) ok = false, want true", sql)
+	}
+	if sub.Name != "sink1" || sub.Database != "test" || sub.Measurement != "cpu" {
+		t.Errorf("Name/Database/Measurement = %q/%q/%q, want sink1/test/cpu", sub.Name, sub.Database, sub.Measurement)
+	}
+	if sub.Mode != SubscriptionAll {
+		t.Errorf("Mode = %q, want ALL", sub.Mode)
+	}
+	if len(sub.Destinations) != 2 || sub.Destinations[0] != "http://sink1" || sub.Destinations[1] != "http://sink2" {
+		t.Errorf("Destinations = %v, want [http://sink1 http://sink2]", sub.Destinations)
+	}
+}
+
+func TestParseCreateSubscriptionSQL_NoMeasurementQualifier(t *testing.T) {
+	sub, ok := parseCreateSubscriptionSQL("CREATE SUBSCRIPTION all_writes ON test DESTINATIONS ANY 'http://sink'")
+	if !ok {
+		t.Fatal("parseCreateSubscriptionSQL() ok = false, want true")
+	}
+	if sub.Measurement != "" {
+		t.Errorf("Measurement = %q, want empty (matches every measurement)", sub.Measurement)
+	}
+	if sub.Mode != SubscriptionAny {
+		t.Errorf("Mode = %q, want ANY", sub.Mode)
+	}
+}
+
+func TestSubscriptionManager_ForwardFanOutToAllDestinations(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		received = append(received, string(body))
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer sink.Close()
+
+	path := filepath.Join(t.TempDir(), "subscriptions.json")
+	sm, err := NewSubscriptionManager(path, 2)
+	if err != nil {
+		t.Fatalf("NewSubscriptionManager() error = %v", err)
+	}
+	defer sm.Stop()
+
+	if err := sm.CreateSubscription(Subscription{
+		Name: "fanout", Database: "test", Mode: SubscriptionAll,
+		Destinations: []string{sink.URL, sink.URL},
+	}); err != nil {
+		t.Fatalf("CreateSubscription() error = %v", err)
+	}
+
+	sm.Forward("test", "cpu", []byte("cpu value=1 1000"))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(received)
+		mu.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 {
+		t.Fatalf("sink received %d forwards, want 2 (ALL mode fans out to both destinations)", len(received))
+	}
+	for _, body := range received {
+		if body != "cpu value=1 1000" {
+			t.Errorf("forwarded payload = %q, want %q", body, "cpu value=1 1000")
+		}
+	}
+}
+
+func TestSubscriptionManager_ForwardIgnoresNonMatchingMeasurement(t *testing.T) {
+	var mu sync.Mutex
+	hits := 0
+
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits++
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer sink.Close()
+
+	path := filepath.Join(t.TempDir(), "subscriptions.json")
+	sm, err := NewSubscriptionManager(path, 1)
+	if err != nil {
+		t.Fatalf("NewSubscriptionManager() error = %v", err)
+	}
+	defer sm.Stop()
+
+	sm.CreateSubscription(Subscription{
+		Name: "cpu_only", Database: "test", Measurement: "cpu", Mode: SubscriptionAll,
+		Destinations: []string{sink.URL},
+	})
+
+	sm.Forward("test", "mem", []byte("mem value=1 1000"))
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if hits != 0 {
+		t.Errorf("hits = %d, want 0 (subscription is scoped to measurement cpu, not mem)", hits)
+	}
+}
+
+func TestRenderLineProtocol(t *testing.T) {
+	p := &InfluxDBLineProtocol{
+		Measurement: "cpu",
+		Tags:        map[string]string{"host": "a"},
+		Fields:      map[string]interface{}{"value": 42.5},
+		Timestamp:   1000,
+	}
+	got := string(renderLineProtocol(p))
+	want := "cpu,host=a value=42.5 1000"
+	if got != want {
+		t.Errorf("renderLineProtocol() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,430 @@
+package tdengine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tmqPartitions is the fixed number of partitions every topic's ring buffer
+// is split into. TDengine itself shards a topic across a variable number of
+// vgroups; a fixed count here keeps sticky rebalancing simple while still
+// giving a consumer group something to spread across.
+const tmqPartitions = 4
+
+// tmqRingCapacity bounds how many messages each partition retains before the
+// oldest are evicted, so a disconnected consumer group can't grow a topic's
+// memory use without bound.
+const tmqRingCapacity = 10000
+
+// TMQMessage is one row delivered to a TMQ consumer.
+type TMQMessage struct {
+	Partition int             `json:"partition"`
+	Offset    int64           `json:"offset"`
+	Value     json.RawMessage `json:"value"`
+	Timestamp int64           `json:"timestamp"`
+}
+
+// partitionRing is a fixed-capacity circular buffer of TMQMessages for one
+// topic partition. It tracks base, the offset of the oldest message still
+// retained, so a consumer asking for an evicted offset gets fast-forwarded
+// to the oldest one available rather than waiting forever.
+type partitionRing struct {
+	mu       sync.Mutex
+	messages []TMQMessage
+	base     int64
+	next     int64
+	capacity int
+	waitCh   chan struct{}
+}
+
+func newPartitionRing(capacity int) *partitionRing {
+	return &partitionRing{
+		messages: make([]TMQMessage, 0, capacity),
+		capacity: capacity,
+		waitCh:   make(chan struct{}),
+	}
+}
+
+func (r *partitionRing) append(partition int, value json.RawMessage) TMQMessage {
+	r.mu.Lock()
+	msg := TMQMessage{Partition: partition, Offset: r.next, Value: value, Timestamp: time.Now().UnixMilli()}
+	r.messages = append(r.messages, msg)
+	r.next++
+	if len(r.messages) > r.capacity {
+		r.messages = r.messages[1:]
+		r.base++
+	}
+	ch := r.waitCh
+	r.waitCh = make(chan struct{})
+	r.mu.Unlock()
+	close(ch)
+	return msg
+}
+
+// since returns up to limit messages starting at offset (or the oldest
+// retained offset, if offset has already been evicted).
+func (r *partitionRing) since(offset int64, limit int) []TMQMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if offset < r.base {
+		offset = r.base
+	}
+	start := int(offset - r.base)
+	if start >= len(r.messages) {
+		return nil
+	}
+	end := start + limit
+	if end > len(r.messages) {
+		end = len(r.messages)
+	}
+	out := make([]TMQMessage, end-start)
+	copy(out, r.messages[start:end])
+	return out
+}
+
+// wait blocks until either a new message is appended, ctx is done, or
+// deadline passes, whichever comes first. It returns false on timeout.
+func (r *partitionRing) wait(ctx context.Context, deadline time.Time) bool {
+	r.mu.Lock()
+	ch := r.waitCh
+	r.mu.Unlock()
+
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return false
+	}
+	timer := time.NewTimer(remaining)
+	defer timer.Stop()
+
+	select {
+	case <-ch:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Topic is a registered `CREATE TOPIC ... AS SELECT` definition plus the
+// ring buffers that back it - one per partition, fed by Publish whenever a
+// matching row is written.
+type Topic struct {
+	Def        TopicDefinition
+	partitions [tmqPartitions]*partitionRing
+}
+
+func newTopic(def TopicDefinition) *Topic {
+	t := &Topic{Def: def}
+	for i := range t.partitions {
+		t.partitions[i] = newPartitionRing(tmqRingCapacity)
+	}
+	return t
+}
+
+// partitionFor hashes key (typically a row's subtable key, see
+// InfluxDBLineProtocol.SubtableKey) so every row for the same series always
+// lands in the same partition and is therefore delivered to consumers in
+// the order it was written.
+func partitionFor(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % tmqPartitions)
+}
+
+// Publish appends value, keyed by key, to the topic's ring buffer.
+func (t *Topic) Publish(key string, value interface{}) (TMQMessage, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return TMQMessage{}, err
+	}
+	p := partitionFor(key)
+	return t.partitions[p].append(p, data), nil
+}
+
+// matchesTable reports whether t's backing SQL selects from table. This is
+// a plain substring check on the FROM clause, not a real predicate
+// evaluator - good enough to route a row to the right topics, but it can't
+// tell whether the row would also satisfy a WHERE clause in Def.SQL.
+func (t *Topic) matchesTable(table string) bool {
+	re := regexp.MustCompile(`(?is)FROM\s+` + regexp.QuoteMeta(table) + `\b`)
+	return re.MatchString(t.Def.SQL)
+}
+
+// createTopicPattern matches `CREATE TOPIC <name> AS <select ...>`.
+var createTopicPattern = regexp.MustCompile(`(?is)^\s*CREATE\s+TOPIC\s+(?:IF\s+NOT\s+EXISTS\s+)?(\S+)\s+AS\s+(SELECT\s.+)$`)
+
+// parseCreateTopicSQL recognizes a CREATE TOPIC ... AS SELECT ... statement
+// and returns the TopicDefinition it describes. It reports false for any
+// other statement, including CREATE TOPIC's other TDengine forms (AS
+// STABLE, AS DATABASE) which aren't backed by a ring buffer and so fall
+// through to the engine unchanged.
+func parseCreateTopicSQL(sql string) (TopicDefinition, bool) {
+	m := createTopicPattern.FindStringSubmatch(strings.TrimSpace(sql))
+	if m == nil {
+		return TopicDefinition{}, false
+	}
+	return TopicDefinition{Name: m[1], SQL: strings.TrimSpace(m[2])}, true
+}
+
+// ConsumerGroup tracks membership, sticky partition assignment, and
+// per-partition committed offsets for one topic + group_id pair. Offsets
+// are shared across the whole group, TMQ/Kafka-style, so a rebalance hands
+// a partition to its new owner starting exactly where the old one left off.
+type ConsumerGroup struct {
+	mu         sync.Mutex
+	topic      *Topic
+	groupID    string
+	members    []string
+	assignment map[string][]int
+	offsets    [tmqPartitions]int64
+}
+
+// rebalance assigns each partition to the member with the highest
+// rendezvous (HRW) hash score for that partition. HRW makes the assignment
+// sticky for free: a partition's owner only changes if the member set
+// change alters *that partition's* top-scoring member, so one consumer
+// joining or leaving doesn't reshuffle partitions it never owned.
+func (g *ConsumerGroup) rebalance() {
+	assignment := make(map[string][]int)
+	for p := 0; p < tmqPartitions; p++ {
+		var owner string
+		var best uint64
+		for _, id := range g.members {
+			h := fnv.New64a()
+			h.Write([]byte(id))
+			h.Write([]byte{':'})
+			h.Write([]byte(strconv.Itoa(p)))
+			score := h.Sum64()
+			if owner == "" || score > best {
+				owner, best = id, score
+			}
+		}
+		if owner != "" {
+			assignment[owner] = append(assignment[owner], p)
+		}
+	}
+	g.assignment = assignment
+}
+
+func (g *ConsumerGroup) join(clientID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, id := range g.members {
+		if id == clientID {
+			return
+		}
+	}
+	g.members = append(g.members, clientID)
+	g.rebalance()
+}
+
+func (g *ConsumerGroup) leave(clientID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i, id := range g.members {
+		if id == clientID {
+			g.members = append(g.members[:i], g.members[i+1:]...)
+			g.rebalance()
+			return
+		}
+	}
+}
+
+func (g *ConsumerGroup) assignedPartitions(clientID string) []int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	out := make([]int, len(g.assignment[clientID]))
+	copy(out, g.assignment[clientID])
+	return out
+}
+
+func (g *ConsumerGroup) commit(partition int, offset int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.offsets[partition] = offset
+}
+
+func (g *ConsumerGroup) committed(partition int) int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.offsets[partition]
+}
+
+// Consumer is one group member's handle onto a Topic, returned by
+// TopicRegistry.Subscribe.
+type Consumer struct {
+	topic    *Topic
+	group    *ConsumerGroup
+	clientID string
+
+	mu     sync.Mutex
+	cursor map[int]int64
+}
+
+// Assignment reports the partitions currently owned by this consumer.
+func (c *Consumer) Assignment() []int {
+	return c.group.assignedPartitions(c.clientID)
+}
+
+// Poll waits up to timeout for new messages across this consumer's assigned
+// partitions, returning as soon as any partition has something rather than
+// waiting for all of them. A nil, nil result means the timeout elapsed with
+// nothing new, the same empty-poll outcome a real TMQ consumer would see.
+func (c *Consumer) Poll(ctx context.Context, timeout time.Duration) ([]TMQMessage, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		partitions := c.group.assignedPartitions(c.clientID)
+		if len(partitions) == 0 {
+			return nil, fmt.Errorf("consumer %s has no assigned partitions", c.clientID)
+		}
+
+		var out []TMQMessage
+		c.mu.Lock()
+		for _, p := range partitions {
+			msgs := c.topic.partitions[p].since(c.cursor[p], 100)
+			if len(msgs) > 0 {
+				c.cursor[p] = msgs[len(msgs)-1].Offset + 1
+				out = append(out, msgs...)
+			}
+		}
+		c.mu.Unlock()
+		if len(out) > 0 {
+			return out, nil
+		}
+		if !c.topic.partitions[partitions[0]].wait(ctx, deadline) {
+			return nil, nil
+		}
+	}
+}
+
+// Commit advances this consumer's group's committed offset for partition,
+// so a future rebalance hands that partition off starting from offset
+// rather than wherever its new owner's cursor happens to be. Partition is
+// explicit (unlike a single-partition topic) because a sticky-assigned
+// consumer can own more than one.
+func (c *Consumer) Commit(partition int, offset int64) error {
+	if !c.owns(partition) {
+		return fmt.Errorf("consumer %s does not own partition %d", c.clientID, partition)
+	}
+	c.group.commit(partition, offset)
+	return nil
+}
+
+// Seek rewinds this consumer's own read cursor for partition to offset,
+// without touching the group's committed offset, so already-committed
+// messages can be replayed.
+func (c *Consumer) Seek(partition int, offset int64) error {
+	if !c.owns(partition) {
+		return fmt.Errorf("consumer %s does not own partition %d", c.clientID, partition)
+	}
+	c.mu.Lock()
+	c.cursor[partition] = offset
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Consumer) owns(partition int) bool {
+	for _, p := range c.group.assignedPartitions(c.clientID) {
+		if p == partition {
+			return true
+		}
+	}
+	return false
+}
+
+// Close removes this consumer from its group, triggering a sticky
+// rebalance of the partitions it owned onto the group's remaining members.
+func (c *Consumer) Close() {
+	c.group.leave(c.clientID)
+}
+
+// TopicRegistry owns every registered Topic and the ConsumerGroups
+// subscribed to them - the engine-side state behind CREATE TOPIC and
+// /rest/tmq.
+type TopicRegistry struct {
+	mu     sync.RWMutex
+	topics map[string]*Topic
+	groups map[string]*ConsumerGroup
+}
+
+// NewTopicRegistry creates an empty TopicRegistry.
+func NewTopicRegistry() *TopicRegistry {
+	return &TopicRegistry{
+		topics: make(map[string]*Topic),
+		groups: make(map[string]*ConsumerGroup),
+	}
+}
+
+// CreateTopic registers a new topic backed by its own ring buffer. It
+// returns an error if the name is already taken, matching CREATE TABLE's
+// behavior elsewhere in this package.
+func (reg *TopicRegistry) CreateTopic(def TopicDefinition) (*Topic, error) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if _, exists := reg.topics[def.Name]; exists {
+		return nil, fmt.Errorf("topic %s already exists", def.Name)
+	}
+	t := newTopic(def)
+	reg.topics[def.Name] = t
+	return t, nil
+}
+
+// Topic looks up a registered topic by name.
+func (reg *TopicRegistry) Topic(name string) (*Topic, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	t, ok := reg.topics[name]
+	return t, ok
+}
+
+// PublishMatching feeds row into every registered topic in database whose
+// SQL selects from table. The engine's INSERT path would call this after a
+// successful write; see Topic.matchesTable for the (intentionally simple)
+// matching rule.
+func (reg *TopicRegistry) PublishMatching(database, table, subtableKey string, row interface{}) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	for _, t := range reg.topics {
+		if t.Def.Database == database && t.matchesTable(table) {
+			t.Publish(subtableKey, row)
+		}
+	}
+}
+
+func (reg *TopicRegistry) group(t *Topic, groupID string) *ConsumerGroup {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	key := t.Def.Name + "/" + groupID
+	g, ok := reg.groups[key]
+	if !ok {
+		g = &ConsumerGroup{topic: t, groupID: groupID, assignment: make(map[string][]int)}
+		reg.groups[key] = g
+	}
+	return g
+}
+
+// Subscribe joins clientID to topic's groupID consumer group, triggering a
+// sticky rebalance, and returns a Consumer bound to whatever partitions
+// that rebalance assigned it.
+func (reg *TopicRegistry) Subscribe(topic, groupID, clientID string) (*Consumer, error) {
+	t, ok := reg.Topic(topic)
+	if !ok {
+		return nil, fmt.Errorf("unknown topic: %s", topic)
+	}
+	g := reg.group(t, groupID)
+	g.join(clientID)
+
+	c := &Consumer{topic: t, group: g, clientID: clientID, cursor: make(map[int]int64)}
+	for _, p := range g.assignedPartitions(clientID) {
+		c.cursor[p] = g.committed(p)
+	}
+	return c, nil
+}
@@ -0,0 +1,121 @@
+package tdengine
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// tmqUpgrader upgrades /rest/tmq to a WebSocket carrying the JSON
+// message-frame protocol driver-go-style TMQ consumers speak, since polling
+// needs to block server-side between frames rather than round-trip over
+// separate HTTP requests.
+var tmqUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// tmqFrame is the shape of every /rest/tmq message, request and response
+// alike; each action only sets the fields it needs, the rest are omitted.
+type tmqFrame struct {
+	Action     string       `json:"action"`
+	Topic      string       `json:"topic,omitempty"`
+	GroupID    string       `json:"group_id,omitempty"`
+	ClientID   string       `json:"client_id,omitempty"`
+	TimeoutMs  int64        `json:"timeout_ms,omitempty"`
+	Partition  int          `json:"partition,omitempty"`
+	Offset     int64        `json:"offset,omitempty"`
+	Messages   []TMQMessage `json:"messages,omitempty"`
+	Partitions []int        `json:"partitions,omitempty"`
+	Error      string       `json:"error,omitempty"`
+}
+
+// TMQ handles /rest/tmq: a long-lived WebSocket connection a consumer opens
+// once and then drives with subscribe/poll/commit/seek frames. It replies
+// with an "assignment" frame on subscribe (and would on any later
+// rebalance-driven reassignment), mirroring the `{action:"assignment", ...}`
+// frame TMQ consumers expect.
+func (a *API) TMQ(w http.ResponseWriter, r *http.Request) {
+	if a.tmq == nil {
+		http.Error(w, "TMQ is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	conn, err := tmqUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var consumer *Consumer
+	defer func() {
+		if consumer != nil {
+			consumer.Close()
+		}
+	}()
+
+	for {
+		var frame tmqFrame
+		if err := conn.ReadJSON(&frame); err != nil {
+			return
+		}
+
+		switch frame.Action {
+		case "subscribe":
+			if consumer != nil {
+				consumer.Close()
+			}
+			c, err := a.tmq.Subscribe(frame.Topic, frame.GroupID, frame.ClientID)
+			if err != nil {
+				conn.WriteJSON(tmqFrame{Action: "error", Error: err.Error()})
+				continue
+			}
+			consumer = c
+			conn.WriteJSON(tmqFrame{Action: "assignment", Partitions: consumer.Assignment()})
+
+		case "poll":
+			if consumer == nil {
+				conn.WriteJSON(tmqFrame{Action: "error", Error: "not subscribed"})
+				continue
+			}
+			timeout := time.Duration(frame.TimeoutMs) * time.Millisecond
+			if timeout <= 0 {
+				timeout = time.Second
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), timeout+time.Second)
+			msgs, err := consumer.Poll(ctx, timeout)
+			cancel()
+			if err != nil {
+				conn.WriteJSON(tmqFrame{Action: "error", Error: err.Error()})
+				continue
+			}
+			conn.WriteJSON(tmqFrame{Action: "poll", Messages: msgs})
+
+		case "commit":
+			if consumer == nil {
+				conn.WriteJSON(tmqFrame{Action: "error", Error: "not subscribed"})
+				continue
+			}
+			if err := consumer.Commit(frame.Partition, frame.Offset); err != nil {
+				conn.WriteJSON(tmqFrame{Action: "error", Error: err.Error()})
+				continue
+			}
+			conn.WriteJSON(tmqFrame{Action: "commit", Partition: frame.Partition, Offset: frame.Offset})
+
+		case "seek":
+			if consumer == nil {
+				conn.WriteJSON(tmqFrame{Action: "error", Error: "not subscribed"})
+				continue
+			}
+			if err := consumer.Seek(frame.Partition, frame.Offset); err != nil {
+				conn.WriteJSON(tmqFrame{Action: "error", Error: err.Error()})
+				continue
+			}
+			conn.WriteJSON(tmqFrame{Action: "seek", Partition: frame.Partition, Offset: frame.Offset})
+
+		default:
+			conn.WriteJSON(tmqFrame{Action: "error", Error: "unknown action: " + frame.Action})
+		}
+	}
+}
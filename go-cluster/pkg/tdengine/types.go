@@ -34,6 +34,20 @@ type ExecuteOptions struct {
 	RowWithMeta bool
 }
 
+// DefaultChunkSize is the row-batch size /rest/sql/stream uses when the
+// caller doesn't override it via ?chunk_size=, mirroring InfluxDB's own
+// DefaultChunkSize for chunked query execution.
+const DefaultChunkSize = 10000
+
+// RowBatch is one chunk of a streamed query result, as produced by
+// Engine.ExecuteStream and written out as one NDJSON line per batch by
+// API.SQLStream.
+type RowBatch struct {
+	ColumnMeta [][]interface{} `json:"column_meta,omitempty"`
+	Data       [][]interface{} `json:"data"`
+	Rows       int             `json:"rows"`
+}
+
 // ColumnMeta represents column metadata
 type ColumnMeta struct {
 	Name   string `json:"name"`
@@ -43,27 +57,27 @@ type ColumnMeta struct {
 
 // TDengine data type constants
 const (
-	TSDB_DATA_TYPE_NULL      = 0
-	TSDB_DATA_TYPE_BOOL      = 1
-	TSDB_DATA_TYPE_TINYINT   = 2
-	TSDB_DATA_TYPE_SMALLINT  = 3
-	TSDB_DATA_TYPE_INT       = 4
-	TSDB_DATA_TYPE_BIGINT    = 5
-	TSDB_DATA_TYPE_FLOAT     = 6
-	TSDB_DATA_TYPE_DOUBLE    = 7
-	TSDB_DATA_TYPE_BINARY    = 8
-	TSDB_DATA_TYPE_TIMESTAMP = 9
-	TSDB_DATA_TYPE_NCHAR     = 10
-	TSDB_DATA_TYPE_UTINYINT  = 11
-	TSDB_DATA_TYPE_USMALLINT = 12
-	TSDB_DATA_TYPE_UINT      = 13
-	TSDB_DATA_TYPE_UBIGINT   = 14
-	TSDB_DATA_TYPE_JSON      = 15
-	TSDB_DATA_TYPE_VARBINARY = 16
-	TSDB_DATA_TYPE_DECIMAL   = 17
-	TSDB_DATA_TYPE_BLOB      = 18
-	TSDB_DATA_TYPE_MEDIUMBLOB= 19
-	TSDB_DATA_TYPE_GEOMETRY  = 20
+	TSDB_DATA_TYPE_NULL       = 0
+	TSDB_DATA_TYPE_BOOL       = 1
+	TSDB_DATA_TYPE_TINYINT    = 2
+	TSDB_DATA_TYPE_SMALLINT   = 3
+	TSDB_DATA_TYPE_INT        = 4
+	TSDB_DATA_TYPE_BIGINT     = 5
+	TSDB_DATA_TYPE_FLOAT      = 6
+	TSDB_DATA_TYPE_DOUBLE     = 7
+	TSDB_DATA_TYPE_BINARY     = 8
+	TSDB_DATA_TYPE_TIMESTAMP  = 9
+	TSDB_DATA_TYPE_NCHAR      = 10
+	TSDB_DATA_TYPE_UTINYINT   = 11
+	TSDB_DATA_TYPE_USMALLINT  = 12
+	TSDB_DATA_TYPE_UINT       = 13
+	TSDB_DATA_TYPE_UBIGINT    = 14
+	TSDB_DATA_TYPE_JSON       = 15
+	TSDB_DATA_TYPE_VARBINARY  = 16
+	TSDB_DATA_TYPE_DECIMAL    = 17
+	TSDB_DATA_TYPE_BLOB       = 18
+	TSDB_DATA_TYPE_MEDIUMBLOB = 19
+	TSDB_DATA_TYPE_GEOMETRY   = 20
 )
 
 // Error codes matching TDengine
@@ -78,26 +92,28 @@ const (
 	TSDB_CODE_MND_INVALID_TABLE     = 0x80000391
 	TSDB_CODE_TSC_AUTH_FAILURE      = 0x80000357
 	TSDB_CODE_TSC_INVALID_OPERATION = 0x80000356
+	TSDB_CODE_TSC_TOKEN_EXPIRED     = 0x80000358
+	TSDB_CODE_TSC_INSUFFICIENT_PRIV = 0x80000359
 )
 
 // Database represents a TDengine database
 type Database struct {
-	Name        string
-	Precision   string // "ms", "us", "ns"
-	Buffer      int
-	Pages       int
-	PageSize    int
-	MinRows     int
-	MaxRows     int
-	WAL         int
-	Comp        int
-	Replica     int
-	Keep        string // e.g., "3650d,3650d,3650d"
-	CacheModel  string
-	CacheSize   int
-	STables     map[string]*SuperTable
-	Tables      map[string]*Table
-	CreatedAt   time.Time
+	Name       string
+	Precision  string // "ms", "us", "ns"
+	Buffer     int
+	Pages      int
+	PageSize   int
+	MinRows    int
+	MaxRows    int
+	WAL        int
+	Comp       int
+	Replica    int
+	Keep       string // e.g., "3650d,3650d,3650d"
+	CacheModel string
+	CacheSize  int
+	STables    map[string]*SuperTable
+	Tables     map[string]*Table
+	CreatedAt  time.Time
 }
 
 // SuperTable represents a TDengine super table (template)
@@ -159,17 +175,21 @@ const (
 
 // StreamDefinition represents a TDengine stream
 type StreamDefinition struct {
-	Name        string
-	SourceTable string
-	TargetTable string
-	SQL         string
-	Trigger     string // "at_once", "window_close", "max_delay"
-	Watermark   string
+	Name          string
+	Database      string
+	SourceTable   string
+	TargetTable   string
+	SQL           string
+	Trigger       string // "at_once", "window_close", "max_delay"
+	MaxDelay      string // duration, only set when Trigger is "max_delay"
+	Watermark     string
+	Interval      string // window size, e.g. "1m"
+	Fill          string // raw FILL(...) spec, e.g. "PREV" or "VALUE 0"
 	IgnoreExpired bool
-	DeleteMark  string
-	FillHistory bool
-	IgnoreUpdate bool
-	CreatedAt   time.Time
+	DeleteMark    string
+	FillHistory   bool
+	IgnoreUpdate  bool
+	CreatedAt     time.Time
 }
 
 // TopicDefinition represents a TDengine topic (for TMQ)
@@ -196,6 +216,11 @@ type InfluxDBLineProtocol struct {
 	Tags        map[string]string
 	Fields      map[string]interface{}
 	Timestamp   int64
+	// SubtableKey identifies the subtable this point belongs to: a hash of
+	// Measurement plus its sorted tag set, so that points sharing the same
+	// tags always land in the same subtable regardless of the order tags
+	// appeared on the line.
+	SubtableKey string
 }
 
 // QueryPlan represents a query execution plan
@@ -206,11 +231,11 @@ type QueryPlan struct {
 
 // PlanNode represents a node in the query plan
 type PlanNode struct {
-	ID        int
-	Name      string
-	NodeType  string
-	Cost      float64
-	Rows      int64
-	Width     int
-	Children  []int
+	ID       int
+	Name     string
+	NodeType string
+	Cost     float64
+	Rows     int64
+	Width    int
+	Children []int
 }
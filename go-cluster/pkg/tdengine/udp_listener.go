@@ -0,0 +1,229 @@
+package tdengine
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// UDPPrecision is the timestamp unit a UDPListener's incoming datagrams are
+// written in, matching the precision query parameter InfluxDBWrite accepts.
+type UDPPrecision string
+
+const (
+	UDPPrecisionNanoseconds  UDPPrecision = "ns"
+	UDPPrecisionMicroseconds UDPPrecision = "us"
+	UDPPrecisionMilliseconds UDPPrecision = "ms"
+	UDPPrecisionSeconds      UDPPrecision = "s"
+)
+
+const (
+	defaultUDPBatchSize    = 1000
+	defaultUDPBatchTimeout = 1 * time.Second
+	udpQueueSize           = 1024
+	udpReadBufferSize      = 64 * 1024
+)
+
+// UDPListenerConfig configures a UDPListener.
+type UDPListenerConfig struct {
+	// Addr is the UDP address to bind, e.g. ":8089" (InfluxDB's own UDP
+	// line-protocol default port).
+	Addr string
+	// Database is the target database every datagram is written into.
+	Database string
+	// AutoCreate, when true, issues CREATE DATABASE IF NOT EXISTS for
+	// Database once before Serve starts reading datagrams.
+	AutoCreate bool
+	// Precision is the timestamp unit incoming points are written in.
+	// Defaults to UDPPrecisionNanoseconds when empty.
+	Precision UDPPrecision
+	// BatchSize flushes accumulated points once this many are queued.
+	// Defaults to 1000 when <= 0.
+	BatchSize int
+	// BatchTimeout flushes whatever is queued, even a partial batch, once
+	// this long has elapsed since the last flush. Defaults to 1s when <= 0.
+	BatchTimeout time.Duration
+}
+
+// UDPStats are the backpressure counters a UDPListener exposes.
+type UDPStats struct {
+	// PacketsDropped counts datagrams discarded because the internal queue
+	// between the UDP reader and the batching loop was full - backpressure
+	// from a batch flush running behind the incoming rate.
+	PacketsDropped int64
+	// LastFlushLatency is how long the most recently completed batch flush
+	// took to write every point in it to the engine.
+	LastFlushLatency time.Duration
+}
+
+// UDPListener ingests InfluxDB line protocol over UDP, parallel to the
+// /influxdb/v1/write HTTP endpoint InfluxDBWrite answers: every datagram is
+// parsed as one or more line-protocol lines and queued, then flushed as a
+// batch into the same super-table auto-creation path (engine.WriteInfluxDB)
+// the HTTP handler uses. Unlike the HTTP path, UDP has no response channel
+// back to the sender, so parse and write errors are counted rather than
+// returned - the same fire-and-forget contract InfluxDB's own UDP listener
+// makes. Call Serve in its own goroutine; it blocks until Close is called.
+type UDPListener struct {
+	engine       *Engine
+	db           string
+	autoCreate   bool
+	precision    UDPPrecision
+	batchSize    int
+	batchTimeout time.Duration
+
+	conn  net.PacketConn
+	queue chan []byte
+	done  chan struct{}
+
+	packetsDropped        int64
+	lastFlushLatencyNanos int64
+}
+
+// NewUDPListener binds cfg.Addr and returns a listener ready for Serve.
+func NewUDPListener(engine *Engine, cfg UDPListenerConfig) (*UDPListener, error) {
+	if cfg.Database == "" {
+		return nil, fmt.Errorf("tdengine: UDPListener requires a database")
+	}
+
+	precision := cfg.Precision
+	if precision == "" {
+		precision = UDPPrecisionNanoseconds
+	}
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultUDPBatchSize
+	}
+	batchTimeout := cfg.BatchTimeout
+	if batchTimeout <= 0 {
+		batchTimeout = defaultUDPBatchTimeout
+	}
+
+	conn, err := net.ListenPacket("udp", cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UDPListener{
+		engine:       engine,
+		db:           cfg.Database,
+		autoCreate:   cfg.AutoCreate,
+		precision:    precision,
+		batchSize:    batchSize,
+		batchTimeout: batchTimeout,
+		conn:         conn,
+		queue:        make(chan []byte, udpQueueSize),
+		done:         make(chan struct{}),
+	}, nil
+}
+
+// Serve auto-creates the target database (if configured to), then reads
+// datagrams until Close is called, handing each off to the batching loop.
+// A full queue drops the datagram rather than blocking the reader, so a
+// slow flush can't cause the kernel's own UDP receive buffer to back up and
+// drop packets less predictably.
+func (l *UDPListener) Serve() error {
+	if l.autoCreate {
+		l.ensureDatabase()
+	}
+
+	go l.batchLoop()
+
+	buf := make([]byte, udpReadBufferSize)
+	for {
+		n, _, err := l.conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-l.done:
+				return nil
+			default:
+				return err
+			}
+		}
+
+		datagram := make([]byte, n)
+		copy(datagram, buf[:n])
+		l.enqueue(datagram)
+	}
+}
+
+// enqueue hands datagram to the batching loop, dropping it and counting the
+// drop if the queue is full.
+func (l *UDPListener) enqueue(datagram []byte) {
+	select {
+	case l.queue <- datagram:
+	default:
+		atomic.AddInt64(&l.packetsDropped, 1)
+	}
+}
+
+// Close stops Serve and the batching loop, flushing nothing still queued.
+func (l *UDPListener) Close() error {
+	close(l.done)
+	return l.conn.Close()
+}
+
+// Stats returns a snapshot of the listener's backpressure counters.
+func (l *UDPListener) Stats() UDPStats {
+	return UDPStats{
+		PacketsDropped:   atomic.LoadInt64(&l.packetsDropped),
+		LastFlushLatency: time.Duration(atomic.LoadInt64(&l.lastFlushLatencyNanos)),
+	}
+}
+
+// ensureDatabase issues CREATE DATABASE IF NOT EXISTS for l.db, best-effort:
+// UDP ingestion has no response channel to report the failure through, so a
+// create error just leaves points failing to write until the database is
+// created some other way.
+func (l *UDPListener) ensureDatabase() {
+	l.engine.Execute(l.db, fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", l.db), &ExecuteOptions{})
+}
+
+// batchLoop parses queued datagrams into line-protocol points, flushing
+// once BatchSize points have accumulated or BatchTimeout elapses since the
+// last flush, whichever comes first.
+func (l *UDPListener) batchLoop() {
+	ticker := time.NewTicker(l.batchTimeout)
+	defer ticker.Stop()
+
+	var batch []*InfluxDBLineProtocol
+	for {
+		select {
+		case <-l.done:
+			return
+		case datagram := <-l.queue:
+			for _, line := range strings.Split(string(datagram), "\n") {
+				line = strings.TrimSpace(line)
+				if line == "" || strings.HasPrefix(line, "#") {
+					continue
+				}
+				parsed, lerr := parseInfluxDBLine(1, line, string(l.precision))
+				if lerr != nil {
+					continue
+				}
+				batch = append(batch, parsed)
+			}
+			if len(batch) >= l.batchSize {
+				l.flush(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				l.flush(batch)
+				batch = nil
+			}
+		}
+	}
+}
+
+// flush writes every point in batch to the engine, recording how long the
+// write took.
+func (l *UDPListener) flush(batch []*InfluxDBLineProtocol) {
+	start := time.Now()
+	for _, p := range batch {
+		l.engine.WriteInfluxDB(l.db, p)
+	}
+	atomic.StoreInt64(&l.lastFlushLatencyNanos, int64(time.Since(start)))
+}
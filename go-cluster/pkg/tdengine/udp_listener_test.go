@@ -0,0 +1,76 @@
+package tdengine
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestUDPListener_AutoCreatesDatabaseAndIngests(t *testing.T) {
+	engine := NewEngine()
+
+	l, err := NewUDPListener(engine, UDPListenerConfig{
+		Addr:         "127.0.0.1:0",
+		Database:     "udp_test",
+		AutoCreate:   true,
+		BatchSize:    10,
+		BatchTimeout: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewUDPListener() error = %v", err)
+	}
+	defer l.Close()
+
+	go l.Serve()
+
+	conn, err := net.Dial("udp", l.conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("net.Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("cpu,host=a value=1 1000000000\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, ok := engine.databases["udp_test"]; ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("database udp_test was never auto-created from the UDP datagram")
+}
+
+func TestUDPListener_DropsPacketsWhenQueueIsFull(t *testing.T) {
+	engine := NewEngine()
+
+	l, err := NewUDPListener(engine, UDPListenerConfig{
+		Addr:     "127.0.0.1:0",
+		Database: "udp_backpressure",
+	})
+	if err != nil {
+		t.Fatalf("NewUDPListener() error = %v", err)
+	}
+	defer l.Close()
+
+	// Fill the queue directly, without running Serve/batchLoop to drain it,
+	// so the next enqueue is guaranteed to find it full.
+	for i := 0; i < udpQueueSize; i++ {
+		l.queue <- []byte("cpu value=1 1000000000")
+	}
+
+	l.enqueue([]byte("cpu value=2 1000000000"))
+
+	if got := l.Stats().PacketsDropped; got != 1 {
+		t.Errorf("PacketsDropped = %d, want 1", got)
+	}
+}
+
+func TestNewUDPListener_RequiresDatabase(t *testing.T) {
+	engine := NewEngine()
+	if _, err := NewUDPListener(engine, UDPListenerConfig{Addr: "127.0.0.1:0"}); err == nil {
+		t.Fatal("NewUDPListener() error = nil, want error for empty Database")
+	}
+}